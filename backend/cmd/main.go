@@ -11,6 +11,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/mplaczek99/SkillSwap/config"
 	"github.com/mplaczek99/SkillSwap/controllers"
+	"github.com/mplaczek99/SkillSwap/middleware"
 	"github.com/mplaczek99/SkillSwap/models"
 	"github.com/mplaczek99/SkillSwap/repositories"
 	"github.com/mplaczek99/SkillSwap/routes"
@@ -45,10 +46,29 @@ func main() {
 	authService := services.NewAuthService(userRepo)
 	authController := controllers.NewAuthController(authService)
 
-	// 7) Set up the Gin router
+	// 7) Set up the search index
+	searchIndex := setupSearchIndex(appConfig, db)
+
+	// 8) Alert users about new matches of their saved searches
+	mailer := newMailer(appConfig)
+	alerter := services.NewSavedSearchAlerter(db, searchIndex, mailer)
+	go alerter.Run(appConfig.SavedSearchInterval, appConfig.DigestInterval, nil)
+
+	// 9) Remind participants of their upcoming sessions
+	reminders := services.NewReminderScheduler(db, mailer, appConfig.ReminderLeadTimes)
+	go reminders.Run(appConfig.ReminderInterval, nil)
+
+	// 10) Release session payments held in escrow that weren't confirmed in time
+	escrow := services.NewEscrowService(db, appConfig.EscrowReleaseAfter)
+	go escrow.Run(appConfig.EscrowInterval, nil)
+
+	// 11) Forget responses kept for retried requests once their retention ends
+	go services.NewIdempotencyKeyPurger(db).Run(appConfig.IdempotencyPurgeInterval, nil)
+
+	// 12) Set up the Gin router
 	router := gin.Default()
 
-	// 8) Enable CORS middleware with configuration from appConfig
+	// 13) Enable CORS middleware with configuration from appConfig
 	corsConfig := cors.DefaultConfig()
 
 	if appConfig.Environment == "production" {
@@ -74,28 +94,29 @@ func main() {
 
 	// Common CORS settings
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Authorization", "Content-Type", "Origin", "Accept", "X-Requested-With"}
-	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Type"}
+	corsConfig.AllowHeaders = []string{"Authorization", "Content-Type", "Origin", "Accept", "X-Requested-With", middleware.IdempotencyKeyHeader}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Type", "Idempotent-Replayed"}
 	corsConfig.MaxAge = appConfig.CORSMaxAge
 
 	router.Use(cors.New(corsConfig))
 
-	// 9) Add database to the gin context for controllers
+	// 14) Add database and search index to the gin context for controllers
 	router.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		c.Set("search_index", searchIndex)
 		c.Next()
 	})
 
-	// 10) Swagger route
+	// 15) Swagger route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// 11) Setup routes
+	// 16) Setup routes
 	routes.SetupRoutes(router, authController)
 
-	// 12) Create uploads directory if it doesn't exist
+	// 17) Create uploads directory if it doesn't exist
 	os.MkdirAll("./uploads", os.ModePerm)
 
-	// 13) Start the server
+	// 18) Start the server
 	addr := fmt.Sprintf(":%s", appConfig.ServerPort)
 	log.Printf("Server starting on port %s\n", appConfig.ServerPort)
 	log.Printf("CORS configuration: AllowAllOrigins=%v, AllowedOrigins=%v",
@@ -105,6 +126,56 @@ func main() {
 	}
 }
 
+// setupSearchIndex creates the configured search index. The embedded index is
+// loaded from its last snapshot so search works immediately, then rebuilt from
+// the database in the background to pick up anything the snapshot missed.
+func setupSearchIndex(appConfig *config.AppConfig, db *gorm.DB) services.SearchIndex {
+	if appConfig.SearchBackend != services.SearchBackendMemory {
+		return services.NewDatabaseSearchIndex(db)
+	}
+
+	index := services.NewMemorySearchIndex()
+	if err := services.RegisterSearchIndexCallbacks(db, index); err != nil {
+		log.Fatalf("Failed to register search index callbacks: %v", err)
+	}
+
+	rebuild := func() {
+		if err := index.Rebuild(db); err != nil {
+			log.Printf("Failed to rebuild search index: %v", err)
+			return
+		}
+		log.Printf("Search index rebuilt with %d documents", index.Len())
+	}
+
+	if err := index.LoadSnapshot(appConfig.SearchIndexPath); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Ignoring search index snapshot: %v", err)
+		}
+		rebuild()
+	} else {
+		log.Printf("Search index loaded %d documents from %s", index.Len(), appConfig.SearchIndexPath)
+		go rebuild()
+	}
+
+	go index.RunSnapshots(appConfig.SearchIndexPath, appConfig.SearchSnapshotInterval, nil)
+	return index
+}
+
+// newMailer returns an SMTP mailer when a host is configured, otherwise one
+// that writes email to the log.
+func newMailer(appConfig *config.AppConfig) services.Mailer {
+	if appConfig.SMTPHost == "" {
+		return services.LogMailer{}
+	}
+	return services.SMTPMailer{
+		Host:     appConfig.SMTPHost,
+		Port:     appConfig.SMTPPort,
+		Username: appConfig.SMTPUsername,
+		Password: appConfig.SMTPPassword,
+		From:     appConfig.SMTPFrom,
+	}
+}
+
 // seedTestUsers creates test users if they don't already exist
 func seedTestUsers(db *gorm.DB) {
 	testUsers := []struct {
@@ -126,7 +197,7 @@ func seedTestUsers(db *gorm.DB) {
 					Email:    testUser.email,
 					Password: testUser.password,
 				}
-				if err := db.Create(&newUser).Error; err != nil {
+				if err := repositories.NewUserRepository(db).CreateUser(&newUser); err != nil {
 					log.Printf("Failed to create test user: %v", err)
 				} else {
 					log.Printf("Test user created: %s / %s", testUser.email, testUser.password)
@@ -29,6 +29,33 @@ type AppConfig struct {
 	CORSAllowAll       bool
 	CORSMaxAge         time.Duration
 
+	// Search settings
+	SearchBackend          string // "postgres" or "memory"
+	SearchIndexPath        string // where the memory backend snapshots its index
+	SearchSnapshotInterval time.Duration
+
+	// Saved search alert settings
+	SavedSearchInterval time.Duration // how often saved searches are evaluated
+	DigestInterval      time.Duration // how often email digests are sent
+
+	// Session reminder settings
+	ReminderInterval  time.Duration   // how often due reminders are sent
+	ReminderLeadTimes []time.Duration // how long before sessions their participants are reminded
+
+	// Escrow settings
+	EscrowReleaseAfter time.Duration // how long after a session ends its payment is released without both confirming
+	EscrowInterval     time.Duration // how often due payments are released
+
+	// How often idempotency keys past their retention are deleted
+	IdempotencyPurgeInterval time.Duration
+
+	// Email settings; without an SMTP host, email is written to the log
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
 	// Environment setting
 	Environment string
 }
@@ -44,6 +71,21 @@ func LoadConfig() *AppConfig {
 		CORSAllowAll:       false,
 		CORSMaxAge:         12 * time.Hour,
 		Environment:        "development", // Default to development
+
+		SearchBackend:          searchBackend(),
+		SearchIndexPath:        "./data/search_index.json",
+		SearchSnapshotInterval: time.Minute,
+
+		SavedSearchInterval: 5 * time.Minute,
+		DigestInterval:      24 * time.Hour,
+		ReminderInterval:    time.Minute,
+		ReminderLeadTimes:   []time.Duration{24 * time.Hour, 15 * time.Minute},
+		EscrowReleaseAfter:  72 * time.Hour,
+		EscrowInterval:      10 * time.Minute,
+		SMTPPort:            "587",
+		SMTPFrom:            "SkillSwap <no-reply@skillswap.local>",
+
+		IdempotencyPurgeInterval: time.Hour,
 	}
 
 	// Read environment from env var
@@ -89,6 +131,35 @@ func LoadConfig() *AppConfig {
 		}
 	}
 
+	if path := os.Getenv("SEARCH_INDEX_PATH"); path != "" {
+		config.SearchIndexPath = path
+	}
+
+	config.SearchSnapshotInterval = durationEnv("SEARCH_SNAPSHOT_INTERVAL", config.SearchSnapshotInterval)
+	config.SavedSearchInterval = durationEnv("SAVED_SEARCH_INTERVAL", config.SavedSearchInterval)
+	config.DigestInterval = durationEnv("DIGEST_INTERVAL", config.DigestInterval)
+	config.ReminderInterval = durationEnv("REMINDER_INTERVAL", config.ReminderInterval)
+	config.ReminderLeadTimes = durationsEnv("REMINDER_LEAD_TIMES", config.ReminderLeadTimes)
+	config.EscrowReleaseAfter = durationEnv("ESCROW_RELEASE_AFTER", config.EscrowReleaseAfter)
+	config.EscrowInterval = durationEnv("ESCROW_INTERVAL", config.EscrowInterval)
+	config.IdempotencyPurgeInterval = durationEnv("IDEMPOTENCY_PURGE_INTERVAL", config.IdempotencyPurgeInterval)
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		config.SMTPHost = host
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		config.SMTPPort = port
+	}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		config.SMTPUsername = username
+	}
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		config.SMTPPassword = password
+	}
+	if from := os.Getenv("SMTP_FROM"); from != "" {
+		config.SMTPFrom = from
+	}
+
 	// Validate critical configuration
 	if config.JWTSecret == "" {
 		log.Fatal("JWT_SECRET environment variable is required")
@@ -116,14 +187,82 @@ func ConnectDB() *gorm.DB {
 
 // Migrate runs AutoMigrate on your models.
 func Migrate(db *gorm.DB) {
+	renameScheduleColumns(db)
+	dropEscrowScheduleIndex(db)
+
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Skill{},
 		&models.Transaction{},
 		&models.Schedule{},
+		&models.ScheduleSeries{},
+		&models.ScheduleEvent{},
+		&models.Availability{},
+		&models.BusyBlock{},
+		&models.SessionReminder{},
+		&models.Escrow{},
+		&models.Workshop{},
+		&models.WorkshopSeat{},
 		&models.Job{}, // Add Job model to migrations
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.LedgerAccount{},
+		&models.LedgerJournal{},
+		&models.LedgerEntry{},
+		&models.IdempotencyKey{},
+		&models.TransactionDispute{},
+		&models.DisputeMessage{},
+		&models.DisputeEvidence{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
+
+	migrateSchedules(db)
+	migrateSearch(db)
+	migrateLedger(db)
+}
+
+// durationEnv reads a positive duration such as "5m" from an environment
+// variable, keeping the default when it is unset or invalid.
+func durationEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("WARNING: Invalid %s %q, using %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// durationsEnv reads a comma-separated list of positive durations such as
+// "24h,15m" from an environment variable, keeping the default when it is unset
+// or any of them is invalid.
+func durationsEnv(name string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	var parsed []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			log.Printf("WARNING: Invalid %s %q, using %v", name, value, defaultValue)
+			return defaultValue
+		}
+		parsed = append(parsed, d)
+	}
+	return parsed
+}
+
+// searchBackend returns the configured search backend: "memory" for the embedded
+// index, otherwise "postgres" for full-text search in the database.
+func searchBackend() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("SEARCH_BACKEND")), "memory") {
+		return "memory"
+	}
+	return "postgres"
 }
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+// migrateLedger opens ledger accounts for balances from before the ledger,
+// then checks the ledger against itself and the users' cached skill_points.
+// Disagreements are logged rather than fixed, as the ledger is append-only
+// and only a person can tell which side is wrong.
+func migrateLedger(db *gorm.DB) {
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	if err := ledgerRepo.OpenBalances(time.Now()); err != nil {
+		log.Fatalf("Failed to open ledger balances: %v", err)
+	}
+
+	unbalanced, err := ledgerRepo.UnbalancedJournals()
+	if err != nil {
+		log.Printf("WARNING: Failed to check ledger journals: %v", err)
+	} else if len(unbalanced) > 0 {
+		log.Printf("WARNING: Ledger journals that don't balance: %v", unbalanced)
+	}
+
+	mismatches, err := ledgerRepo.BalanceMismatches()
+	if err != nil {
+		log.Printf("WARNING: Failed to check balances against the ledger: %v", err)
+		return
+	}
+	for _, mismatch := range mismatches {
+		log.Printf("WARNING: User %d has %d skill_points but %d in the ledger",
+			mismatch.UserID, mismatch.SkillPoints, mismatch.Ledger)
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"log"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+)
+
+// renameScheduleColumns renames the schedules' user_id column, which held the
+// learner, to learner_id before AutoMigrate would add an empty learner_id.
+func renameScheduleColumns(db *gorm.DB) {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Schedule{}) || !migrator.HasColumn(&models.Schedule{}, "user_id") ||
+		migrator.HasColumn(&models.Schedule{}, "learner_id") {
+		return
+	}
+	if err := migrator.RenameColumn(&models.Schedule{}, "user_id", "learner_id"); err != nil {
+		log.Fatalf("Failed to rename schedules.user_id: %v", err)
+	}
+}
+
+// dropEscrowScheduleIndex drops the unique index escrows had on schedule_id
+// alone, from before workshop seats were paid in escrow too, so AutoMigrate
+// can replace it with one on the session or seat paid for.
+func dropEscrowScheduleIndex(db *gorm.DB) {
+	migrator := db.Migrator()
+	if !migrator.HasIndex(&models.Escrow{}, "idx_escrows_schedule_id") {
+		return
+	}
+	if err := migrator.DropIndex(&models.Escrow{}, "idx_escrows_schedule_id"); err != nil {
+		log.Fatalf("Failed to drop escrows.idx_escrows_schedule_id: %v", err)
+	}
+}
+
+// scheduleMigrations fill in the teacher of sessions booked before schedules
+// recorded it, then add exclusion constraints so Postgres itself rejects two
+// overlapping confirmed sessions for the same learner or the same teacher.
+// Requests may overlap until one of them is accepted.
+var scheduleMigrations = []string{
+	`UPDATE schedules SET teacher_id = skills.user_id
+	FROM skills WHERE schedules.skill_id = skills.id AND schedules.teacher_id = 0`,
+
+	`CREATE EXTENSION IF NOT EXISTS btree_gist`,
+
+	// These covered every session, before requests and cancellations existed
+	`ALTER TABLE schedules DROP CONSTRAINT IF EXISTS schedules_learner_no_overlap`,
+	`ALTER TABLE schedules DROP CONSTRAINT IF EXISTS schedules_teacher_no_overlap`,
+}
+
+// scheduleConstraints are added only when missing, as ALTER TABLE has no IF NOT EXISTS for constraints
+var scheduleConstraints = map[string]string{
+	"schedules_learner_confirmed_no_overlap": `ALTER TABLE schedules ADD CONSTRAINT schedules_learner_confirmed_no_overlap
+		EXCLUDE USING gist (learner_id WITH =, tstzrange(start_time, end_time) WITH &&) WHERE (status = 'confirmed')`,
+	"schedules_teacher_confirmed_no_overlap": `ALTER TABLE schedules ADD CONSTRAINT schedules_teacher_confirmed_no_overlap
+		EXCLUDE USING gist (teacher_id WITH =, tstzrange(start_time, end_time) WITH &&) WHERE (status = 'confirmed' AND teacher_id <> 0)`,
+}
+
+// migrateSchedules adds the schedules' no-overlap constraints on Postgres.
+// Bookings are already checked for conflicts under advisory locks, so when
+// existing overlapping sessions or a missing btree_gist extension prevent a
+// constraint, the failure is logged rather than stopping the server.
+func migrateSchedules(db *gorm.DB) {
+	if db.Dialector.Name() != "postgres" {
+		log.Println("Skipping schedule constraints: database is not Postgres")
+		return
+	}
+
+	for _, stmt := range scheduleMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Printf("Skipping schedule constraints: %v", err)
+			return
+		}
+	}
+
+	for name, stmt := range scheduleConstraints {
+		var count int64
+		if err := db.Raw("SELECT count(*) FROM pg_constraint WHERE conname = ?", name).Scan(&count).Error; err != nil {
+			log.Printf("Failed to check schedule constraint %s: %v", name, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Printf("Failed to add schedule constraint %s: %v", name, err)
+		}
+	}
+}
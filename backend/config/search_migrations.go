@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// searchMigrations add weighted tsvector columns and GIN indexes used by full-text search,
+// plus pg_trgm indexes for fuzzy matching. The tsvector columns are generated by Postgres,
+// so they stay current without application code.
+var searchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(bio, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+
+	`ALTER TABLE skills ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_skills_search_vector ON skills USING GIN (search_vector)`,
+
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(jsonb_to_tsvector('english', coalesce(skills_required, '[]'::jsonb), '["string"]'), 'A') ||
+		setweight(to_tsvector('english', coalesce(company, '') || ' ' || coalesce(location, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_search_vector ON jobs USING GIN (search_vector)`,
+
+	// Trigram indexes back typo-tolerant matching and prefix suggestions
+	`CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_skills_name_trgm ON skills USING GIN (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_title_trgm ON jobs USING GIN (title gin_trgm_ops)`,
+}
+
+// migrateSearch creates the full-text search columns and indexes on Postgres.
+// Deployments using the embedded search index may lack pg_trgm, so they skip it.
+func migrateSearch(db *gorm.DB) {
+	if searchBackend() == "memory" {
+		log.Println("Skipping full-text search migrations: using the embedded search index")
+		return
+	}
+	if db.Dialector.Name() != "postgres" {
+		log.Println("Skipping full-text search migrations: database is not Postgres")
+		return
+	}
+
+	for _, stmt := range searchMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Fatalf("Failed to migrate search indexes: %v", err)
+		}
+	}
+}
@@ -25,6 +25,7 @@ type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	TimeZone string `json:"time_zone"` // IANA time zone; UTC when omitted
 }
 
 // RegisterResponse defines the response after successful registration.
@@ -47,10 +48,18 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		return
 	}
 
+	if req.TimeZone != "" {
+		if err := models.ValidateTimeZone(req.TimeZone); err != nil {
+			utils.JSONError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	user := &models.User{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: req.Password,
+		TimeZone: req.TimeZone,
 	}
 
 	token, err := c.AuthService.Register(user)
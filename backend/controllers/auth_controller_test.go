@@ -142,6 +142,28 @@ func TestAuthController_Register(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid Time Zone", func(t *testing.T) {
+		router := gin.New()
+		router.POST("/register", controller.Register)
+
+		reqBody := `{
+			"name": "Test User",
+			"email": "success@example.com",
+			"password": "password123",
+			"time_zone": "Mars/Olympus"
+		}`
+
+		req, _ := http.NewRequest("POST", "/register", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
 	t.Run("Invalid Request Body", func(t *testing.T) {
 		router := gin.New()
 		router.POST("/register", controller.Register)
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// maxSlotRange is the longest span open slots are listed for at once
+const maxSlotRange = 31 * 24 * time.Hour
+
+// AvailabilityRequest defines the fields of an availability window a teacher can set.
+type AvailabilityRequest struct {
+	SkillID        *uint    `json:"skill_id"`  // omit to cover all the teacher's skills
+	TimeZone       string   `json:"time_zone"` // omit to use the teacher's time zone
+	StartDate      string   `json:"start_date" binding:"required"`
+	StartsAt       string   `json:"starts_at" binding:"required"`
+	EndsAt         string   `json:"ends_at" binding:"required"`
+	RRule          string   `json:"rrule"`
+	ExceptionDates []string `json:"exception_dates"`
+	BufferMinutes  int      `json:"buffer_minutes"`
+}
+
+// apply copies the request onto an availability window and validates it.
+// Windows without a time zone use the one defaultZone returns.
+func (req AvailabilityRequest) apply(availability *models.Availability, defaultZone func() string) error {
+	availability.SkillID = req.SkillID
+	availability.TimeZone = req.TimeZone
+	if availability.TimeZone == "" {
+		availability.TimeZone = defaultZone()
+	}
+	availability.StartDate = req.StartDate
+	availability.StartsAt = req.StartsAt
+	availability.EndsAt = req.EndsAt
+	availability.RRule = req.RRule
+	availability.ExceptionDates = models.StringArray(req.ExceptionDates)
+	if availability.ExceptionDates == nil {
+		availability.ExceptionDates = models.StringArray{}
+	}
+	availability.BufferMinutes = req.BufferMinutes
+	return availability.Validate()
+}
+
+// GetAvailability lists the authenticated teacher's availability windows.
+func GetAvailability(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	availability, err := repositories.NewAvailabilityRepository(db.(*gorm.DB)).GetAvailabilityByTeacher(userID.(uint))
+	if err != nil {
+		utils.Error("Failed to retrieve availability: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve availability")
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// CreateAvailability adds an availability window for the authenticated teacher.
+func CreateAvailability(c *gin.Context) {
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid availability data")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	availability := models.Availability{TeacherID: userID.(uint)}
+	if err := req.apply(&availability, func() string { return viewerZone(c) }); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	if !checkOwnSkill(c, db.(*gorm.DB), availability.SkillID, userID.(uint)) {
+		return
+	}
+
+	if err := repositories.NewAvailabilityRepository(db.(*gorm.DB)).CreateAvailability(&availability); err != nil {
+		utils.Error("Failed to create availability: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to create availability")
+		return
+	}
+
+	c.JSON(http.StatusCreated, availability)
+}
+
+// UpdateAvailability replaces one of the authenticated teacher's availability windows.
+func UpdateAvailability(c *gin.Context) {
+	var req AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid availability data")
+		return
+	}
+
+	availability, repo, ok := loadOwnAvailability(c)
+	if !ok {
+		return
+	}
+
+	if err := req.apply(availability, func() string { return viewerZone(c) }); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !checkOwnSkill(c, repo.DB, availability.SkillID, availability.TeacherID) {
+		return
+	}
+
+	if err := repo.UpdateAvailability(availability); err != nil {
+		utils.Error("Failed to update availability: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update availability")
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// DeleteAvailability deletes one of the authenticated teacher's availability windows.
+func DeleteAvailability(c *gin.Context) {
+	availability, repo, ok := loadOwnAvailability(c)
+	if !ok {
+		return
+	}
+
+	if err := repo.DeleteAvailability(availability.ID); err != nil {
+		utils.Error("Failed to delete availability: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to delete availability")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Availability deleted successfully"})
+}
+
+// GetUserSlots lists the open slots of a teacher's skill.
+// Parameters: skill, from and to (RFC 3339, at most 31 days apart), and
+// optionally duration in minutes, which defaults to the skill's shortest session,
+// and time_zone, the zone slots are shown in. It defaults to the viewer's time
+// zone when logged in, and the teacher's otherwise.
+func GetUserSlots(c *gin.Context) {
+	teacherID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	skillID, err := strconv.ParseUint(c.Query("skill"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid skill")
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid from time, expected RFC 3339")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid to time, expected RFC 3339")
+		return
+	}
+	if !to.After(from) {
+		utils.JSONError(c, http.StatusBadRequest, "to must be after from")
+		return
+	}
+	if to.Sub(from) > maxSlotRange {
+		utils.JSONError(c, http.StatusBadRequest, "The range cannot be longer than 31 days")
+		return
+	}
+
+	var loc *time.Location
+	if zone := c.Query("time_zone"); zone != "" {
+		if err := models.ValidateTimeZone(zone); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		loc, _ = time.LoadLocation(zone)
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	teacher, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByID(uint(teacherID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.Error("Failed to retrieve user: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+	_, authenticated := c.Get("user_id")
+	if !teacher.VisibleTo(authenticated) {
+		utils.JSONError(c, http.StatusUnauthorized, "Log in to view this profile")
+		return
+	}
+
+	skill, err := repositories.NewSkillRepository(db.(*gorm.DB)).GetSkillByID(uint(skillID))
+	if err != nil || skill.UserID != teacher.ID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Skill not found")
+			return
+		}
+		utils.Error("Failed to retrieve skill: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve skill")
+		return
+	}
+
+	if loc == nil {
+		loc = teacher.Location()
+		if authenticated {
+			loc = viewerLocation(c, db.(*gorm.DB))
+		}
+	}
+
+	minMinutes, maxMinutes := skill.SessionLimits()
+	minutes := minMinutes
+	if param := c.Query("duration"); param != "" {
+		minutes, err = strconv.Atoi(param)
+		if err != nil || minutes < minMinutes || minutes > maxMinutes {
+			utils.JSONError(c, http.StatusBadRequest, "duration must be between "+strconv.Itoa(minMinutes)+" and "+strconv.Itoa(maxMinutes)+" minutes")
+			return
+		}
+	}
+
+	slots, err := services.NewAvailabilityService(db.(*gorm.DB)).
+		OpenSlots(teacher.ID, skill.ID, from, to, time.Duration(minutes)*time.Minute, time.Now())
+	if err != nil {
+		utils.Error("Failed to compute open slots: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to compute open slots")
+		return
+	}
+
+	for i := range slots {
+		slots[i] = slots[i].In(loc)
+	}
+	c.JSON(http.StatusOK, slots)
+}
+
+// viewerZone returns the name of the authenticated user's time zone
+func viewerZone(c *gin.Context) string {
+	db, exists := c.Get("db")
+	if !exists {
+		return models.DefaultTimeZone
+	}
+	return viewerLocation(c, db.(*gorm.DB)).String()
+}
+
+// checkOwnSkill checks an availability window's skill, if any, is offered by
+// the teacher. It writes the error response when it fails.
+func checkOwnSkill(c *gin.Context, db *gorm.DB, skillID *uint, teacherID uint) bool {
+	if skillID == nil {
+		return true
+	}
+
+	skill, err := repositories.NewSkillRepository(db).GetSkillByID(*skillID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error("Failed to retrieve skill: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve skill")
+		return false
+	}
+	if err != nil || skill.UserID != teacherID {
+		utils.JSONError(c, http.StatusBadRequest, "You can only set availability for your own skills")
+		return false
+	}
+	return true
+}
+
+// loadOwnAvailability loads the availability window named by the id parameter and
+// checks the authenticated user owns it. It writes the error response when it fails.
+func loadOwnAvailability(c *gin.Context) (*models.Availability, *repositories.AvailabilityRepository, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid availability ID")
+		return nil, nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return nil, nil, false
+	}
+
+	repo := repositories.NewAvailabilityRepository(db.(*gorm.DB))
+	availability, err := repo.GetAvailabilityByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Availability not found")
+			return nil, nil, false
+		}
+		utils.Error("Failed to retrieve availability: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve availability")
+		return nil, nil, false
+	}
+
+	if availability.TeacherID != userID.(uint) {
+		utils.JSONError(c, http.StatusNotFound, "Availability not found")
+		return nil, nil, false
+	}
+
+	return availability, repo, true
+}
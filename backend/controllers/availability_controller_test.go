@@ -0,0 +1,94 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestCreateAvailability(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"Invalid JSON", `{`, http.StatusBadRequest},
+		{"Missing Fields", `{"time_zone":"UTC"}`, http.StatusBadRequest},
+		{"Unknown Time Zone", `{"time_zone":"Mars/Olympus","start_date":"2026-03-02","starts_at":"09:00","ends_at":"17:00"}`, http.StatusBadRequest},
+		{"Ends Before Start", `{"time_zone":"UTC","start_date":"2026-03-02","starts_at":"17:00","ends_at":"09:00"}`, http.StatusBadRequest},
+		{"Valid Without Database", `{"time_zone":"UTC","start_date":"2026-03-02","starts_at":"09:00","ends_at":"17:00","rrule":"FREQ=WEEKLY"}`, http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/availability", func(c *gin.Context) {
+				c.Set("user_id", uint(1))
+				controllers.CreateAvailability(c)
+			})
+
+			req, _ := http.NewRequest("POST", "/availability", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteAvailabilityInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.DELETE("/availability/:id", controllers.DeleteAvailability)
+
+	req, _ := http.NewRequest("DELETE", "/availability/abc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetUserSlots(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name         string
+		url          string
+		expectedCode int
+	}{
+		{"Invalid User ID", "/users/abc/slots?skill=1&from=2026-03-02T00:00:00Z&to=2026-03-09T00:00:00Z", http.StatusBadRequest},
+		{"Missing Skill", "/users/1/slots?from=2026-03-02T00:00:00Z&to=2026-03-09T00:00:00Z", http.StatusBadRequest},
+		{"Invalid From", "/users/1/slots?skill=1&from=monday&to=2026-03-09T00:00:00Z", http.StatusBadRequest},
+		{"To Before From", "/users/1/slots?skill=1&from=2026-03-09T00:00:00Z&to=2026-03-02T00:00:00Z", http.StatusBadRequest},
+		{"Range Too Long", "/users/1/slots?skill=1&from=2026-03-01T00:00:00Z&to=2026-05-01T00:00:00Z", http.StatusBadRequest},
+		{"Unknown Time Zone", "/users/1/slots?skill=1&from=2026-03-02T00:00:00Z&to=2026-03-09T00:00:00Z&time_zone=Mars/Olympus", http.StatusBadRequest},
+		{"Valid Without Database", "/users/1/slots?skill=1&from=2026-03-02T00:00:00Z&to=2026-03-09T00:00:00Z&time_zone=Europe/Warsaw", http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/users/:id/slots", controllers.GetUserSlots)
+
+			req, _ := http.NewRequest("GET", tc.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
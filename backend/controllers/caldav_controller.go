@@ -0,0 +1,537 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// CalDAVMethods are the methods the CalDAV endpoint answers
+var CalDAVMethods = []string{http.MethodOptions, "PROPFIND", "REPORT", http.MethodGet, http.MethodPut, http.MethodDelete}
+
+// CalDAVPath is where the CalDAV endpoint is served
+const CalDAVPath = "/caldav/"
+
+// calDAVCollection is the name of the calendar collection holding a user's sessions
+const calDAVCollection = "sessions"
+
+// maxCalDAVBody is the largest request body the CalDAV endpoint reads (1MB)
+const maxCalDAVBody = 1 << 20
+
+// XML namespaces of CalDAV responses
+const (
+	davNS       = "DAV:"
+	calDAVNS    = "urn:ietf:params:xml:ns:caldav"
+	calServerNS = "http://calendarserver.org/ns/"
+)
+
+// davMultistatus is a WebDAV multi-status response
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	DAV       string        `xml:"xmlns:D,attr"`
+	CalDAV    string        `xml:"xmlns:C,attr"`
+	CalServer string        `xml:"xmlns:CS,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+// davResponse describes one resource of a multi-status response
+type davResponse struct {
+	Href     string       `xml:"D:href"`
+	Status   string       `xml:"D:status,omitempty"`
+	Propstat *davPropstat `xml:"D:propstat,omitempty"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+// davProp holds the properties of a resource; unset ones are left out
+type davProp struct {
+	ResourceType         *davResourceType `xml:"D:resourcetype,omitempty"`
+	DisplayName          string           `xml:"D:displayname,omitempty"`
+	CurrentUserPrincipal *davHref         `xml:"D:current-user-principal,omitempty"`
+	PrincipalURL         *davHref         `xml:"D:principal-URL,omitempty"`
+	CalendarHomeSet      *davHref         `xml:"C:calendar-home-set,omitempty"`
+	SupportedComponents  *davComponentSet `xml:"C:supported-calendar-component-set,omitempty"`
+	CTag                 string           `xml:"CS:getctag,omitempty"`
+	ETag                 string           `xml:"D:getetag,omitempty"`
+	ContentType          string           `xml:"D:getcontenttype,omitempty"`
+	CalendarData         string           `xml:"C:calendar-data,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+	Principal  *struct{} `xml:"D:principal,omitempty"`
+	Calendar   *struct{} `xml:"C:calendar,omitempty"`
+}
+
+type davHref struct {
+	Href string `xml:"D:href"`
+}
+
+type davComponentSet struct {
+	Components []davComponent `xml:"C:comp"`
+}
+
+type davComponent struct {
+	Name string `xml:"name,attr"`
+}
+
+// calendarReport is what a REPORT request asks for
+type calendarReport struct {
+	Multiget bool // calendar-multiget, for the events named by Hrefs
+	Hrefs    []string
+	Start    time.Time // calendar-query time range; zero when unbounded
+	End      time.Time
+}
+
+// CalDAVWellKnown points calendar clients looking for the CalDAV endpoint to it.
+func CalDAVWellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, CalDAVPath)
+}
+
+// CalDAV serves the authenticated user's sessions as a CalDAV calendar collection.
+// The endpoint is laid out as:
+//
+//	/caldav/                           finds the user's principal
+//	/caldav/:user/                     the user's principal and calendar home
+//	/caldav/:user/sessions/            the calendar of the user's sessions
+//	/caldav/:user/sessions/schedule-1.ics  one session
+//
+// Moving an event in a calendar client proposes the new time, and cancelling or
+// deleting it cancels the session, through the booking rules every change passes.
+func CalDAV(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	if c.Request.Method == http.MethodOptions {
+		c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.String(http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.String(http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	segments := strings.FieldsFunc(c.Param("path"), func(r rune) bool { return r == '/' })
+	if len(segments) > 0 && segments[0] != strconv.FormatUint(uint64(userID.(uint)), 10) {
+		c.String(http.StatusNotFound, "Not found")
+		return
+	}
+
+	dav := &calDAVRequest{c: c, db: db.(*gorm.DB), userID: userID.(uint)}
+	switch {
+	case len(segments) <= 1 && c.Request.Method == "PROPFIND":
+		dav.propfindPrincipal(len(segments) == 0)
+	case len(segments) == 2 && segments[1] == calDAVCollection:
+		dav.serveCollection()
+	case len(segments) == 3 && segments[1] == calDAVCollection:
+		id, ok := services.ParseSessionResourceName(segments[2])
+		if !ok {
+			c.String(http.StatusNotFound, "Not found")
+			return
+		}
+		dav.serveEvent(id)
+	case len(segments) <= 1:
+		c.String(http.StatusMethodNotAllowed, "Method not allowed")
+	default:
+		c.String(http.StatusNotFound, "Not found")
+	}
+}
+
+// calDAVRequest carries what a CalDAV request's handlers share
+type calDAVRequest struct {
+	c      *gin.Context
+	db     *gorm.DB
+	userID uint
+}
+
+func (d *calDAVRequest) principalHref() string {
+	return fmt.Sprintf("%s%d/", CalDAVPath, d.userID)
+}
+
+func (d *calDAVRequest) collectionHref() string {
+	return d.principalHref() + calDAVCollection + "/"
+}
+
+func (d *calDAVRequest) eventHref(id uint) string {
+	return d.collectionHref() + services.SessionResourceName(id)
+}
+
+// depth returns the Depth header of a PROPFIND; "infinity" is treated as 1
+func (d *calDAVRequest) depth() int {
+	if d.c.GetHeader("Depth") == "0" {
+		return 0
+	}
+	return 1
+}
+
+// propfindPrincipal describes the user's principal, which is also their calendar
+// home. The root answers with where the principal is.
+func (d *calDAVRequest) propfindPrincipal(root bool) {
+	principal := &davHref{Href: d.principalHref()}
+	if root {
+		d.multistatus([]davResponse{okResponse(CalDAVPath, davProp{
+			ResourceType:         &davResourceType{Collection: &struct{}{}},
+			CurrentUserPrincipal: principal,
+		})})
+		return
+	}
+
+	user, err := repositories.NewUserRepository(d.db).GetUserByID(d.userID)
+	if err != nil {
+		d.serverError("Failed to retrieve user", err)
+		return
+	}
+
+	responses := []davResponse{okResponse(d.principalHref(), davProp{
+		ResourceType:         &davResourceType{Collection: &struct{}{}, Principal: &struct{}{}},
+		DisplayName:          user.Name,
+		CurrentUserPrincipal: principal,
+		PrincipalURL:         principal,
+		CalendarHomeSet:      principal,
+	})}
+	if d.depth() > 0 {
+		schedules, err := services.NewCalendarService(d.db).Sessions(d.userID, time.Now())
+		if err != nil {
+			d.serverError("Failed to retrieve sessions", err)
+			return
+		}
+		responses = append(responses, d.collectionResponse(schedules))
+	}
+	d.multistatus(responses)
+}
+
+// collectionResponse describes the calendar collection
+func (d *calDAVRequest) collectionResponse(schedules []models.Schedule) davResponse {
+	return okResponse(d.collectionHref(), davProp{
+		ResourceType:         &davResourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+		DisplayName:          "SkillSwap sessions",
+		CurrentUserPrincipal: &davHref{Href: d.principalHref()},
+		SupportedComponents:  &davComponentSet{Components: []davComponent{{Name: "VEVENT"}}},
+		CTag:                 services.CalendarTag(schedules),
+	})
+}
+
+// serveCollection answers requests for the calendar collection
+func (d *calDAVRequest) serveCollection() {
+	calendar := services.NewCalendarService(d.db)
+	schedules, err := calendar.Sessions(d.userID, time.Now())
+	if err != nil {
+		d.serverError("Failed to retrieve sessions", err)
+		return
+	}
+
+	switch d.c.Request.Method {
+	case "PROPFIND":
+		responses := []davResponse{d.collectionResponse(schedules)}
+		if d.depth() > 0 {
+			for i := range schedules {
+				responses = append(responses, d.eventResponse(&schedules[i], ""))
+			}
+		}
+		d.multistatus(responses)
+
+	case "REPORT":
+		d.report(calendar, schedules)
+
+	case http.MethodGet:
+		user, err := repositories.NewUserRepository(d.db).GetUserByID(d.userID)
+		if err != nil {
+			d.serverError("Failed to retrieve user", err)
+			return
+		}
+		feed, err := calendar.Feed(user, time.Now())
+		if err != nil {
+			d.serverError("Failed to build calendar", err)
+			return
+		}
+		d.c.Data(http.StatusOK, calendarContentType, []byte(feed))
+
+	default:
+		d.c.String(http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// report answers calendar-query and calendar-multiget reports with the matching
+// events and their data
+func (d *calDAVRequest) report(calendar *services.CalendarService, schedules []models.Schedule) {
+	body, ok := d.readBody()
+	if !ok {
+		return
+	}
+	report, err := parseCalendarReport(body)
+	if err != nil {
+		d.c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		matched []models.Schedule
+		missing []string
+	)
+	if report.Multiget {
+		byID := make(map[uint]models.Schedule, len(schedules))
+		for _, schedule := range schedules {
+			byID[schedule.ID] = schedule
+		}
+		for _, href := range report.Hrefs {
+			id, ok := services.ParseSessionResourceName(path.Base(href))
+			schedule, found := byID[id]
+			if !ok || !found {
+				missing = append(missing, href)
+				continue
+			}
+			matched = append(matched, schedule)
+		}
+	} else {
+		for _, schedule := range schedules {
+			if (report.End.IsZero() || schedule.StartTime.Before(report.End)) &&
+				(report.Start.IsZero() || schedule.EndTime.After(report.Start)) {
+				matched = append(matched, schedule)
+			}
+		}
+	}
+
+	data, err := calendar.SessionCalendars(matched, d.userID)
+	if err != nil {
+		d.serverError("Failed to build calendar", err)
+		return
+	}
+
+	responses := make([]davResponse, 0, len(matched)+len(missing))
+	for i := range matched {
+		responses = append(responses, d.eventResponse(&matched[i], data[matched[i].ID]))
+	}
+	for _, href := range missing {
+		responses = append(responses, davResponse{Href: href, Status: "HTTP/1.1 404 Not Found"})
+	}
+	d.multistatus(responses)
+}
+
+// eventResponse describes a session's event, with its data when given
+func (d *calDAVRequest) eventResponse(schedule *models.Schedule, data string) davResponse {
+	return okResponse(d.eventHref(schedule.ID), davProp{
+		ResourceType: &davResourceType{},
+		ETag:         services.SessionETag(schedule),
+		ContentType:  "text/calendar; charset=utf-8; component=vevent",
+		CalendarData: data,
+	})
+}
+
+// serveEvent answers requests for one session's event
+func (d *calDAVRequest) serveEvent(id uint) {
+	schedule, err := repositories.NewScheduleRepository(d.db).GetScheduleByID(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		d.serverError("Failed to retrieve schedule", err)
+		return
+	}
+	if err != nil || !schedule.IsParticipant(d.userID) {
+		if d.c.Request.Method == http.MethodPut {
+			// Sessions are booked in SkillSwap, not created from calendar clients
+			d.c.String(http.StatusForbidden, "New sessions must be booked in SkillSwap")
+			return
+		}
+		d.c.String(http.StatusNotFound, "Not found")
+		return
+	}
+
+	calendar := services.NewCalendarService(d.db)
+	switch d.c.Request.Method {
+	case "PROPFIND":
+		d.multistatus([]davResponse{d.eventResponse(schedule, "")})
+
+	case http.MethodGet:
+		data, err := calendar.SessionCalendar(schedule, d.userID)
+		if err != nil {
+			d.serverError("Failed to build calendar", err)
+			return
+		}
+		d.c.Header("ETag", services.SessionETag(schedule))
+		d.c.Data(http.StatusOK, calendarContentType, []byte(data))
+
+	case http.MethodPut:
+		if !d.checkETag(schedule) {
+			return
+		}
+		body, ok := d.readBody()
+		if !ok {
+			return
+		}
+		events, err := utils.ParseICalendar(bytes.NewReader(body), viewerLocation(d.c, d.db))
+		if err != nil {
+			d.c.String(http.StatusBadRequest, "Invalid calendar data: "+err.Error())
+			return
+		}
+		if len(events) != 1 || events[0].UID != services.SessionUID(schedule.ID) {
+			d.c.String(http.StatusBadRequest, "Expected the session's event")
+			return
+		}
+		if events[0].Status != utils.ICalCancelled {
+			if err := validateSessionTimes(events[0].Start, events[0].End); err != nil {
+				d.c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if _, err := calendar.ApplyEventChange(schedule, d.userID, events[0]); err != nil {
+			d.bookingError(err)
+			return
+		}
+		// No ETag is returned, as the stored event may differ from the one sent
+		d.c.Status(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if !d.checkETag(schedule) {
+			return
+		}
+		if schedule.Status != models.StatusCancelled {
+			if _, err := services.NewBookingService(d.db).CancelSession(schedule.ID, d.userID); err != nil {
+				d.bookingError(err)
+				return
+			}
+		}
+		d.c.Status(http.StatusNoContent)
+
+	default:
+		d.c.String(http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// checkETag checks an If-Match header names the session's current revision, so
+// clients don't overwrite changes they haven't seen. It writes the error response when it fails.
+func (d *calDAVRequest) checkETag(schedule *models.Schedule) bool {
+	ifMatch := d.c.GetHeader("If-Match")
+	if ifMatch == "" || ifMatch == "*" || ifMatch == services.SessionETag(schedule) {
+		return true
+	}
+	d.c.String(http.StatusPreconditionFailed, "The session has changed")
+	return false
+}
+
+// readBody reads the request body up to maxCalDAVBody. It writes the error response when it fails.
+func (d *calDAVRequest) readBody() ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(d.c.Writer, d.c.Request.Body, maxCalDAVBody))
+	if err != nil {
+		d.c.String(http.StatusRequestEntityTooLarge, "Request body too large")
+		return nil, false
+	}
+	return body, true
+}
+
+// bookingError writes the response for an error from the booking service
+func (d *calDAVRequest) bookingError(err error) {
+	var invalid *services.InvalidSessionError
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		d.c.String(http.StatusNotFound, "Not found")
+	case errors.As(err, &invalid), errors.Is(err, services.ErrPaidSessionLength):
+		d.c.String(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrScheduleConflict), errors.Is(err, services.ErrParticipantBusy),
+		errors.Is(err, services.ErrOutsideAvailability), errors.Is(err, services.ErrSessionStarted),
+		errors.Is(err, services.ErrAwaitingOtherParty), errors.Is(err, models.ErrIllegalTransition),
+		errors.Is(err, repositories.ErrInsufficientPoints):
+		d.c.String(http.StatusConflict, err.Error())
+	default:
+		d.serverError("Failed to update session", err)
+	}
+}
+
+func (d *calDAVRequest) serverError(message string, err error) {
+	utils.Error(message + ": " + err.Error())
+	d.c.String(http.StatusInternalServerError, message)
+}
+
+// multistatus writes a 207 Multi-Status response
+func (d *calDAVRequest) multistatus(responses []davResponse) {
+	body, err := xml.Marshal(davMultistatus{DAV: davNS, CalDAV: calDAVNS, CalServer: calServerNS, Responses: responses})
+	if err != nil {
+		d.serverError("Failed to write response", err)
+		return
+	}
+	d.c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// okResponse describes a resource whose properties were all found
+func okResponse(href string, prop davProp) davResponse {
+	return davResponse{Href: href, Propstat: &davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"}}
+}
+
+// parseCalendarReport reads a calendar-query or calendar-multiget REPORT body
+func parseCalendarReport(body []byte) (calendarReport, error) {
+	var report calendarReport
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return calendarReport{}, errors.New("invalid report body")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			root = false
+			switch {
+			case start.Name.Space == calDAVNS && start.Name.Local == "calendar-multiget":
+				report.Multiget = true
+			case start.Name.Space == calDAVNS && start.Name.Local == "calendar-query":
+			default:
+				return calendarReport{}, fmt.Errorf("unsupported report %q", start.Name.Local)
+			}
+			continue
+		}
+
+		switch {
+		case start.Name.Space == davNS && start.Name.Local == "href":
+			var href string
+			if err := decoder.DecodeElement(&href, &start); err != nil {
+				return calendarReport{}, errors.New("invalid report body")
+			}
+			report.Hrefs = append(report.Hrefs, strings.TrimSpace(href))
+		case start.Name.Space == calDAVNS && start.Name.Local == "time-range":
+			for _, attr := range start.Attr {
+				t, err := time.Parse("20060102T150405Z", attr.Value)
+				if err != nil {
+					return calendarReport{}, fmt.Errorf("invalid time range %q", attr.Value)
+				}
+				switch attr.Name.Local {
+				case "start":
+					report.Start = t
+				case "end":
+					report.End = t
+				}
+			}
+		}
+	}
+	if root {
+		return calendarReport{}, errors.New("report body is required")
+	}
+	return report, nil
+}
@@ -0,0 +1,65 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+	"gorm.io/gorm"
+)
+
+// calDAVRouter serves the CalDAV endpoint as user 1, without a usable database
+func calDAVRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("db", (*gorm.DB)(nil))
+	})
+	for _, method := range controllers.CalDAVMethods {
+		router.Handle(method, "/caldav/*path", controllers.CalDAV)
+	}
+	return router
+}
+
+func TestCalDAVOptions(t *testing.T) {
+	req, _ := http.NewRequest("OPTIONS", "/caldav/1/sessions/", nil)
+	w := httptest.NewRecorder()
+	calDAVRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Header().Get("DAV"), "calendar-access") || !strings.Contains(w.Header().Get("Allow"), "REPORT") {
+		t.Errorf("Expected CalDAV headers, got %v", w.Header())
+	}
+}
+
+func TestCalDAVFindsPrincipal(t *testing.T) {
+	req, _ := http.NewRequest("PROPFIND", "/caldav/", strings.NewReader(`<propfind xmlns="DAV:"><prop><current-user-principal/></prop></propfind>`))
+	req.Header.Set("Depth", "0")
+	w := httptest.NewRecorder()
+	calDAVRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("Expected status %d, got %d", http.StatusMultiStatus, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "<D:current-user-principal><D:href>/caldav/1/</D:href></D:current-user-principal>") {
+		t.Errorf("Expected the user's principal, got %s", w.Body.String())
+	}
+}
+
+func TestCalDAVOtherUsersCalendars(t *testing.T) {
+	for _, path := range []string{"/caldav/2/", "/caldav/2/sessions/", "/caldav/2/sessions/schedule-1.ics", "/caldav/1/other/"} {
+		req, _ := http.NewRequest("PROPFIND", path, nil)
+		w := httptest.NewRecorder()
+		calDAVRouter().ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+}
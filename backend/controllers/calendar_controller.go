@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// calendarContentType is the content type of iCalendar responses
+const calendarContentType = "text/calendar; charset=utf-8"
+
+// maxCalendarImportSize is the largest calendar file that can be imported (1MB)
+const maxCalendarImportSize = 1 << 20
+
+// calendarFeedPath is where calendar feeds are served, followed by the feed's token
+const calendarFeedPath = "/api/calendar/feeds/"
+
+// GetCalendarFeedURL returns the secret URL of the authenticated user's calendar feed.
+func GetCalendarFeedURL(c *gin.Context) {
+	calendarFeedURL(c, false)
+}
+
+// ResetCalendarFeedURL replaces the authenticated user's calendar feed URL, so the old one stops working.
+func ResetCalendarFeedURL(c *gin.Context) {
+	calendarFeedURL(c, true)
+}
+
+// calendarFeedURL writes the user's feed URL, giving the feed a new token first when reset is set
+func calendarFeedURL(c *gin.Context, reset bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	user, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByID(userID.(uint))
+	if err != nil {
+		utils.Error("Failed to retrieve user: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve calendar feed")
+		return
+	}
+
+	calendar := services.NewCalendarService(db.(*gorm.DB))
+	token, err := calendar.FeedToken(user)
+	if reset {
+		token, err = calendar.ResetFeedToken(user)
+	}
+	if err != nil {
+		utils.Error("Failed to save calendar token: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve calendar feed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": calendarFeedPath + token + ".ics"})
+}
+
+// ResetCalDAVPassword gives the authenticated user a new app password for
+// signing calendar clients in to CalDAV, replacing any earlier one. The
+// password is only shown in this response.
+func ResetCalDAVPassword(c *gin.Context) {
+	user, calendar, ok := calendarUser(c)
+	if !ok {
+		return
+	}
+
+	password, err := calendar.ResetCalDAVPassword(user)
+	if err != nil {
+		utils.Error("Failed to save CalDAV password: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to create CalDAV password")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"url": "/caldav/", "username": user.Email, "password": password})
+}
+
+// RevokeCalDAVPassword removes the authenticated user's CalDAV app password,
+// signing out their calendar clients.
+func RevokeCalDAVPassword(c *gin.Context) {
+	user, calendar, ok := calendarUser(c)
+	if !ok {
+		return
+	}
+
+	if err := calendar.RevokeCalDAVPassword(user); err != nil {
+		utils.Error("Failed to revoke CalDAV password: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to revoke CalDAV password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "CalDAV password revoked"})
+}
+
+// calendarUser reads the authenticated user and their calendar service,
+// writing the error response when it fails
+func calendarUser(c *gin.Context) (*models.User, *services.CalendarService, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return nil, nil, false
+	}
+
+	user, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByID(userID.(uint))
+	if err != nil {
+		utils.Error("Failed to retrieve user: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return nil, nil, false
+	}
+	return user, services.NewCalendarService(db.(*gorm.DB)), true
+}
+
+// GetCalendarFeed serves a user's sessions as an iCalendar feed. The secret token in
+// the URL stands in for a login, so calendar apps can subscribe to it.
+func GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		utils.JSONError(c, http.StatusNotFound, "Calendar not found")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	user, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByCalendarToken(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Calendar not found")
+			return
+		}
+		utils.Error("Failed to retrieve calendar owner: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve calendar")
+		return
+	}
+
+	feed, err := services.NewCalendarService(db.(*gorm.DB)).Feed(user, time.Now())
+	if err != nil {
+		utils.Error("Failed to build calendar feed: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve calendar")
+		return
+	}
+
+	c.Data(http.StatusOK, calendarContentType, []byte(feed))
+}
+
+// ImportCalendar saves the events of an uploaded iCalendar file as the authenticated
+// user's busy times. The file is sent as the "file" form field or as the request body.
+func ImportCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCalendarImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "Calendar file is required")
+			return
+		}
+		src, err := file.Open()
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "Could not read file")
+			return
+		}
+		defer src.Close()
+		body = src
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Calendar file too large. Maximum size is 1MB")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	result, err := services.NewCalendarService(db.(*gorm.DB)).ImportBusyTimes(userID.(uint), bytes.NewReader(data), time.Now())
+	if err != nil {
+		var parseErr *utils.ICalError
+		if errors.As(err, &parseErr) {
+			utils.JSONError(c, http.StatusBadRequest, "Invalid calendar file: "+parseErr.Error())
+			return
+		}
+		utils.Error("Failed to import calendar: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to import calendar")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetBusyBlocks lists the authenticated user's imported busy times from now on.
+func GetBusyBlocks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	blocks, err := repositories.NewBusyBlockRepository(db.(*gorm.DB)).GetBusyBlocksByUser(userID.(uint), time.Now(), time.Time{})
+	if err != nil {
+		utils.Error("Failed to retrieve busy times: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve busy times")
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// DeleteBusyBlock deletes one of the authenticated user's imported busy times.
+func DeleteBusyBlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid busy time ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	repo := repositories.NewBusyBlockRepository(db.(*gorm.DB))
+	block, err := repo.GetBusyBlockByID(uint(id))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error("Failed to retrieve busy time: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve busy time")
+		return
+	}
+	if err != nil || block.UserID != userID.(uint) {
+		utils.JSONError(c, http.StatusNotFound, "Busy time not found")
+		return
+	}
+
+	if err := repo.DeleteBusyBlock(block.ID); err != nil {
+		utils.Error("Failed to delete busy time: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to delete busy time")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Busy time deleted successfully"})
+}
@@ -0,0 +1,70 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestCalendarEndpointsWithoutAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/calendar/feed", controllers.GetCalendarFeedURL)
+	router.POST("/calendar/import", controllers.ImportCalendar)
+	router.GET("/calendar/busy", controllers.GetBusyBlocks)
+
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/calendar/feed"},
+		{"POST", "/calendar/import"},
+		{"GET", "/calendar/busy"},
+	} {
+		req, _ := http.NewRequest(route.method, route.path, bytes.NewBufferString("BEGIN:VCALENDAR"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected status %d, got %d", route.method, route.path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+func TestInvalidCalendarIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.DELETE("/calendar/busy/:id", controllers.DeleteBusyBlock)
+	router.GET("/schedule/:id/ics", controllers.GetScheduleCalendar)
+
+	for _, route := range []struct{ method, path string }{
+		{"DELETE", "/calendar/busy/abc"},
+		{"GET", "/schedule/abc/ics"},
+	} {
+		req, _ := http.NewRequest(route.method, route.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status %d, got %d", route.method, route.path, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetCalendarFeedWithoutDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/calendar/feeds/:token", controllers.GetCalendarFeed)
+
+	req, _ := http.NewRequest("GET", "/calendar/feeds/abc.ics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// disputeEvidenceDir is where dispute evidence is stored, away from the
+// public uploads
+const disputeEvidenceDir = "./data/dispute_evidence"
+
+// RefundRequest gives back all or part of a transaction the user received
+type RefundRequest struct {
+	Amount int    `json:"amount" binding:"min=0"` // 0 or left out refunds everything left
+	Note   string `json:"note"`
+}
+
+// DisputeRequest opens a dispute over a transaction the user sent
+type DisputeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// DisputeMessageRequest adds a message to a dispute
+type DisputeMessageRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// DisputeResolutionRequest is an admin's resolution of a dispute
+type DisputeResolutionRequest struct {
+	Refund     int    `json:"refund" binding:"min=0"` // points given back to the sender; 0 rejects the dispute
+	Resolution string `json:"resolution" binding:"required"`
+}
+
+// RefundTransaction gives back all or part of a transaction the authenticated
+// user received, as a new transaction linked to it.
+func RefundTransaction(c *gin.Context) {
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid refund data")
+		return
+	}
+
+	id, userID, db, ok := disputeContext(c, "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	refund, err := disputeService(db).Refund(id, userID, req.Amount, req.Note)
+	if err != nil {
+		disputeError(c, err, "Failed to refund transaction")
+		return
+	}
+	c.JSON(http.StatusCreated, refund)
+}
+
+// OpenTransactionDispute disputes a transaction the authenticated user sent,
+// for an admin to resolve.
+func OpenTransactionDispute(c *gin.Context) {
+	var req DisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "A reason is required")
+		return
+	}
+
+	id, userID, db, ok := disputeContext(c, "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	details, err := disputeService(db).OpenDispute(id, userID, req.Reason)
+	if err != nil {
+		disputeError(c, err, "Failed to open dispute")
+		return
+	}
+	c.JSON(http.StatusCreated, details)
+}
+
+// GetDispute returns a dispute over one of the authenticated user's
+// transactions with its messages and evidence. Admins can see any dispute.
+func GetDispute(c *gin.Context) {
+	id, userID, db, ok := disputeContext(c, "Invalid dispute ID")
+	if !ok {
+		return
+	}
+
+	details, err := disputeService(db).Dispute(id, userID, isAdmin(c))
+	if err != nil {
+		disputeError(c, err, "Failed to retrieve dispute")
+		return
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+// AddDisputeMessage adds the authenticated user's message to an open dispute.
+func AddDisputeMessage(c *gin.Context) {
+	var req DisputeMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "A message body is required")
+		return
+	}
+
+	id, userID, db, ok := disputeContext(c, "Invalid dispute ID")
+	if !ok {
+		return
+	}
+
+	message, err := disputeService(db).AddMessage(id, userID, isAdmin(c), req.Body)
+	if err != nil {
+		disputeError(c, err, "Failed to add message")
+		return
+	}
+	c.JSON(http.StatusCreated, message)
+}
+
+// AddDisputeEvidence attaches a file, sent as the multipart field "file", to an open dispute.
+func AddDisputeEvidence(c *gin.Context) {
+	// Allow for the multipart framing around the largest file accepted
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxEvidenceSize+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "An evidence file of up to 10MB is required")
+		return
+	}
+
+	id, userID, db, ok := disputeContext(c, "Invalid dispute ID")
+	if !ok {
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Could not read file")
+		return
+	}
+	defer content.Close()
+
+	evidence, err := disputeService(db).AddEvidence(id, userID, isAdmin(c), file.Filename, content)
+	if err != nil {
+		disputeError(c, err, "Failed to add evidence")
+		return
+	}
+	c.JSON(http.StatusCreated, evidence)
+}
+
+// GetDisputeEvidence downloads one of a dispute's evidence files.
+func GetDisputeEvidence(c *gin.Context) {
+	evidenceID, err := strconv.ParseUint(c.Param("evidenceId"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid evidence ID")
+		return
+	}
+
+	id, userID, db, ok := disputeContext(c, "Invalid dispute ID")
+	if !ok {
+		return
+	}
+
+	evidence, data, err := disputeService(db).Evidence(id, uint(evidenceID), userID, isAdmin(c))
+	if err != nil {
+		disputeError(c, err, "Failed to retrieve evidence")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", evidence.FileName))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, evidence.ContentType, data)
+}
+
+// GetDisputes lists disputes for admins, oldest first.
+// Optional parameter: status ("open", "refunded" or "rejected").
+func GetDisputes(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.DisputeOpen, models.DisputeRefunded, models.DisputeRejected:
+	default:
+		utils.JSONError(c, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	disputes, err := disputeService(db.(*gorm.DB)).Disputes(status)
+	if err != nil {
+		utils.Error("Failed to retrieve disputes: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve disputes")
+		return
+	}
+	c.JSON(http.StatusOK, disputes)
+}
+
+// ResolveDispute closes an open dispute on an admin's ruling, refunding the
+// sender as much as they rule with a compensating transaction.
+func ResolveDispute(c *gin.Context) {
+	var req DisputeResolutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "A resolution and a refund of 0 or more are required")
+		return
+	}
+
+	id, adminID, db, ok := disputeContext(c, "Invalid dispute ID")
+	if !ok {
+		return
+	}
+
+	details, err := disputeService(db).ResolveDispute(id, adminID, req.Refund, req.Resolution)
+	if err != nil {
+		disputeError(c, err, "Failed to resolve dispute")
+		return
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+// disputeService creates the dispute service of a request
+func disputeService(db *gorm.DB) *services.DisputeService {
+	return services.NewDisputeService(db, disputeEvidenceDir)
+}
+
+// isAdmin reports whether the authenticated user is an admin
+func isAdmin(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == "Admin"
+}
+
+// disputeContext reads the transaction or dispute named by the id parameter,
+// the authenticated user and the database. It writes the error response,
+// with invalidID for a malformed id, when it fails.
+func disputeContext(c *gin.Context, invalidID string) (uint, uint, *gorm.DB, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, invalidID)
+		return 0, 0, nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return 0, 0, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return 0, 0, nil, false
+	}
+
+	return uint(id), userID.(uint), db.(*gorm.DB), true
+}
+
+// disputeError writes the response for an error from the dispute service
+func disputeError(c *gin.Context, err error, message string) {
+	var invalid *services.InvalidDisputeError
+	switch {
+	case errors.Is(err, services.ErrTransactionNotFound):
+		utils.JSONError(c, http.StatusNotFound, "Transaction not found")
+	case errors.Is(err, services.ErrDisputeNotFound):
+		utils.JSONError(c, http.StatusNotFound, "Dispute not found")
+	case errors.Is(err, services.ErrEvidenceNotFound):
+		utils.JSONError(c, http.StatusNotFound, "Evidence not found")
+	case errors.Is(err, services.ErrNotReceiver):
+		utils.JSONError(c, http.StatusForbidden, "Only the receiver can refund a transaction")
+	case errors.Is(err, services.ErrNotSender):
+		utils.JSONError(c, http.StatusForbidden, "Only the sender can dispute a transaction")
+	case errors.Is(err, services.ErrRefundOfRefund):
+		utils.JSONError(c, http.StatusBadRequest, "Refunds can't be refunded or disputed")
+	case errors.Is(err, services.ErrInvalidRefund):
+		utils.JSONError(c, http.StatusBadRequest, "The refund can't be negative")
+	case errors.Is(err, services.ErrRefundTooLarge):
+		utils.JSONError(c, http.StatusBadRequest, "The refund is larger than what is left of the transaction")
+	case errors.Is(err, services.ErrEvidenceTooLarge):
+		utils.JSONError(c, http.StatusBadRequest, "Evidence files can be at most 10MB")
+	case errors.Is(err, services.ErrEvidenceType):
+		utils.JSONError(c, http.StatusBadRequest, "Evidence must be an image, PDF or text file")
+	case errors.Is(err, repositories.ErrInsufficientPoints):
+		utils.JSONError(c, http.StatusConflict, "The receiver doesn't have enough SkillPoints")
+	case errors.Is(err, services.ErrNothingToRefund):
+		utils.JSONError(c, http.StatusConflict, "The transaction was already refunded in full")
+	case errors.Is(err, services.ErrDisputeOpen):
+		utils.JSONError(c, http.StatusConflict, "The transaction already has an open dispute")
+	case errors.Is(err, services.ErrDisputeClosed):
+		utils.JSONError(c, http.StatusConflict, "The dispute has been resolved")
+	case errors.As(err, &invalid):
+		utils.JSONError(c, http.StatusBadRequest, invalid.Error())
+	default:
+		utils.Error(message + ": " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestDisputeRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("role", "Admin")
+		c.Next()
+	})
+	router.POST("/transactions/:id/refund", controllers.RefundTransaction)
+	router.POST("/transactions/:id/disputes", controllers.OpenTransactionDispute)
+	router.GET("/disputes/:id", controllers.GetDispute)
+	router.POST("/disputes/:id/messages", controllers.AddDisputeMessage)
+	router.POST("/disputes/:id/evidence", controllers.AddDisputeEvidence)
+	router.GET("/disputes/:id/evidence/:evidenceId", controllers.GetDisputeEvidence)
+	router.GET("/admin/disputes", controllers.GetDisputes)
+	router.POST("/admin/disputes/:id/resolve", controllers.ResolveDispute)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"Negative Refund", "POST", "/transactions/1/refund", `{"amount": -5}`},
+		{"Invalid Refund ID", "POST", "/transactions/abc/refund", `{"amount": 5}`},
+		{"Missing Reason", "POST", "/transactions/1/disputes", `{}`},
+		{"Invalid Dispute Transaction ID", "POST", "/transactions/-1/disputes", `{"reason": "Never taught"}`},
+		{"Invalid Dispute ID", "GET", "/disputes/abc", ""},
+		{"Missing Message Body", "POST", "/disputes/1/messages", `{}`},
+		{"Missing Evidence File", "POST", "/disputes/1/evidence", ""},
+		{"Invalid Evidence ID", "GET", "/disputes/1/evidence/abc", ""},
+		{"Invalid Status", "GET", "/admin/disputes?status=closed", ""},
+		{"Missing Resolution", "POST", "/admin/disputes/1/resolve", `{"refund": 10}`},
+		{"Negative Resolution Refund", "POST", "/admin/disputes/1/resolve", `{"refund": -1, "resolution": "Refunded"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// Notification page sizes
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+// GetNotifications lists the authenticated user's notifications, newest first.
+// Optional parameters: unread=true to skip read ones, limit.
+func GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit := defaultNotificationLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n < 1 {
+			utils.JSONError(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		if n > maxNotificationLimit {
+			n = maxNotificationLimit
+		}
+		limit = n
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	notifications, err := repositories.NewNotificationRepository(db.(*gorm.DB)).
+		GetNotificationsByUser(userID.(uint), unreadOnly, limit)
+	if err != nil {
+		utils.Error("Failed to retrieve notifications: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkNotificationRead marks one of the authenticated user's notifications as read.
+func MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	err = repositories.NewNotificationRepository(db.(*gorm.DB)).MarkNotificationRead(userID.(uint), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Notification not found")
+			return
+		}
+		utils.Error("Failed to mark notification read: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks every notification of the authenticated user as read.
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	if err := repositories.NewNotificationRepository(db.(*gorm.DB)).MarkAllNotificationsRead(userID.(uint)); err != nil {
+		utils.Error("Failed to mark notifications read: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read"})
+}
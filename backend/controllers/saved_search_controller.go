@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// SavedSearchRequest defines the fields of a saved search a user can set.
+// Types and filters take the same values as the search endpoint's parameters.
+type SavedSearchRequest struct {
+	Name    string            `json:"name"`
+	Query   string            `json:"query" binding:"required"`
+	Types   []string          `json:"types"`
+	Filters map[string]string `json:"filters"`
+	Alert   string            `json:"alert"` // "in_app" (default) or "email"
+}
+
+// apply copies the request onto a saved search and validates it
+func (req SavedSearchRequest) apply(search *models.SavedSearch) error {
+	search.Name = req.Name
+	search.Query = req.Query
+	search.Alert = req.Alert
+
+	search.Types = models.StringArray{}
+	for _, name := range req.Types {
+		searchType, ok := services.ParseSearchType(name)
+		if !ok {
+			return errors.New("Invalid type: " + strings.TrimSpace(name))
+		}
+		search.Types = append(search.Types, searchType)
+	}
+
+	search.Filters = models.StringMap{}
+	for name, value := range req.Filters {
+		if !containsString(services.SearchFacets, name) {
+			return errors.New("Invalid filter: " + name)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			search.Filters[name] = value
+		}
+	}
+
+	return search.Validate()
+}
+
+// GetSavedSearches lists the authenticated user's saved searches.
+func GetSavedSearches(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	searches, err := repositories.NewSavedSearchRepository(db.(*gorm.DB)).GetSavedSearchesByUser(userID.(uint))
+	if err != nil {
+		utils.Error("Failed to retrieve saved searches: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve saved searches")
+		return
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// CreateSavedSearch saves a search for the authenticated user. Only records
+// created from now on are alerted.
+func CreateSavedSearch(c *gin.Context) {
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid saved search data")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	search := models.SavedSearch{UserID: userID.(uint), LastRunAt: time.Now()}
+	if err := req.apply(&search); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	if err := repositories.NewSavedSearchRepository(db.(*gorm.DB)).CreateSavedSearch(&search); err != nil {
+		utils.Error("Failed to create saved search: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to create saved search")
+		return
+	}
+
+	c.JSON(http.StatusCreated, search)
+}
+
+// GetSavedSearch returns one of the authenticated user's saved searches.
+func GetSavedSearch(c *gin.Context) {
+	search, _, ok := loadOwnSavedSearch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, search)
+}
+
+// UpdateSavedSearch replaces the query, filters or alert setting of a saved search.
+func UpdateSavedSearch(c *gin.Context) {
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid saved search data")
+		return
+	}
+
+	search, repo, ok := loadOwnSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	if err := req.apply(search); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := repo.UpdateSavedSearch(search); err != nil {
+		utils.Error("Failed to update saved search: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update saved search")
+		return
+	}
+
+	c.JSON(http.StatusOK, search)
+}
+
+// DeleteSavedSearch deletes one of the authenticated user's saved searches.
+func DeleteSavedSearch(c *gin.Context) {
+	search, repo, ok := loadOwnSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	if err := repo.DeleteSavedSearch(search.ID); err != nil {
+		utils.Error("Failed to delete saved search: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to delete saved search")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// loadOwnSavedSearch loads the saved search named by the id parameter and checks
+// the authenticated user owns it. It writes the error response when it fails.
+func loadOwnSavedSearch(c *gin.Context) (*models.SavedSearch, *repositories.SavedSearchRepository, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid saved search ID")
+		return nil, nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return nil, nil, false
+	}
+
+	repo := repositories.NewSavedSearchRepository(db.(*gorm.DB))
+	search, err := repo.GetSavedSearchByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Saved search not found")
+			return nil, nil, false
+		}
+		utils.Error("Failed to retrieve saved search: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve saved search")
+		return nil, nil, false
+	}
+
+	// Saved searches are private, so other users' are reported as missing
+	if search.UserID != userID.(uint) {
+		utils.JSONError(c, http.StatusNotFound, "Saved search not found")
+		return nil, nil, false
+	}
+
+	return search, repo, true
+}
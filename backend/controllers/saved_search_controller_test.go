@@ -0,0 +1,46 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestCreateSavedSearchValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.POST("/saved-searches", controllers.CreateSavedSearch)
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"Missing Query", `{"name": "Guitar"}`},
+		{"Invalid Type", `{"query": "guitar", "types": ["planets"]}`},
+		{"Invalid Filter", `{"query": "guitar", "filters": {"color": "red"}}`},
+		{"Invalid Alert", `{"query": "guitar", "alert": "sms"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/saved-searches", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
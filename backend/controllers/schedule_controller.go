@@ -1,15 +1,52 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mplaczek99/SkillSwap/models"
 	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
 )
 
-// CreateSchedule handles scheduling a new session.
+// RescheduleRequest defines the new times of a session
+type RescheduleRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// validateSessionTimes checks a session is in the future and ends after it starts
+func validateSessionTimes(start, end time.Time) error {
+	if start.Before(time.Now()) {
+		return errors.New("Schedule start time must be in the future")
+	}
+	if !end.After(start) {
+		return errors.New("Schedule end time must be after start time")
+	}
+	return nil
+}
+
+// timeQuery parses an optional RFC 3339 query parameter, returning the zero time when it is absent
+func timeQuery(c *gin.Context, name string) (time.Time, error) {
+	param := c.Query(name)
+	if param == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid " + name + " time, expected RFC 3339")
+	}
+	return t, nil
+}
+
+// CreateSchedule requests a session of a skill for the authenticated learner.
+// The teacher is notified and the session is confirmed once they accept.
 func CreateSchedule(c *gin.Context) {
 	var schedule models.Schedule
 	if err := c.ShouldBindJSON(&schedule); err != nil {
@@ -17,27 +54,43 @@ func CreateSchedule(c *gin.Context) {
 		return
 	}
 
-	// Validate that the session is scheduled for the future.
-	now := time.Now()
-	if schedule.StartTime.Before(now) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Schedule start time must be in the future"})
+	if err := validateSessionTimes(schedule.StartTime, schedule.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Validate that the end time is after the start time.
-	if !schedule.EndTime.After(schedule.StartTime) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Schedule end time must be after start time"})
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
 		return
 	}
 
-	created, err := repositories.InsertSchedule(&schedule)
+	// Only the skill and times come from the request; the learner is the logged-in user
+	request := models.Schedule{
+		LearnerID: userID.(uint),
+		SkillID:   schedule.SkillID,
+		StartTime: schedule.StartTime,
+		EndTime:   schedule.EndTime,
+	}
+
+	created, err := services.NewBookingService(db.(*gorm.DB)).RequestSession(&request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule session"})
+		scheduleError(c, err, "Failed to schedule session")
 		return
 	}
-	c.JSON(http.StatusCreated, created)
+	c.JSON(http.StatusCreated, created.In(viewerLocation(c, db.(*gorm.DB))))
 }
 
-// GetSchedules retrieves scheduled sessions for the authenticated user.
+// GetSchedules retrieves the sessions the authenticated user learns or teaches.
+// Times are shown in the user's time zone.
+// Optional parameters: from and to (RFC 3339) keep sessions overlapping that range,
+// status keeps sessions in that status.
 func GetSchedules(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -45,10 +98,320 @@ func GetSchedules(c *gin.Context) {
 		return
 	}
 
-	schedules, err := repositories.GetSchedulesByUserID(userID.(uint))
+	from, err := timeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := timeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+
+	var statuses []string
+	if status := c.Query("status"); status != "" {
+		if !models.IsScheduleStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		statuses = append(statuses, status)
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	schedules, err := repositories.NewScheduleRepository(db.(*gorm.DB)).GetSchedulesByUser(userID.(uint), from, to, statuses...)
 	if err != nil {
+		utils.Error("Failed to retrieve schedules: " + err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedules"})
 		return
 	}
+
+	loc := viewerLocation(c, db.(*gorm.DB))
+	for i := range schedules {
+		schedules[i] = schedules[i].In(loc)
+	}
 	c.JSON(http.StatusOK, schedules)
 }
+
+// GetSchedule returns one of the authenticated user's sessions.
+func GetSchedule(c *gin.Context) {
+	schedule, db, ok := loadOwnSchedule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, schedule.In(viewerLocation(c, db)))
+}
+
+// GetScheduleEvents returns the history of one of the authenticated user's sessions.
+func GetScheduleEvents(c *gin.Context) {
+	schedule, db, ok := loadOwnSchedule(c)
+	if !ok {
+		return
+	}
+
+	events, err := repositories.NewScheduleRepository(db).GetScheduleEvents(schedule.ID)
+	if err != nil {
+		utils.Error("Failed to retrieve schedule events: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve session history"})
+		return
+	}
+
+	loc := viewerLocation(c, db)
+	for i := range events {
+		events[i] = events[i].In(loc)
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// GetScheduleCalendar downloads one of the authenticated user's sessions as an iCalendar file.
+func GetScheduleCalendar(c *gin.Context) {
+	schedule, db, ok := loadOwnSchedule(c)
+	if !ok {
+		return
+	}
+
+	calendar, err := services.NewCalendarService(db).SessionCalendar(schedule, c.MustGet("user_id").(uint))
+	if err != nil {
+		utils.Error("Failed to export schedule: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export session"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%d.ics"`, schedule.ID))
+	c.Data(http.StatusOK, calendarContentType, []byte(calendar))
+}
+
+// UpdateSchedule proposes new times for one of the authenticated user's sessions.
+func UpdateSchedule(c *gin.Context) {
+	var req RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule data"})
+		return
+	}
+	if err := validateSessionTimes(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changeSchedule(c, func(booking *services.BookingService, id, userID uint) (*models.Schedule, error) {
+		return booking.ProposeTime(id, userID, req.StartTime, req.EndTime)
+	})
+}
+
+// AcceptSchedule accepts the time the other participant proposed for a session.
+func AcceptSchedule(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).AcceptSession)
+}
+
+// DeclineSchedule turns down the time the other participant proposed for a session.
+func DeclineSchedule(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).DeclineSession)
+}
+
+// DeleteSchedule cancels one of the authenticated user's sessions, refunding it if it was paid.
+func DeleteSchedule(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).CancelSession)
+}
+
+// CompleteSchedule marks one of the authenticated user's sessions as completed.
+func CompleteSchedule(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).CompleteSession)
+}
+
+// ReportScheduleNoShow marks one of the authenticated user's sessions as a
+// no-show by the other participant.
+func ReportScheduleNoShow(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).ReportNoShow)
+}
+
+// DisputeScheduleNoShow disputes a no-show report against the authenticated user.
+func DisputeScheduleNoShow(c *gin.Context) {
+	changeSchedule(c, (*services.BookingService).DisputeNoShow)
+}
+
+// NoShowRulingRequest is an admin's ruling on a disputed no-show report.
+type NoShowRulingRequest struct {
+	Upheld *bool `json:"upheld" binding:"required"` // whether the participant was absent as reported
+}
+
+// ResolveScheduleNoShow settles a disputed no-show report on an admin's ruling.
+func ResolveScheduleNoShow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	var req NoShowRulingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "upheld is required"})
+		return
+	}
+
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	schedule, err := services.NewBookingService(db.(*gorm.DB)).ResolveNoShow(uint(id), adminID.(uint), *req.Upheld)
+	if err != nil {
+		scheduleError(c, err, "Failed to settle no-show report")
+		return
+	}
+	c.JSON(http.StatusOK, schedule.In(viewerLocation(c, db.(*gorm.DB))))
+}
+
+// changeSchedule runs a participant's change to the session named by the id
+// parameter and writes the changed session or the error
+func changeSchedule(c *gin.Context, change func(booking *services.BookingService, id, userID uint) (*models.Schedule, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	schedule, err := change(services.NewBookingService(db.(*gorm.DB)), uint(id), userID.(uint))
+	if err != nil {
+		scheduleError(c, err, "Failed to update session")
+		return
+	}
+	c.JSON(http.StatusOK, schedule.In(viewerLocation(c, db.(*gorm.DB))))
+}
+
+// scheduleError writes the response for an error from the booking service.
+// Errors about one session of a series say which one.
+func scheduleError(c *gin.Context, err error, message string) {
+	status, text := scheduleErrorResponse(err)
+	var occurrence *services.OccurrenceError
+	switch {
+	case status == http.StatusInternalServerError:
+		utils.Error(message + ": " + err.Error())
+		text = message
+	case errors.As(err, &occurrence):
+		loc := time.UTC
+		if db, exists := c.Get("db"); exists {
+			loc = viewerLocation(c, db.(*gorm.DB))
+		}
+		text = fmt.Sprintf("Session on %s: %s", services.FormatSessionTime(occurrence.Start, loc), text)
+	}
+	c.JSON(status, gin.H{"error": text})
+}
+
+// scheduleErrorResponse returns the status and message for an error from the
+// booking service, or http.StatusInternalServerError for unexpected ones
+func scheduleErrorResponse(err error) (int, string) {
+	var invalid *services.InvalidSessionError
+	switch {
+	case errors.Is(err, services.ErrSkillNotFound):
+		return http.StatusNotFound, "Skill not found"
+	case errors.Is(err, services.ErrSessionNotFound):
+		return http.StatusNotFound, "Schedule not found"
+	case errors.Is(err, services.ErrSeriesNotFound):
+		return http.StatusNotFound, "Series not found"
+	case errors.Is(err, services.ErrOwnSkill):
+		return http.StatusBadRequest, "Cannot book a session of your own skill"
+	case errors.Is(err, repositories.ErrInsufficientPoints):
+		return http.StatusBadRequest, "The learner doesn't have enough SkillPoints"
+	case errors.Is(err, services.ErrScheduleConflict):
+		return http.StatusConflict, "You or the other participant already have a session or workshop at that time"
+	case errors.Is(err, services.ErrParticipantBusy):
+		return http.StatusConflict, "You or the other participant are busy at that time"
+	case errors.Is(err, services.ErrOutsideAvailability):
+		return http.StatusConflict, "The teacher is not available at that time"
+	case errors.Is(err, services.ErrSessionStarted):
+		return http.StatusConflict, "The session has already started"
+	case errors.Is(err, services.ErrSessionNotStarted):
+		return http.StatusConflict, "The session has not started yet"
+	case errors.Is(err, services.ErrSessionNotOver):
+		return http.StatusConflict, "The session has not ended yet"
+	case errors.Is(err, services.ErrAwaitingOtherParty):
+		return http.StatusConflict, "Waiting for the other participant to answer"
+	case errors.Is(err, services.ErrNoProposal):
+		return http.StatusConflict, "There is no proposed time to answer"
+	case errors.Is(err, models.ErrIllegalTransition):
+		return http.StatusConflict, "The session can't be changed in its current status"
+	case errors.Is(err, services.ErrNotDisputable):
+		return http.StatusConflict, "This no-show report can't be disputed"
+	case errors.Is(err, services.ErrNoDispute):
+		return http.StatusConflict, "The session has no open no-show dispute"
+	case errors.Is(err, services.ErrSeriesCancelled):
+		return http.StatusConflict, "The series has been cancelled"
+	case errors.Is(err, services.ErrNoUpcomingSessions):
+		return http.StatusConflict, "The series has no upcoming sessions"
+	case errors.Is(err, services.ErrPaidSessionLength):
+		return http.StatusBadRequest, "A session paid by the hour must keep its length"
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, invalid.Error()
+	default:
+		return http.StatusInternalServerError, ""
+	}
+}
+
+// loadOwnSchedule loads the session named by the id parameter and checks the
+// authenticated user takes part in it. It writes the error response when it fails.
+func loadOwnSchedule(c *gin.Context) (*models.Schedule, *gorm.DB, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return nil, nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return nil, nil, false
+	}
+
+	schedule, err := repositories.NewScheduleRepository(db.(*gorm.DB)).GetScheduleByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return nil, nil, false
+		}
+		utils.Error("Failed to retrieve schedule: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedule"})
+		return nil, nil, false
+	}
+
+	// Sessions are private, so other users' are reported as missing
+	if !schedule.IsParticipant(userID.(uint)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return nil, nil, false
+	}
+
+	return schedule, db.(*gorm.DB), true
+}
@@ -17,45 +17,28 @@ func TestCreateSchedule(t *testing.T) {
 	// Use test mode
 	gin.SetMode(gin.TestMode)
 
-	t.Run("Create Valid Schedule", func(t *testing.T) {
-		// Set up router with the controller
+	t.Run("Create Schedule Without Authentication", func(t *testing.T) {
 		router := gin.New()
 		router.POST("/schedule", controllers.CreateSchedule)
 
-		// Create valid schedule data
-		startTime := time.Now().Add(24 * time.Hour) // 1 day in future
-		endTime := startTime.Add(2 * time.Hour)     // 2 hours duration
-
+		startTime := time.Now().Add(24 * time.Hour)
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: startTime,
-			EndTime:   endTime,
+			EndTime:   startTime.Add(2 * time.Hour),
 		}
 
 		reqBody, _ := json.Marshal(schedule)
-
-		// Create request
 		req, _ := http.NewRequest("POST", "/schedule", bytes.NewBuffer(reqBody))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
-		// Serve request
 		router.ServeHTTP(w, req)
 
-		// Verify response
-		if w.Code != http.StatusCreated {
-			t.Errorf("Expected status 201 for valid schedule, got %d: %s", w.Code, w.Body.String())
-		}
-
-		// Verify response contains created schedule
-		var response models.Schedule
-		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-			t.Errorf("Failed to unmarshal response: %v", err)
-		}
-
-		if response.ID == 0 {
-			t.Error("Expected ID to be set in response")
+		// A client-supplied user_id does not stand in for a login
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without authentication, got %d: %s", w.Code, w.Body.String())
 		}
 	})
 
@@ -69,7 +52,7 @@ func TestCreateSchedule(t *testing.T) {
 		endTime := startTime.Add(2 * time.Hour)      // 2 hours duration
 
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: startTime,
 			EndTime:   endTime,
@@ -101,7 +84,7 @@ func TestCreateSchedule(t *testing.T) {
 		endTime := startTime.Add(-1 * time.Hour)    // 1 hour before start time
 
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: startTime,
 			EndTime:   endTime,
@@ -168,35 +151,125 @@ func TestGetSchedules(t *testing.T) {
 		}
 	})
 
-	t.Run("Get Schedules With Authentication", func(t *testing.T) {
-		// Set up router with the controller and middleware to set user_id
+	t.Run("Get Schedules With Invalid Range", func(t *testing.T) {
 		router := gin.New()
 		router.GET("/schedule", func(c *gin.Context) {
-			// Set user_id in context to simulate authentication
 			c.Set("user_id", uint(1))
 			controllers.GetSchedules(c)
 		})
 
-		// Create request
-		req, _ := http.NewRequest("GET", "/schedule", nil)
+		for _, query := range []string{
+			"status=pending",
+			"from=tomorrow",
+			"to=2030-01-01",
+			"from=2030-01-02T00:00:00Z&to=2030-01-01T00:00:00Z",
+		} {
+			req, _ := http.NewRequest("GET", "/schedule?"+query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+			}
+		}
+	})
+}
+
+func TestScheduleByID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	withUser := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", uint(1))
+			handler(c)
+		}
+	}
+	router.GET("/schedule/:id", withUser(controllers.GetSchedule))
+	router.PUT("/schedule/:id", withUser(controllers.UpdateSchedule))
+	router.DELETE("/schedule/:id", withUser(controllers.DeleteSchedule))
+	router.GET("/schedule/:id/events", withUser(controllers.GetScheduleEvents))
+	router.POST("/schedule/:id/accept", withUser(controllers.AcceptSchedule))
+	router.POST("/schedule/:id/decline", withUser(controllers.DeclineSchedule))
+	router.POST("/schedule/:id/complete", withUser(controllers.CompleteSchedule))
+	router.POST("/schedule/:id/no-show", withUser(controllers.ReportScheduleNoShow))
+	router.POST("/schedule/:id/dispute", withUser(controllers.DisputeScheduleNoShow))
+	router.POST("/admin/schedule/:id/no-show", withUser(controllers.ResolveScheduleNoShow))
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		for _, route := range []struct{ method, path string }{
+			{"GET", "/schedule/abc"},
+			{"DELETE", "/schedule/abc"},
+			{"GET", "/schedule/abc/events"},
+			{"POST", "/schedule/abc/accept"},
+			{"POST", "/schedule/abc/decline"},
+			{"POST", "/schedule/abc/complete"},
+			{"POST", "/schedule/abc/no-show"},
+			{"POST", "/schedule/abc/dispute"},
+			{"POST", "/admin/schedule/abc/no-show"},
+		} {
+			req, _ := http.NewRequest(route.method, route.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s %s, got %d", route.method, route.path, w.Code)
+			}
+		}
+	})
+
+	t.Run("Accept Without Authentication", func(t *testing.T) {
+		router := gin.New()
+		router.POST("/schedule/:id/accept", controllers.AcceptSchedule)
+
+		req, _ := http.NewRequest("POST", "/schedule/1/accept", nil)
 		w := httptest.NewRecorder()
 
-		// Serve request
 		router.ServeHTTP(w, req)
 
-		// Verify response
-		if w.Code != http.StatusOK {
-			t.Errorf("Expected status 200 for authenticated request, got %d", w.Code)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without authentication, got %d", w.Code)
 		}
+	})
 
-		// Verify response contains schedules
-		var schedules []models.Schedule
-		if err := json.Unmarshal(w.Body.Bytes(), &schedules); err != nil {
-			t.Errorf("Failed to unmarshal response: %v", err)
+	t.Run("Reschedule Into The Past", func(t *testing.T) {
+		startTime := time.Now().Add(-time.Hour)
+		reqBody, _ := json.Marshal(controllers.RescheduleRequest{StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+
+		req, _ := http.NewRequest("PUT", "/schedule/1", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for rescheduling into the past, got %d", w.Code)
 		}
+	})
 
-		if len(schedules) == 0 {
-			t.Error("Expected at least one schedule in response")
+	t.Run("No-Show Ruling Without Verdict", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/admin/schedule/1/no-show", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 without upheld, got %d", w.Code)
+		}
+	})
+
+	t.Run("Reschedule Without Times", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/schedule/1", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 without times, got %d", w.Code)
 		}
 	})
 }
@@ -246,7 +319,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 		// Check for overlaps with existing schedules
 		for _, existing := range existingSchedules {
 			// Only check schedules for the same user
-			if existing.UserID == schedule.UserID {
+			if existing.LearnerID == schedule.LearnerID {
 				// Check for overlap: new start time is within existing schedule
 				if (schedule.StartTime.After(existing.StartTime) && schedule.StartTime.Before(existing.EndTime)) ||
 					// Check for overlap: new end time is within existing schedule
@@ -274,7 +347,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 		endTime := startTime.Add(2 * time.Hour)
 
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: startTime,
 			EndTime:   endTime,
@@ -300,7 +373,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 		endTime := existingSchedule.EndTime.Add(1 * time.Hour)
 
 		schedule := models.Schedule{
-			UserID:    1, // Same user
+			LearnerID: 1, // Same user
 			SkillID:   2,
 			StartTime: startTime,
 			EndTime:   endTime,
@@ -323,7 +396,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 
 		// Create a schedule with the same time but for a different user
 		schedule := models.Schedule{
-			UserID:    2, // Different user
+			LearnerID: 2, // Different user
 			SkillID:   2,
 			StartTime: existingSchedule.StartTime,
 			EndTime:   existingSchedule.EndTime,
@@ -346,7 +419,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 		endTime := startTime.Add(15 * time.Minute) // Too short
 
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: startTime,
 			EndTime:   endTime,
@@ -368,7 +441,7 @@ func TestScheduleOverlapAndEdgeCases(t *testing.T) {
 		exactTime := time.Now().Add(72 * time.Hour)
 
 		schedule := models.Schedule{
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: exactTime,
 			EndTime:   exactTime, // Same as start time
@@ -1,94 +1,193 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
 	"github.com/mplaczek99/SkillSwap/utils"
 	"gorm.io/gorm"
 )
 
-// Search handles GET requests to search for skills and users.
-// It expects a query parameter "q" and returns matching results.
+// Search handles GET requests to search for users, skills, jobs and videos.
+// It expects a query parameter "q" and returns a versioned page of typed hits.
+// Optional parameters:
+//   - type: comma-separated types to search (jobs, users, skills, videos)
+//   - category, experience_level, job_type, location: facet filters
+//   - limit: page size, cursor: value of next_cursor from the previous page
 func Search(c *gin.Context) {
-	q := c.Query("q")
-	if q == "" {
-		// Return JSON object with error message for missing query parameter
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	searchTerm := strings.ToLower(q)
-	var results []interface{}
+	var response *services.SearchResponse
 
-	// Initialize user repository to search users from the database
-	db, exists := c.Get("db")
+	index, exists := searchIndexFromContext(c)
 	if !exists {
-		// For tests or when db isn't available, return mock data
-		utils.Info("Database connection not found in context, using mock data")
+		// For tests or when no index is available, search mock data
+		utils.Info("Search index not found in context, using mock data")
+		response, err = services.BuildSearchResponse(query, getMockSearchResults(query))
+	} else {
+		response, err = services.NewSearchService(index).Search(query)
+	}
 
-		// Return mock data for testing purposes
-		mockResults := getMockSearchResults(searchTerm)
-		c.JSON(http.StatusOK, mockResults)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		utils.Error("Search failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
 		return
 	}
 
-	userRepo := repositories.NewUserRepository(db.(*gorm.DB))
+	c.JSON(http.StatusOK, response)
+}
 
-	// Search users from the database
-	users, err := userRepo.SearchUsers(searchTerm)
-	if err != nil {
-		utils.Error("Failed to search users: " + err.Error())
-		c.JSON(http.StatusOK, []interface{}{})
+// SearchSuggest handles GET requests for autocomplete suggestions.
+// It expects a query parameter "q" holding what the user has typed so far and
+// returns ranked completions from skill names, job titles and user names.
+// Optional parameters: type (comma-separated), limit.
+func SearchSuggest(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
 		return
 	}
 
-	// Add users to results
-	for _, user := range users {
-		results = append(results, user)
+	var types []string
+	if typeParam := c.Query("type"); typeParam != "" {
+		for _, name := range strings.Split(typeParam, ",") {
+			searchType, ok := services.ParseSearchType(name)
+			if !ok || searchType == services.SearchTypeVideo {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type: " + strings.TrimSpace(name)})
+				return
+			}
+			types = append(types, searchType)
+		}
 	}
 
-	// Search skills from the repository
-	skills, err := repositories.SearchSkills(searchTerm)
-	if err != nil {
-		utils.Error("Failed to search skills: " + err.Error())
-		c.JSON(http.StatusOK, []interface{}{})
-		return
+	limit := services.DefaultSuggestLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
 	}
 
-	// Add skills to results
-	for _, skill := range skills {
-		results = append(results, skill)
+	// Members-only profiles are only suggested to logged-in users
+	_, authenticated := c.Get("user_id")
+
+	var suggestions []services.SearchSuggestion
+	index, exists := searchIndexFromContext(c)
+	if !exists {
+		// For tests or when no index is available, complete from mock data
+		suggestions = getMockSuggestions(prefix, limit)
+	} else {
+		var err error
+		suggestions, err = services.NewSearchService(index).Suggest(prefix, types, limit, authenticated)
+		if err != nil {
+			utils.Error("Suggest failed: " + err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suggestions"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, results)
+	// Suggestions are requested on every keystroke; let clients reuse them briefly
+	c.Header("Cache-Control", "private, max-age=30")
+	c.JSON(http.StatusOK, suggestions)
 }
 
-// getMockSearchResults provides mock data for testing purposes
-func getMockSearchResults(searchTerm string) []interface{} {
-	// Create some mock results for testing
-	var results []interface{}
+// searchIndexFromContext returns the search index configured for the server,
+// or one backed by the request's database when none is configured.
+func searchIndexFromContext(c *gin.Context) (services.SearchIndex, bool) {
+	if index, exists := c.Get("search_index"); exists {
+		return index.(services.SearchIndex), true
+	}
+	if db, exists := c.Get("db"); exists {
+		return services.NewDatabaseSearchIndex(db.(*gorm.DB)), true
+	}
+	return nil, false
+}
 
-	// Mock users
-	mockUsers := []map[string]interface{}{
-		{"id": 1, "name": "Test User", "email": "test@example.com"},
-		{"id": 2, "name": "Alice Smith", "email": "alice@example.com"},
+// parseSearchQuery reads the search parameters from the request
+func parseSearchQuery(c *gin.Context) (services.SearchQuery, error) {
+	query := services.SearchQuery{
+		Text:    strings.TrimSpace(c.Query("q")),
+		Filters: make(map[string]string),
+		Cursor:  c.Query("cursor"),
+	}
+	if query.Text == "" {
+		return query, errors.New("Query parameter 'q' is required")
+	}
+
+	// Members-only profiles are only found by logged-in users
+	_, query.Authenticated = c.Get("user_id")
+
+	if types := c.Query("type"); types != "" {
+		for _, name := range strings.Split(types, ",") {
+			searchType, ok := services.ParseSearchType(name)
+			if !ok {
+				return query, errors.New("Invalid type: " + strings.TrimSpace(name))
+			}
+			query.Types = append(query.Types, searchType)
+		}
+	}
+
+	for _, facet := range services.SearchFacets {
+		if value := strings.TrimSpace(c.Query(facet)); value != "" {
+			query.Filters[facet] = value
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return query, errors.New("Invalid limit")
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
+
+// getMockSearchResults provides mock data for testing purposes
+func getMockSearchResults(query services.SearchQuery) []services.SearchHit {
+	searchTerm := strings.ToLower(query.Text)
+	var results []services.SearchHit
+
+	// Mock users, in their public form
+	mockUsers := []models.PublicProfile{
+		{ID: 1, Name: "Test User", Bio: "Here to test things"},
+		{ID: 2, Name: "Alice Smith", Bio: "Guitar teacher"},
 	}
 
 	// Mock skills
 	mockSkills := []map[string]interface{}{
-		{"id": 1, "name": "Programming", "description": "Learn to code"},
-		{"id": 2, "name": "Music", "description": "Learn to play instruments"},
+		{"id": 1, "name": "Programming", "description": "Learn to code", "category": "Technology"},
+		{"id": 2, "name": "Music", "description": "Learn to play instruments", "category": "Arts"},
 	}
 
 	// Filter mock data based on search term
 	for _, user := range mockUsers {
-		name := strings.ToLower(user["name"].(string))
-		email := strings.ToLower(user["email"].(string))
-		if strings.Contains(name, searchTerm) || strings.Contains(email, searchTerm) {
-			results = append(results, user)
+		name := strings.ToLower(user.Name)
+		bio := strings.ToLower(user.Bio)
+		if strings.Contains(name, searchTerm) || strings.Contains(bio, searchTerm) {
+			results = append(results, services.SearchHit{
+				Type:  services.SearchTypeUser,
+				ID:    strconv.FormatUint(uint64(user.ID), 10),
+				Title: user.Name,
+				Rank:  1,
+				Data:  user,
+			})
 		}
 	}
 
@@ -96,9 +195,54 @@ func getMockSearchResults(searchTerm string) []interface{} {
 		name := strings.ToLower(skill["name"].(string))
 		desc := strings.ToLower(skill["description"].(string))
 		if strings.Contains(name, searchTerm) || strings.Contains(desc, searchTerm) {
-			results = append(results, skill)
+			results = append(results, services.SearchHit{
+				Type:   services.SearchTypeSkill,
+				ID:     strconv.Itoa(skill["id"].(int)),
+				Title:  skill["name"].(string),
+				Rank:   1,
+				Data:   skill,
+				Facets: map[string]string{services.FacetCategory: skill["category"].(string)},
+			})
+		}
+	}
+
+	// Only keep the types the query asked for
+	var typed []services.SearchHit
+	for _, hit := range results {
+		if len(query.Types) == 0 || containsString(query.Types, hit.Type) {
+			typed = append(typed, hit)
+		}
+	}
+
+	return typed
+}
+
+// getMockSuggestions completes a prefix from mock skill and user names
+func getMockSuggestions(prefix string, limit int) []services.SearchSuggestion {
+	candidates := []services.SearchSuggestion{
+		{Type: services.SearchTypeSkill, ID: 1, Text: "Programming"},
+		{Type: services.SearchTypeSkill, ID: 2, Text: "Music"},
+		{Type: services.SearchTypeSkill, ID: 3, Text: "Python"},
+		{Type: services.SearchTypeUser, ID: 1, Text: "Test User"},
+		{Type: services.SearchTypeUser, ID: 2, Text: "Alice Smith"},
+	}
+
+	var matches []services.SearchSuggestion
+	for _, candidate := range candidates {
+		if candidate.Score = utils.SuggestionScore(prefix, candidate.Text); candidate.Score > 0 {
+			matches = append(matches, candidate)
 		}
 	}
 
-	return results
+	return services.RankSuggestions(matches, limit)
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
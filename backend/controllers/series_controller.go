@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// SeriesRequest defines a recurring series of sessions: the times of the
+// first one and how they repeat, such as "FREQ=WEEKLY;COUNT=8"
+type SeriesRequest struct {
+	SkillID   uint      `json:"skill_id" binding:"required"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	RRule     string    `json:"rrule" binding:"required"`
+	TimeZone  string    `json:"time_zone"` // defaults to the learner's
+}
+
+// CreateSeries requests a recurring series of sessions of a skill for the
+// authenticated learner. Every session is checked up front, and the series is
+// only booked if all of them can be.
+func CreateSeries(c *gin.Context) {
+	var req SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series data"})
+		return
+	}
+	if err := validateSessionTimes(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := utils.ParseRRule(req.RRule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+	if req.TimeZone != "" {
+		if err := models.ValidateTimeZone(req.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	loc := viewerLocation(c, db.(*gorm.DB))
+	series := models.ScheduleSeries{
+		LearnerID: userID.(uint),
+		SkillID:   req.SkillID,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		RRule:     req.RRule,
+		TimeZone:  req.TimeZone,
+	}
+	if series.TimeZone == "" {
+		series.TimeZone = loc.String()
+	}
+
+	details, err := services.NewBookingService(db.(*gorm.DB)).RequestSeries(&series)
+	if err != nil {
+		scheduleError(c, err, "Failed to schedule series")
+		return
+	}
+	c.JSON(http.StatusCreated, details.In(loc))
+}
+
+// GetSeries returns one of the authenticated user's series with its sessions.
+func GetSeries(c *gin.Context) {
+	handleSeries(c, "Failed to retrieve series", (*services.BookingService).Series)
+}
+
+// AcceptSeries accepts every request and new time the other participant made
+// for the upcoming sessions of a series.
+func AcceptSeries(c *gin.Context) {
+	handleSeries(c, "Failed to update series", (*services.BookingService).AcceptSeries)
+}
+
+// DeclineSeries turns down every request and new time the other participant
+// made for the upcoming sessions of a series.
+func DeclineSeries(c *gin.Context) {
+	handleSeries(c, "Failed to update series", (*services.BookingService).DeclineSeries)
+}
+
+// CancelSeries cancels a series and all its upcoming sessions.
+func CancelSeries(c *gin.Context) {
+	handleSeries(c, "Failed to update series", (*services.BookingService).CancelSeries)
+}
+
+// RescheduleSeries proposes new times for all the upcoming sessions of a
+// series, given as the new times of the next one.
+func RescheduleSeries(c *gin.Context) {
+	var req RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule data"})
+		return
+	}
+	if err := validateSessionTimes(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handleSeries(c, "Failed to update series", func(booking *services.BookingService, id, userID uint) (*services.SeriesDetails, error) {
+		return booking.RescheduleSeries(id, userID, req.StartTime, req.EndTime)
+	})
+}
+
+// handleSeries runs a participant's request for the series named by the id
+// parameter and writes the series or the error
+func handleSeries(c *gin.Context, message string, change func(booking *services.BookingService, id, userID uint) (*services.SeriesDetails, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	details, err := change(services.NewBookingService(db.(*gorm.DB)), uint(id), userID.(uint))
+	if err != nil {
+		scheduleError(c, err, message)
+		return
+	}
+	c.JSON(http.StatusOK, details.In(viewerLocation(c, db.(*gorm.DB))))
+}
@@ -0,0 +1,54 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestSeriesRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.POST("/schedule/series", controllers.CreateSeries)
+	router.GET("/schedule/series/:id", controllers.GetSeries)
+	router.POST("/schedule/series/:id/propose", controllers.RescheduleSeries)
+	router.POST("/schedule/series/:id/cancel", controllers.CancelSeries)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"Missing Rule", "POST", "/schedule/series", `{"skill_id": 1, "start_time": "2030-01-01T18:00:00Z", "end_time": "2030-01-01T19:00:00Z"}`},
+		{"Invalid Rule", "POST", "/schedule/series", `{"skill_id": 1, "start_time": "2030-01-01T18:00:00Z", "end_time": "2030-01-01T19:00:00Z", "rrule": "FREQ=YEARLY"}`},
+		{"Invalid Time Zone", "POST", "/schedule/series", `{"skill_id": 1, "start_time": "2030-01-01T18:00:00Z", "end_time": "2030-01-01T19:00:00Z", "rrule": "FREQ=WEEKLY;COUNT=8", "time_zone": "Mars/Olympus"}`},
+		{"Past Series", "POST", "/schedule/series", `{"skill_id": 1, "start_time": "2020-01-01T18:00:00Z", "end_time": "2020-01-01T19:00:00Z", "rrule": "FREQ=WEEKLY;COUNT=8"}`},
+		{"Invalid Series ID", "GET", "/schedule/series/abc", ""},
+		{"Invalid Cancel Series ID", "POST", "/schedule/series/-1/cancel", ""},
+		{"Reschedule Without Times", "POST", "/schedule/series/1/propose", `{}`},
+		{"Reschedule Ending First", "POST", "/schedule/series/1/propose", `{"start_time": "2030-01-01T19:00:00Z", "end_time": "2030-01-01T18:00:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
 	"github.com/mplaczek99/SkillSwap/services"
 	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
 )
 
 // AddSkill handles adding a new skill.
@@ -39,3 +44,185 @@ func GetSkills(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, skills)
 }
+
+// SetSkillPricingRequest defines the pricing fields a teacher can set on a skill.
+type SetSkillPricingRequest struct {
+	Price                int    `json:"price" binding:"min=0"`
+	PriceUnit            string `json:"price_unit" binding:"required"`
+	FirstSessionDiscount int    `json:"first_session_discount"`
+}
+
+// SetSkillPricing lets the teacher offering a skill set its rate.
+func SetSkillPricing(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid skill ID")
+		return
+	}
+
+	var req SetSkillPricingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid pricing data")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	skillRepo := repositories.NewSkillRepository(db.(*gorm.DB))
+	skill, err := skillRepo.GetSkillByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Skill not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve skill")
+		return
+	}
+
+	if skill.UserID != userID.(uint) {
+		utils.JSONError(c, http.StatusForbidden, "You do not have permission to price this skill")
+		return
+	}
+
+	skill.Price = req.Price
+	skill.PriceUnit = req.PriceUnit
+	skill.FirstSessionDiscount = req.FirstSessionDiscount
+	if err := skill.ValidatePricing(); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := skillRepo.UpdateSkill(skill); err != nil {
+		utils.Error("Failed to update skill pricing: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update skill pricing")
+		return
+	}
+
+	c.JSON(http.StatusOK, skill)
+}
+
+// SetSessionLimitsRequest defines the session lengths a teacher accepts for a skill, in minutes.
+// 0 keeps the default limit.
+type SetSessionLimitsRequest struct {
+	MinSessionMinutes int `json:"min_session_minutes"`
+	MaxSessionMinutes int `json:"max_session_minutes"`
+}
+
+// SetSkillSessionLimits lets the teacher offering a skill set how long its sessions can be.
+func SetSkillSessionLimits(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid skill ID")
+		return
+	}
+
+	var req SetSessionLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid session limits")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	skillRepo := repositories.NewSkillRepository(db.(*gorm.DB))
+	skill, err := skillRepo.GetSkillByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Skill not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve skill")
+		return
+	}
+
+	if skill.UserID != userID.(uint) {
+		utils.JSONError(c, http.StatusForbidden, "You do not have permission to change this skill")
+		return
+	}
+
+	skill.MinSessionMinutes = req.MinSessionMinutes
+	skill.MaxSessionMinutes = req.MaxSessionMinutes
+	if err := skill.ValidateSessionLimits(); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := skillRepo.UpdateSkill(skill); err != nil {
+		utils.Error("Failed to update skill session limits: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update session limits")
+		return
+	}
+
+	c.JSON(http.StatusOK, skill)
+}
+
+// GetSkillQuote prices a session of a skill for the authenticated learner.
+// It expects "start_time" and "end_time" query parameters in RFC 3339 format.
+func GetSkillQuote(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid skill ID")
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start_time"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid start_time")
+		return
+	}
+	end, err := time.Parse(time.RFC3339, c.Query("end_time"))
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid end_time")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	skill, err := repositories.NewSkillRepository(db.(*gorm.DB)).GetSkillByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "Skill not found")
+			return
+		}
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve skill")
+		return
+	}
+
+	quote, err := services.NewBookingService(db.(*gorm.DB)).QuoteSession(userID.(uint), skill, start, end)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
@@ -1,12 +1,18 @@
 package controllers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mplaczek99/SkillSwap/models"
 	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
 	"github.com/mplaczek99/SkillSwap/utils"
 	"gorm.io/gorm"
 )
@@ -17,7 +23,15 @@ type CreateTransactionRequest struct {
 	Note           string `json:"note"`
 }
 
-// GetTransactions retrieves transactions for the current user
+// GetTransactions returns a page of the current user's transactions, newest
+// first, with their balance after each.
+// Optional parameters:
+//   - direction: sent or received
+//   - from, to: RFC 3339 range of when they were made
+//   - counterparty: ID of the other user
+//   - min_amount, max_amount: range of amounts
+//   - note: text in the note
+//   - limit: page size, cursor: value of next_cursor from the previous page
 func GetTransactions(c *gin.Context) {
 	// Get the user ID from the context (set by the auth middleware)
 	userID, exists := c.Get("user_id")
@@ -26,74 +40,165 @@ func GetTransactions(c *gin.Context) {
 		return
 	}
 
+	filter, err := transactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := 0
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
 	db, exists := c.Get("db")
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
 		return
 	}
 
-	// Initialize repositories
-	txRepo := repositories.NewTransactionRepository(db.(*gorm.DB))
-	userRepo := repositories.NewUserRepository(db.(*gorm.DB))
-
-	// Get transactions
-	transactions, err := txRepo.GetTransactionsByUserID(userID.(uint))
+	page, err := services.NewTransactionFeedService(db.(*gorm.DB)).Page(userID.(uint), filter, c.Query("cursor"), limit)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
 		utils.Error("Failed to retrieve transactions: " + err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
 		return
 	}
 
-	// Create a map of user IDs to names (fetch from database)
-	userNames := make(map[uint]string)
-	currentUserID := userID.(uint)
+	c.JSON(http.StatusOK, page)
+}
 
-	// Enhanced response format for frontend
-	type EnhancedTransaction struct {
-		models.Transaction
-		SenderName   string `json:"senderName"`
-		ReceiverName string `json:"receiverName"`
+// ExportTransactions downloads all of the current user's transactions matching
+// the filters of GetTransactions, in the format given by the format parameter:
+// csv, ofx or json
+func ExportTransactions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
 	}
 
-	enhancedTransactions := make([]EnhancedTransaction, len(transactions))
-	for i, tx := range transactions {
-		// Get sender name if not cached
-		if _, exists := userNames[tx.SenderID]; !exists {
-			if tx.SenderID == currentUserID {
-				userNames[tx.SenderID] = "You" // Special case for current user
-			} else {
-				user, err := userRepo.GetUserByID(tx.SenderID)
-				if err == nil {
-					userNames[tx.SenderID] = user.Name
-				} else {
-					userNames[tx.SenderID] = "Unknown User"
-				}
-			}
+	format := c.DefaultQuery("format", services.ExportCSV)
+	contentType, ok := transactionExportTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected csv, ofx or json"})
+		return
+	}
+	filter, err := transactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	// Written to a buffer first so a failure can still be reported as an error
+	var export bytes.Buffer
+	now := time.Now()
+	if err := services.NewTransactionFeedService(db.(*gorm.DB)).Export(&export, userID.(uint), filter, format, now); err != nil {
+		utils.Error("Failed to export transactions: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export transactions"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions-%s.%s"`, now.Format("2006-01-02"), format))
+	c.Data(http.StatusOK, contentType, export.Bytes())
+}
+
+// transactionExportTypes maps export formats to their content types
+var transactionExportTypes = map[string]string{
+	services.ExportCSV:  "text/csv; charset=utf-8",
+	services.ExportOFX:  "application/x-ofx",
+	services.ExportJSON: "application/json; charset=utf-8",
+}
+
+// transactionFilter reads the transaction history filters from the request
+func transactionFilter(c *gin.Context) (repositories.TransactionFilter, error) {
+	var filter repositories.TransactionFilter
+	var err error
+
+	switch direction := c.Query("direction"); direction {
+	case "", repositories.TransactionsSent, repositories.TransactionsReceived:
+		filter.Direction = direction
+	default:
+		return filter, errors.New("Invalid direction, expected sent or received")
+	}
+
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		return filter, errors.New("to must be after from")
+	}
+
+	if counterparty := c.Query("counterparty"); counterparty != "" {
+		id, err := strconv.ParseUint(counterparty, 10, 32)
+		if err != nil || id == 0 {
+			return filter, errors.New("Invalid counterparty")
 		}
+		filter.CounterpartyID = uint(id)
+	}
 
-		// Get receiver name if not cached
-		if _, exists := userNames[tx.ReceiverID]; !exists {
-			if tx.ReceiverID == currentUserID {
-				userNames[tx.ReceiverID] = "You" // Special case for current user
-			} else {
-				user, err := userRepo.GetUserByID(tx.ReceiverID)
-				if err == nil {
-					userNames[tx.ReceiverID] = user.Name
-				} else {
-					userNames[tx.ReceiverID] = "Unknown User"
-				}
+	for _, bound := range []struct {
+		name  string
+		value *int
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		if param := c.Query(bound.name); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 {
+				return filter, errors.New("Invalid " + bound.name)
 			}
+			*bound.value = n
 		}
+	}
+	if filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return filter, errors.New("min_amount can't be more than max_amount")
+	}
+
+	filter.Note = strings.TrimSpace(c.Query("note"))
+	return filter, nil
+}
+
+// GetBalance returns the current user's spendable SkillPoints and the points
+// held in escrow for their sessions
+func GetBalance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-		// Create enhanced transaction
-		enhancedTransactions[i] = EnhancedTransaction{
-			Transaction:  tx,
-			SenderName:   userNames[tx.SenderID],
-			ReceiverName: userNames[tx.ReceiverID],
+	db, exists := c.Get("db")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not found"})
+		return
+	}
+
+	balance, err := services.NewEscrowService(db.(*gorm.DB), 0).Balance(userID.(uint))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
 		}
+		utils.Error("Failed to retrieve balance: " + err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve balance"})
+		return
 	}
 
-	c.JSON(http.StatusOK, enhancedTransactions)
+	c.JSON(http.StatusOK, balance)
 }
 
 // CreateTransaction handles the creation of a new transaction
@@ -136,6 +241,12 @@ func CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	sender, err := userRepo.GetUserByID(senderID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
 	// Create transaction
 	transaction := models.Transaction{
 		SenderID:   senderID.(uint),
@@ -151,7 +262,7 @@ func CreateTransaction(c *gin.Context) {
 		utils.Error("Failed to create transaction: " + err.Error())
 
 		// Return specific error for insufficient funds
-		if err.Error() == "insufficient skill points" {
+		if errors.Is(err, repositories.ErrInsufficientPoints) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "You don't have enough SkillPoints"})
 			return
 		}
@@ -170,8 +281,10 @@ func CreateTransaction(c *gin.Context) {
 			"amount":       transaction.Amount,
 			"note":         transaction.Note,
 			"created_at":   transaction.CreatedAt,
-			"senderName":   "You",
+			"senderName":   sender.Name,
 			"receiverName": recipient.Name,
+			"isSender":     true,
+			"isReceiver":   false,
 		},
 	})
 }
@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestTransactionHistoryValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.GET("/transactions", controllers.GetTransactions)
+	router.GET("/transactions/export", controllers.ExportTransactions)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Invalid Direction", "/transactions?direction=both"},
+		{"Invalid From", "/transactions?from=yesterday"},
+		{"Empty Range", "/transactions?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z"},
+		{"Invalid Counterparty", "/transactions?counterparty=bob"},
+		{"Invalid Min Amount", "/transactions?min_amount=0"},
+		{"Reversed Amounts", "/transactions?min_amount=50&max_amount=10"},
+		{"Invalid Limit", "/transactions?limit=-5"},
+		{"Invalid Export Format", "/transactions/export?format=xlsx"},
+		{"Invalid Export Filter", "/transactions/export?format=csv&max_amount=many"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// PrivacySettings are the privacy choices of a user.
+type PrivacySettings struct {
+	Searchable        bool   `json:"searchable"`
+	ShowEmail         bool   `json:"show_email"`
+	ProfileVisibility string `json:"profile_visibility"`
+}
+
+// UpdatePrivacySettingsRequest changes some or all privacy settings; omitted fields keep their value.
+type UpdatePrivacySettingsRequest struct {
+	Searchable        *bool   `json:"searchable"`
+	ShowEmail         *bool   `json:"show_email"`
+	ProfileVisibility *string `json:"profile_visibility"`
+}
+
+// TimeZoneSettings is the time zone a user sees times in.
+type TimeZoneSettings struct {
+	TimeZone string `json:"time_zone" binding:"required"`
+}
+
+// ReminderSettings are how a user is reminded of upcoming sessions.
+type ReminderSettings struct {
+	InApp bool `json:"in_app"`
+	Email bool `json:"email"`
+}
+
+// UpdateReminderSettingsRequest changes some or all reminder settings; omitted fields keep their value.
+type UpdateReminderSettingsRequest struct {
+	InApp *bool `json:"in_app"`
+	Email *bool `json:"email"`
+}
+
+// GetUserProfile returns the public profile of a user.
+// Members-only profiles require the viewer to be logged in.
+func GetUserProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	user, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.Error("Failed to retrieve user: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return
+	}
+
+	_, authenticated := c.Get("user_id")
+	if !user.VisibleTo(authenticated) {
+		utils.JSONError(c, http.StatusUnauthorized, "Log in to view this profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, user.PublicProfile())
+}
+
+// GetPrivacySettings returns the authenticated user's privacy settings.
+func GetPrivacySettings(c *gin.Context) {
+	user, _, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, privacySettingsOf(user))
+}
+
+// UpdatePrivacySettings changes the authenticated user's privacy settings.
+func UpdatePrivacySettings(c *gin.Context) {
+	var req UpdatePrivacySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid privacy settings")
+		return
+	}
+	if req.ProfileVisibility != nil {
+		if err := models.ValidateProfileVisibility(*req.ProfileVisibility); err != nil {
+			utils.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	user, repo, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if req.Searchable != nil {
+		user.Searchable = *req.Searchable
+	}
+	if req.ShowEmail != nil {
+		user.ShowEmail = *req.ShowEmail
+	}
+	if req.ProfileVisibility != nil {
+		user.ProfileVisibility = *req.ProfileVisibility
+	}
+
+	if err := repo.UpdatePrivacy(user); err != nil {
+		utils.Error("Failed to update privacy settings: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update privacy settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, privacySettingsOf(user))
+}
+
+// GetTimeZone returns the authenticated user's time zone.
+func GetTimeZone(c *gin.Context) {
+	user, _, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, TimeZoneSettings{TimeZone: user.TimeZone})
+}
+
+// UpdateTimeZone changes the authenticated user's time zone.
+func UpdateTimeZone(c *gin.Context) {
+	var req TimeZoneSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid time zone settings")
+		return
+	}
+	if err := models.ValidateTimeZone(req.TimeZone); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, repo, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	user.TimeZone = req.TimeZone
+	if err := repo.UpdateTimeZone(user); err != nil {
+		utils.Error("Failed to update time zone: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update time zone")
+		return
+	}
+
+	c.JSON(http.StatusOK, TimeZoneSettings{TimeZone: user.TimeZone})
+}
+
+// GetReminderSettings returns how the authenticated user is reminded of sessions.
+func GetReminderSettings(c *gin.Context) {
+	user, _, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, ReminderSettings{InApp: user.RemindInApp, Email: user.RemindByEmail})
+}
+
+// UpdateReminderSettings changes how the authenticated user is reminded of sessions.
+// Turning both off opts out of reminders.
+func UpdateReminderSettings(c *gin.Context) {
+	var req UpdateReminderSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid reminder settings")
+		return
+	}
+
+	user, repo, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if req.InApp != nil {
+		user.RemindInApp = *req.InApp
+	}
+	if req.Email != nil {
+		user.RemindByEmail = *req.Email
+	}
+
+	if err := repo.UpdateReminderSettings(user); err != nil {
+		utils.Error("Failed to update reminder settings: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update reminder settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, ReminderSettings{InApp: user.RemindInApp, Email: user.RemindByEmail})
+}
+
+// GetCancellationPolicy returns the authenticated user's cancellation policy as a teacher.
+func GetCancellationPolicy(c *gin.Context) {
+	user, _, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, user.CancellationPolicy)
+}
+
+// UpdateCancellationPolicy changes the authenticated user's cancellation policy
+// as a teacher. Sessions already confirmed keep the policy they were booked under.
+func UpdateCancellationPolicy(c *gin.Context) {
+	var req models.CancellationPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid cancellation policy")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, repo, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	user.CancellationPolicy = req
+	if err := repo.UpdateCancellationPolicy(user); err != nil {
+		utils.Error("Failed to update cancellation policy: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to update cancellation policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, user.CancellationPolicy)
+}
+
+// viewerLocation returns the time zone of the authenticated user, or UTC when
+// there is none or it can't be loaded
+func viewerLocation(c *gin.Context, db *gorm.DB) *time.Location {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return time.UTC
+	}
+	user, err := repositories.NewUserRepository(db).GetUserByID(userID.(uint))
+	if err != nil {
+		utils.Error("Failed to retrieve user time zone: " + err.Error())
+		return time.UTC
+	}
+	return user.Location()
+}
+
+// loadCurrentUser loads the authenticated user. It writes the error response when it fails.
+func loadCurrentUser(c *gin.Context) (*models.User, *repositories.UserRepository, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return nil, nil, false
+	}
+
+	repo := repositories.NewUserRepository(db.(*gorm.DB))
+	user, err := repo.GetUserByID(userID.(uint))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.JSONError(c, http.StatusNotFound, "User not found")
+			return nil, nil, false
+		}
+		utils.Error("Failed to retrieve user: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve user")
+		return nil, nil, false
+	}
+
+	return user, repo, true
+}
+
+// privacySettingsOf returns the privacy settings of a user
+func privacySettingsOf(user *models.User) PrivacySettings {
+	return PrivacySettings{
+		Searchable:        user.Searchable,
+		ShowEmail:         user.ShowEmail,
+		ProfileVisibility: user.ProfileVisibility,
+	}
+}
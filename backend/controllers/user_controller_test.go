@@ -0,0 +1,119 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestUpdatePrivacySettingsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.PUT("/users/me/privacy", controllers.UpdatePrivacySettings)
+
+	req, _ := http.NewRequest("PUT", "/users/me/privacy", bytes.NewBufferString(`{"profile_visibility": "friends"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetUserProfileInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/users/:id", controllers.GetUserProfile)
+
+	req, _ := http.NewRequest("GET", "/users/abc", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateTimeZoneValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.PUT("/users/me/timezone", controllers.UpdateTimeZone)
+
+	for _, body := range []string{`{}`, `{"time_zone": "Local"}`, `{"time_zone": "Mars/Olympus"}`} {
+		req, _ := http.NewRequest("PUT", "/users/me/timezone", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestUpdateReminderSettingsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.PUT("/users/me/reminders", controllers.UpdateReminderSettings)
+
+	req, _ := http.NewRequest("PUT", "/users/me/reminders", bytes.NewBufferString(`{"email": "no"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateCancellationPolicyValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.PUT("/users/me/cancellation-policy", controllers.UpdateCancellationPolicy)
+
+	for _, body := range []string{
+		`{"free_cancellation_hours": -1}`,
+		`{"free_cancellation_hours": 24, "late_cancellation_percent": 150}`,
+		`{"late_cancellation_percent": "half"}`,
+	} {
+		req, _ := http.NewRequest("PUT", "/users/me/cancellation-policy", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
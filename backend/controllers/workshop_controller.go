@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// workshopListLimit is the most workshops listed at once
+const workshopListLimit = 50
+
+// WorkshopRequest defines a workshop a teacher schedules
+type WorkshopRequest struct {
+	SkillID   uint      `json:"skill_id" binding:"required"`
+	Title     string    `json:"title" binding:"required"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	Capacity  int       `json:"capacity" binding:"required"`
+	SeatPrice int       `json:"seat_price"`
+}
+
+// CheckInRequest carries the check-in code an attendee shows their teacher
+type CheckInRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetWorkshops lists upcoming workshops, soonest first, in the viewer's time zone.
+// Optional parameters: skill_id and teacher_id keep the workshops of that skill or teacher.
+func GetWorkshops(c *gin.Context) {
+	var ids [2]uint
+	for i, name := range []string{"skill_id", "teacher_id"} {
+		param := c.Query(name)
+		if param == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "Invalid "+name)
+			return
+		}
+		ids[i] = uint(id)
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	workshops, err := repositories.NewWorkshopRepository(db.(*gorm.DB)).GetUpcomingWorkshops(time.Now(), ids[0], ids[1], workshopListLimit)
+	if err != nil {
+		utils.Error("Failed to retrieve workshops: " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, "Failed to retrieve workshops")
+		return
+	}
+
+	loc := viewerLocation(c, db.(*gorm.DB))
+	for i := range workshops {
+		workshops[i] = workshops[i].In(loc)
+	}
+	c.JSON(http.StatusOK, workshops)
+}
+
+// GetWorkshop returns a workshop with how full it is and the authenticated
+// user's seat, including their check-in code once booked.
+func GetWorkshop(c *gin.Context) {
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	details, err := services.NewWorkshopService(db).Details(id, userID)
+	if err != nil {
+		workshopError(c, err, "Failed to retrieve workshop")
+		return
+	}
+	details.Workshop = details.Workshop.In(viewerLocation(c, db))
+	c.JSON(http.StatusOK, details)
+}
+
+// CreateWorkshop schedules a workshop of one of the authenticated teacher's skills.
+func CreateWorkshop(c *gin.Context) {
+	var req WorkshopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid workshop data")
+		return
+	}
+	if err := validateSessionTimes(req.StartTime, req.EndTime); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return
+	}
+
+	workshop := models.Workshop{
+		TeacherID: userID.(uint),
+		SkillID:   req.SkillID,
+		Title:     req.Title,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Capacity:  req.Capacity,
+		SeatPrice: req.SeatPrice,
+	}
+	created, err := services.NewWorkshopService(db.(*gorm.DB)).CreateWorkshop(&workshop)
+	if err != nil {
+		workshopError(c, err, "Failed to schedule workshop")
+		return
+	}
+	c.JSON(http.StatusCreated, created.In(viewerLocation(c, db.(*gorm.DB))))
+}
+
+// CancelWorkshop cancels one of the authenticated teacher's workshops and refunds every seat.
+func CancelWorkshop(c *gin.Context) {
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	workshop, err := services.NewWorkshopService(db).CancelWorkshop(id, userID)
+	if err != nil {
+		workshopError(c, err, "Failed to cancel workshop")
+		return
+	}
+	c.JSON(http.StatusOK, workshop.In(viewerLocation(c, db)))
+}
+
+// BookWorkshopSeat books a seat in a workshop for the authenticated user, or
+// puts them on its waitlist when it is full.
+func BookWorkshopSeat(c *gin.Context) {
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	seat, err := services.NewWorkshopService(db).BookSeat(id, userID)
+	if err != nil {
+		workshopError(c, err, "Failed to book workshop seat")
+		return
+	}
+	c.JSON(http.StatusCreated, seat)
+}
+
+// CancelWorkshopSeat gives up the authenticated user's seat or waitlist place in a workshop.
+func CancelWorkshopSeat(c *gin.Context) {
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	seat, err := services.NewWorkshopService(db).CancelSeat(id, userID)
+	if err != nil {
+		workshopError(c, err, "Failed to cancel workshop seat")
+		return
+	}
+	c.JSON(http.StatusOK, seat)
+}
+
+// GetWorkshopRoster lists the attendees and waitlist of one of the authenticated teacher's workshops.
+func GetWorkshopRoster(c *gin.Context) {
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	roster, err := services.NewWorkshopService(db).Roster(id, userID)
+	if err != nil {
+		workshopError(c, err, "Failed to retrieve roster")
+		return
+	}
+	roster.Workshop = roster.Workshop.In(viewerLocation(c, db))
+	c.JSON(http.StatusOK, roster)
+}
+
+// CheckInWorkshopSeat checks in the attendee of one of the authenticated
+// teacher's workshops holding a check-in code.
+func CheckInWorkshopSeat(c *gin.Context) {
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Check-in code is required")
+		return
+	}
+
+	id, userID, db, ok := workshopContext(c)
+	if !ok {
+		return
+	}
+
+	seat, err := services.NewWorkshopService(db).CheckIn(id, userID, req.Code)
+	if err != nil {
+		workshopError(c, err, "Failed to check in attendee")
+		return
+	}
+	c.JSON(http.StatusOK, seat)
+}
+
+// workshopContext reads the workshop named by the id parameter, the
+// authenticated user and the database. It writes the error response when it fails.
+func workshopContext(c *gin.Context) (uint, uint, *gorm.DB, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.JSONError(c, http.StatusBadRequest, "Invalid workshop ID")
+		return 0, 0, nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+		return 0, 0, nil, false
+	}
+
+	db, exists := c.Get("db")
+	if !exists {
+		utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+		return 0, 0, nil, false
+	}
+
+	return uint(id), userID.(uint), db.(*gorm.DB), true
+}
+
+// workshopError writes the response for an error from the workshop service
+func workshopError(c *gin.Context, err error, message string) {
+	var invalid *services.InvalidSessionError
+	switch {
+	case errors.Is(err, services.ErrWorkshopNotFound):
+		utils.JSONError(c, http.StatusNotFound, "Workshop not found")
+	case errors.Is(err, services.ErrSkillNotFound):
+		utils.JSONError(c, http.StatusNotFound, "Skill not found")
+	case errors.Is(err, services.ErrNoSeat):
+		utils.JSONError(c, http.StatusNotFound, "You have no seat or waitlist place in this workshop")
+	case errors.Is(err, services.ErrNotSkillTeacher):
+		utils.JSONError(c, http.StatusForbidden, "You can only run workshops of your own skills")
+	case errors.Is(err, services.ErrNotWorkshopTeacher):
+		utils.JSONError(c, http.StatusForbidden, "Only the workshop's teacher can do that")
+	case errors.Is(err, services.ErrOwnWorkshop):
+		utils.JSONError(c, http.StatusBadRequest, "Cannot book a seat in your own workshop")
+	case errors.Is(err, services.ErrInvalidCheckInCode):
+		utils.JSONError(c, http.StatusBadRequest, "Invalid check-in code")
+	case errors.Is(err, repositories.ErrInsufficientPoints):
+		utils.JSONError(c, http.StatusBadRequest, "You don't have enough SkillPoints")
+	case errors.Is(err, services.ErrAlreadyBooked):
+		utils.JSONError(c, http.StatusConflict, "You already have a seat or waitlist place in this workshop")
+	case errors.Is(err, services.ErrWorkshopClosed):
+		utils.JSONError(c, http.StatusConflict, "The workshop has already started or been cancelled")
+	case errors.Is(err, services.ErrCheckInClosed):
+		utils.JSONError(c, http.StatusConflict, "Check-in is open from 30 minutes before the workshop starts until it ends")
+	case errors.Is(err, services.ErrAlreadyCheckedIn):
+		utils.JSONError(c, http.StatusConflict, "The attendee is already checked in")
+	case errors.Is(err, services.ErrScheduleConflict):
+		utils.JSONError(c, http.StatusConflict, "You already have a session or workshop at that time")
+	case errors.Is(err, services.ErrParticipantBusy):
+		utils.JSONError(c, http.StatusConflict, "You are busy at that time")
+	case errors.As(err, &invalid):
+		utils.JSONError(c, http.StatusBadRequest, invalid.Error())
+	default:
+		utils.Error(message + ": " + err.Error())
+		utils.JSONError(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,58 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/controllers"
+)
+
+func TestWorkshopRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.GET("/workshops", controllers.GetWorkshops)
+	router.POST("/workshops", controllers.CreateWorkshop)
+	router.GET("/workshops/:id", controllers.GetWorkshop)
+	router.POST("/workshops/:id/seats", controllers.BookWorkshopSeat)
+	router.DELETE("/workshops/:id/seats", controllers.CancelWorkshopSeat)
+	router.POST("/workshops/:id/check-in", controllers.CheckInWorkshopSeat)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"Invalid Skill Filter", "GET", "/workshops?skill_id=guitar", ""},
+		{"Invalid Teacher Filter", "GET", "/workshops?teacher_id=-1", ""},
+		{"Missing Title", "POST", "/workshops", `{"skill_id": 1, "start_time": "2030-01-01T10:00:00Z", "end_time": "2030-01-01T12:00:00Z", "capacity": 10}`},
+		{"Missing Capacity", "POST", "/workshops", `{"skill_id": 1, "title": "Guitar", "start_time": "2030-01-01T10:00:00Z", "end_time": "2030-01-01T12:00:00Z"}`},
+		{"Past Workshop", "POST", "/workshops", `{"skill_id": 1, "title": "Guitar", "start_time": "2020-01-01T10:00:00Z", "end_time": "2020-01-01T12:00:00Z", "capacity": 10}`},
+		{"Ends Before Start", "POST", "/workshops", `{"skill_id": 1, "title": "Guitar", "start_time": "2030-01-01T12:00:00Z", "end_time": "2030-01-01T10:00:00Z", "capacity": 10}`},
+		{"Invalid Workshop ID", "GET", "/workshops/abc", ""},
+		{"Invalid Seat Workshop ID", "POST", "/workshops/abc/seats", ""},
+		{"Invalid Cancel Workshop ID", "DELETE", "/workshops/-1/seats", ""},
+		{"Missing Check-In Code", "POST", "/workshops/1/check-in", `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+}
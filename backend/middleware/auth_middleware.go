@@ -125,32 +125,15 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check token cache first
-		claims, found := tokenCache.Get(tokenString)
-
-		if !found {
-			// Validate the token
-			var err error
-			claims, err = utils.ValidateToken(tokenString)
-			if err != nil {
-				if err == utils.ErrExpiredToken {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "token has expired"})
-				} else {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-				}
-				c.Abort()
-				return
+		claims, err := validateToken(tokenString)
+		if err != nil {
+			if err == utils.ErrExpiredToken {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "token has expired"})
+			} else {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			}
-
-			// Calculate expiry time for cache
-			tokenExpiry := time.Unix(claims.ExpiresAt.Time.Unix(), 0)
-			cacheExpiry := time.Now().Add(10 * time.Minute) // 10 minute cache
-			if tokenExpiry.Before(cacheExpiry) {
-				cacheExpiry = tokenExpiry
-			}
-
-			// Cache the validated token
-			tokenCache.Set(tokenString, claims, cacheExpiry)
+			c.Abort()
+			return
 		}
 
 		// Set user details in context
@@ -161,3 +144,44 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware sets user context when the request carries a valid
+// token and lets it through anonymously otherwise, for public endpoints that
+// show logged-in users more.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := extractToken(c.GetHeader("Authorization")); tokenString != "" {
+			if claims, err := validateToken(tokenString); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("role", claims.Role)
+				c.Set("email", claims.Email)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// validateToken returns the claims of a token, using the cache when possible
+func validateToken(tokenString string) (*utils.Claims, error) {
+	// Check token cache first
+	if claims, found := tokenCache.Get(tokenString); found {
+		return claims, nil
+	}
+
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate expiry time for cache
+	tokenExpiry := time.Unix(claims.ExpiresAt.Time.Unix(), 0)
+	cacheExpiry := time.Now().Add(10 * time.Minute) // 10 minute cache
+	if tokenExpiry.Before(cacheExpiry) {
+		cacheExpiry = tokenExpiry
+	}
+
+	// Cache the validated token
+	tokenCache.Set(tokenString, claims, cacheExpiry)
+	return claims, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+// CalDAVAuthMiddleware signs in calendar clients with HTTP Basic auth, using the
+// user's email and their CalDAV app password. Calendar clients keep the
+// password, so the account password is never stored in them, and resetting or
+// revoking the app password signs them out. The calendar feed token is not
+// accepted: it is only good for reading the feed. OPTIONS requests need no sign-in.
+func CalDAVAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		email, password, ok := c.Request.BasicAuth()
+		if !ok || email == "" || password == "" {
+			calDAVUnauthorized(c)
+			return
+		}
+
+		db, exists := c.Get("db")
+		if !exists {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		user, err := repositories.NewUserRepository(db.(*gorm.DB)).GetUserByEmail(email)
+		if err != nil || !user.CompareCalDAVPassword(password) {
+			calDAVUnauthorized(c)
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("role", user.Role)
+		c.Set("email", user.Email)
+
+		c.Next()
+	}
+}
+
+// calDAVUnauthorized asks the client to sign in
+func calDAVUnauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="SkillSwap", charset="UTF-8"`)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
@@ -0,0 +1,82 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/middleware"
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestCalDAVAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.CalDAVAuthMiddleware())
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.Handle("PROPFIND", "/caldav/", handler)
+	router.OPTIONS("/caldav/", handler)
+
+	t.Run("Asks For Basic Auth", func(t *testing.T) {
+		req, _ := http.NewRequest("PROPFIND", "/caldav/", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Error("Expected a WWW-Authenticate header")
+		}
+	})
+
+	t.Run("Options Without Sign-In", func(t *testing.T) {
+		req, _ := http.NewRequest("OPTIONS", "/caldav/", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+}
+
+func TestCalDAVAuthRejectsFeedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openIdempotencyTestDB(t)
+
+	user := models.User{
+		Name:               "Calendar User",
+		Email:              "caldav-auth@example.com",
+		Password:           "password123",
+		CalendarToken:      "feed-token-shared-with-calendar-services",
+		CalDAVPasswordHash: models.HashCalDAVPassword("app-password"),
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("db", db)
+		c.Next()
+	})
+	router.Use(middleware.CalDAVAuthMiddleware())
+	router.Handle("PUT", "/caldav/*path", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for password, want := range map[string]int{
+		user.CalendarToken: http.StatusUnauthorized,
+		"app-password":     http.StatusNoContent,
+	} {
+		req, _ := http.NewRequest("PUT", "/caldav/1/sessions/1.ics", nil)
+		req.SetBasicAuth(user.Email, password)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("Expected status %d signing in with %q, got %d", want, password, w.Code)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyHeader names the header clients send to make a request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxBufferedBody is the most of a request body kept in memory while it is
+// fingerprinted; larger bodies, such as uploads, are spooled to a temporary file
+const maxBufferedBody = 1 << 20
+
+// IdempotencyMiddleware makes a mutating endpoint safe to retry. The first
+// request a user sends with an Idempotency-Key runs as usual and its response
+// is stored for models.IdempotencyRetention; retries with the same key get
+// that response replayed, marked by an Idempotent-Replayed header, without
+// running again. Reusing a key for a different request gets 422, and retrying
+// while the first request still runs gets 409. Server errors aren't stored,
+// so a retry after one runs again. Requests without the header are let
+// through untouched. It must run after AuthMiddleware.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyValue := c.GetHeader(IdempotencyKeyHeader)
+		if keyValue == "" {
+			c.Next()
+			return
+		}
+		if len(keyValue) > models.MaxIdempotencyKeyLength {
+			utils.JSONError(c, http.StatusBadRequest, fmt.Sprintf("%s can be at most %d characters", IdempotencyKeyHeader, models.MaxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			utils.JSONError(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+		db, exists := c.Get("db")
+		if !exists {
+			utils.JSONError(c, http.StatusInternalServerError, "Database connection not found")
+			c.Abort()
+			return
+		}
+
+		fingerprint, body, err := fingerprintRequest(c.Request)
+		if err != nil {
+			utils.JSONError(c, http.StatusBadRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		defer body.remove()
+		if c.Request.Body, err = body.reader(); err != nil {
+			utils.Error("Failed to reread request body: " + err.Error())
+			utils.JSONError(c, http.StatusInternalServerError, "Failed to read request body")
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		repo := repositories.NewIdempotencyRepository(db.(*gorm.DB))
+		key := models.IdempotencyKey{
+			UserID:      userID.(uint),
+			Key:         keyValue,
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(models.IdempotencyLease),
+		}
+		existing, err := repo.ClaimKey(&key, now)
+		if err != nil {
+			utils.Error("Failed to claim idempotency key: " + err.Error())
+			utils.JSONError(c, http.StatusInternalServerError, "Failed to process request")
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			replay(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := repo.ReleaseKey(&key); err != nil {
+				utils.Error("Failed to release idempotency key: " + err.Error())
+			}
+			return
+		}
+		key.StatusCode = recorder.Status()
+		key.ContentType = recorder.Header().Get("Content-Type")
+		key.Body = recorder.body.Bytes()
+		key.ExpiresAt = time.Now().Add(models.IdempotencyRetention)
+		if err := repo.CompleteKey(&key); err != nil {
+			utils.Error("Failed to store idempotent response: " + err.Error())
+		}
+	}
+}
+
+// replay answers a retry from the key the first request stored
+func replay(c *gin.Context, key *models.IdempotencyKey, fingerprint string) {
+	switch {
+	case key.Fingerprint != fingerprint:
+		utils.JSONError(c, http.StatusUnprocessableEntity, IdempotencyKeyHeader+" was already used for a different request")
+	case !key.Completed():
+		utils.JSONError(c, http.StatusConflict, "A request with this "+IdempotencyKeyHeader+" is still being processed")
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(key.StatusCode, key.ContentType, key.Body)
+	}
+	c.Abort()
+}
+
+// fingerprintRequest hashes the request's method, path and body, returning
+// the hex digest and the body kept to be read again. Multipart bodies are
+// hashed part by part, so a retry that picked a new boundary still matches.
+func fingerprintRequest(r *http.Request) (string, *spooledBody, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+
+	body := &spooledBody{}
+	if r.Body == nil {
+		return hex.EncodeToString(hash.Sum(nil)), body, nil
+	}
+	source := io.TeeReader(r.Body, body)
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" && params["boundary"] != "" {
+		parts := multipart.NewReader(source, params["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if err != nil {
+				break // the end, or a malformed body hashed from here on as it is
+			}
+			fmt.Fprintf(hash, "%q %q %q\n", part.FormName(), part.FileName(), part.Header.Get("Content-Type"))
+			if _, err := io.Copy(hash, part); err != nil {
+				body.remove()
+				return "", nil, err
+			}
+		}
+	}
+	if _, err := io.Copy(hash, source); err != nil {
+		body.remove()
+		return "", nil, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), body, nil
+}
+
+// spooledBody keeps a request body in memory, moving it to a temporary file
+// once it outgrows maxBufferedBody
+type spooledBody struct {
+	buf  bytes.Buffer
+	file *os.File
+}
+
+func (b *spooledBody) Write(p []byte) (int, error) {
+	if b.file == nil && b.buf.Len()+len(p) <= maxBufferedBody {
+		return b.buf.Write(p)
+	}
+	if b.file == nil {
+		file, err := os.CreateTemp("", "skillswap-request-*")
+		if err != nil {
+			return 0, err
+		}
+		b.file = file
+		if _, err := b.file.Write(b.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		b.buf = bytes.Buffer{}
+	}
+	return b.file.Write(p)
+}
+
+// reader returns the body from its start
+func (b *spooledBody) reader() (io.ReadCloser, error) {
+	if b.file == nil {
+		return io.NopCloser(bytes.NewReader(b.buf.Bytes())), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(b.file), nil
+}
+
+// remove deletes the temporary file, if the body needed one
+func (b *spooledBody) remove() {
+	if b.file != nil {
+		b.file.Close()
+		os.Remove(b.file.Name())
+		b.file = nil
+	}
+}
+
+// responseRecorder keeps a copy of the response written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,157 @@
+package middleware_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mplaczek99/SkillSwap/middleware"
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// idempotentRouter serves POST /things through IdempotencyMiddleware as user
+// 1, answering with status and counting the requests that ran
+func idempotentRouter(db *gorm.DB, status int, runs *int) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if db != nil {
+			c.Set("db", db)
+		}
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	router.POST("/things", middleware.IdempotencyMiddleware(), func(c *gin.Context) {
+		*runs++
+		var body struct {
+			Name string `json:"name"`
+		}
+		c.ShouldBindJSON(&body)
+		if c.ContentType() == "multipart/form-data" {
+			body.Name = c.PostForm("name")
+		}
+		c.JSON(status, gin.H{"name": body.Name, "run": *runs})
+	})
+	return router
+}
+
+func postThing(router *gin.Engine, key, contentType, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddlewareWithoutKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	runs := 0
+	router := idempotentRouter(nil, http.StatusCreated, &runs)
+
+	for i := 0; i < 2; i++ {
+		if w := postThing(router, "", "application/json", `{"name":"guitar"}`); w.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+	}
+	if runs != 2 {
+		t.Errorf("Expected requests without a key to run every time, ran %d times", runs)
+	}
+
+	if w := postThing(router, strings.Repeat("k", models.MaxIdempotencyKeyLength+1), "application/json", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an overlong key, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openIdempotencyTestDB(t)
+
+	runs := 0
+	router := idempotentRouter(db, http.StatusCreated, &runs)
+
+	first := postThing(router, "key-1", "application/json", `{"name":"guitar"}`)
+	retry := postThing(router, "key-1", "application/json", `{"name":"guitar"}`)
+	if runs != 1 {
+		t.Fatalf("Expected the retry not to run again, ran %d times", runs)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the first response replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected the replay to be marked")
+	}
+
+	if w := postThing(router, "key-1", "application/json", `{"name":"piano"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for a reused key, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	// A retried upload may pick a new multipart boundary
+	upload := func(boundary string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.SetBoundary(boundary)
+		writer.WriteField("name", "video")
+		writer.Close()
+		return postThing(router, "key-2", writer.FormDataContentType(), body.String())
+	}
+	if w := upload("first-boundary"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected the upload to run, got %d", w.Code)
+	}
+	if w := upload("second-boundary"); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the upload replayed, got %d", w.Code)
+	}
+	if runs != 2 {
+		t.Errorf("Expected the upload to run once, ran %d times", runs-1)
+	}
+}
+
+func TestIdempotencyMiddlewareRetriesServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openIdempotencyTestDB(t)
+
+	runs := 0
+	router := idempotentRouter(db, http.StatusInternalServerError, &runs)
+	postThing(router, "key-3", "application/json", `{}`)
+	postThing(router, "key-3", "application/json", `{}`)
+	if runs != 2 {
+		t.Errorf("Expected a retry after a server error to run again, ran %d times", runs)
+	}
+}
+
+// openIdempotencyTestDB connects to the Postgres database named by
+// TEST_DB_SOURCE and returns a transaction rolled back when the test ends.
+// Tests using it are skipped when TEST_DB_SOURCE is not set.
+func openIdempotencyTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	source := os.Getenv("TEST_DB_SOURCE")
+	if source == "" {
+		t.Skip("TEST_DB_SOURCE not set, skipping database test")
+	}
+	db, err := gorm.Open(postgres.Open(source), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
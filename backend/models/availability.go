@@ -0,0 +1,160 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Availability time zones must load on hosts without a time zone database
+	_ "time/tzdata"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+// MaxBufferMinutes is the longest break a teacher can keep between sessions
+const MaxBufferMinutes = 4 * 60
+
+// TimeRange is a span of time from Start up to End.
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// In returns the range with its times in loc.
+func (r TimeRange) In(loc *time.Location) TimeRange {
+	return TimeRange{Start: r.Start.In(loc), End: r.End.In(loc)}
+}
+
+// Availability is a recurring window of local time in which a teacher takes sessions.
+// Windows start and end on the same day, at the same wall-clock time whatever
+// the daylight saving offset.
+type Availability struct {
+	ID        uint  `gorm:"primaryKey" json:"id"`
+	TeacherID uint  `gorm:"index" json:"teacher_id"`
+	SkillID   *uint `json:"skill_id,omitempty"` // nil covers all the teacher's skills
+
+	TimeZone       string      `json:"time_zone"`                         // IANA name such as "Europe/Warsaw"
+	StartDate      string      `json:"start_date"`                        // First date, "2006-01-02"
+	StartsAt       string      `json:"starts_at"`                         // Local start, "09:00"
+	EndsAt         string      `json:"ends_at"`                           // Local end, "17:00"; "24:00" is midnight
+	RRule          string      `json:"rrule,omitempty"`                   // e.g. "FREQ=WEEKLY;BYDAY=MO,WE"; empty for one date
+	ExceptionDates StringArray `gorm:"type:jsonb" json:"exception_dates"` // Dates the window is skipped
+	BufferMinutes  int         `json:"buffer_minutes"`                    // Break kept around other sessions
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// Validate checks the availability's time zone, dates, times and rule
+func (a *Availability) Validate() error {
+	if a.TeacherID == 0 {
+		return errors.New("teacher_id is required")
+	}
+	if _, err := a.Location(); err != nil {
+		return err
+	}
+	if _, err := time.Parse(utils.DateLayout, a.StartDate); err != nil {
+		return errors.New("start_date must be a date such as 2026-01-31")
+	}
+
+	startsAt, err := parseClock(a.StartsAt)
+	if err != nil {
+		return fmt.Errorf("starts_at: %w", err)
+	}
+	endsAt, err := parseClock(a.EndsAt)
+	if err != nil {
+		return fmt.Errorf("ends_at: %w", err)
+	}
+	if endsAt <= startsAt {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	if a.RRule != "" {
+		if _, err := utils.ParseRRule(a.RRule); err != nil {
+			return fmt.Errorf("rrule: %w", err)
+		}
+	}
+	for _, date := range a.ExceptionDates {
+		if _, err := time.Parse(utils.DateLayout, date); err != nil {
+			return fmt.Errorf("invalid exception date %q", date)
+		}
+	}
+	if a.BufferMinutes < 0 || a.BufferMinutes > MaxBufferMinutes {
+		return fmt.Errorf("buffer_minutes must be between 0 and %d", MaxBufferMinutes)
+	}
+	return nil
+}
+
+// Location loads the availability's time zone
+func (a *Availability) Location() (*time.Location, error) {
+	if a.TimeZone == "" {
+		return nil, errors.New("time_zone is required")
+	}
+	loc, err := time.LoadLocation(a.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", a.TimeZone)
+	}
+	return loc, nil
+}
+
+// Covers reports whether the availability applies to sessions of a skill
+func (a *Availability) Covers(skillID uint) bool {
+	return a.SkillID == nil || *a.SkillID == skillID
+}
+
+// Buffer returns the break kept around other sessions
+func (a *Availability) Buffer() time.Duration {
+	return time.Duration(a.BufferMinutes) * time.Minute
+}
+
+// Windows returns the availability's windows overlapping from up to to, in start order
+func (a *Availability) Windows(from, to time.Time) ([]TimeRange, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	loc, _ := a.Location()
+	start, _ := time.Parse(utils.DateLayout, a.StartDate)
+	startsAt, _ := parseClock(a.StartsAt)
+	endsAt, _ := parseClock(a.EndsAt)
+
+	// Local dates a window touching the range could fall on
+	first := utils.Date(from.In(loc)).AddDate(0, 0, -1)
+	last := utils.Date(to.In(loc))
+
+	dates := []time.Time{start}
+	if a.RRule != "" {
+		rule, _ := utils.ParseRRule(a.RRule)
+		dates = rule.Dates(start, first, last)
+	}
+
+	skip := map[string]bool{}
+	for _, date := range a.ExceptionDates {
+		skip[date] = true
+	}
+
+	var windows []TimeRange
+	for _, date := range dates {
+		if skip[date.Format(utils.DateLayout)] {
+			continue
+		}
+		window := TimeRange{Start: utils.At(date, startsAt, loc), End: utils.At(date, endsAt, loc)}
+		if window.Start.Before(to) && window.End.After(from) {
+			windows = append(windows, window)
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, nil
+}
+
+// parseClock reads a wall-clock time such as "09:30" as minutes after midnight
+func parseClock(clock string) (int, error) {
+	hours, minutes, ok := strings.Cut(clock, ":")
+	h, herr := strconv.Atoi(hours)
+	m, merr := strconv.Atoi(minutes)
+	if !ok || herr != nil || merr != nil || len(minutes) != 2 || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func validAvailability() models.Availability {
+	return models.Availability{
+		TeacherID: 1,
+		TimeZone:  "Europe/Warsaw",
+		StartDate: "2026-03-02",
+		StartsAt:  "09:00",
+		EndsAt:    "17:00",
+		RRule:     "FREQ=WEEKLY;BYDAY=MO,WE",
+	}
+}
+
+func TestAvailabilityValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		modify      func(a *models.Availability)
+		expectError bool
+	}{
+		{"Valid Availability", func(a *models.Availability) {}, false},
+		{"Until Midnight", func(a *models.Availability) { a.EndsAt = "24:00" }, false},
+		{"Missing Teacher", func(a *models.Availability) { a.TeacherID = 0 }, true},
+		{"Missing Time Zone", func(a *models.Availability) { a.TimeZone = "" }, true},
+		{"Unknown Time Zone", func(a *models.Availability) { a.TimeZone = "Mars/Olympus" }, true},
+		{"Invalid Start Date", func(a *models.Availability) { a.StartDate = "02/03/2026" }, true},
+		{"Invalid Clock", func(a *models.Availability) { a.StartsAt = "9am" }, true},
+		{"Ends Before Start", func(a *models.Availability) { a.EndsAt = "08:00" }, true},
+		{"Invalid Rule", func(a *models.Availability) { a.RRule = "FREQ=YEARLY" }, true},
+		{"Invalid Exception Date", func(a *models.Availability) { a.ExceptionDates = models.StringArray{"tomorrow"} }, true},
+		{"Negative Buffer", func(a *models.Availability) { a.BufferMinutes = -5 }, true},
+		{"Buffer Too Long", func(a *models.Availability) { a.BufferMinutes = models.MaxBufferMinutes + 1 }, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := validAvailability()
+			tc.modify(&a)
+			err := a.Validate()
+			if tc.expectError && err == nil {
+				t.Error("Expected an error but got none")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestAvailabilityWindows(t *testing.T) {
+	a := validAvailability()
+	a.ExceptionDates = models.StringArray{"2026-03-04"}
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	windows, err := a.Windows(from, to)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	// Wednesday is skipped, leaving Monday 09:00-17:00 Warsaw time (UTC+1)
+	if len(windows) != 1 {
+		t.Fatalf("Expected 1 window, got %d", len(windows))
+	}
+	if want := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC); !windows[0].Start.Equal(want) {
+		t.Errorf("Expected window to start at %v, got %v", want, windows[0].Start.UTC())
+	}
+
+	skillID := uint(3)
+	a.SkillID = &skillID
+	if !a.Covers(3) || a.Covers(4) {
+		t.Error("Expected availability to cover only its own skill")
+	}
+}
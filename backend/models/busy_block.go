@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// BusyBlock is a time a user is busy elsewhere, imported from their own calendar.
+// Sessions can't be booked over it.
+type BusyBlock struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	UID       string    `gorm:"size:255;index" json:"uid"` // UID of the imported event
+	Summary   string    `json:"summary"`
+	StartTime time.Time `gorm:"index" json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	CreatedAt time.Time `json:"created_at"`
+}
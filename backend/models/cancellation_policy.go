@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxFreeCancellationHours is the longest notice a teacher can ask for free cancellation
+const MaxFreeCancellationHours = 14 * 24
+
+// CancellationPolicy is what a learner pays when they cancel a confirmed
+// session late or miss it. The zero policy lets learners cancel for free
+// until the session starts.
+type CancellationPolicy struct {
+	// Learners cancelling at least this many hours before the start pay nothing
+	FreeCancellationHours int `json:"free_cancellation_hours"`
+	// Share of the price, in percent, paid to the teacher for later cancellations and no-shows
+	LateCancellationPercent int `json:"late_cancellation_percent"`
+}
+
+// Validate checks the notice and share are in range
+func (p CancellationPolicy) Validate() error {
+	if p.FreeCancellationHours < 0 || p.FreeCancellationHours > MaxFreeCancellationHours {
+		return errors.New("free_cancellation_hours must be between 0 and 336")
+	}
+	if p.LateCancellationPercent < 0 || p.LateCancellationPercent > 100 {
+		return errors.New("late_cancellation_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Fee returns the part of price a learner pays for cancelling a session
+// starting at start at the time now
+func (p CancellationPolicy) Fee(price int, start, now time.Time) int {
+	if !now.After(start.Add(-time.Duration(p.FreeCancellationHours) * time.Hour)) {
+		return 0
+	}
+	return p.LateFee(price)
+}
+
+// LateFee returns the part of price a learner pays for a late cancellation or a no-show
+func (p CancellationPolicy) LateFee(price int) int {
+	return price * p.LateCancellationPercent / 100
+}
@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestCancellationPolicyValidate(t *testing.T) {
+	valid := []models.CancellationPolicy{
+		{},
+		{FreeCancellationHours: 24, LateCancellationPercent: 50},
+		{FreeCancellationHours: models.MaxFreeCancellationHours, LateCancellationPercent: 100},
+	}
+	for _, policy := range valid {
+		if err := policy.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", policy, err)
+		}
+	}
+
+	invalid := []models.CancellationPolicy{
+		{FreeCancellationHours: -1},
+		{FreeCancellationHours: models.MaxFreeCancellationHours + 1},
+		{LateCancellationPercent: -5},
+		{LateCancellationPercent: 101},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", policy)
+		}
+	}
+}
+
+func TestCancellationPolicyFee(t *testing.T) {
+	policy := models.CancellationPolicy{FreeCancellationHours: 24, LateCancellationPercent: 50}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"well ahead", start.Add(-48 * time.Hour), 0},
+		{"exactly at the notice", start.Add(-24 * time.Hour), 0},
+		{"inside the notice", start.Add(-23 * time.Hour), 15},
+		{"just before the start", start.Add(-time.Minute), 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Fee(30, start, tt.now); got != tt.want {
+				t.Errorf("Expected fee %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	if fee := (models.CancellationPolicy{}).Fee(30, start, start.Add(-time.Minute)); fee != 0 {
+		t.Errorf("Expected the zero policy to be free, got %d", fee)
+	}
+}
+
+func TestScheduleNoShowDisputable(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(time.Hour)
+	schedule := models.Schedule{Status: models.StatusNoShow, NoShowUserID: 1, DisputeDeadline: &deadline}
+
+	if !schedule.NoShowDisputable(now) {
+		t.Error("Expected the report to be disputable inside the window")
+	}
+	if schedule.NoShowDisputable(deadline) {
+		t.Error("Expected the window to close at the deadline")
+	}
+
+	schedule.DisputedAt = &now
+	if schedule.NoShowDisputable(now) {
+		t.Error("Expected a disputed report not to be disputed again")
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Dispute statuses
+const (
+	DisputeOpen     = "open"
+	DisputeRefunded = "refunded" // resolved with points given back to the sender
+	DisputeRejected = "rejected" // resolved with nothing given back
+)
+
+// MaxDisputeMessageLength is the longest dispute message or reason accepted
+const MaxDisputeMessageLength = 2000
+
+// MaxEvidenceSize is the largest evidence file accepted, in bytes
+const MaxEvidenceSize = 10 << 20
+
+// EvidenceTypes are the content types evidence files may have
+var EvidenceTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// TransactionDispute is a sender's claim that points they sent should come
+// back. Both participants can add messages and evidence while it is open,
+// and an admin resolves it, refunding the sender as much as they rule.
+// A transaction has at most one open dispute.
+type TransactionDispute struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	TransactionID uint   `gorm:"index;uniqueIndex:idx_transaction_disputes_open,where:status = 'open'" json:"transaction_id"`
+	OpenedByID    uint   `gorm:"index" json:"opened_by_id"`
+	Reason        string `json:"reason"`
+	Status        string `gorm:"size:16;default:open;index;uniqueIndex:idx_transaction_disputes_open,where:status = 'open'" json:"status"`
+
+	// The admin's resolution
+	ResolvedByID        *uint      `json:"resolved_by_id,omitempty"`
+	Resolution          string     `json:"resolution,omitempty"`            // the admin's explanation
+	RefundTransactionID *uint      `json:"refund_transaction_id,omitempty"` // the compensating transaction, if any
+	ResolvedAt          *time.Time `json:"resolved_at,omitempty"`
+
+	Messages  []DisputeMessage  `gorm:"foreignKey:DisputeID" json:"messages"`
+	Evidence  []DisputeEvidence `gorm:"foreignKey:DisputeID" json:"evidence"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// IsOpen reports whether the dispute is waiting for an admin's resolution
+func (d *TransactionDispute) IsOpen() bool {
+	return d.Status == DisputeOpen
+}
+
+// DisputeMessage is a note a participant or admin added to a dispute
+type DisputeMessage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	DisputeID uint      `gorm:"index" json:"dispute_id"`
+	AuthorID  uint      `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DisputeEvidence is a file a participant or admin attached to a dispute.
+// Files are stored outside the public uploads and only served to those who
+// can see the dispute.
+type DisputeEvidence struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	DisputeID   uint      `gorm:"index" json:"dispute_id"`
+	UploaderID  uint      `json:"uploader_id"`
+	FileName    string    `json:"file_name"` // as uploaded
+	ContentType string    `gorm:"size:64" json:"content_type"`
+	Size        int64     `json:"size"`
+	StoredName  string    `gorm:"size:64" json:"-"` // name of the file in the evidence directory
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ValidateDisputeText trims a dispute's reason or message, named by field,
+// and checks it isn't empty or too long
+func ValidateDisputeText(field, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("%s is required", field)
+	}
+	if len(text) > MaxDisputeMessageLength {
+		return "", fmt.Errorf("%s can be at most %d characters", field, MaxDisputeMessageLength)
+	}
+	return text, nil
+}
@@ -0,0 +1,66 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestValidateDisputeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		valid bool
+	}{
+		{"trimmed", "  Lesson never happened \n", "Lesson never happened", true},
+		{"longest", strings.Repeat("a", models.MaxDisputeMessageLength), strings.Repeat("a", models.MaxDisputeMessageLength), true},
+		{"empty", "", "", false},
+		{"blank", " \t\n", "", false},
+		{"too long", strings.Repeat("a", models.MaxDisputeMessageLength+1), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := models.ValidateDisputeText("reason", tt.text)
+			if tt.valid && (err != nil || got != tt.want) {
+				t.Errorf("Expected %q, got %q (%v)", tt.want, got, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected %q to be rejected", tt.text)
+			}
+		})
+	}
+}
+
+func TestTransactionDisputeIsOpen(t *testing.T) {
+	for status, open := range map[string]bool{
+		models.DisputeOpen:     true,
+		models.DisputeRefunded: false,
+		models.DisputeRejected: false,
+	} {
+		dispute := models.TransactionDispute{Status: status}
+		if dispute.IsOpen() != open {
+			t.Errorf("Expected IsOpen() to be %v for a %s dispute", open, status)
+		}
+	}
+}
+
+func TestTransactionRefundAndParticipants(t *testing.T) {
+	originalID := uint(7)
+	original := models.Transaction{ID: originalID, SenderID: 1, ReceiverID: 2, Amount: 30}
+	refund := models.Transaction{SenderID: 2, ReceiverID: 1, Amount: 10, RefundOf: &originalID}
+
+	if original.IsRefund() {
+		t.Error("Expected a transfer not to be a refund")
+	}
+	if !refund.IsRefund() {
+		t.Error("Expected a transaction with RefundOf to be a refund")
+	}
+	for userID, participant := range map[uint]bool{1: true, 2: true, 3: false} {
+		if original.IsParticipant(userID) != participant {
+			t.Errorf("Expected IsParticipant(%d) to be %v", userID, participant)
+		}
+	}
+}
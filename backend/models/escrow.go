@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Escrow statuses
+const (
+	EscrowHeld     = "held"
+	EscrowReleased = "released" // paid to the teacher, in full or in part
+	EscrowRefunded = "refunded" // returned to the learner in full
+)
+
+// Escrow holds a learner's payment for a confirmed session or a booked
+// workshop seat until it is released to the teacher or refunded. Held points
+// have left the learner's spendable balance but not yet reached the teacher's.
+type Escrow struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ScheduleID uint   `gorm:"uniqueIndex:idx_escrows_subject" json:"schedule_id,omitempty"`
+	SeatID     uint   `gorm:"uniqueIndex:idx_escrows_subject" json:"seat_id,omitempty"` // workshop seat paid for
+	LearnerID  uint   `gorm:"index" json:"learner_id"`
+	TeacherID  uint   `gorm:"index" json:"teacher_id"`
+	Amount     int    `json:"amount"`
+	Status     string `gorm:"size:16;default:held;index" json:"status"`
+
+	// When each participant confirmed the session took place
+	LearnerConfirmedAt *time.Time `json:"learner_confirmed_at,omitempty"`
+	TeacherConfirmedAt *time.Time `json:"teacher_confirmed_at,omitempty"`
+
+	// The teacher's cancellation policy when the session was booked
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy" gorm:"embedded"`
+
+	TransactionID *uint      `json:"transaction_id,omitempty"` // the payment to the teacher once released
+	Refunded      int        `json:"refunded"`                 // the part returned to the learner once settled
+	SettledAt     *time.Time `json:"settled_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ConfirmCompletion records that a participant confirmed the session took
+// place. It reports false if they had already confirmed it.
+func (e *Escrow) ConfirmCompletion(userID uint, at time.Time) bool {
+	confirmed := &e.LearnerConfirmedAt
+	if userID == e.TeacherID {
+		confirmed = &e.TeacherConfirmedAt
+	}
+	if *confirmed != nil {
+		return false
+	}
+	*confirmed = &at
+	return true
+}
+
+// BothConfirmed reports whether both participants confirmed the session took place
+func (e *Escrow) BothConfirmed() bool {
+	return e.LearnerConfirmedAt != nil && e.TeacherConfirmedAt != nil
+}
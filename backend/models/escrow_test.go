@@ -0,0 +1,26 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestEscrowConfirmCompletion(t *testing.T) {
+	escrow := models.Escrow{LearnerID: 1, TeacherID: 2}
+	now := time.Now()
+
+	if !escrow.ConfirmCompletion(1, now) {
+		t.Error("Expected the learner's first confirmation to be recorded")
+	}
+	if escrow.ConfirmCompletion(1, now) {
+		t.Error("Expected the learner's second confirmation to be ignored")
+	}
+	if escrow.BothConfirmed() {
+		t.Error("Expected the escrow to wait for the teacher")
+	}
+	if !escrow.ConfirmCompletion(2, now) || !escrow.BothConfirmed() {
+		t.Error("Expected both participants to have confirmed")
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// IdempotencyRetention is how long the response to a request with an
+// Idempotency-Key is kept to be replayed to retries
+const IdempotencyRetention = 24 * time.Hour
+
+// IdempotencyLease is how long the first request with a key may run before a
+// retry takes the key over, in case the server handling it went away
+const IdempotencyLease = 5 * time.Minute
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey records a request a user sent with an Idempotency-Key and,
+// once it finished, the response to replay when the request is retried.
+// Keys belong to the user, so two users can't see each other's responses.
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	Key         string    `gorm:"size:255;uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	Fingerprint string    `gorm:"size:64" json:"fingerprint"` // SHA-256 of the request's method, path and body
+	StatusCode  int       `json:"status_code"`                // 0 while the first request is running
+	ContentType string    `gorm:"size:255" json:"content_type,omitempty"`
+	Body        []byte    `json:"-"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"` // the lease while running, then the end of retention
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Completed reports whether the first request finished and its response is stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SignupGrant is the SkillPoints every new user is granted from the mint
+const SignupGrant = 100
+
+// Ledger account kinds. Each user has one account; the escrow and mint
+// accounts belong to the platform.
+const (
+	LedgerUser   = "user"   // a user's spendable points
+	LedgerEscrow = "escrow" // points held for sessions and workshop seats
+	LedgerMint   = "mint"   // where points are issued from; its balance is minus all points issued
+)
+
+// Ledger journal kinds, one for each way points move
+const (
+	JournalTransfer    = "transfer"     // a user sending points to another
+	JournalEscrowHold  = "escrow_hold"  // a learner's payment going into escrow
+	JournalRelease     = "release"      // a held payment paid to the teacher in full
+	JournalFee         = "fee"          // part of a held payment paid to the teacher, the rest refunded
+	JournalRefund      = "refund"       // a payment returned to the learner
+	JournalSignupGrant = "signup_grant" // points minted for a new user
+	JournalOpening     = "opening"      // a balance from before the ledger, minted into it
+)
+
+// ErrLedgerAppendOnly is returned when changing or deleting posted ledger rows
+var ErrLedgerAppendOnly = errors.New("the ledger is append-only")
+
+// LedgerAccount holds SkillPoints: a user's, or the platform's escrow or mint.
+// Its balance is the sum of its entries.
+type LedgerAccount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Kind      string    `gorm:"size:16;uniqueIndex:idx_ledger_accounts_owner" json:"kind"`
+	UserID    uint      `gorm:"uniqueIndex:idx_ledger_accounts_owner" json:"user_id,omitempty"` // 0 for the platform's accounts
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerJournal is one movement of points, posted as entries that debit and
+// credit accounts by the same total. Journals and entries are never changed
+// once posted; mistakes are corrected by posting another journal.
+type LedgerJournal struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	Kind          string        `gorm:"size:16;index" json:"kind"`
+	Note          string        `json:"note,omitempty"`
+	TransactionID *uint         `gorm:"index" json:"transaction_id,omitempty"` // the transaction users see for it, if any
+	EscrowID      *uint         `gorm:"index" json:"escrow_id,omitempty"`      // the escrow it holds or settles
+	Entries       []LedgerEntry `gorm:"foreignKey:JournalID" json:"entries"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// LedgerEntry adds points to an account, or takes them out when negative
+type LedgerEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JournalID uint      `gorm:"index" json:"journal_id"`
+	AccountID uint      `gorm:"index" json:"account_id"`
+	Amount    int       `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the journal balances: at least two entries, none of them
+// zero, summing to zero
+func (j *LedgerJournal) Validate() error {
+	if j.Kind == "" {
+		return errors.New("journal kind is required")
+	}
+	if len(j.Entries) < 2 {
+		return errors.New("a journal needs at least two entries")
+	}
+	sum := 0
+	for _, entry := range j.Entries {
+		if entry.Amount == 0 {
+			return errors.New("journal entries can't be zero")
+		}
+		sum += entry.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("journal entries sum to %d, not 0", sum)
+	}
+	return nil
+}
+
+// BeforeUpdate keeps posted journals from being changed
+func (j *LedgerJournal) BeforeUpdate(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// BeforeDelete keeps posted journals from being deleted
+func (j *LedgerJournal) BeforeDelete(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// BeforeUpdate keeps posted entries from being changed
+func (e *LedgerEntry) BeforeUpdate(*gorm.DB) error { return ErrLedgerAppendOnly }
+
+// BeforeDelete keeps posted entries from being deleted
+func (e *LedgerEntry) BeforeDelete(*gorm.DB) error { return ErrLedgerAppendOnly }
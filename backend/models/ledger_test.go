@@ -0,0 +1,45 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestLedgerJournalValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		journal models.LedgerJournal
+		valid   bool
+	}{
+		{"transfer", models.LedgerJournal{Kind: models.JournalTransfer, Entries: []models.LedgerEntry{{Amount: -10}, {Amount: 10}}}, true},
+		{"fee and refund", models.LedgerJournal{Kind: models.JournalFee, Entries: []models.LedgerEntry{{Amount: -40}, {Amount: 30}, {Amount: 10}}}, true},
+		{"no kind", models.LedgerJournal{Entries: []models.LedgerEntry{{Amount: -10}, {Amount: 10}}}, false},
+		{"one entry", models.LedgerJournal{Kind: models.JournalTransfer, Entries: []models.LedgerEntry{{Amount: 10}}}, false},
+		{"zero entry", models.LedgerJournal{Kind: models.JournalTransfer, Entries: []models.LedgerEntry{{Amount: 0}, {Amount: 0}}}, false},
+		{"unbalanced", models.LedgerJournal{Kind: models.JournalTransfer, Entries: []models.LedgerEntry{{Amount: -10}, {Amount: 9}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.journal.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected a valid journal, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected the journal to be rejected")
+			}
+		})
+	}
+}
+
+func TestLedgerIsAppendOnly(t *testing.T) {
+	journal := &models.LedgerJournal{}
+	entry := &models.LedgerEntry{}
+	for _, err := range []error{journal.BeforeUpdate(nil), journal.BeforeDelete(nil), entry.BeforeUpdate(nil), entry.BeforeDelete(nil)} {
+		if !errors.Is(err, models.ErrLedgerAppendOnly) {
+			t.Errorf("Expected ErrLedgerAppendOnly, got %v", err)
+		}
+	}
+}
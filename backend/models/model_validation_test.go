@@ -25,39 +25,6 @@ func validateSkill(s *models.Skill) error {
 	return nil
 }
 
-// Validate validates Schedule fields
-func validateSchedule(s *models.Schedule) error {
-	if s.UserID == 0 {
-		return errors.New("user_id is required")
-	}
-	if s.SkillID == 0 {
-		return errors.New("skill_id is required")
-	}
-	if s.StartTime.IsZero() {
-		return errors.New("start_time is required")
-	}
-	if s.EndTime.IsZero() {
-		return errors.New("end_time is required")
-	}
-	if !s.EndTime.After(s.StartTime) {
-		return errors.New("end_time must be after start_time")
-	}
-	if s.StartTime.Before(time.Now()) {
-		return errors.New("start_time must be in the future")
-	}
-	// Validate duration (e.g., minimum 30 minutes, maximum 4 hours)
-	minDuration := 30 * time.Minute
-	maxDuration := 4 * time.Hour
-	duration := s.EndTime.Sub(s.StartTime)
-	if duration < minDuration {
-		return errors.New("schedule duration must be at least 30 minutes")
-	}
-	if duration > maxDuration {
-		return errors.New("schedule duration cannot exceed 4 hours")
-	}
-	return nil
-}
-
 func TestSkillValidation(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -133,7 +100,7 @@ func TestScheduleValidation(t *testing.T) {
 		{
 			name: "Valid Schedule",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: future,
 				EndTime:   future.Add(1 * time.Hour),
@@ -141,19 +108,19 @@ func TestScheduleValidation(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name: "Missing UserID",
+			name: "Missing LearnerID",
 			schedule: models.Schedule{
 				SkillID:   2,
 				StartTime: future,
 				EndTime:   future.Add(1 * time.Hour),
 			},
 			expectError: true,
-			errorMsg:    "user_id is required",
+			errorMsg:    "learner_id is required",
 		},
 		{
 			name: "Missing SkillID",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				StartTime: future,
 				EndTime:   future.Add(1 * time.Hour),
 			},
@@ -163,7 +130,7 @@ func TestScheduleValidation(t *testing.T) {
 		{
 			name: "EndTime Before StartTime",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: future.Add(2 * time.Hour),
 				EndTime:   future.Add(1 * time.Hour),
@@ -174,7 +141,7 @@ func TestScheduleValidation(t *testing.T) {
 		{
 			name: "StartTime In Past",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: past,
 				EndTime:   future,
@@ -185,7 +152,7 @@ func TestScheduleValidation(t *testing.T) {
 		{
 			name: "Duration Too Short",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: future,
 				EndTime:   future.Add(15 * time.Minute),
@@ -196,7 +163,7 @@ func TestScheduleValidation(t *testing.T) {
 		{
 			name: "Duration Too Long",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: future,
 				EndTime:   future.Add(5 * time.Hour),
@@ -208,7 +175,7 @@ func TestScheduleValidation(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := validateSchedule(&tc.schedule)
+			err := tc.schedule.Validate(nil)
 			if tc.expectError && err == nil {
 				t.Errorf("Expected validation error, got nil")
 			}
@@ -226,28 +193,11 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 	now := time.Now()
 	tomorrow := now.Add(24 * time.Hour)
 
-	// Create a function that would validate if a new schedule conflicts with existing ones
+	// validateNoConflict rejects a schedule conflicting with any existing one
 	validateNoConflict := func(newSchedule models.Schedule, existingSchedules []models.Schedule) error {
 		for _, existing := range existingSchedules {
-			// Only check conflicts for the same user
-			if existing.UserID == newSchedule.UserID {
-				// Check if new schedule starts during existing schedule
-				if newSchedule.StartTime.After(existing.StartTime) &&
-					newSchedule.StartTime.Before(existing.EndTime) {
-					return errors.New("schedule conflicts with an existing schedule")
-				}
-
-				// Check if new schedule ends during existing schedule
-				if newSchedule.EndTime.After(existing.StartTime) &&
-					newSchedule.EndTime.Before(existing.EndTime) {
-					return errors.New("schedule conflicts with an existing schedule")
-				}
-
-				// Check if new schedule encompasses existing schedule
-				if newSchedule.StartTime.Before(existing.StartTime) &&
-					newSchedule.EndTime.After(existing.EndTime) {
-					return errors.New("schedule conflicts with an existing schedule")
-				}
+			if newSchedule.ConflictsWith(&existing) {
+				return errors.New("schedule conflicts with an existing schedule")
 			}
 		}
 		return nil
@@ -257,21 +207,21 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 	existingSchedules := []models.Schedule{
 		{
 			ID:        1,
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   2,
 			StartTime: tomorrow.Add(10 * time.Hour), // 10 AM
 			EndTime:   tomorrow.Add(12 * time.Hour), // 12 PM
 		},
 		{
 			ID:        2,
-			UserID:    1,
+			LearnerID: 1,
 			SkillID:   3,
 			StartTime: tomorrow.Add(14 * time.Hour), // 2 PM
 			EndTime:   tomorrow.Add(16 * time.Hour), // 4 PM
 		},
 		{
 			ID:        3,
-			UserID:    2, // Different user
+			LearnerID: 2, // Different user
 			SkillID:   2,
 			StartTime: tomorrow.Add(10 * time.Hour), // 10 AM (same time as user 1's schedule)
 			EndTime:   tomorrow.Add(12 * time.Hour), // 12 PM
@@ -286,7 +236,7 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		{
 			name: "Non-conflicting Schedule",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: tomorrow.Add(8 * time.Hour), // 8 AM
 				EndTime:   tomorrow.Add(9 * time.Hour), // 9 AM
@@ -296,7 +246,7 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		{
 			name: "Conflicting Start Time",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: tomorrow.Add(11 * time.Hour), // 11 AM (during first schedule)
 				EndTime:   tomorrow.Add(13 * time.Hour), // 1 PM
@@ -306,7 +256,7 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		{
 			name: "Conflicting End Time",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: tomorrow.Add(9 * time.Hour),  // 9 AM
 				EndTime:   tomorrow.Add(11 * time.Hour), // 11 AM (during first schedule)
@@ -316,7 +266,7 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		{
 			name: "Encompassing Existing Schedule",
 			schedule: models.Schedule{
-				UserID:    1,
+				LearnerID: 1,
 				SkillID:   2,
 				StartTime: tomorrow.Add(9 * time.Hour),  // 9 AM
 				EndTime:   tomorrow.Add(13 * time.Hour), // 1 PM (encompasses first schedule)
@@ -326,13 +276,44 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		{
 			name: "Same Time but Different User",
 			schedule: models.Schedule{
-				UserID:    3, // Different user
+				LearnerID: 3, // Different user
 				SkillID:   2,
 				StartTime: tomorrow.Add(10 * time.Hour), // 10 AM (same as first schedule)
 				EndTime:   tomorrow.Add(12 * time.Hour), // 12 PM
 			},
 			expectError: false, // No conflict because it's a different user
 		},
+		{
+			name: "Same Time as Existing Schedule",
+			schedule: models.Schedule{
+				LearnerID: 1,
+				SkillID:   2,
+				StartTime: tomorrow.Add(10 * time.Hour), // 10 AM (exactly the first schedule)
+				EndTime:   tomorrow.Add(12 * time.Hour), // 12 PM
+			},
+			expectError: true,
+		},
+		{
+			name: "Starting When Existing Schedule Ends",
+			schedule: models.Schedule{
+				LearnerID: 1,
+				SkillID:   2,
+				StartTime: tomorrow.Add(12 * time.Hour), // 12 PM (first schedule ends)
+				EndTime:   tomorrow.Add(13 * time.Hour), // 1 PM
+			},
+			expectError: false,
+		},
+		{
+			name: "Teaching During Own Lesson",
+			schedule: models.Schedule{
+				LearnerID: 3,
+				TeacherID: 1, // User 1 is learning at this time
+				SkillID:   4,
+				StartTime: tomorrow.Add(15 * time.Hour), // 3 PM (during second schedule)
+				EndTime:   tomorrow.Add(17 * time.Hour), // 5 PM
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -347,3 +328,130 @@ func TestScheduleConcurrentValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestScheduleValidationWithSkillLimits(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	skill := &models.Skill{MinSessionMinutes: 60, MaxSessionMinutes: 90}
+
+	short := models.Schedule{LearnerID: 1, SkillID: 2, StartTime: future, EndTime: future.Add(45 * time.Minute)}
+	if err := short.Validate(skill); err == nil || err.Error() != "schedule duration must be at least 1 hour" {
+		t.Errorf("Expected minimum length error, got %v", err)
+	}
+
+	long := models.Schedule{LearnerID: 1, SkillID: 2, StartTime: future, EndTime: future.Add(2 * time.Hour)}
+	if err := long.Validate(skill); err == nil || err.Error() != "schedule duration cannot exceed 90 minutes" {
+		t.Errorf("Expected maximum length error, got %v", err)
+	}
+
+	ok := models.Schedule{LearnerID: 1, SkillID: 2, StartTime: future, EndTime: future.Add(75 * time.Minute)}
+	if err := ok.Validate(skill); err != nil {
+		t.Errorf("Expected session within limits to be valid, got %v", err)
+	}
+}
+
+func TestSkillSessionLimitsValidation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		skill       models.Skill
+		expectError bool
+	}{
+		{name: "Defaults", skill: models.Skill{}},
+		{name: "Custom Limits", skill: models.Skill{MinSessionMinutes: 15, MaxSessionMinutes: 480}},
+		{name: "Negative Limit", skill: models.Skill{MinSessionMinutes: -1}, expectError: true},
+		{name: "Minimum Above Maximum", skill: models.Skill{MinSessionMinutes: 120, MaxSessionMinutes: 60}, expectError: true},
+		{name: "Minimum Above Default Maximum", skill: models.Skill{MinSessionMinutes: 300}, expectError: true},
+		{name: "Maximum Over A Day", skill: models.Skill{MaxSessionMinutes: 25 * 60}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.skill.ValidateSessionLimits()
+			if tc.expectError && err == nil {
+				t.Error("Expected validation error, got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no validation error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestScheduleTransitions(t *testing.T) {
+	testCases := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{models.StatusRequested, models.StatusConfirmed, true},
+		{models.StatusRequested, models.StatusCancelled, true},
+		{models.StatusRequested, models.StatusCompleted, false},
+		{models.StatusRequested, models.StatusNoShow, false},
+		{models.StatusConfirmed, models.StatusCancelled, true},
+		{models.StatusConfirmed, models.StatusCompleted, true},
+		{models.StatusConfirmed, models.StatusNoShow, true},
+		{models.StatusConfirmed, models.StatusRequested, false},
+		{models.StatusCancelled, models.StatusConfirmed, false},
+		{models.StatusCompleted, models.StatusCancelled, false},
+		{models.StatusNoShow, models.StatusCompleted, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.from+" to "+tc.to, func(t *testing.T) {
+			schedule := models.Schedule{Status: tc.from}
+			err := schedule.TransitionTo(tc.to)
+			if tc.allowed && err != nil {
+				t.Errorf("Expected transition to be allowed, got %v", err)
+			}
+			if !tc.allowed {
+				if !errors.Is(err, models.ErrIllegalTransition) {
+					t.Errorf("Expected ErrIllegalTransition, got %v", err)
+				}
+				if schedule.Status != tc.from {
+					t.Errorf("Expected status to stay %s, got %s", tc.from, schedule.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleParticipants(t *testing.T) {
+	schedule := models.Schedule{LearnerID: 1, TeacherID: 2}
+
+	if !schedule.IsParticipant(1) || !schedule.IsParticipant(2) || schedule.IsParticipant(3) {
+		t.Error("Expected only the learner and teacher to be participants")
+	}
+	if schedule.OtherParticipant(1) != 2 || schedule.OtherParticipant(2) != 1 {
+		t.Error("Expected the other participant to be the opposite role")
+	}
+}
+
+func TestScheduleIn(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	start := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+	proposed := start.Add(24 * time.Hour)
+	schedule := models.Schedule{
+		StartTime:         start,
+		EndTime:           start.Add(time.Hour),
+		ProposedStartTime: &proposed,
+	}
+
+	local := schedule.In(warsaw)
+	if local.TimeZone != "Europe/Warsaw" {
+		t.Errorf("Expected time zone Europe/Warsaw, got %q", local.TimeZone)
+	}
+	if got := local.StartTime.Format(time.RFC3339); got != "2025-07-01T10:00:00+02:00" {
+		t.Errorf("Expected start in Warsaw time, got %s", got)
+	}
+	if got := local.ProposedStartTime.Format(time.RFC3339); got != "2025-07-02T10:00:00+02:00" {
+		t.Errorf("Expected proposed start in Warsaw time, got %s", got)
+	}
+	if !local.StartTime.Equal(start) {
+		t.Error("Expected the converted start to be the same instant")
+	}
+	if schedule.ProposedStartTime.Location() != time.UTC {
+		t.Error("Expected the original schedule to be left unchanged")
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Notification kinds
+const (
+	NotificationSavedSearchMatch = "saved_search_match"
+	NotificationSessionUpdate    = "session_update"
+	NotificationSessionReminder  = "session_reminder"
+	NotificationWorkshopUpdate   = "workshop_update"
+	NotificationTransaction      = "transaction_update" // refunds and disputes
+)
+
+// Notification is an in-app message to a user.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Message   string     `json:"message"`
+	Link      string     `json:"link,omitempty"` // frontend path of the subject
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Saved search alerts record what matched, so a result is only alerted once
+	SavedSearchID *uint  `gorm:"uniqueIndex:idx_notifications_saved_search_match" json:"saved_search_id,omitempty"`
+	ResultType    string `gorm:"uniqueIndex:idx_notifications_saved_search_match" json:"result_type,omitempty"`
+	ResultID      string `gorm:"uniqueIndex:idx_notifications_saved_search_match" json:"result_id,omitempty"`
+
+	// Notifications waiting for the owner's next email digest
+	EmailPending bool       `gorm:"index" json:"-"`
+	EmailedAt    *time.Time `json:"-"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session reminder statuses
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderSkipped = "skipped" // the session was cancelled or moved, or the participant opted out
+)
+
+// SessionReminder is a reminder due to a participant some time before a
+// confirmed session. Reminders are stored so they survive restarts, and each
+// one is for a single start time: moving a session queues reminders for the
+// new time, and those of the old time are skipped rather than sent.
+type SessionReminder struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ScheduleID  uint       `gorm:"uniqueIndex:idx_session_reminders_once" json:"schedule_id"`
+	UserID      uint       `gorm:"uniqueIndex:idx_session_reminders_once" json:"user_id"`
+	LeadMinutes int        `gorm:"uniqueIndex:idx_session_reminders_once" json:"lead_minutes"` // how long before the start it is sent
+	StartTime   time.Time  `gorm:"uniqueIndex:idx_session_reminders_once" json:"start_time"`   // the session start it reminds of
+	SendAt      time.Time  `gorm:"index:idx_session_reminders_due,priority:2" json:"send_at"`
+	Status      string     `gorm:"size:16;default:pending;index:idx_session_reminders_due,priority:1" json:"status"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Lead is how long before the session start the reminder is sent
+func (r *SessionReminder) Lead() time.Duration {
+	return time.Duration(r.LeadMinutes) * time.Minute
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ways a saved search alerts its owner about new matches.
+const (
+	AlertInApp = "in_app" // notification only
+	AlertEmail = "email"  // notification plus an email digest
+)
+
+// StringMap is a custom type to handle string maps in PostgreSQL
+type StringMap map[string]string
+
+// Value converts StringMap to a JSON string for storage
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan converts a stored JSON string back to a StringMap
+func (m *StringMap) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, m)
+}
+
+// SavedSearch is a search query a user wants to be alerted about.
+// New users, skills and jobs matching it produce notifications.
+type SavedSearch struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	UserID    uint        `gorm:"index" json:"user_id"`
+	Name      string      `json:"name"`
+	Query     string      `json:"query"`
+	Types     StringArray `gorm:"type:jsonb" json:"types"`   // empty means every type
+	Filters   StringMap   `gorm:"type:jsonb" json:"filters"` // facet name to required value
+	Alert     string      `json:"alert"`                     // "in_app" or "email"
+	LastRunAt time.Time   `json:"last_run_at"`               // matches created before this were already alerted
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Validate checks the saved search fields and fills in defaults.
+func (s *SavedSearch) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Query = strings.TrimSpace(s.Query)
+
+	if s.Query == "" {
+		return errors.New("query is required")
+	}
+	if s.Name == "" {
+		s.Name = s.Query
+	}
+	if len(s.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+
+	switch s.Alert {
+	case "":
+		s.Alert = AlertInApp
+	case AlertInApp, AlertEmail:
+	default:
+		return errors.New("alert must be \"in_app\" or \"email\"")
+	}
+	return nil
+}
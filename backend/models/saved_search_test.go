@@ -0,0 +1,46 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestSavedSearchValidate(t *testing.T) {
+	search := models.SavedSearch{Query: "  guitar lessons "}
+	if err := search.Validate(); err != nil {
+		t.Fatalf("Expected valid saved search, got %v", err)
+	}
+	if search.Name != "guitar lessons" {
+		t.Errorf("Expected name to default to the query, got %q", search.Name)
+	}
+	if search.Alert != models.AlertInApp {
+		t.Errorf("Expected alert to default to %q, got %q", models.AlertInApp, search.Alert)
+	}
+
+	invalid := []models.SavedSearch{
+		{Query: " "},
+		{Query: "guitar", Alert: "sms"},
+	}
+	for _, search := range invalid {
+		if err := search.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", search)
+		}
+	}
+}
+
+func TestStringMapRoundTrip(t *testing.T) {
+	original := models.StringMap{"category": "Music"}
+	value, err := original.Value()
+	if err != nil {
+		t.Fatalf("Value failed: %v", err)
+	}
+
+	var decoded models.StringMap
+	if err := decoded.Scan(value); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if decoded["category"] != "Music" {
+		t.Errorf("Expected category Music, got %v", decoded)
+	}
+}
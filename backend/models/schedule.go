@@ -1,13 +1,269 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Session length limits used when a skill doesn't set its own, in minutes.
+const (
+	DefaultMinSessionMinutes = 30
+	DefaultMaxSessionMinutes = 4 * 60
+)
+
+// Session statuses. A learner requests a session, and it is confirmed once the
+// other party accepts the time last proposed. Cancelled, completed and no-show
+// sessions are final.
+const (
+	StatusRequested = "requested"
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusCompleted = "completed"
+	StatusNoShow    = "no_show"
+)
+
+// How a no-show report was settled
+const (
+	NoShowAccepted = "accepted" // not disputed within the dispute window
+	NoShowUpheld   = "upheld"   // disputed, and an admin agreed with the report
+	NoShowRejected = "rejected" // disputed, and an admin found the session took place
+)
+
+// ErrIllegalTransition is returned when a session can't move to the requested status
+var ErrIllegalTransition = errors.New("illegal session status change")
+
+// scheduleTransitions lists the statuses each status can move to
+var scheduleTransitions = map[string][]string{
+	StatusRequested: {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusCancelled, StatusCompleted, StatusNoShow},
+}
 
 // Schedule represents a scheduled skill exchange session.
 type Schedule struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `json:"user_id"`
+	LearnerID uint      `gorm:"index" json:"learner_id"` // Learner who requested the session
+	TeacherID uint      `gorm:"index" json:"teacher_id"` // Teacher offering the skill
 	SkillID   uint      `json:"skill_id"`
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
+	// Sessions booked before requests existed were confirmed when booked
+	Status        string `gorm:"size:20;index;default:confirmed" json:"status"`
+	Price         int    `json:"price"`                    // SkillPoints charged for the session
+	TransactionID *uint  `json:"transaction_id,omitempty"` // Payment of sessions confirmed before payments were held in escrow
+
+	// Recurring series the session was booked in, if any
+	SeriesID *uint `gorm:"index" json:"series_id,omitempty"`
+
+	// The party whose time is waiting for the other's answer. While requested this is
+	// the start and end time; once confirmed it is the proposed new time, if any.
+	ProposedByID      uint       `json:"proposed_by_id,omitempty"`
+	ProposedStartTime *time.Time `json:"proposed_start_time,omitempty"`
+	ProposedEndTime   *time.Time `json:"proposed_end_time,omitempty"`
+
+	// A no-show report: the participant reported absent, until when they may
+	// dispute it, when they did, and how the report was settled
+	NoShowUserID     uint       `json:"no_show_user_id,omitempty"`
+	DisputeDeadline  *time.Time `json:"dispute_deadline,omitempty"`
+	DisputedAt       *time.Time `json:"disputed_at,omitempty"`
+	NoShowResolution string     `gorm:"size:16" json:"no_show_resolution,omitempty"`
+
+	// Revision of the session, bumped on every change so calendar feeds pick it up
+	Sequence int `json:"sequence"`
+
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Time zone the times are shown in, set by In
+	TimeZone string `gorm:"-" json:"time_zone,omitempty"`
+}
+
+// ScheduleEvent records one change to a session, so both parties can see its history.
+type ScheduleEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ScheduleID uint      `gorm:"index" json:"schedule_id"`
+	ActorID    uint      `json:"actor_id"`
+	Action     string    `json:"action"` // requested, proposed, accepted, rescheduled, declined, cancelled, completed or no_show
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// In returns a copy of the schedule with its times in loc, so they are shown
+// with that zone's offset.
+func (s Schedule) In(loc *time.Location) Schedule {
+	s.StartTime = s.StartTime.In(loc)
+	s.EndTime = s.EndTime.In(loc)
+	if s.ProposedStartTime != nil {
+		start := s.ProposedStartTime.In(loc)
+		s.ProposedStartTime = &start
+	}
+	if s.ProposedEndTime != nil {
+		end := s.ProposedEndTime.In(loc)
+		s.ProposedEndTime = &end
+	}
+	if s.DisputeDeadline != nil {
+		deadline := s.DisputeDeadline.In(loc)
+		s.DisputeDeadline = &deadline
+	}
+	if s.DisputedAt != nil {
+		disputed := s.DisputedAt.In(loc)
+		s.DisputedAt = &disputed
+	}
+	s.CreatedAt = s.CreatedAt.In(loc)
+	s.UpdatedAt = s.UpdatedAt.In(loc)
+	s.TimeZone = loc.String()
+	return s
+}
+
+// In returns a copy of the event with its times in loc.
+func (e ScheduleEvent) In(loc *time.Location) ScheduleEvent {
+	e.StartTime = e.StartTime.In(loc)
+	e.EndTime = e.EndTime.In(loc)
+	e.CreatedAt = e.CreatedAt.In(loc)
+	return e
+}
+
+// IsScheduleStatus reports whether status is one of the session statuses
+func IsScheduleStatus(status string) bool {
+	switch status {
+	case StatusRequested, StatusConfirmed, StatusCancelled, StatusCompleted, StatusNoShow:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a session may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range scheduleTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the session to a new status if that change is allowed
+func (s *Schedule) TransitionTo(status string) error {
+	if !CanTransition(s.Status, status) {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, s.Status, status)
+	}
+	s.Status = status
+	return nil
+}
+
+// IsParticipant reports whether the user is the session's learner or teacher
+func (s *Schedule) IsParticipant(userID uint) bool {
+	return userID != 0 && (userID == s.LearnerID || userID == s.TeacherID)
+}
+
+// OtherParticipant returns the learner for the teacher and the teacher for the learner
+func (s *Schedule) OtherParticipant(userID uint) uint {
+	if userID == s.TeacherID {
+		return s.LearnerID
+	}
+	return s.TeacherID
+}
+
+// NoShowDisputable reports whether the participant reported absent may still dispute the report
+func (s *Schedule) NoShowDisputable(now time.Time) bool {
+	return s.Status == StatusNoShow && s.NoShowResolution == "" && s.DisputedAt == nil &&
+		s.DisputeDeadline != nil && now.Before(*s.DisputeDeadline)
+}
+
+// HasProposal reports whether a confirmed session has a new time waiting for an answer
+func (s *Schedule) HasProposal() bool {
+	return s.ProposedStartTime != nil && s.ProposedEndTime != nil
+}
+
+// ClearProposal drops a confirmed session's proposed new time
+func (s *Schedule) ClearProposal() {
+	s.ProposedByID = 0
+	s.ProposedStartTime = nil
+	s.ProposedEndTime = nil
+}
+
+// Validate checks the session is complete, in the future and as long as the
+// skill allows. A nil skill uses the default limits.
+func (s *Schedule) Validate(skill *Skill) error {
+	if s.LearnerID == 0 {
+		return errors.New("learner_id is required")
+	}
+	if s.SkillID == 0 {
+		return errors.New("skill_id is required")
+	}
+	if s.StartTime.IsZero() {
+		return errors.New("start_time is required")
+	}
+	if s.EndTime.IsZero() {
+		return errors.New("end_time is required")
+	}
+	if !s.EndTime.After(s.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	if s.StartTime.Before(time.Now()) {
+		return errors.New("start_time must be in the future")
+	}
+
+	minDuration, maxDuration := DefaultMinSessionMinutes, DefaultMaxSessionMinutes
+	if skill != nil {
+		minDuration, maxDuration = skill.SessionLimits()
+	}
+	duration := s.EndTime.Sub(s.StartTime)
+	if duration < time.Duration(minDuration)*time.Minute {
+		return fmt.Errorf("schedule duration must be at least %s", formatMinutes(minDuration))
+	}
+	if duration > time.Duration(maxDuration)*time.Minute {
+		return fmt.Errorf("schedule duration cannot exceed %s", formatMinutes(maxDuration))
+	}
+	return nil
+}
+
+// Overlaps reports whether two sessions share any time. Sessions that only
+// touch, one ending as the other starts, don't overlap.
+func (s *Schedule) Overlaps(other *Schedule) bool {
+	return s.StartTime.Before(other.EndTime) && other.StartTime.Before(s.EndTime)
+}
+
+// ConflictsWith reports whether two sessions overlap and share a participant,
+// in either role.
+func (s *Schedule) ConflictsWith(other *Schedule) bool {
+	if !s.Overlaps(other) {
+		return false
+	}
+	for _, id := range s.Participants() {
+		for _, otherID := range other.Participants() {
+			if id == otherID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Participants returns the IDs of the learner and teacher that are set
+func (s *Schedule) Participants() []uint {
+	var ids []uint
+	for _, id := range []uint{s.LearnerID, s.TeacherID} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// formatMinutes writes a duration limit in whole hours when it is one
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes == 60:
+		return "1 hour"
+	case minutes > 0 && minutes%60 == 0:
+		return fmt.Sprintf("%d hours", minutes/60)
+	case minutes == 1:
+		return "1 minute"
+	default:
+		return fmt.Sprintf("%d minutes", minutes)
+	}
 }
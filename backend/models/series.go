@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+// MaxSeriesOccurrences is the most sessions a recurring series can book
+const MaxSeriesOccurrences = 52
+
+// MaxSeriesSpan is how far ahead of its first session a series can run
+const MaxSeriesSpan = 366 * 24 * time.Hour
+
+// Series statuses. Cancelling a series cancels its sessions still to come.
+const (
+	SeriesActive    = "active"
+	SeriesCancelled = "cancelled"
+)
+
+// ScheduleSeries is a recurring series of sessions booked together, such as
+// every Tuesday at 18:00 for 8 weeks. Each occurrence is a Schedule of its
+// own, paid and reminded like any other, which can also be moved or
+// cancelled on its own.
+type ScheduleSeries struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LearnerID uint      `gorm:"index" json:"learner_id"`
+	TeacherID uint      `gorm:"index" json:"teacher_id"`
+	SkillID   uint      `json:"skill_id"`
+	StartTime time.Time `json:"start_time"` // start of the first occurrence
+	EndTime   time.Time `json:"end_time"`   // end of the first occurrence
+	RRule     string    `json:"rrule"`      // e.g. "FREQ=WEEKLY;COUNT=8"; COUNT or UNTIL is required
+	TimeZone  string    `json:"time_zone"`  // zone occurrences keep their wall-clock time in
+	Status    string    `gorm:"size:20;default:active" json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Location loads the series' time zone
+func (s *ScheduleSeries) Location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return nil, errors.New("time_zone is required")
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", s.TimeZone)
+	}
+	return loc, nil
+}
+
+// Occurrences returns the times of the series' sessions in order. They recur
+// by the rule from the date of the first session, at its wall-clock time in
+// the series' time zone whatever the daylight saving offset, and keep its
+// length. Each session is checked like a single one when it is booked.
+func (s *ScheduleSeries) Occurrences() ([]TimeRange, error) {
+	loc, err := s.Location()
+	if err != nil {
+		return nil, err
+	}
+	if s.StartTime.IsZero() || !s.EndTime.After(s.StartTime) {
+		return nil, errors.New("end_time must be after start_time")
+	}
+	rule, err := utils.ParseRRule(s.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("rrule: %w", err)
+	}
+	if rule.Count == 0 && rule.Until.IsZero() {
+		return nil, errors.New("rrule must end with COUNT or UNTIL")
+	}
+
+	local := s.StartTime.In(loc)
+	first := utils.Date(local)
+	clock := local.Hour()*60 + local.Minute()
+	length := s.EndTime.Sub(s.StartTime)
+
+	dates := rule.Dates(first, first, first.Add(MaxSeriesSpan))
+	if rule.Count > len(dates) || (!rule.Until.IsZero() && rule.Until.After(first.Add(MaxSeriesSpan))) {
+		return nil, errors.New("a series can't run for more than a year")
+	}
+	if len(dates) < 2 {
+		return nil, errors.New("a series needs at least two sessions")
+	}
+	if len(dates) > MaxSeriesOccurrences {
+		return nil, fmt.Errorf("a series can't have more than %d sessions", MaxSeriesOccurrences)
+	}
+
+	occurrences := make([]TimeRange, len(dates))
+	for i, date := range dates {
+		start := utils.At(date, clock, loc)
+		occurrences[i] = TimeRange{Start: start, End: start.Add(length)}
+		if i > 0 && occurrences[i-1].End.After(start) {
+			return nil, errors.New("sessions of a series can't overlap")
+		}
+	}
+	return occurrences, nil
+}
+
+// IsParticipant reports whether the user is the series' learner or teacher
+func (s *ScheduleSeries) IsParticipant(userID uint) bool {
+	return userID != 0 && (userID == s.LearnerID || userID == s.TeacherID)
+}
+
+// In returns a copy of the series with its times in loc.
+func (s ScheduleSeries) In(loc *time.Location) ScheduleSeries {
+	s.StartTime = s.StartTime.In(loc)
+	s.EndTime = s.EndTime.In(loc)
+	s.CreatedAt = s.CreatedAt.In(loc)
+	s.UpdatedAt = s.UpdatedAt.In(loc)
+	return s
+}
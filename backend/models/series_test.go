@@ -0,0 +1,73 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestScheduleSeriesOccurrences(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// Tuesdays at 18:00 for 8 weeks, across the change to summer time on March 29
+	start := time.Date(2026, 3, 10, 18, 0, 0, 0, warsaw)
+	series := models.ScheduleSeries{
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		RRule:     "FREQ=WEEKLY;COUNT=8",
+		TimeZone:  "Europe/Warsaw",
+	}
+
+	occurrences, err := series.Occurrences()
+	if err != nil {
+		t.Fatalf("Occurrences failed: %v", err)
+	}
+	if len(occurrences) != 8 {
+		t.Fatalf("Expected 8 sessions, got %d", len(occurrences))
+	}
+	for i, o := range occurrences {
+		local := o.Start.In(warsaw)
+		if local.Weekday() != time.Tuesday || local.Hour() != 18 || local.Minute() != 0 {
+			t.Errorf("Session %d: expected Tuesday 18:00 in Warsaw, got %v", i, local)
+		}
+		if o.End.Sub(o.Start) != time.Hour {
+			t.Errorf("Session %d: expected an hour, got %v", i, o.End.Sub(o.Start))
+		}
+	}
+	if !occurrences[0].Start.Equal(start) || occurrences[7].Start.In(warsaw).Day() != 28 {
+		t.Errorf("Expected March 10 to April 28, got %v to %v", occurrences[0].Start, occurrences[7].Start)
+	}
+}
+
+func TestScheduleSeriesOccurrencesInvalid(t *testing.T) {
+	start := time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     string
+		timeZone string
+		length   time.Duration
+	}{
+		{"no end", "FREQ=WEEKLY", "UTC", time.Hour},
+		{"one session", "FREQ=WEEKLY;COUNT=1", "UTC", time.Hour},
+		{"too many sessions", "FREQ=DAILY;COUNT=60", "UTC", time.Hour},
+		{"longer than a year", "FREQ=WEEKLY;INTERVAL=4;COUNT=20", "UTC", time.Hour},
+		{"until too far", "FREQ=WEEKLY;INTERVAL=8;UNTIL=20300101", "UTC", time.Hour},
+		{"overlapping", "FREQ=DAILY;COUNT=3", "UTC", 25 * time.Hour},
+		{"bad rule", "FREQ=MONTHLY;COUNT=3", "UTC", time.Hour},
+		{"unknown zone", "FREQ=WEEKLY;COUNT=3", "Mars/Olympus", time.Hour},
+		{"no zone", "FREQ=WEEKLY;COUNT=3", "", time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := models.ScheduleSeries{StartTime: start, EndTime: start.Add(tt.length), RRule: tt.rule, TimeZone: tt.timeZone}
+			if _, err := series.Occurrences(); err == nil {
+				t.Errorf("Expected %q in %q to be rejected", tt.rule, tt.timeZone)
+			}
+		})
+	}
+}
@@ -1,12 +1,121 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// Pricing units a teacher can charge a skill in.
+const (
+	PricePerSession = "session"
+	PricePerHour    = "hour"
+)
 
 // Skill represents a skill that a user can offer or request.
 type Skill struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	Category    string    `json:"category"`
 	UserID      uint      `json:"user_id"` // ID of the user offering the skill
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Pricing set by the teacher, in SkillPoints
+	Price                int    `json:"price"`                  // 0 means the skill is free
+	PriceUnit            string `json:"price_unit"`             // "session" or "hour"
+	FirstSessionDiscount int    `json:"first_session_discount"` // Percentage off a learner's first session
+
+	// Session length limits in minutes; 0 uses the default
+	MinSessionMinutes int `json:"min_session_minutes"`
+	MaxSessionMinutes int `json:"max_session_minutes"`
+}
+
+// PriceQuote is the price of one session of a skill.
+type PriceQuote struct {
+	SkillID      uint   `json:"skill_id"`
+	Price        int    `json:"price"`
+	PriceUnit    string `json:"price_unit"`
+	Minutes      int    `json:"minutes"`
+	Subtotal     int    `json:"subtotal"`
+	Discount     int    `json:"discount"`
+	Total        int    `json:"total"`
+	FirstSession bool   `json:"first_session"`
+}
+
+// ValidatePricing checks that the skill's pricing fields are consistent.
+func (s *Skill) ValidatePricing() error {
+	if s.Price < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if s.PriceUnit != PricePerSession && s.PriceUnit != PricePerHour {
+		return errors.New("price_unit must be 'session' or 'hour'")
+	}
+	if s.FirstSessionDiscount < 0 || s.FirstSessionDiscount > 100 {
+		return errors.New("first_session_discount must be between 0 and 100")
+	}
+	return nil
+}
+
+// SessionLimits returns the shortest and longest session of the skill in minutes
+func (s *Skill) SessionLimits() (int, int) {
+	minMinutes, maxMinutes := s.MinSessionMinutes, s.MaxSessionMinutes
+	if minMinutes == 0 {
+		minMinutes = DefaultMinSessionMinutes
+	}
+	if maxMinutes == 0 {
+		maxMinutes = DefaultMaxSessionMinutes
+	}
+	return minMinutes, maxMinutes
+}
+
+// ValidateSessionLimits checks that the skill's session length limits are consistent.
+func (s *Skill) ValidateSessionLimits() error {
+	if s.MinSessionMinutes < 0 || s.MaxSessionMinutes < 0 {
+		return errors.New("session limits cannot be negative")
+	}
+	if s.MaxSessionMinutes > 24*60 {
+		return errors.New("max_session_minutes cannot exceed 24 hours")
+	}
+	if minMinutes, maxMinutes := s.SessionLimits(); minMinutes > maxMinutes {
+		return errors.New("min_session_minutes cannot exceed max_session_minutes")
+	}
+	return nil
+}
+
+// Quote calculates the price of a session running from start to end.
+// Hourly rates are charged per started minute, rounded up to whole points.
+func (s *Skill) Quote(start, end time.Time, firstSession bool) (PriceQuote, error) {
+	if !end.After(start) {
+		return PriceQuote{}, errors.New("end time must be after start time")
+	}
+
+	unit := s.PriceUnit
+	if unit == "" {
+		unit = PricePerSession
+	}
+
+	minutes := int((end.Sub(start) + time.Minute - 1) / time.Minute)
+	quote := PriceQuote{
+		SkillID:      s.ID,
+		Price:        s.Price,
+		PriceUnit:    unit,
+		Minutes:      minutes,
+		FirstSession: firstSession,
+	}
+
+	switch unit {
+	case PricePerSession:
+		quote.Subtotal = s.Price
+	case PricePerHour:
+		quote.Subtotal = (s.Price*minutes + 59) / 60
+	default:
+		return PriceQuote{}, errors.New("unknown price unit")
+	}
+
+	if firstSession && s.FirstSessionDiscount > 0 {
+		quote.Discount = quote.Subtotal * s.FirstSessionDiscount / 100
+	}
+	quote.Total = quote.Subtotal - quote.Discount
+
+	return quote, nil
 }
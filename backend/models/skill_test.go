@@ -0,0 +1,103 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestSkillQuote(t *testing.T) {
+	start := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		skill         models.Skill
+		duration      time.Duration
+		firstSession  bool
+		expectedTotal int
+	}{
+		{
+			name:          "Per Session",
+			skill:         models.Skill{Price: 20, PriceUnit: models.PricePerSession},
+			duration:      90 * time.Minute,
+			expectedTotal: 20,
+		},
+		{
+			name:          "Per Hour",
+			skill:         models.Skill{Price: 10, PriceUnit: models.PricePerHour},
+			duration:      90 * time.Minute,
+			expectedTotal: 15,
+		},
+		{
+			name:          "Per Hour Rounds Up",
+			skill:         models.Skill{Price: 10, PriceUnit: models.PricePerHour},
+			duration:      40 * time.Minute,
+			expectedTotal: 7,
+		},
+		{
+			name:          "First Session Discount",
+			skill:         models.Skill{Price: 20, PriceUnit: models.PricePerSession, FirstSessionDiscount: 50},
+			duration:      time.Hour,
+			firstSession:  true,
+			expectedTotal: 10,
+		},
+		{
+			name:          "Discount Only Applies To First Session",
+			skill:         models.Skill{Price: 20, PriceUnit: models.PricePerSession, FirstSessionDiscount: 50},
+			duration:      time.Hour,
+			expectedTotal: 20,
+		},
+		{
+			name:          "Free Skill",
+			skill:         models.Skill{},
+			duration:      time.Hour,
+			expectedTotal: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			quote, err := tc.skill.Quote(start, start.Add(tc.duration), tc.firstSession)
+			if err != nil {
+				t.Fatalf("Quote returned error: %v", err)
+			}
+			if quote.Total != tc.expectedTotal {
+				t.Errorf("Expected total %d, got %d", tc.expectedTotal, quote.Total)
+			}
+		})
+	}
+
+	t.Run("End Before Start", func(t *testing.T) {
+		skill := models.Skill{Price: 10, PriceUnit: models.PricePerHour}
+		if _, err := skill.Quote(start, start.Add(-time.Hour), false); err == nil {
+			t.Error("Expected error for end time before start time, got nil")
+		}
+	})
+}
+
+func TestSkillValidatePricing(t *testing.T) {
+	testCases := []struct {
+		name        string
+		skill       models.Skill
+		expectError bool
+	}{
+		{"Valid Per Hour", models.Skill{Price: 10, PriceUnit: models.PricePerHour}, false},
+		{"Valid Per Session With Discount", models.Skill{Price: 10, PriceUnit: models.PricePerSession, FirstSessionDiscount: 25}, false},
+		{"Negative Price", models.Skill{Price: -1, PriceUnit: models.PricePerHour}, true},
+		{"Unknown Unit", models.Skill{Price: 10, PriceUnit: "day"}, true},
+		{"Discount Over 100", models.Skill{Price: 10, PriceUnit: models.PricePerHour, FirstSessionDiscount: 101}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.skill.ValidatePricing()
+			if tc.expectError && err == nil {
+				t.Errorf("Expected validation error, got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Expected no validation error, got: %v", err)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package models
 import "time"
 
 // Transaction records the exchange of SkillPoints between users.
+// Transactions are never changed once made: points are given back by a
+// refund, a new transaction the other way linked to the original by RefundOf.
 type Transaction struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
 	SenderID   uint      `json:"sender_id"`
@@ -11,4 +13,17 @@ type Transaction struct {
 	Note       string    `json:"note,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+
+	// The transaction this one refunds, by its receiver or on a dispute's resolution
+	RefundOf *uint `gorm:"index" json:"refund_of,omitempty"`
+}
+
+// IsRefund reports whether the transaction gives back points of another one
+func (t *Transaction) IsRefund() bool {
+	return t.RefundOf != nil
+}
+
+// IsParticipant reports whether the user sent or received the transaction
+func (t *Transaction) IsParticipant(userID uint) bool {
+	return t.SenderID == userID || t.ReceiverID == userID
 }
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -14,9 +19,105 @@ type User struct {
 	Email       string    `gorm:"unique" json:"email"`
 	Password    string    `json:"-"` // omit from JSON responses
 	Bio         string    `json:"bio"`
-	Role        string    `json:"role"`                           // "User" or "Admin"
-	SkillPoints int       `json:"skillPoints" gorm:"default:100"` // Default starting balance
+	Role        string    `json:"role"`        // "User" or "Admin"
+	SkillPoints int       `json:"skillPoints"` // cached balance of the user's ledger account, starting at the SignupGrant
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Privacy settings
+	Searchable        bool   `json:"searchable" gorm:"default:true"`           // listed in search results
+	ShowEmail         bool   `json:"show_email"`                               // email shown on the public profile
+	ProfileVisibility string `json:"profile_visibility" gorm:"default:public"` // "public" or "members"
+
+	// Secret in the user's calendar feed URL; empty until the feed is first requested
+	CalendarToken string `json:"-" gorm:"size:64;index"`
+
+	// SHA-256 of the app password calendar clients sign in to CalDAV with; empty when there is none.
+	// It is separate from the read-only feed token, which users hand to other services.
+	CalDAVPasswordHash string `json:"-" gorm:"column:caldav_password_hash;size:64"`
+
+	// Time zone times are shown to the user in, an IANA name such as "Europe/Warsaw"
+	TimeZone string `json:"time_zone" gorm:"size:64;default:UTC"`
+
+	// Reminder settings: how the user is reminded of upcoming sessions
+	RemindInApp   bool `json:"remind_in_app" gorm:"default:true"`
+	RemindByEmail bool `json:"remind_by_email" gorm:"default:true"`
+
+	// What learners pay the user, as a teacher, for late cancellations and no-shows
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy" gorm:"embedded"`
+}
+
+// DefaultTimeZone is the time zone of users who haven't chosen one
+const DefaultTimeZone = "UTC"
+
+// Profile visibility levels
+const (
+	ProfilePublic  = "public"  // anyone can view the profile
+	ProfileMembers = "members" // only logged-in users can view the profile
+)
+
+// PublicProfile is the part of a user other people may see.
+// The email is only included when the user chose to show it.
+type PublicProfile struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy"`
+}
+
+// PublicProfile returns the user's public projection.
+func (u *User) PublicProfile() PublicProfile {
+	profile := PublicProfile{
+		ID:        u.ID,
+		Name:      u.Name,
+		Bio:       u.Bio,
+		CreatedAt: u.CreatedAt,
+
+		CancellationPolicy: u.CancellationPolicy,
+	}
+	if u.ShowEmail {
+		profile.Email = u.Email
+	}
+	return profile
+}
+
+// VisibleTo reports whether the profile may be shown to a viewer, who is
+// logged in when authenticated is true.
+func (u *User) VisibleTo(authenticated bool) bool {
+	return authenticated || u.ProfileVisibility != ProfileMembers
+}
+
+// Location returns the user's time zone, or UTC when it is unset or unknown.
+func (u *User) Location() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidateTimeZone checks a time zone is an IANA name such as "Europe/Warsaw".
+func ValidateTimeZone(name string) error {
+	if name == "" || name == "Local" {
+		return errors.New("time_zone must be an IANA time zone such as \"Europe/Warsaw\"")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	return nil
+}
+
+// ValidateProfileVisibility checks a profile visibility level.
+func ValidateProfileVisibility(visibility string) error {
+	if visibility != ProfilePublic && visibility != ProfileMembers {
+		return errors.New("profile_visibility must be \"public\" or \"members\"")
+	}
+	return nil
 }
 
 // BeforeSave hashes the password and sets default role if empty.
@@ -33,6 +134,12 @@ func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 	if u.Role == "" {
 		u.Role = "User"
 	}
+	if u.ProfileVisibility == "" {
+		u.ProfileVisibility = ProfilePublic
+	}
+	if u.TimeZone == "" {
+		u.TimeZone = DefaultTimeZone
+	}
 	return nil
 }
 
@@ -41,3 +148,16 @@ func (u *User) ComparePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// HashCalDAVPassword returns the hash stored for a CalDAV app password. App
+// passwords are long and random, so a fast hash is enough.
+func HashCalDAVPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// CompareCalDAVPassword checks a CalDAV app password against the user's, if they have one.
+func (u *User) CompareCalDAVPassword(password string) bool {
+	return u.CalDAVPasswordHash != "" && password != "" &&
+		subtle.ConstantTimeCompare([]byte(u.CalDAVPasswordHash), []byte(HashCalDAVPassword(password))) == 1
+}
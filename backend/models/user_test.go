@@ -2,6 +2,7 @@ package models_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mplaczek99/SkillSwap/models"
 	"gorm.io/gorm"
@@ -122,3 +123,80 @@ func TestUserComparePassword(t *testing.T) {
 		}
 	})
 }
+
+func TestUserPublicProfile(t *testing.T) {
+	user := models.User{
+		ID:          7,
+		Name:        "Alice",
+		Email:       "alice@example.com",
+		Bio:         "Guitar teacher",
+		Role:        "Admin",
+		SkillPoints: 250,
+	}
+
+	profile := user.PublicProfile()
+	if profile.Email != "" {
+		t.Errorf("Expected email to be hidden, got %q", profile.Email)
+	}
+	if profile.Name != "Alice" || profile.Bio != "Guitar teacher" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+
+	user.ShowEmail = true
+	if profile := user.PublicProfile(); profile.Email != "alice@example.com" {
+		t.Errorf("Expected email to be shown, got %q", profile.Email)
+	}
+}
+
+func TestUserVisibleTo(t *testing.T) {
+	public := models.User{ProfileVisibility: models.ProfilePublic}
+	if !public.VisibleTo(false) || !public.VisibleTo(true) {
+		t.Error("Expected a public profile to be visible to everyone")
+	}
+
+	members := models.User{ProfileVisibility: models.ProfileMembers}
+	if members.VisibleTo(false) {
+		t.Error("Expected a members-only profile to be hidden from anonymous viewers")
+	}
+	if !members.VisibleTo(true) {
+		t.Error("Expected a members-only profile to be visible to logged-in users")
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	if loc := (&models.User{}).Location(); loc != time.UTC {
+		t.Errorf("Expected users without a time zone to get UTC, got %s", loc)
+	}
+	if loc := (&models.User{TimeZone: "Mars/Olympus"}).Location(); loc != time.UTC {
+		t.Errorf("Expected an unknown time zone to fall back to UTC, got %s", loc)
+	}
+	if loc := (&models.User{TimeZone: "Europe/Warsaw"}).Location(); loc.String() != "Europe/Warsaw" {
+		t.Errorf("Expected Europe/Warsaw, got %s", loc)
+	}
+}
+
+func TestValidateTimeZone(t *testing.T) {
+	if err := models.ValidateTimeZone("Europe/Warsaw"); err != nil {
+		t.Errorf("Expected Europe/Warsaw to be valid, got %v", err)
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if err := models.ValidateTimeZone(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestCompareCalDAVPassword(t *testing.T) {
+	user := models.User{CalendarToken: "feed-token", CalDAVPasswordHash: models.HashCalDAVPassword("app-password")}
+	if !user.CompareCalDAVPassword("app-password") {
+		t.Error("Expected the app password to match")
+	}
+	for _, password := range []string{"", "feed-token", "app-password2"} {
+		if user.CompareCalDAVPassword(password) {
+			t.Errorf("Expected %q not to match", password)
+		}
+	}
+	if (&models.User{}).CompareCalDAVPassword("") {
+		t.Error("Expected users without an app password never to match")
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxWorkshopCapacity is the largest number of seats a workshop can have
+const MaxWorkshopCapacity = 500
+
+// Workshop statuses
+const (
+	WorkshopScheduled = "scheduled"
+	WorkshopCancelled = "cancelled"
+)
+
+// Seat statuses. Learners book seats while there are some left and join the
+// waitlist after that; waitlisted learners are booked in the order they joined
+// as seats free up.
+const (
+	SeatBooked     = "booked"
+	SeatWaitlisted = "waitlisted"
+	SeatCancelled  = "cancelled"
+)
+
+// CheckInWindow is how long before a workshop starts attendees can be checked in
+const CheckInWindow = 30 * time.Minute
+
+// Workshop is a group session of a skill, taught to several learners who each
+// book and pay for a seat.
+type Workshop struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TeacherID uint      `gorm:"index" json:"teacher_id"`
+	SkillID   uint      `gorm:"index" json:"skill_id"`
+	Title     string    `json:"title"`
+	StartTime time.Time `gorm:"index" json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Capacity  int       `json:"capacity"`
+	SeatPrice int       `json:"seat_price"` // SkillPoints each attendee pays
+	Status    string    `gorm:"size:20;index;default:scheduled" json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Time zone the times are shown in, set by In
+	TimeZone string `gorm:"-" json:"time_zone,omitempty"`
+}
+
+// WorkshopSeat is a learner's seat in a workshop, or their place on its waitlist.
+type WorkshopSeat struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	WorkshopID uint   `gorm:"index" json:"workshop_id"`
+	UserID     uint   `gorm:"index" json:"user_id"`
+	Status     string `gorm:"size:20;index" json:"status"`
+	Price      int    `json:"price"` // SkillPoints held for the seat once booked
+
+	// Code the attendee gives the teacher to be checked in, set once booked
+	CheckInCode string     `gorm:"size:16;index" json:"check_in_code,omitempty"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+
+	BookedAt  *time.Time `json:"booked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"` // waitlist order
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Validate checks the workshop is complete, in the future, as long as the
+// skill allows and has a sensible capacity and price. A nil skill uses the
+// default length limits.
+func (w *Workshop) Validate(skill *Skill) error {
+	if w.Title == "" {
+		return errors.New("title is required")
+	}
+	if w.Capacity < 1 || w.Capacity > MaxWorkshopCapacity {
+		return fmt.Errorf("capacity must be between 1 and %d", MaxWorkshopCapacity)
+	}
+	if w.SeatPrice < 0 {
+		return errors.New("seat_price cannot be negative")
+	}
+
+	// Workshops follow the same time rules as one-on-one sessions
+	session := Schedule{LearnerID: w.TeacherID, SkillID: w.SkillID, StartTime: w.StartTime, EndTime: w.EndTime}
+	return session.Validate(skill)
+}
+
+// In returns a copy of the workshop with its times in loc.
+func (w Workshop) In(loc *time.Location) Workshop {
+	w.StartTime = w.StartTime.In(loc)
+	w.EndTime = w.EndTime.In(loc)
+	w.CreatedAt = w.CreatedAt.In(loc)
+	w.UpdatedAt = w.UpdatedAt.In(loc)
+	w.TimeZone = loc.String()
+	return w
+}
+
+// CheckInOpen reports whether attendees can be checked in at now: from
+// CheckInWindow before the workshop starts until it ends
+func (w *Workshop) CheckInOpen(now time.Time) bool {
+	return !now.Before(w.StartTime.Add(-CheckInWindow)) && now.Before(w.EndTime)
+}
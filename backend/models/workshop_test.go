@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+)
+
+func TestWorkshopValidate(t *testing.T) {
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	valid := models.Workshop{
+		TeacherID: 1,
+		SkillID:   1,
+		Title:     "Intro to guitar",
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Hour),
+		Capacity:  10,
+		SeatPrice: 5,
+	}
+	if err := valid.Validate(nil); err != nil {
+		t.Fatalf("Expected a valid workshop, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(w *models.Workshop)
+	}{
+		{"missing title", func(w *models.Workshop) { w.Title = "" }},
+		{"no seats", func(w *models.Workshop) { w.Capacity = 0 }},
+		{"too many seats", func(w *models.Workshop) { w.Capacity = models.MaxWorkshopCapacity + 1 }},
+		{"negative price", func(w *models.Workshop) { w.SeatPrice = -1 }},
+		{"in the past", func(w *models.Workshop) {
+			w.StartTime = time.Now().Add(-2 * time.Hour)
+			w.EndTime = time.Now().Add(-time.Hour)
+		}},
+		{"ends before it starts", func(w *models.Workshop) { w.EndTime = w.StartTime.Add(-time.Hour) }},
+		{"too long", func(w *models.Workshop) { w.EndTime = w.StartTime.Add(24 * time.Hour) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workshop := valid
+			tt.change(&workshop)
+			if err := workshop.Validate(nil); err == nil {
+				t.Errorf("Expected %+v to be rejected", workshop)
+			}
+		})
+	}
+}
+
+func TestWorkshopCheckInOpen(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	workshop := models.Workshop{StartTime: start, EndTime: start.Add(2 * time.Hour)}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"an hour before", start.Add(-time.Hour), false},
+		{"as the window opens", start.Add(-models.CheckInWindow), true},
+		{"during the workshop", start.Add(time.Hour), true},
+		{"as it ends", start.Add(2 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workshop.CheckInOpen(tt.now); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWorkshopIn(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	workshop := models.Workshop{StartTime: start, EndTime: start.Add(time.Hour)}
+
+	local := workshop.In(loc)
+	if local.StartTime.Hour() != 10 || !local.StartTime.Equal(start) || local.TimeZone != "Europe/Warsaw" {
+		t.Errorf("Expected the same instant at 10:00 in Warsaw, got %v (%s)", local.StartTime, local.TimeZone)
+	}
+	if workshop.TimeZone != "" {
+		t.Error("Expected In to leave the original unchanged")
+	}
+}
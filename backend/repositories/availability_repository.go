@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+)
+
+// AvailabilityRepository handles database operations for teacher availability
+type AvailabilityRepository struct {
+	DB *gorm.DB
+}
+
+// NewAvailabilityRepository creates a new instance of AvailabilityRepository
+func NewAvailabilityRepository(db *gorm.DB) *AvailabilityRepository {
+	return &AvailabilityRepository{DB: db}
+}
+
+// GetAvailabilityByTeacher returns a teacher's availability windows
+func (r *AvailabilityRepository) GetAvailabilityByTeacher(teacherID uint) ([]models.Availability, error) {
+	var availability []models.Availability
+	err := r.DB.Where("teacher_id = ?", teacherID).Order("id").Find(&availability).Error
+	return availability, err
+}
+
+// GetAvailabilityByID returns an availability window by ID
+func (r *AvailabilityRepository) GetAvailabilityByID(id uint) (*models.Availability, error) {
+	var availability models.Availability
+	err := r.DB.First(&availability, id).Error
+	return &availability, err
+}
+
+// CreateAvailability creates a new availability window
+func (r *AvailabilityRepository) CreateAvailability(availability *models.Availability) error {
+	return r.DB.Create(availability).Error
+}
+
+// UpdateAvailability saves changes to an availability window
+func (r *AvailabilityRepository) UpdateAvailability(availability *models.Availability) error {
+	return r.DB.Save(availability).Error
+}
+
+// DeleteAvailability deletes an availability window
+func (r *AvailabilityRepository) DeleteAvailability(id uint) error {
+	return r.DB.Delete(&models.Availability{ID: id}).Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+)
+
+// BusyBlockRepository handles database operations for imported busy times
+type BusyBlockRepository struct {
+	DB *gorm.DB
+}
+
+// NewBusyBlockRepository creates a new instance of BusyBlockRepository
+func NewBusyBlockRepository(db *gorm.DB) *BusyBlockRepository {
+	return &BusyBlockRepository{DB: db}
+}
+
+// GetBusyBlocksByUser returns a user's busy times in start time order.
+// A non-zero from or to keeps only blocks overlapping the range.
+func (r *BusyBlockRepository) GetBusyBlocksByUser(userID uint, from, to time.Time) ([]models.BusyBlock, error) {
+	query := r.DB.Where("user_id = ?", userID)
+	if !from.IsZero() {
+		query = query.Where("end_time > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_time < ?", to)
+	}
+
+	var blocks []models.BusyBlock
+	err := query.Order("start_time").Find(&blocks).Error
+	return blocks, err
+}
+
+// FindOverlapping returns the busy times of any of the users overlapping start up to end
+func (r *BusyBlockRepository) FindOverlapping(userIDs []uint, start, end time.Time) ([]models.BusyBlock, error) {
+	var blocks []models.BusyBlock
+	err := r.DB.
+		Where("user_id IN ?", userIDs).
+		Where("start_time < ? AND end_time > ?", end, start).
+		Order("start_time").
+		Find(&blocks).Error
+	return blocks, err
+}
+
+// ReplaceBusyBlocks deletes a user's busy times imported from events with the
+// given UIDs and saves blocks in their place
+func (r *BusyBlockRepository) ReplaceBusyBlocks(userID uint, uids []string, blocks []models.BusyBlock) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if len(uids) > 0 {
+			if err := tx.Where("user_id = ? AND uid IN ?", userID, uids).Delete(&models.BusyBlock{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(blocks, 100).Error
+	})
+}
+
+// GetBusyBlockByID returns a busy time by ID
+func (r *BusyBlockRepository) GetBusyBlockByID(id uint) (*models.BusyBlock, error) {
+	var block models.BusyBlock
+	err := r.DB.First(&block, id).Error
+	return &block, err
+}
+
+// DeleteBusyBlock deletes a busy time
+func (r *BusyBlockRepository) DeleteBusyBlock(id uint) error {
+	return r.DB.Delete(&models.BusyBlock{}, id).Error
+}
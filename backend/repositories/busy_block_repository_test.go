@@ -0,0 +1,44 @@
+package repositories_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestReplaceBusyBlocks(t *testing.T) {
+	repo := repositories.NewBusyBlockRepository(openTestDB(t))
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	blocks := []models.BusyBlock{
+		{UserID: 1, UID: "a", StartTime: start, EndTime: start.Add(time.Hour)},
+		{UserID: 1, UID: "b", StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)},
+	}
+	if err := repo.ReplaceBusyBlocks(1, []string{"a", "b"}, blocks); err != nil {
+		t.Fatalf("ReplaceBusyBlocks failed: %v", err)
+	}
+
+	// Importing "a" again moves it and leaves "b" alone
+	moved := []models.BusyBlock{{UserID: 1, UID: "a", StartTime: start.Add(4 * time.Hour), EndTime: start.Add(5 * time.Hour)}}
+	if err := repo.ReplaceBusyBlocks(1, []string{"a"}, moved); err != nil {
+		t.Fatalf("ReplaceBusyBlocks failed: %v", err)
+	}
+
+	found, err := repo.GetBusyBlocksByUser(1, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetBusyBlocksByUser failed: %v", err)
+	}
+	if len(found) != 2 || found[0].UID != "b" || found[1].UID != "a" {
+		t.Errorf("Expected b then the moved a, got %+v", found)
+	}
+
+	overlapping, err := repo.FindOverlapping([]uint{1, 2}, start.Add(30*time.Minute), start.Add(150*time.Minute))
+	if err != nil {
+		t.Fatalf("FindOverlapping failed: %v", err)
+	}
+	if len(overlapping) != 1 || overlapping[0].UID != "b" {
+		t.Errorf("Expected only b to overlap, got %+v", overlapping)
+	}
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DisputeRepository handles database operations for transaction disputes
+type DisputeRepository struct {
+	DB *gorm.DB
+}
+
+// NewDisputeRepository creates a new instance of DisputeRepository
+func NewDisputeRepository(db *gorm.DB) *DisputeRepository {
+	return &DisputeRepository{DB: db}
+}
+
+// CreateDispute stores a new dispute
+func (r *DisputeRepository) CreateDispute(dispute *models.TransactionDispute) error {
+	return r.DB.Create(dispute).Error
+}
+
+// GetDisputeByID returns a dispute without its messages and evidence
+func (r *DisputeRepository) GetDisputeByID(id uint) (*models.TransactionDispute, error) {
+	var dispute models.TransactionDispute
+	if err := r.DB.First(&dispute, id).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// GetDisputeWithThread returns a dispute with its messages and evidence, oldest first
+func (r *DisputeRepository) GetDisputeWithThread(id uint) (*models.TransactionDispute, error) {
+	var dispute models.TransactionDispute
+	err := r.DB.Preload("Messages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Preload("Evidence", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		First(&dispute, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// GetDisputeForUpdate returns a dispute without its messages and evidence,
+// locking its row until the surrounding transaction ends
+func (r *DisputeRepository) GetDisputeForUpdate(id uint) (*models.TransactionDispute, error) {
+	var dispute models.TransactionDispute
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// HasOpenDispute reports whether a transaction has a dispute waiting for resolution
+func (r *DisputeRepository) HasOpenDispute(transactionID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.TransactionDispute{}).
+		Where("transaction_id = ? AND status = ?", transactionID, models.DisputeOpen).Count(&count).Error
+	return count > 0, err
+}
+
+// GetDisputes returns up to limit disputes with a status, or of any status
+// when it is empty, oldest first
+func (r *DisputeRepository) GetDisputes(status string, limit int) ([]models.TransactionDispute, error) {
+	query := r.DB.Order("created_at, id").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var disputes []models.TransactionDispute
+	err := query.Find(&disputes).Error
+	return disputes, err
+}
+
+// UpdateDisputeResolution saves a dispute's status and resolution
+func (r *DisputeRepository) UpdateDisputeResolution(dispute *models.TransactionDispute) error {
+	return r.DB.Model(dispute).
+		Select("status", "resolved_by_id", "resolution", "refund_transaction_id", "resolved_at").
+		Updates(dispute).Error
+}
+
+// CreateMessage stores a dispute message
+func (r *DisputeRepository) CreateMessage(message *models.DisputeMessage) error {
+	return r.DB.Create(message).Error
+}
+
+// CreateEvidence stores a record of a dispute's evidence file
+func (r *DisputeRepository) CreateEvidence(evidence *models.DisputeEvidence) error {
+	return r.DB.Create(evidence).Error
+}
+
+// GetEvidence returns one of a dispute's evidence files
+func (r *DisputeRepository) GetEvidence(disputeID, id uint) (*models.DisputeEvidence, error) {
+	var evidence models.DisputeEvidence
+	if err := r.DB.Where("dispute_id = ?", disputeID).First(&evidence, id).Error; err != nil {
+		return nil, err
+	}
+	return &evidence, nil
+}
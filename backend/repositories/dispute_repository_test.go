@@ -0,0 +1,81 @@
+package repositories_test
+
+import (
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestRefundsAndOpenDisputes(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	transactionRepo := repositories.NewTransactionRepository(db)
+	disputeRepo := repositories.NewDisputeRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+
+	sender := models.User{Name: "Sender", Email: "dispute-sender@example.com", Password: "password123"}
+	receiver := models.User{Name: "Receiver", Email: "dispute-receiver@example.com", Password: "password123"}
+	for _, user := range []*models.User{&sender, &receiver} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	original := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: 40, Note: "Guitar lesson"}
+	if err := transactionRepo.CreateTransaction(&original); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	refund := models.Transaction{SenderID: receiver.ID, ReceiverID: sender.ID, Amount: 15, RefundOf: &original.ID}
+	if err := transactionRepo.CreateTransaction(&refund); err != nil {
+		t.Fatalf("CreateTransaction of the refund failed: %v", err)
+	}
+
+	refunded, err := transactionRepo.RefundedAmount(original.ID)
+	if err != nil || refunded != 15 {
+		t.Errorf("Expected 15 points refunded, got %d (%v)", refunded, err)
+	}
+	refunds, err := transactionRepo.GetRefunds(original.ID)
+	if err != nil || len(refunds) != 1 || refunds[0].ID != refund.ID {
+		t.Errorf("Expected the refund to be listed, got %+v (%v)", refunds, err)
+	}
+
+	var journal models.LedgerJournal
+	if err := db.Where("transaction_id = ?", refund.ID).First(&journal).Error; err != nil || journal.Kind != models.JournalRefund {
+		t.Errorf("Expected the refund to be posted as a refund journal, got %+v (%v)", journal, err)
+	}
+	for _, want := range []struct {
+		user    *models.User
+		balance int
+	}{{&sender, 75}, {&receiver, 125}} {
+		balance, err := ledgerRepo.Balance(models.LedgerUser, want.user.ID)
+		if err != nil || balance != want.balance {
+			t.Errorf("Expected %s to have %d in the ledger, got %d (%v)", want.user.Name, want.balance, balance, err)
+		}
+	}
+
+	dispute := models.TransactionDispute{TransactionID: original.ID, OpenedByID: sender.ID, Reason: "Lesson was cut short", Status: models.DisputeOpen}
+	if err := disputeRepo.CreateDispute(&dispute); err != nil {
+		t.Fatalf("CreateDispute failed: %v", err)
+	}
+	if open, err := disputeRepo.HasOpenDispute(original.ID); err != nil || !open {
+		t.Errorf("Expected an open dispute, got %v (%v)", open, err)
+	}
+	second := models.TransactionDispute{TransactionID: original.ID, OpenedByID: sender.ID, Reason: "Again", Status: models.DisputeOpen}
+	if err := db.SavePoint("second").Error; err != nil {
+		t.Fatalf("SavePoint failed: %v", err)
+	}
+	if err := disputeRepo.CreateDispute(&second); err == nil {
+		t.Error("Expected a second open dispute of the transaction to be refused")
+	}
+	db.RollbackTo("second")
+
+	dispute.Status = models.DisputeRejected
+	dispute.Resolution = "The lesson was delivered"
+	if err := disputeRepo.UpdateDisputeResolution(&dispute); err != nil {
+		t.Fatalf("UpdateDisputeResolution failed: %v", err)
+	}
+	if open, err := disputeRepo.HasOpenDispute(original.ID); err != nil || open {
+		t.Errorf("Expected no open dispute once resolved, got %v (%v)", open, err)
+	}
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EscrowRepository handles database operations for session payments held in escrow
+type EscrowRepository struct {
+	DB *gorm.DB
+}
+
+// NewEscrowRepository creates a new instance of EscrowRepository
+func NewEscrowRepository(db *gorm.DB) *EscrowRepository {
+	return &EscrowRepository{DB: db}
+}
+
+// HoldEscrow moves the escrow's amount from the learner's balance into the
+// escrow account and stores the escrow. It returns ErrInsufficientPoints if
+// the learner can't cover it.
+func (r *EscrowRepository) HoldEscrow(escrow *models.Escrow) error {
+	return r.DB.Transaction(func(dbTx *gorm.DB) error {
+		escrow.Status = models.EscrowHeld
+		if err := dbTx.Create(escrow).Error; err != nil {
+			return err
+		}
+		journal := models.LedgerJournal{Kind: models.JournalEscrowHold, EscrowID: &escrow.ID}
+		return NewLedgerRepository(dbTx).Post(&journal,
+			UserPosting(escrow.LearnerID, -escrow.Amount), EscrowPosting(escrow.Amount))
+	})
+}
+
+// GetEscrowForUpdate returns a session's escrow, locking its row until the
+// surrounding transaction ends
+func (r *EscrowRepository) GetEscrowForUpdate(scheduleID uint) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("schedule_id = ?", scheduleID).First(&escrow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &escrow, nil
+}
+
+// GetSeatEscrowForUpdate returns a workshop seat's escrow, locking its row
+// until the surrounding transaction ends
+func (r *EscrowRepository) GetSeatEscrowForUpdate(seatID uint) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("seat_id = ?", seatID).First(&escrow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &escrow, nil
+}
+
+// UpdateEscrowConfirmations saves which participants confirmed the session took place
+func (r *EscrowRepository) UpdateEscrowConfirmations(escrow *models.Escrow) error {
+	return r.DB.Model(escrow).Select("learner_confirmed_at", "teacher_confirmed_at").Updates(escrow).Error
+}
+
+// ReleaseEscrow pays a held escrow to the teacher in full
+func (r *EscrowRepository) ReleaseEscrow(escrow *models.Escrow, note string, at time.Time) error {
+	return r.SettleEscrow(escrow, escrow.Amount, note, at)
+}
+
+// RefundEscrow returns a held escrow to the learner in full
+func (r *EscrowRepository) RefundEscrow(escrow *models.Escrow, at time.Time) error {
+	return r.SettleEscrow(escrow, 0, "", at)
+}
+
+// SettleEscrow pays toTeacher of a held escrow to the teacher, recording it as
+// a transaction from the learner, and returns the rest to the learner. Both
+// come out of the escrow account in one journal.
+func (r *EscrowRepository) SettleEscrow(escrow *models.Escrow, toTeacher int, note string, at time.Time) error {
+	return r.DB.Transaction(func(dbTx *gorm.DB) error {
+		refund := escrow.Amount - toTeacher
+		journal := models.LedgerJournal{Kind: models.JournalRefund, Note: note, EscrowID: &escrow.ID, CreatedAt: at}
+		postings := []Posting{EscrowPosting(-escrow.Amount)}
+		if refund > 0 {
+			postings = append(postings, UserPosting(escrow.LearnerID, refund))
+		}
+
+		escrow.Status = models.EscrowRefunded
+		if toTeacher > 0 {
+			payment := models.Transaction{
+				SenderID:   escrow.LearnerID,
+				ReceiverID: escrow.TeacherID,
+				Amount:     toTeacher,
+				Note:       note,
+				CreatedAt:  at,
+				UpdatedAt:  at,
+			}
+			if err := dbTx.Create(&payment).Error; err != nil {
+				return err
+			}
+			escrow.Status = models.EscrowReleased
+			escrow.TransactionID = &payment.ID
+
+			journal.Kind = models.JournalRelease
+			if refund > 0 {
+				journal.Kind = models.JournalFee
+			}
+			journal.TransactionID = &payment.ID
+			postings = append(postings, UserPosting(escrow.TeacherID, toTeacher))
+		}
+		if err := NewLedgerRepository(dbTx).Post(&journal, postings...); err != nil {
+			return err
+		}
+
+		escrow.Refunded = refund
+		escrow.SettledAt = &at
+		return dbTx.Model(escrow).Select("status", "transaction_id", "refunded", "settled_at").Updates(escrow).Error
+	})
+}
+
+// ClaimReleasableEscrows returns up to limit held escrows of sessions that are
+// confirmed or completed and ended by endedBefore, locking them until the
+// surrounding transaction ends. Escrows another transaction has claimed are
+// skipped, so several servers can release escrows at once.
+func (r *EscrowRepository) ClaimReleasableEscrows(endedBefore time.Time, limit int) ([]models.Escrow, error) {
+	var escrows []models.Escrow
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "escrows"}, Options: "SKIP LOCKED"}).
+		Joins("JOIN schedules ON schedules.id = escrows.schedule_id").
+		Where("escrows.status = ? AND schedules.status IN ? AND schedules.end_time <= ?",
+			models.EscrowHeld, []string{models.StatusConfirmed, models.StatusCompleted}, endedBefore).
+		Order("escrows.id").Limit(limit).Find(&escrows).Error
+	return escrows, err
+}
+
+// ClaimReleasableSeatEscrows returns up to limit held escrows of booked seats
+// in scheduled workshops that ended by endedBefore, locking them like
+// ClaimReleasableEscrows
+func (r *EscrowRepository) ClaimReleasableSeatEscrows(endedBefore time.Time, limit int) ([]models.Escrow, error) {
+	var escrows []models.Escrow
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "escrows"}, Options: "SKIP LOCKED"}).
+		Joins("JOIN workshop_seats ON workshop_seats.id = escrows.seat_id").
+		Joins("JOIN workshops ON workshops.id = workshop_seats.workshop_id").
+		Where("escrows.status = ? AND workshop_seats.status = ? AND workshops.status = ? AND workshops.end_time <= ?",
+			models.EscrowHeld, models.SeatBooked, models.WorkshopScheduled, endedBefore).
+		Order("escrows.id").Limit(limit).Find(&escrows).Error
+	return escrows, err
+}
+
+// HeldPoints returns the points a user has in escrow: held paying for their
+// sessions as a learner, and pending release to them as a teacher
+func (r *EscrowRepository) HeldPoints(userID uint) (held, pending int, err error) {
+	var sums struct {
+		Held    int
+		Pending int
+	}
+	err = r.DB.Model(&models.Escrow{}).
+		Select("COALESCE(SUM(CASE WHEN learner_id = ? THEN amount END), 0) AS held, "+
+			"COALESCE(SUM(CASE WHEN teacher_id = ? THEN amount END), 0) AS pending", userID, userID).
+		Where("status = ? AND (learner_id = ? OR teacher_id = ?)", models.EscrowHeld, userID, userID).
+		Scan(&sums).Error
+	return sums.Held, sums.Pending, err
+}
@@ -0,0 +1,118 @@
+package repositories_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestEscrowHoldAndRelease(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewEscrowRepository(db)
+
+	learner := models.User{Name: "Learner", Email: "escrow-learner@example.com", Password: "password123", SkillPoints: 30}
+	teacher := models.User{Name: "Teacher", Email: "escrow-teacher@example.com", Password: "password123", SkillPoints: 0}
+	for _, user := range []*models.User{&learner, &teacher} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	tooMuch := models.Escrow{ScheduleID: 1, LearnerID: learner.ID, TeacherID: teacher.ID, Amount: 50}
+	if err := repo.HoldEscrow(&tooMuch); !errors.Is(err, repositories.ErrInsufficientPoints) {
+		t.Fatalf("Expected ErrInsufficientPoints, got %v", err)
+	}
+
+	escrow := models.Escrow{ScheduleID: 2, LearnerID: learner.ID, TeacherID: teacher.ID, Amount: 20}
+	if err := repo.HoldEscrow(&escrow); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+
+	held, pending, err := repo.HeldPoints(learner.ID)
+	if err != nil || held != 20 || pending != 0 {
+		t.Errorf("Expected the learner to have 20 held, got %d held, %d pending (%v)", held, pending, err)
+	}
+	held, pending, err = repo.HeldPoints(teacher.ID)
+	if err != nil || held != 0 || pending != 20 {
+		t.Errorf("Expected the teacher to have 20 pending, got %d held, %d pending (%v)", held, pending, err)
+	}
+
+	if err := repo.ReleaseEscrow(&escrow, "Session: Guitar", time.Now()); err != nil {
+		t.Fatalf("ReleaseEscrow failed: %v", err)
+	}
+	if escrow.Status != models.EscrowReleased || escrow.TransactionID == nil {
+		t.Errorf("Expected a released escrow with its payment, got %+v", escrow)
+	}
+
+	var balances []models.User
+	if err := db.Where("id IN ?", []uint{learner.ID, teacher.ID}).Order("id").Find(&balances).Error; err != nil {
+		t.Fatalf("Failed to reload users: %v", err)
+	}
+	if balances[0].SkillPoints != 10 || balances[1].SkillPoints != 20 {
+		t.Errorf("Expected balances 10 and 20, got %d and %d", balances[0].SkillPoints, balances[1].SkillPoints)
+	}
+	if held, pending, _ := repo.HeldPoints(teacher.ID); held != 0 || pending != 0 {
+		t.Errorf("Expected nothing held after release, got %d held, %d pending", held, pending)
+	}
+}
+
+func TestEscrowRefund(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewEscrowRepository(db)
+
+	learner := models.User{Name: "Learner", Email: "refund-learner@example.com", Password: "password123", SkillPoints: 30}
+	if err := db.Create(&learner).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	escrow := models.Escrow{ScheduleID: 3, LearnerID: learner.ID, TeacherID: learner.ID + 1, Amount: 25}
+	if err := repo.HoldEscrow(&escrow); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	if err := repo.RefundEscrow(&escrow, time.Now()); err != nil {
+		t.Fatalf("RefundEscrow failed: %v", err)
+	}
+
+	var reloaded models.User
+	if err := db.First(&reloaded, learner.ID).Error; err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if reloaded.SkillPoints != 30 || escrow.Status != models.EscrowRefunded {
+		t.Errorf("Expected the full balance back and a refunded escrow, got %d and %s", reloaded.SkillPoints, escrow.Status)
+	}
+}
+
+func TestEscrowSettlePartly(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewEscrowRepository(db)
+
+	learner := models.User{Name: "Learner", Email: "late-learner@example.com", Password: "password123", SkillPoints: 40}
+	teacher := models.User{Name: "Teacher", Email: "late-teacher@example.com", Password: "password123", SkillPoints: 0}
+	for _, user := range []*models.User{&learner, &teacher} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	escrow := models.Escrow{ScheduleID: 4, LearnerID: learner.ID, TeacherID: teacher.ID, Amount: 40}
+	if err := repo.HoldEscrow(&escrow); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	if err := repo.SettleEscrow(&escrow, 10, "Late cancellation: Guitar", time.Now()); err != nil {
+		t.Fatalf("SettleEscrow failed: %v", err)
+	}
+	if escrow.Status != models.EscrowReleased || escrow.Refunded != 30 || escrow.TransactionID == nil {
+		t.Errorf("Expected 10 paid and 30 refunded, got %+v", escrow)
+	}
+
+	var balances []models.User
+	if err := db.Where("id IN ?", []uint{learner.ID, teacher.ID}).Order("id").Find(&balances).Error; err != nil {
+		t.Fatalf("Failed to reload users: %v", err)
+	}
+	if balances[0].SkillPoints != 30 || balances[1].SkillPoints != 10 {
+		t.Errorf("Expected balances 30 and 10, got %d and %d", balances[0].SkillPoints, balances[1].SkillPoints)
+	}
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct {
+	DB *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new instance of IdempotencyRepository
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{DB: db}
+}
+
+// ClaimKey stores the key for the request about to run. When the user
+// already used the key, nothing is stored and the existing key is returned,
+// unless it expired, in which case it is replaced. Two requests racing with
+// the same key can't both claim it.
+func (r *IdempotencyRepository) ClaimKey(key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, error) {
+	for attempt := 0; ; attempt++ {
+		key.ID = 0
+		result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := r.DB.Where("user_id = ? AND key = ?", key.UserID, key.Key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) && attempt == 0 {
+			continue // released between the insert and the read
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !existing.ExpiresAt.Before(now) || attempt > 0 {
+			return &existing, nil
+		}
+		if err := r.DB.Where("id = ? AND expires_at < ?", existing.ID, now).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+	}
+}
+
+// CompleteKey stores the response to replay for a claimed key until it expires
+func (r *IdempotencyRepository) CompleteKey(key *models.IdempotencyKey) error {
+	return r.DB.Model(key).Select("status_code", "content_type", "body", "expires_at").Updates(key).Error
+}
+
+// ReleaseKey deletes a claimed key, so a retry runs the request again
+func (r *IdempotencyRepository) ReleaseKey(key *models.IdempotencyKey) error {
+	return r.DB.Delete(key).Error
+}
+
+// DeleteExpired deletes keys that expired before now and returns how many there were
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,53 @@
+package repositories_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestIdempotencyClaimKey(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewIdempotencyRepository(db)
+	now := time.Now()
+
+	key := models.IdempotencyKey{UserID: 1, Key: "retry-me", Fingerprint: "a", ExpiresAt: now.Add(models.IdempotencyLease)}
+	if existing, err := repo.ClaimKey(&key, now); err != nil || existing != nil {
+		t.Fatalf("Expected the key to be claimed, got %+v (%v)", existing, err)
+	}
+
+	// Another user may use the same key
+	other := models.IdempotencyKey{UserID: 2, Key: "retry-me", Fingerprint: "b", ExpiresAt: now.Add(models.IdempotencyLease)}
+	if existing, err := repo.ClaimKey(&other, now); err != nil || existing != nil {
+		t.Fatalf("Expected another user's key to be claimed, got %+v (%v)", existing, err)
+	}
+
+	retry := models.IdempotencyKey{UserID: 1, Key: "retry-me", Fingerprint: "a", ExpiresAt: now.Add(models.IdempotencyLease)}
+	existing, err := repo.ClaimKey(&retry, now)
+	if err != nil || existing == nil || existing.ID != key.ID || existing.Completed() {
+		t.Fatalf("Expected the running request's key back, got %+v (%v)", existing, err)
+	}
+
+	key.StatusCode, key.Body, key.ExpiresAt = 201, []byte(`{"id":1}`), now.Add(models.IdempotencyRetention)
+	if err := repo.CompleteKey(&key); err != nil {
+		t.Fatalf("CompleteKey failed: %v", err)
+	}
+	existing, err = repo.ClaimKey(&retry, now)
+	if err != nil || existing == nil || existing.StatusCode != 201 || string(existing.Body) != `{"id":1}` {
+		t.Fatalf("Expected the stored response, got %+v (%v)", existing, err)
+	}
+
+	// Once retention ends the key can be used again
+	later := now.Add(models.IdempotencyRetention + time.Minute)
+	reuse := models.IdempotencyKey{UserID: 1, Key: "retry-me", Fingerprint: "c", ExpiresAt: later.Add(models.IdempotencyLease)}
+	if existing, err := repo.ClaimKey(&reuse, later); err != nil || existing != nil {
+		t.Fatalf("Expected the expired key to be replaced, got %+v (%v)", existing, err)
+	}
+
+	deleted, err := repo.DeleteExpired(later.Add(models.IdempotencyLease + time.Minute))
+	if err != nil || deleted < 2 {
+		t.Errorf("Expected the expired keys deleted, got %d (%v)", deleted, err)
+	}
+}
@@ -1,7 +1,10 @@
 package repositories
 
 import (
+	"strings"
+
 	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
 	"gorm.io/gorm"
 )
 
@@ -41,7 +44,8 @@ func (r *JobRepository) UpdateJob(job *models.Job) error {
 
 // DeleteJob deletes a job posting
 func (r *JobRepository) DeleteJob(id uint) error {
-	return r.DB.Delete(&models.Job{}, id).Error
+	// Delete by model so callbacks, such as search indexing, see the ID
+	return r.DB.Delete(&models.Job{ID: id}).Error
 }
 
 // GetJobsByUser returns all job postings by a specific user
@@ -50,3 +54,83 @@ func (r *JobRepository) GetJobsByUser(userID uint) ([]models.Job, error) {
 	err := r.DB.Where("posted_by_user_id = ?", userID).Find(&jobs).Error
 	return jobs, err
 }
+
+// jobCountColumns are the columns CountJobs counts matches by
+var jobCountColumns = []string{"experience_level", "job_type", "location"}
+
+// SearchJobs returns one page of a ranked full-text search over job titles,
+// required skills and descriptions. Close trigram matches on the title are
+// included so typos still find postings. Scopes such as CreatedAfter narrow
+// the rows searched.
+func (r *JobRepository) SearchJobs(searchTerm string, page SearchPage, scopes ...func(*gorm.DB) *gorm.DB) ([]JobSearchResult, error) {
+	var jobs []JobSearchResult
+
+	if !isPostgres(r.DB) {
+		ranked, err := r.rankJobs(searchTerm, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		key := func(i int) (float64, uint) { return ranked[i].Rank, ranked[i].ID }
+		for _, i := range fallbackPage(len(ranked), key, page) {
+			job := ranked[i]
+			job.Snippet = utils.Highlight(job.Title+" "+job.Description, utils.SearchTerms(searchTerm), snippetLength)
+			jobs = append(jobs, job)
+		}
+		return jobs, nil
+	}
+
+	err := withFuzzyThreshold(r.DB, func(tx *gorm.DB) error {
+		return fullTextSearch(tx, "jobs", "title", []string{"title", "description"}, searchTerm, page, scopes...).
+			Scan(&jobs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CountJobs counts the jobs SearchJobs matches, in total and by experience
+// level, job type and location
+func (r *JobRepository) CountJobs(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) (SearchCounts, error) {
+	if isPostgres(r.DB) {
+		return countSearch(r.DB, "jobs", "title", searchTerm, jobCountColumns, scopes...)
+	}
+
+	ranked, err := r.rankJobs(searchTerm, scopes...)
+	if err != nil {
+		return SearchCounts{}, err
+	}
+	counts := SearchCounts{Total: int64(len(ranked)), Values: make(map[string]map[string]int64)}
+	for _, column := range jobCountColumns {
+		counts.Values[column] = make(map[string]int64)
+	}
+	for _, job := range ranked {
+		counts.Values["experience_level"][job.ExperienceLevel]++
+		counts.Values["job_type"][job.JobType]++
+		counts.Values["location"][job.Location]++
+	}
+	return counts, nil
+}
+
+// rankJobs scores jobs against a search in Go, for databases without
+// full-text search. Jobs that don't match are left out.
+func (r *JobRepository) rankJobs(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) ([]JobSearchResult, error) {
+	var candidates []models.Job
+	if err := r.DB.Scopes(scopes...).Limit(fallbackCandidateLimit).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var jobs []JobSearchResult
+	for _, job := range candidates {
+		skills := strings.Join(job.SkillsRequired, " ")
+		if rank := fuzzyRank(searchTerm, job.Title, skills, job.Description); rank > 0 {
+			jobs = append(jobs, JobSearchResult{Job: job, Rank: rank})
+		}
+	}
+	return jobs, nil
+}
+
+// SuggestJobs returns job titles completing the given prefix
+func (r *JobRepository) SuggestJobs(prefix string, limit int) ([]Suggestion, error) {
+	return suggest(r.DB, "jobs", "title", prefix, limit)
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerRepository posts movements of SkillPoints to the double-entry ledger
+// and reads balances from it
+type LedgerRepository struct {
+	DB *gorm.DB
+}
+
+// NewLedgerRepository creates a new instance of LedgerRepository
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{DB: db}
+}
+
+// Posting is one side of a movement: points added to an account, or taken
+// out of it when negative
+type Posting struct {
+	Kind   string // the account's kind
+	UserID uint   // the user, for user accounts
+	Amount int
+}
+
+// UserPosting adds amount to a user's spendable points
+func UserPosting(userID uint, amount int) Posting {
+	return Posting{Kind: models.LedgerUser, UserID: userID, Amount: amount}
+}
+
+// EscrowPosting adds amount to the points held in escrow
+func EscrowPosting(amount int) Posting {
+	return Posting{Kind: models.LedgerEscrow, Amount: amount}
+}
+
+// MintPosting adds amount to the mint; minting points takes them out of it
+func MintPosting(amount int) Posting {
+	return Posting{Kind: models.LedgerMint, Amount: amount}
+}
+
+// BalanceMismatch is a user whose cached skill_points disagree with their ledger account
+type BalanceMismatch struct {
+	UserID      uint
+	SkillPoints int
+	Ledger      int
+}
+
+// Post records a journal of the postings, which must sum to zero, and keeps
+// each user's cached skill_points in step with their account. Postings that
+// take points out of a user fail with ErrInsufficientPoints, and post
+// nothing, if the user's balance can't cover them.
+//
+// Balances change with conditional updates rather than being read and
+// written back, so concurrent postings can't overspend or lose each other's
+// changes. Users are updated in order of ID, so postings touching the same
+// users lock their rows in the same order and can't deadlock.
+func (r *LedgerRepository) Post(journal *models.LedgerJournal, postings ...Posting) error {
+	if journal.CreatedAt.IsZero() {
+		journal.CreatedAt = time.Now()
+	}
+	journal.Entries = make([]models.LedgerEntry, 0, len(postings))
+	for _, posting := range postings {
+		journal.Entries = append(journal.Entries, models.LedgerEntry{Amount: posting.Amount, CreatedAt: journal.CreatedAt})
+	}
+	if err := journal.Validate(); err != nil {
+		return err
+	}
+
+	return r.DB.Transaction(func(dbTx *gorm.DB) error {
+		var users []Posting
+		for _, posting := range postings {
+			if posting.Kind == models.LedgerUser {
+				users = append(users, posting)
+			}
+		}
+		sort.SliceStable(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+		for _, posting := range users {
+			query := dbTx.Model(&models.User{}).Where("id = ?", posting.UserID)
+			if posting.Amount < 0 {
+				query = query.Where("skill_points >= ?", -posting.Amount)
+			}
+			// UpdateColumn skips the User hooks, which would hash a password under the row locks
+			result := query.UpdateColumn("skill_points", gorm.Expr("skill_points + ?", posting.Amount))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				if posting.Amount < 0 {
+					return ErrInsufficientPoints
+				}
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		ledgerRepo := &LedgerRepository{DB: dbTx}
+		for i, posting := range postings {
+			account, err := ledgerRepo.Account(posting.Kind, posting.UserID)
+			if err != nil {
+				return err
+			}
+			journal.Entries[i].AccountID = account.ID
+		}
+		return dbTx.Create(journal).Error
+	})
+}
+
+// Account returns the ledger account of a kind, and of a user for user
+// accounts, opening it if it doesn't exist yet
+func (r *LedgerRepository) Account(kind string, userID uint) (*models.LedgerAccount, error) {
+	account := models.LedgerAccount{Kind: kind, UserID: userID}
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+	if account.ID != 0 {
+		return &account, nil
+	}
+	err := r.DB.Where("kind = ? AND user_id = ?", kind, userID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Balance returns the sum of an account's entries. Accounts never posted to have none.
+func (r *LedgerRepository) Balance(kind string, userID uint) (int, error) {
+	var balance int
+	err := r.DB.Model(&models.LedgerEntry{}).
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = ledger_entries.account_id").
+		Where("ledger_accounts.kind = ? AND ledger_accounts.user_id = ?", kind, userID).
+		Select("COALESCE(SUM(ledger_entries.amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// GetEscrowJournals returns the journals posted for an escrow, oldest first,
+// with their entries
+func (r *LedgerRepository) GetEscrowJournals(escrowID uint) ([]models.LedgerJournal, error) {
+	var journals []models.LedgerJournal
+	err := r.DB.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("escrow_id = ?", escrowID).Order("id").Find(&journals).Error
+	return journals, err
+}
+
+// UnbalancedJournals returns the IDs of journals whose entries don't sum to
+// zero. Post never writes one, so any found were written around it.
+func (r *LedgerRepository) UnbalancedJournals() ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&models.LedgerEntry{}).
+		Group("journal_id").Having("SUM(amount) <> 0").Order("journal_id").
+		Pluck("journal_id", &ids).Error
+	return ids, err
+}
+
+// BalanceMismatches returns the users whose cached skill_points differ from
+// the balance of their ledger account
+func (r *LedgerRepository) BalanceMismatches() ([]BalanceMismatch, error) {
+	var mismatches []BalanceMismatch
+	err := r.DB.Raw(`SELECT users.id AS user_id, users.skill_points, COALESCE(SUM(ledger_entries.amount), 0) AS ledger
+		FROM users
+		LEFT JOIN ledger_accounts ON ledger_accounts.kind = ? AND ledger_accounts.user_id = users.id
+		LEFT JOIN ledger_entries ON ledger_entries.account_id = ledger_accounts.id
+		GROUP BY users.id, users.skill_points
+		HAVING users.skill_points <> COALESCE(SUM(ledger_entries.amount), 0)
+		ORDER BY users.id`, models.LedgerUser).Scan(&mismatches).Error
+	return mismatches, err
+}
+
+// OpenBalances brings balances from before the ledger into it. Users without
+// a ledger account get one with their skill_points minted into it, and the
+// escrow account, when first opened, gets the points of escrows already held.
+// Cached balances are left as they are.
+func (r *LedgerRepository) OpenBalances(at time.Time) error {
+	return r.DB.Transaction(func(dbTx *gorm.DB) error {
+		ledgerRepo := &LedgerRepository{DB: dbTx}
+
+		var users []models.User
+		err := dbTx.Select("id", "skill_points").
+			Where("NOT EXISTS (SELECT 1 FROM ledger_accounts WHERE ledger_accounts.kind = ? AND ledger_accounts.user_id = users.id)", models.LedgerUser).
+			Order("id").Find(&users).Error
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := ledgerRepo.open(models.LedgerUser, user.ID, user.SkillPoints, at); err != nil {
+				return err
+			}
+		}
+
+		var opened int64
+		if err := dbTx.Model(&models.LedgerAccount{}).Where("kind = ?", models.LedgerEscrow).Count(&opened).Error; err != nil || opened > 0 {
+			return err
+		}
+		var held int
+		err = dbTx.Model(&models.Escrow{}).Where("status = ?", models.EscrowHeld).
+			Select("COALESCE(SUM(amount), 0)").Scan(&held).Error
+		if err != nil {
+			return err
+		}
+		return ledgerRepo.open(models.LedgerEscrow, 0, held, at)
+	})
+}
+
+// open opens an account with an opening balance minted into it, without
+// touching cached balances
+func (r *LedgerRepository) open(kind string, userID uint, balance int, at time.Time) error {
+	account, err := r.Account(kind, userID)
+	if err != nil || balance == 0 {
+		return err
+	}
+	mint, err := r.Account(models.LedgerMint, 0)
+	if err != nil {
+		return err
+	}
+	journal := models.LedgerJournal{
+		Kind: models.JournalOpening,
+		Entries: []models.LedgerEntry{
+			{AccountID: mint.ID, Amount: -balance, CreatedAt: at},
+			{AccountID: account.ID, Amount: balance, CreatedAt: at},
+		},
+		CreatedAt: at,
+	}
+	if err := journal.Validate(); err != nil {
+		return err
+	}
+	return r.DB.Create(&journal).Error
+}
@@ -0,0 +1,177 @@
+package repositories_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+func TestLedgerSignupGrantAndTransfer(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+
+	sender := models.User{Name: "Sender", Email: "ledger-sender@example.com", Password: "password123"}
+	receiver := models.User{Name: "Receiver", Email: "ledger-receiver@example.com", Password: "password123"}
+	for _, user := range []*models.User{&sender, &receiver} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		if user.SkillPoints != models.SignupGrant {
+			t.Errorf("Expected a signup grant of %d, got %d", models.SignupGrant, user.SkillPoints)
+		}
+	}
+
+	transfer := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: 30, Note: "Thanks"}
+	if err := repositories.NewTransactionRepository(db).CreateTransaction(&transfer); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	tooMuch := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: 71}
+	if err := repositories.NewTransactionRepository(db).CreateTransaction(&tooMuch); !errors.Is(err, repositories.ErrInsufficientPoints) {
+		t.Fatalf("Expected ErrInsufficientPoints, got %v", err)
+	}
+
+	for _, want := range []struct {
+		user    *models.User
+		balance int
+	}{{&sender, 70}, {&receiver, 130}} {
+		balance, err := ledgerRepo.Balance(models.LedgerUser, want.user.ID)
+		if err != nil || balance != want.balance {
+			t.Errorf("Expected %s to have %d in the ledger, got %d (%v)", want.user.Name, want.balance, balance, err)
+		}
+	}
+
+	var journal models.LedgerJournal
+	if err := db.Preload("Entries").Where("transaction_id = ?", transfer.ID).First(&journal).Error; err != nil {
+		t.Fatalf("Expected the transfer to be posted: %v", err)
+	}
+	if journal.Kind != models.JournalTransfer || len(journal.Entries) != 2 {
+		t.Errorf("Expected a two-entry transfer journal, got %+v", journal)
+	}
+	if err := db.Model(&journal.Entries[0]).Update("amount", 0).Error; !errors.Is(err, models.ErrLedgerAppendOnly) {
+		t.Errorf("Expected posted entries to be read-only, got %v", err)
+	}
+
+	mismatches, err := ledgerRepo.BalanceMismatches()
+	if err != nil {
+		t.Fatalf("BalanceMismatches failed: %v", err)
+	}
+	for _, mismatch := range mismatches {
+		if mismatch.UserID == sender.ID || mismatch.UserID == receiver.ID {
+			t.Errorf("Expected cached balances to match the ledger, got %+v", mismatch)
+		}
+	}
+}
+
+func TestLedgerPostSkipsUserHooks(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+
+	sender := models.User{Name: "Sender", Email: "hooks-sender@example.com", Password: "password123"}
+	receiver := models.User{Name: "Receiver", Email: "hooks-receiver@example.com", Password: "password123"}
+	for _, user := range []*models.User{&sender, &receiver} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	// User.BeforeSave hashes the password, which balance updates must not pay for
+	hooked := 0
+	db.Callback().Update().Before("gorm:before_update").Register("test:user_hooks", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Model.(*models.User); ok && !tx.Statement.SkipHooks {
+			hooked++
+		}
+	})
+
+	transfer := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: 10}
+	if err := repositories.NewTransactionRepository(db).CreateTransaction(&transfer); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	if hooked != 0 {
+		t.Errorf("Expected balance updates to skip the User hooks, they ran %d times", hooked)
+	}
+}
+
+func TestLedgerEscrowJournals(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	escrowRepo := repositories.NewEscrowRepository(db)
+
+	learner := models.User{Name: "Learner", Email: "ledger-learner@example.com", Password: "password123"}
+	teacher := models.User{Name: "Teacher", Email: "ledger-teacher@example.com", Password: "password123"}
+	for _, user := range []*models.User{&learner, &teacher} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+	escrowBefore, err := ledgerRepo.Balance(models.LedgerEscrow, 0)
+	if err != nil {
+		t.Fatalf("Balance failed: %v", err)
+	}
+
+	escrow := models.Escrow{ScheduleID: 5, LearnerID: learner.ID, TeacherID: teacher.ID, Amount: 40}
+	if err := escrowRepo.HoldEscrow(&escrow); err != nil {
+		t.Fatalf("HoldEscrow failed: %v", err)
+	}
+	if held, _ := ledgerRepo.Balance(models.LedgerEscrow, 0); held != escrowBefore+40 {
+		t.Errorf("Expected 40 more in the escrow account, got %d", held-escrowBefore)
+	}
+	if err := escrowRepo.SettleEscrow(&escrow, 10, "Late cancellation: Guitar", time.Now()); err != nil {
+		t.Fatalf("SettleEscrow failed: %v", err)
+	}
+
+	journals, err := ledgerRepo.GetEscrowJournals(escrow.ID)
+	if err != nil {
+		t.Fatalf("GetEscrowJournals failed: %v", err)
+	}
+	if len(journals) != 2 || journals[0].Kind != models.JournalEscrowHold || journals[1].Kind != models.JournalFee {
+		t.Fatalf("Expected a hold and a fee journal, got %+v", journals)
+	}
+	if len(journals[1].Entries) != 3 || journals[1].TransactionID == nil {
+		t.Errorf("Expected the fee to pay the teacher and refund the learner, got %+v", journals[1])
+	}
+
+	if held, _ := ledgerRepo.Balance(models.LedgerEscrow, 0); held != escrowBefore {
+		t.Errorf("Expected the escrow account back where it was, got %d more", held-escrowBefore)
+	}
+	learnerBalance, _ := ledgerRepo.Balance(models.LedgerUser, learner.ID)
+	teacherBalance, _ := ledgerRepo.Balance(models.LedgerUser, teacher.ID)
+	if learnerBalance != 90 || teacherBalance != 110 {
+		t.Errorf("Expected ledger balances 90 and 110, got %d and %d", learnerBalance, teacherBalance)
+	}
+}
+
+func TestLedgerOpenBalances(t *testing.T) {
+	db := openTestDB(t)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+
+	// A user from before the ledger, with points but no account
+	user := models.User{Name: "Old User", Email: "ledger-old@example.com", Password: "password123", SkillPoints: 75}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := ledgerRepo.OpenBalances(time.Now()); err != nil {
+		t.Fatalf("OpenBalances failed: %v", err)
+	}
+	if balance, err := ledgerRepo.Balance(models.LedgerUser, user.ID); err != nil || balance != 75 {
+		t.Errorf("Expected an opening balance of 75, got %d (%v)", balance, err)
+	}
+
+	// Opening again leaves accounts already opened alone
+	if err := ledgerRepo.OpenBalances(time.Now()); err != nil {
+		t.Fatalf("OpenBalances failed: %v", err)
+	}
+	if balance, _ := ledgerRepo.Balance(models.LedgerUser, user.ID); balance != 75 {
+		t.Errorf("Expected the opening balance to be posted once, got %d", balance)
+	}
+	unbalanced, err := ledgerRepo.UnbalancedJournals()
+	if err != nil || len(unbalanced) != 0 {
+		t.Errorf("Expected every journal to balance, got %v (%v)", unbalanced, err)
+	}
+}
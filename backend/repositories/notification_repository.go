@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository handles database operations for notifications
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+// NewNotificationRepository creates a new instance of NotificationRepository
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{DB: db}
+}
+
+// CreateNotification stores a notification
+func (r *NotificationRepository) CreateNotification(notification *models.Notification) error {
+	return r.DB.Create(notification).Error
+}
+
+// CreateNotificationsOnce stores notifications, skipping saved search matches
+// that were already recorded. It returns how many were new.
+func (r *NotificationRepository) CreateNotificationsOnce(notifications []models.Notification) (int64, error) {
+	if len(notifications) == 0 {
+		return 0, nil
+	}
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications)
+	return result.RowsAffected, result.Error
+}
+
+// GetNotificationsByUser returns a user's notifications, newest first
+func (r *NotificationRepository) GetNotificationsByUser(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkNotificationRead marks one of a user's notifications as read.
+// It returns gorm.ErrRecordNotFound if the user has no such notification.
+func (r *NotificationRepository) MarkNotificationRead(userID, id uint) error {
+	result := r.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Already read is fine; only a missing notification is an error
+		var count int64
+		if err := r.DB.Model(&models.Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read
+func (r *NotificationRepository) MarkAllNotificationsRead(userID uint) error {
+	return r.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
+
+// GetUsersWithPendingEmail returns the IDs of users with notifications waiting for a digest
+func (r *NotificationRepository) GetUsersWithPendingEmail() ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Model(&models.Notification{}).Where("email_pending").Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// GetPendingEmail returns a user's notifications waiting for a digest, oldest first
+func (r *NotificationRepository) GetPendingEmail(userID uint) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.DB.Where("user_id = ? AND email_pending", userID).Order("created_at, id").Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkEmailed records that notifications were sent in a digest
+func (r *NotificationRepository) MarkEmailed(ids []uint, sentAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.DB.Model(&models.Notification{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"email_pending": false, "emailed_at": sentAt}).Error
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderRepository handles database operations for session reminders
+type ReminderRepository struct {
+	DB *gorm.DB
+}
+
+// NewReminderRepository creates a new instance of ReminderRepository
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{DB: db}
+}
+
+// QueueReminders stores reminders, skipping those already queued for the same
+// session, participant, lead time and start. It returns how many were new.
+func (r *ReminderRepository) QueueReminders(reminders []models.SessionReminder) (int64, error) {
+	if len(reminders) == 0 {
+		return 0, nil
+	}
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminders)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimDueReminders returns up to limit pending reminders due by now, oldest
+// first, locking them until the surrounding transaction ends. Reminders
+// another transaction has claimed are skipped rather than waited for, so
+// several servers can send reminders at once without sending any twice.
+func (r *ReminderRepository) ClaimDueReminders(now time.Time, limit int) ([]models.SessionReminder, error) {
+	var reminders []models.SessionReminder
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND send_at <= ?", models.ReminderPending, now).
+		Order("send_at, id").Limit(limit).Find(&reminders).Error
+	return reminders, err
+}
+
+// MarkReminder records that a reminder was sent or skipped
+func (r *ReminderRepository) MarkReminder(id uint, status string, at time.Time) error {
+	return r.DB.Model(&models.SessionReminder{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "sent_at": at}).Error
+}
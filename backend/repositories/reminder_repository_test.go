@@ -0,0 +1,53 @@
+package repositories_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestQueueAndClaimReminders(t *testing.T) {
+	repo := repositories.NewReminderRepository(openTestDB(t))
+
+	now := time.Now().Truncate(time.Second)
+	start := now.Add(10 * time.Minute)
+	reminders := []models.SessionReminder{
+		{ScheduleID: 1, UserID: 1, LeadMinutes: 15, StartTime: start, SendAt: start.Add(-15 * time.Minute), Status: models.ReminderPending},
+		{ScheduleID: 1, UserID: 1, LeadMinutes: 5, StartTime: start, SendAt: start.Add(-5 * time.Minute), Status: models.ReminderPending},
+	}
+	queued, err := repo.QueueReminders(reminders)
+	if err != nil {
+		t.Fatalf("QueueReminders failed: %v", err)
+	}
+	if queued != 2 {
+		t.Errorf("Expected 2 reminders queued, got %d", queued)
+	}
+
+	// Queueing the same reminders again adds nothing
+	again := []models.SessionReminder{reminders[0], reminders[1]}
+	again[0].ID, again[1].ID = 0, 0
+	if queued, err := repo.QueueReminders(again); err != nil || queued != 0 {
+		t.Errorf("Expected no reminders queued again, got %d (%v)", queued, err)
+	}
+
+	due, err := repo.ClaimDueReminders(now, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders failed: %v", err)
+	}
+	if len(due) != 1 || due[0].LeadMinutes != 15 {
+		t.Fatalf("Expected only the 15 minute reminder to be due, got %+v", due)
+	}
+
+	if err := repo.MarkReminder(due[0].ID, models.ReminderSent, now); err != nil {
+		t.Fatalf("MarkReminder failed: %v", err)
+	}
+	due, err = repo.ClaimDueReminders(now, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders failed: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("Expected a sent reminder not to be claimed again, got %+v", due)
+	}
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+)
+
+// SavedSearchRepository handles database operations for saved searches
+type SavedSearchRepository struct {
+	DB *gorm.DB
+}
+
+// NewSavedSearchRepository creates a new instance of SavedSearchRepository
+func NewSavedSearchRepository(db *gorm.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{DB: db}
+}
+
+// GetSavedSearchesByUser returns a user's saved searches, newest first
+func (r *SavedSearchRepository) GetSavedSearchesByUser(userID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&searches).Error
+	return searches, err
+}
+
+// GetSavedSearchByID returns a saved search by ID
+func (r *SavedSearchRepository) GetSavedSearchByID(id uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.DB.First(&search, id).Error
+	return &search, err
+}
+
+// CreateSavedSearch creates a new saved search
+func (r *SavedSearchRepository) CreateSavedSearch(search *models.SavedSearch) error {
+	return r.DB.Create(search).Error
+}
+
+// UpdateSavedSearch saves changes to a saved search
+func (r *SavedSearchRepository) UpdateSavedSearch(search *models.SavedSearch) error {
+	return r.DB.Save(search).Error
+}
+
+// DeleteSavedSearch deletes a saved search
+func (r *SavedSearchRepository) DeleteSavedSearch(id uint) error {
+	return r.DB.Delete(&models.SavedSearch{ID: id}).Error
+}
+
+// GetSavedSearchesDue returns up to limit saved searches last run before the given time
+// with IDs above afterID, in ID order so callers can page through them.
+func (r *SavedSearchRepository) GetSavedSearchesDue(before time.Time, afterID uint, limit int) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.DB.Where("last_run_at < ? AND id > ?", before, afterID).Order("id").Limit(limit).Find(&searches).Error
+	return searches, err
+}
+
+// MarkSavedSearchRun records when a saved search was last evaluated
+func (r *SavedSearchRepository) MarkSavedSearchRun(id uint, runAt time.Time) error {
+	return r.DB.Model(&models.SavedSearch{}).Where("id = ?", id).Update("last_run_at", runAt).Error
+}
@@ -1,30 +1,186 @@
 package repositories
 
 import (
+	"sort"
 	"time"
 
 	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// InsertSchedule saves a new schedule and returns it.
-func InsertSchedule(schedule *models.Schedule) (*models.Schedule, error) {
-	// In a real implementation, you would insert into your database.
-	// For now, assign a dummy ID and current timestamp.
-	schedule.ID = 1
-	schedule.CreatedAt = time.Now()
-	return schedule, nil
-}
-
-// GetSchedulesByUserID returns all schedules for a given user.
-func GetSchedulesByUserID(userID uint) ([]models.Schedule, error) {
-	// Dummy schedule for demonstration purposes.
-	dummySchedule := models.Schedule{
-		ID:        1,
-		UserID:    userID,
-		SkillID:   1,
-		StartTime: time.Now().Add(48 * time.Hour),
-		EndTime:   time.Now().Add(50 * time.Hour),
-		CreatedAt: time.Now(),
+// ScheduleRepository handles database operations for schedules
+type ScheduleRepository struct {
+	DB *gorm.DB
+}
+
+// NewScheduleRepository creates a new instance of ScheduleRepository
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{DB: db}
+}
+
+// HasBookedSkill reports whether a user has had a session of a skill confirmed before
+func (r *ScheduleRepository) HasBookedSkill(userID, skillID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Schedule{}).
+		Where("learner_id = ? AND skill_id = ?", userID, skillID).
+		Where("status IN ?", []string{models.StatusConfirmed, models.StatusCompleted, models.StatusNoShow}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// scheduleLockSpace keeps the advisory locks taken on users' calendars apart
+// from any other advisory locks using the same user IDs
+const scheduleLockSpace = 1
+
+// LockParticipants serializes bookings involving any of the given users until the
+// surrounding transaction ends, so a conflict check and the insert that follows
+// it can't interleave with another booking. Locks are taken in ID order so two
+// bookings sharing both users can't deadlock. It does nothing outside Postgres.
+func (r *ScheduleRepository) LockParticipants(userIDs ...uint) error {
+	if !isPostgres(r.DB) {
+		return nil
+	}
+
+	ids := append([]uint(nil), userIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		if err := r.DB.Exec("SELECT pg_advisory_xact_lock(?, ?)", scheduleLockSpace, int32(id)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindConflicts returns the confirmed sessions, other than the one excluded, that
+// overlap the given session and share a participant with it in either role.
+// Requests don't block a time until they are accepted.
+func (r *ScheduleRepository) FindConflicts(schedule *models.Schedule, excludeID uint) ([]models.Schedule, error) {
+	participants := schedule.Participants()
+
+	var conflicts []models.Schedule
+	err := r.DB.
+		Where("id <> ? AND status = ?", excludeID, models.StatusConfirmed).
+		Where("learner_id IN ? OR teacher_id IN ?", participants, participants).
+		Where("start_time < ? AND end_time > ?", schedule.EndTime, schedule.StartTime).
+		Order("start_time").
+		Find(&conflicts).Error
+	return conflicts, err
+}
+
+// CreateSchedule creates a new schedule
+func (r *ScheduleRepository) CreateSchedule(schedule *models.Schedule) error {
+	return r.DB.Create(schedule).Error
+}
+
+// GetScheduleByID returns a schedule by ID
+func (r *ScheduleRepository) GetScheduleByID(id uint) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := r.DB.First(&schedule, id).Error
+	return &schedule, err
+}
+
+// GetScheduleForUpdate returns a schedule by ID, locking its row until the
+// surrounding transaction ends so concurrent changes to it are applied in turn
+func (r *ScheduleRepository) GetScheduleForUpdate(id uint) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error
+	return &schedule, err
+}
+
+// GetSchedulesByUser returns the sessions a user learns or teaches in start time order.
+// A non-zero from or to keeps only sessions ending after from or starting before to,
+// so sessions overlapping the range are included. Statuses, when given, narrow the list.
+func (r *ScheduleRepository) GetSchedulesByUser(userID uint, from, to time.Time, statuses ...string) ([]models.Schedule, error) {
+	query := r.DB.Where("learner_id = ? OR teacher_id = ?", userID, userID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if !from.IsZero() {
+		query = query.Where("end_time > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_time < ?", to)
 	}
-	return []models.Schedule{dummySchedule}, nil
+
+	var schedules []models.Schedule
+	err := query.Order("start_time").Find(&schedules).Error
+	return schedules, err
+}
+
+// GetSchedulesBySeries returns the sessions of a recurring series in start time order
+func (r *ScheduleRepository) GetSchedulesBySeries(seriesID uint) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.DB.Where("series_id = ?", seriesID).Order("start_time, id").Find(&schedules).Error
+	return schedules, err
+}
+
+// CreateSeries stores a new recurring series
+func (r *ScheduleRepository) CreateSeries(series *models.ScheduleSeries) error {
+	return r.DB.Create(series).Error
+}
+
+// GetSeriesByID returns a recurring series by ID
+func (r *ScheduleRepository) GetSeriesByID(id uint) (*models.ScheduleSeries, error) {
+	var series models.ScheduleSeries
+	if err := r.DB.First(&series, id).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// GetSeriesForUpdate returns a recurring series by ID, locking its row until
+// the surrounding transaction ends so changes to the whole series run in turn
+func (r *ScheduleRepository) GetSeriesForUpdate(id uint) (*models.ScheduleSeries, error) {
+	var series models.ScheduleSeries
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&series, id).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// UpdateSeries saves a recurring series
+func (r *ScheduleRepository) UpdateSeries(series *models.ScheduleSeries) error {
+	return r.DB.Save(series).Error
+}
+
+// GetConfirmedStartingBetween returns the confirmed sessions starting after from
+// and no later than to, in start time order
+func (r *ScheduleRepository) GetConfirmedStartingBetween(from, to time.Time) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.DB.Where("status = ? AND start_time > ? AND start_time <= ?", models.StatusConfirmed, from, to).
+		Order("start_time, id").Find(&schedules).Error
+	return schedules, err
+}
+
+// ClaimSettleableNoShows returns up to limit no-show sessions whose dispute
+// window closed by now without a dispute or a settlement, locking them until
+// the surrounding transaction ends. Sessions another transaction has locked
+// are skipped, so several servers can settle no-shows at once.
+func (r *ScheduleRepository) ClaimSettleableNoShows(now time.Time, limit int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND no_show_resolution = '' AND disputed_at IS NULL AND dispute_deadline <= ?", models.StatusNoShow, now).
+		Order("dispute_deadline, id").Limit(limit).Find(&schedules).Error
+	return schedules, err
+}
+
+// UpdateSchedule saves changes to a schedule
+func (r *ScheduleRepository) UpdateSchedule(schedule *models.Schedule) error {
+	return r.DB.Save(schedule).Error
+}
+
+// CreateScheduleEvent records a change to a schedule
+func (r *ScheduleRepository) CreateScheduleEvent(event *models.ScheduleEvent) error {
+	return r.DB.Create(event).Error
+}
+
+// GetScheduleEvents returns the changes to a schedule, oldest first
+func (r *ScheduleRepository) GetScheduleEvents(scheduleID uint) ([]models.ScheduleEvent, error) {
+	var events []models.ScheduleEvent
+	err := r.DB.Where("schedule_id = ?", scheduleID).Order("created_at, id").Find(&events).Error
+	return events, err
 }
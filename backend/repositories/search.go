@@ -0,0 +1,281 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// headlineOptions configures ts_headline so matched terms are wrapped in <mark> tags
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// fuzzyRankWeight scales trigram similarity so typo matches rank below exact ones
+const fuzzyRankWeight = 0.1
+
+// fallbackCandidateLimit caps the rows scored in Go when the database is not Postgres
+const fallbackCandidateLimit = 5000
+
+// snippetLength is the approximate length of snippets built outside Postgres
+const snippetLength = 200
+
+// UserSearchResult is a user matched by full-text search
+type UserSearchResult struct {
+	models.User
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SkillSearchResult is a skill matched by full-text search
+type SkillSearchResult struct {
+	models.Skill
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// JobSearchResult is a job posting matched by full-text search
+type JobSearchResult struct {
+	models.Job
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Suggestion is a ranked completion for a partially typed query
+type Suggestion struct {
+	ID    uint    `json:"id"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// CreatedAfter narrows a search to rows of the table created after t
+func CreatedAfter(table string, t time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(table+".created_at > ?", t)
+	}
+}
+
+// isPostgres reports whether the connection supports full-text search and pg_trgm
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// withFuzzyThreshold runs fn in a transaction whose pg_trgm thresholds match
+// utils.FuzzyThreshold, so the trigram operators can still use the GIN indexes.
+func withFuzzyThreshold(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		threshold := fmt.Sprintf("%.2f", utils.FuzzyThreshold)
+		if err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true), set_config('pg_trgm.word_similarity_threshold', ?, true)",
+			threshold, threshold).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// SearchAfter is a position in ranked search results, which are ordered by
+// rank descending and then ID. Rows after it rank lower, or rank the same with
+// a greater ID.
+type SearchAfter struct {
+	Rank float64
+	ID   uint
+}
+
+// SearchPage selects one page of ranked search results
+type SearchPage struct {
+	Limit int          // rows on the page; 0 returns every row
+	After *SearchAfter // when set, the page starts after it
+}
+
+// includes reports whether a row with the rank and ID comes after the page's position
+func (p SearchPage) includes(rank float64, id uint) bool {
+	return p.After == nil || rank < p.After.Rank || (rank == p.After.Rank && id > p.After.ID)
+}
+
+// SearchCounts is the number of rows matching a search, and how many of them
+// share each value of the counted columns
+type SearchCounts struct {
+	Total  int64
+	Values map[string]map[string]int64 // column name to value to count
+}
+
+// ColumnEquals narrows a search to rows of the table whose column matches value, ignoring case
+func ColumnEquals(table, column, value string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(fmt.Sprintf("lower(%s.%s) = lower(?)", table, column), value)
+	}
+}
+
+// matchSearch selects the rows of a table whose search_vector column matches the term.
+// Rows whose fuzzy column is a close trigram match also qualify, so typos still find results.
+func matchSearch(db *gorm.DB, table, fuzzyColumn, searchTerm string) *gorm.DB {
+	return db.Table(table).
+		Joins("CROSS JOIN websearch_to_tsquery('english', ?) AS query", searchTerm).
+		Where(fmt.Sprintf("%s.search_vector @@ query OR ? <%% %s.%s", table, table, fuzzyColumn), searchTerm)
+}
+
+// fullTextSearch builds one page of a ranked full-text search of a table,
+// narrowed by the scopes. Only the rows on the page are highlighted, with the
+// snippet taken from the snippet columns.
+func fullTextSearch(db *gorm.DB, table, fuzzyColumn string, snippetColumns []string, searchTerm string, page SearchPage, scopes ...func(*gorm.DB) *gorm.DB) *gorm.DB {
+	matches := matchSearch(db, table, fuzzyColumn, searchTerm).
+		Select(fmt.Sprintf("%s.*, ts_rank(%s.search_vector, query) + %g * word_similarity(?, %s.%s) AS rank",
+			table, table, fuzzyRankWeight, table, fuzzyColumn), searchTerm).
+		Scopes(scopes...)
+
+	ranked := db.Table("(?) AS ranked", matches)
+	if page.After != nil {
+		ranked = ranked.Where("ranked.rank < ? OR (ranked.rank = ? AND ranked.id > ?)", page.After.Rank, page.After.Rank, page.After.ID)
+	}
+	ranked = ranked.Order("ranked.rank DESC").Order("ranked.id")
+	if page.Limit > 0 {
+		ranked = ranked.Limit(page.Limit)
+	}
+
+	snippet := make([]string, len(snippetColumns))
+	for i, column := range snippetColumns {
+		snippet[i] = fmt.Sprintf("coalesce(page.%s, '')", column)
+	}
+	return db.Table("(?) AS page", ranked).
+		Select(fmt.Sprintf("page.*, ts_headline('english', %s, websearch_to_tsquery('english', ?), '%s') AS snippet",
+			strings.Join(snippet, " || ' ' || "), headlineOptions), searchTerm).
+		Order("page.rank DESC").
+		Order("page.id")
+}
+
+// countSearch counts the rows of a table matching a full-text search, in
+// total and by each value of the facet columns. Scopes narrow the rows counted.
+func countSearch(db *gorm.DB, table, fuzzyColumn, searchTerm string, facetColumns []string, scopes ...func(*gorm.DB) *gorm.DB) (SearchCounts, error) {
+	counts := SearchCounts{Values: make(map[string]map[string]int64)}
+	err := withFuzzyThreshold(db, func(tx *gorm.DB) error {
+		if err := matchSearch(tx, table, fuzzyColumn, searchTerm).Scopes(scopes...).Count(&counts.Total).Error; err != nil {
+			return err
+		}
+
+		for _, column := range facetColumns {
+			var rows []struct {
+				Value string
+				Count int64
+			}
+			err := matchSearch(tx, table, fuzzyColumn, searchTerm).
+				Scopes(scopes...).
+				Select(fmt.Sprintf("coalesce(%s.%s, '') AS value, COUNT(*) AS count", table, column)).
+				Group(fmt.Sprintf("%s.%s", table, column)).
+				Scan(&rows).Error
+			if err != nil {
+				return err
+			}
+
+			values := make(map[string]int64, len(rows))
+			for _, row := range rows {
+				values[row.Value] += row.Count
+			}
+			counts.Values[column] = values
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// fallbackPage orders n rows scored in Go as fullTextSearch orders them and
+// returns the indexes of the rows on the page. key returns the rank and ID of
+// the row at an index.
+func fallbackPage(n int, key func(i int) (float64, uint), page SearchPage) []int {
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if page.includes(key(i)) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	sort.Slice(indexes, func(a, b int) bool {
+		rankA, idA := key(indexes[a])
+		rankB, idB := key(indexes[b])
+		if rankA != rankB {
+			return rankA > rankB
+		}
+		return idA < idB
+	})
+	if page.Limit > 0 && len(indexes) > page.Limit {
+		indexes = indexes[:page.Limit]
+	}
+	return indexes
+}
+
+// fuzzyRank scores fields against each term of the query without database support.
+// Earlier fields weigh more, and the result is 0 when no term matches any field.
+func fuzzyRank(searchTerm string, fields ...string) float64 {
+	terms := utils.SearchTerms(searchTerm)
+	if len(terms) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for i, field := range fields {
+			if score := utils.FuzzyScore(term, field) / float64(i+1); score > best {
+				best = score
+			}
+		}
+		total += best
+	}
+	return total / float64(len(terms))
+}
+
+// suggest returns completions for a prefix from one text column of a table.
+// Prefix matches on the whole value rank first, then prefix matches on a later
+// word, then close trigram matches that tolerate typos. Scopes narrow the rows.
+func suggest(db *gorm.DB, table, column, prefix string, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Suggestion, error) {
+	var suggestions []Suggestion
+
+	if !isPostgres(db) {
+		var rows []struct {
+			ID   uint
+			Text string
+		}
+		if err := db.Table(table).Scopes(scopes...).Select(fmt.Sprintf("id, %s AS text", column)).
+			Limit(fallbackCandidateLimit).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if score := utils.SuggestionScore(prefix, row.Text); score > 0 {
+				suggestions = append(suggestions, Suggestion{ID: row.ID, Text: row.Text, Score: score})
+			}
+		}
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return suggestions[i].Score > suggestions[j].Score
+		})
+		if len(suggestions) > limit {
+			suggestions = suggestions[:limit]
+		}
+		return suggestions, nil
+	}
+
+	escaped := escapeLike(prefix)
+	err := withFuzzyThreshold(db, func(tx *gorm.DB) error {
+		return tx.Table(table).
+			Scopes(scopes...).
+			Select(fmt.Sprintf(`id, %[1]s AS text,
+				(CASE WHEN %[1]s ILIKE ? THEN 2 WHEN %[1]s ILIKE ? THEN 1 ELSE 0 END) + similarity(%[1]s, ?) AS score`, column),
+				escaped+"%", "% "+escaped+"%", prefix).
+			Where(fmt.Sprintf("%[1]s ILIKE ? OR %[1]s ILIKE ? OR ? <%% %[1]s", column),
+				escaped+"%", "% "+escaped+"%", prefix).
+			Order("score DESC").
+			Order(fmt.Sprintf("length(%s)", column)).
+			Limit(limit).
+			Scan(&suggestions).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
 	"gorm.io/gorm"
 )
 
@@ -34,22 +35,92 @@ func (r *SkillRepository) GetAllSkills() ([]models.Skill, error) {
 	return skills, nil
 }
 
-// SearchSkills searches for skills by name or description containing the search term
-func (r *SkillRepository) SearchSkills(searchTerm string) ([]models.Skill, error) {
-	var skills []models.Skill
+// GetSkillByID returns a skill by ID
+func (r *SkillRepository) GetSkillByID(id uint) (*models.Skill, error) {
+	var skill models.Skill
+	if err := r.DB.First(&skill, id).Error; err != nil {
+		return nil, err
+	}
+	return &skill, nil
+}
 
-	// Use ILIKE for case-insensitive search in PostgreSQL
-	// Or you can use LOWER() function with LIKE for more database compatibility
-	err := r.DB.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?",
-		"%"+searchTerm+"%", "%"+searchTerm+"%").Find(&skills).Error
+// UpdateSkill saves changes to an existing skill
+func (r *SkillRepository) UpdateSkill(skill *models.Skill) error {
+	return r.DB.Save(skill).Error
+}
+
+// skillCountColumns are the columns CountSkills counts matches by
+var skillCountColumns = []string{"category"}
+
+// SearchSkills returns one page of a ranked full-text search over skill names
+// and descriptions. Close trigram matches on the name are included so typos
+// still find skills. Scopes such as CreatedAfter narrow the rows searched.
+func (r *SkillRepository) SearchSkills(searchTerm string, page SearchPage, scopes ...func(*gorm.DB) *gorm.DB) ([]SkillSearchResult, error) {
+	var skills []SkillSearchResult
+
+	if !isPostgres(r.DB) {
+		ranked, err := r.rankSkills(searchTerm, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		key := func(i int) (float64, uint) { return ranked[i].Rank, ranked[i].ID }
+		for _, i := range fallbackPage(len(ranked), key, page) {
+			skill := ranked[i]
+			skill.Snippet = utils.Highlight(skill.Name+" "+skill.Description, utils.SearchTerms(searchTerm), snippetLength)
+			skills = append(skills, skill)
+		}
+		return skills, nil
+	}
 
+	err := withFuzzyThreshold(r.DB, func(tx *gorm.DB) error {
+		return fullTextSearch(tx, "skills", "name", []string{"name", "description"}, searchTerm, page, scopes...).
+			Scan(&skills).Error
+	})
 	if err != nil {
 		return nil, err
 	}
+	return skills, nil
+}
 
+// CountSkills counts the skills SearchSkills matches, in total and by category
+func (r *SkillRepository) CountSkills(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) (SearchCounts, error) {
+	if isPostgres(r.DB) {
+		return countSearch(r.DB, "skills", "name", searchTerm, skillCountColumns, scopes...)
+	}
+
+	ranked, err := r.rankSkills(searchTerm, scopes...)
+	if err != nil {
+		return SearchCounts{}, err
+	}
+	counts := SearchCounts{Total: int64(len(ranked)), Values: map[string]map[string]int64{"category": {}}}
+	for _, skill := range ranked {
+		counts.Values["category"][skill.Category]++
+	}
+	return counts, nil
+}
+
+// rankSkills scores skills against a search in Go, for databases without
+// full-text search. Skills that don't match are left out.
+func (r *SkillRepository) rankSkills(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) ([]SkillSearchResult, error) {
+	var candidates []models.Skill
+	if err := r.DB.Scopes(scopes...).Limit(fallbackCandidateLimit).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var skills []SkillSearchResult
+	for _, skill := range candidates {
+		if rank := fuzzyRank(searchTerm, skill.Name, skill.Description); rank > 0 {
+			skills = append(skills, SkillSearchResult{Skill: skill, Rank: rank})
+		}
+	}
 	return skills, nil
 }
 
+// SuggestSkills returns skill names completing the given prefix
+func (r *SkillRepository) SuggestSkills(prefix string, limit int) ([]Suggestion, error) {
+	return suggest(r.DB, "skills", "name", prefix, limit)
+}
+
 // For backward compatibility with existing code, provide these as standalone functions
 
 func InsertSkill(skill *models.Skill) (*models.Skill, error) {
@@ -108,3 +179,20 @@ func SearchSkills(searchTerm string) ([]models.Skill, error) {
 
 	return results, nil
 }
+
+// GetSkillsByIDs returns the skills with the given IDs, keyed by ID
+func (r *SkillRepository) GetSkillsByIDs(ids []uint) (map[uint]*models.Skill, error) {
+	skills := map[uint]*models.Skill{}
+	if len(ids) == 0 {
+		return skills, nil
+	}
+
+	var found []models.Skill
+	if err := r.DB.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		skills[found[i].ID] = &found[i]
+	}
+	return skills, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+)
+
+// Directions of a user's transactions
+const (
+	TransactionsSent     = "sent"
+	TransactionsReceived = "received"
+)
+
+// TransactionFilter narrows a user's transaction feed. Zero fields don't filter.
+type TransactionFilter struct {
+	Direction      string    // TransactionsSent or TransactionsReceived
+	From           time.Time // made at or after From
+	To             time.Time // made before To
+	CounterpartyID uint      // the other participant
+	MinAmount      int
+	MaxAmount      int
+	Note           string // case-insensitive text in the note
+}
+
+// TransactionCursor is the last transaction of a feed page; the next page starts after it
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// TransactionFeedItem is a transaction as shown in a user's feed: with its
+// participants' names, which side of it the user is on, and their balance
+// right after it. Names are empty for users that no longer exist.
+type TransactionFeedItem struct {
+	models.Transaction
+	SenderName   string `json:"senderName"`
+	ReceiverName string `json:"receiverName"`
+	IsSender     bool   `json:"isSender"`
+	IsReceiver   bool   `json:"isReceiver"`
+	Balance      *int   `json:"balance,omitempty"` // unknown for transactions from before the ledger
+}
+
+// TransactionFeedRepository reads users' transaction feeds
+type TransactionFeedRepository struct {
+	DB *gorm.DB
+}
+
+// NewTransactionFeedRepository creates a new instance of TransactionFeedRepository
+func NewTransactionFeedRepository(db *gorm.DB) *TransactionFeedRepository {
+	return &TransactionFeedRepository{DB: db}
+}
+
+// GetFeed returns up to limit of a user's transactions matching the filter,
+// newest first, starting after the cursor when there is one. Everything a
+// feed item shows is read in this one query, however many users the
+// transactions involve. Balances are summed from the user's ledger entries up
+// to each transaction's journal, so they include escrow movements between transactions.
+func (r *TransactionFeedRepository) GetFeed(userID uint, filter TransactionFilter, after *TransactionCursor, limit int) ([]TransactionFeedItem, error) {
+	query := r.DB.Table("transactions").
+		Select(`transactions.*,
+			COALESCE(senders.name, '') AS sender_name, COALESCE(receivers.name, '') AS receiver_name,
+			transactions.sender_id = ? AS is_sender, transactions.receiver_id = ? AS is_receiver,
+			(SELECT SUM(ledger_entries.amount) FROM ledger_entries
+				JOIN ledger_accounts ON ledger_accounts.id = ledger_entries.account_id
+				WHERE ledger_accounts.kind = ? AND ledger_accounts.user_id = ? AND ledger_entries.journal_id <= ledger_journals.id) AS balance`,
+			userID, userID, models.LedgerUser, userID).
+		Joins("LEFT JOIN users AS senders ON senders.id = transactions.sender_id").
+		Joins("LEFT JOIN users AS receivers ON receivers.id = transactions.receiver_id").
+		Joins("LEFT JOIN ledger_journals ON ledger_journals.transaction_id = transactions.id")
+
+	switch filter.Direction {
+	case TransactionsSent:
+		query = query.Where("transactions.sender_id = ?", userID)
+	case TransactionsReceived:
+		query = query.Where("transactions.receiver_id = ?", userID)
+	default:
+		query = query.Where("transactions.sender_id = ? OR transactions.receiver_id = ?", userID, userID)
+	}
+	if filter.CounterpartyID != 0 {
+		query = query.Where("(transactions.sender_id = ? AND transactions.receiver_id = ?) OR (transactions.sender_id = ? AND transactions.receiver_id = ?)",
+			userID, filter.CounterpartyID, filter.CounterpartyID, userID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("transactions.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("transactions.created_at < ?", filter.To)
+	}
+	if filter.MinAmount > 0 {
+		query = query.Where("transactions.amount >= ?", filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query = query.Where("transactions.amount <= ?", filter.MaxAmount)
+	}
+	if filter.Note != "" {
+		query = query.Where("transactions.note ILIKE ?", "%"+escapeLike(filter.Note)+"%")
+	}
+	if after != nil {
+		query = query.Where("(transactions.created_at, transactions.id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	var items []TransactionFeedItem
+	err := query.Order("transactions.created_at DESC, transactions.id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
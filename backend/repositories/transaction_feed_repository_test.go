@@ -0,0 +1,185 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+func TestTransactionFeed(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repositories.NewUserRepository(db)
+	txRepo := repositories.NewTransactionRepository(db)
+	feedRepo := repositories.NewTransactionFeedRepository(db)
+
+	alice := models.User{Name: "Alice", Email: "history-alice@example.com", Password: "password123"}
+	bob := models.User{Name: "Bob", Email: "history-bob@example.com", Password: "password123"}
+	carol := models.User{Name: "Carol", Email: "history-carol@example.com", Password: "password123"}
+	for _, user := range []*models.User{&alice, &bob, &carol} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	transfers := []models.Transaction{
+		{SenderID: alice.ID, ReceiverID: bob.ID, Amount: 10, Note: "Guitar lesson"},
+		{SenderID: bob.ID, ReceiverID: alice.ID, Amount: 25, Note: "Cooking 100% class"},
+		{SenderID: alice.ID, ReceiverID: carol.ID, Amount: 40, Note: "Guitar strings"},
+		{SenderID: carol.ID, ReceiverID: alice.ID, Amount: 5},
+	}
+	for i := range transfers {
+		if err := txRepo.CreateTransaction(&transfers[i]); err != nil {
+			t.Fatalf("CreateTransaction failed: %v", err)
+		}
+		// CreateTransaction stamps the current time, so spread them out afterwards
+		createdAt := start.Add(time.Duration(i) * time.Minute)
+		if err := db.Model(&transfers[i]).UpdateColumn("created_at", createdAt).Error; err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+		transfers[i].CreatedAt = createdAt
+	}
+
+	ids := func(history []repositories.TransactionFeedItem) []uint {
+		var ids []uint
+		for _, tx := range history {
+			ids = append(ids, tx.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name   string
+		filter repositories.TransactionFilter
+		want   []models.Transaction
+	}{
+		{"all", repositories.TransactionFilter{}, []models.Transaction{transfers[3], transfers[2], transfers[1], transfers[0]}},
+		{"sent", repositories.TransactionFilter{Direction: repositories.TransactionsSent}, []models.Transaction{transfers[2], transfers[0]}},
+		{"received", repositories.TransactionFilter{Direction: repositories.TransactionsReceived}, []models.Transaction{transfers[3], transfers[1]}},
+		{"counterparty", repositories.TransactionFilter{CounterpartyID: carol.ID}, []models.Transaction{transfers[3], transfers[2]}},
+		{"dates", repositories.TransactionFilter{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)}, []models.Transaction{transfers[2], transfers[1]}},
+		{"amounts", repositories.TransactionFilter{MinAmount: 10, MaxAmount: 25}, []models.Transaction{transfers[1], transfers[0]}},
+		{"note", repositories.TransactionFilter{Note: "guitar"}, []models.Transaction{transfers[2], transfers[0]}},
+		{"note wildcard", repositories.TransactionFilter{Note: "100%"}, []models.Transaction{transfers[1]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := feedRepo.GetFeed(alice.ID, tt.filter, nil, 10)
+			if err != nil {
+				t.Fatalf("GetFeed failed: %v", err)
+			}
+			got := ids(history)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d transactions, got %v", len(tt.want), got)
+			}
+			for i, tx := range tt.want {
+				if got[i] != tx.ID {
+					t.Errorf("Expected transactions %v in order, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+
+	// Pages continue after the cursor, with Alice's balance after each transaction
+	first, err := feedRepo.GetFeed(alice.ID, repositories.TransactionFilter{}, nil, 2)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Expected a first page of 2, got %v (%v)", ids(first), err)
+	}
+	last := first[len(first)-1]
+	second, err := feedRepo.GetFeed(alice.ID, repositories.TransactionFilter{}, &repositories.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}, 2)
+	if err != nil || len(second) != 2 || second[0].ID != transfers[1].ID {
+		t.Fatalf("Expected the second page to start with %d, got %v (%v)", transfers[1].ID, ids(second), err)
+	}
+	balances := []int{80, 75, 115, 90}
+	for i, item := range append(first, second...) {
+		if item.Balance == nil || *item.Balance != balances[i] {
+			t.Errorf("Expected a balance of %d after transaction %d, got %v", balances[i], item.ID, item.Balance)
+		}
+	}
+
+	// Names are read with the transactions, and the viewer's side is flagged rather than named
+	sent, received := first[1], first[0]
+	if sent.SenderName != "Alice" || sent.ReceiverName != "Carol" || !sent.IsSender || sent.IsReceiver {
+		t.Errorf("Expected Alice's transfer to Carol to be flagged as sent, got %+v", sent)
+	}
+	if received.SenderName != "Carol" || received.ReceiverName != "Alice" || received.IsSender || !received.IsReceiver {
+		t.Errorf("Expected Carol's transfer to Alice to be flagged as received, got %+v", received)
+	}
+}
+
+func TestTransactionFeedQueryCount(t *testing.T) {
+	db := openTestDB(t)
+	user, counterparties := createFeedTransactions(t, db, 30)
+	feedRepo := repositories.NewTransactionFeedRepository(countQueries(db))
+
+	for _, limit := range []int{1, 10, len(counterparties)} {
+		queries = 0
+		items, err := feedRepo.GetFeed(user.ID, repositories.TransactionFilter{}, nil, limit)
+		if err != nil || len(items) != limit {
+			t.Fatalf("Expected %d feed items, got %d (%v)", limit, len(items), err)
+		}
+		if queries != 1 {
+			t.Errorf("Expected one query for a page of %d with %d counterparties, got %d", limit, limit, queries)
+		}
+	}
+}
+
+// BenchmarkTransactionFeed reads feeds of transactions with more and more
+// counterparties; queries/op stays at 1
+func BenchmarkTransactionFeed(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("counterparties=%d", n), func(b *testing.B) {
+			db := openTestDB(b)
+			user, _ := createFeedTransactions(b, db, n)
+			feedRepo := repositories.NewTransactionFeedRepository(countQueries(db))
+
+			queries = 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := feedRepo.GetFeed(user.ID, repositories.TransactionFilter{}, nil, n); err != nil {
+					b.Fatalf("GetFeed failed: %v", err)
+				}
+			}
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// queries counts the queries run through a database returned by countQueries
+var queries int
+
+// countQueries returns a session of db whose queries are counted in queries
+func countQueries(db *gorm.DB) *gorm.DB {
+	counted := db.Session(&gorm.Session{NewDB: true})
+	counted.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) { queries++ })
+	return counted
+}
+
+// createFeedTransactions creates a user who received a transaction from each
+// of n other users, for feeds naming n counterparties
+func createFeedTransactions(t testing.TB, db *gorm.DB, n int) (models.User, []models.User) {
+	t.Helper()
+
+	userRepo := repositories.NewUserRepository(db)
+	txRepo := repositories.NewTransactionRepository(db)
+	user := models.User{Name: "Feed Reader", Email: "feed-reader@example.com", Password: "password123"}
+	if err := userRepo.CreateUser(&user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	counterparties := make([]models.User, n)
+	for i := range counterparties {
+		counterparties[i] = models.User{Name: fmt.Sprintf("Counterparty %d", i), Email: fmt.Sprintf("feed-counterparty-%d@example.com", i), Password: "password123"}
+		if err := userRepo.CreateUser(&counterparties[i]); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		transfer := models.Transaction{SenderID: counterparties[i].ID, ReceiverID: user.ID, Amount: 1}
+		if err := txRepo.CreateTransaction(&transfer); err != nil {
+			t.Fatalf("CreateTransaction failed: %v", err)
+		}
+	}
+	return user, counterparties
+}
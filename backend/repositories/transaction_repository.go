@@ -6,8 +6,12 @@ import (
 
 	"github.com/mplaczek99/SkillSwap/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrInsufficientPoints is returned when the sender cannot cover a transaction
+var ErrInsufficientPoints = errors.New("insufficient skill points")
+
 // TransactionRepository handles database operations for transactions
 type TransactionRepository struct {
 	DB *gorm.DB
@@ -27,35 +31,28 @@ func (r *TransactionRepository) GetTransactionsByUserID(userID uint) ([]models.T
 	return transactions, err
 }
 
-// CreateTransaction creates a new transaction and updates both users' balances
+// CreateTransaction creates a new transaction and posts it to the ledger,
+// moving the points from the sender's balance to the receiver's. Balances are
+// never read and written back here: the posting changes them with
+// conditional updates, so concurrent transfers from the same sender can't
+// overspend and none are lost.
 func (r *TransactionRepository) CreateTransaction(tx *models.Transaction) error {
 	// Use a database transaction to ensure data consistency
 	return r.DB.Transaction(func(dbTx *gorm.DB) error {
-		// Find sender
-		var sender models.User
-		if err := dbTx.Where("id = ?", tx.SenderID).First(&sender).Error; err != nil {
-			return errors.New("sender not found")
-		}
-
-		// Find receiver
-		var receiver models.User
-		if err := dbTx.Where("id = ?", tx.ReceiverID).First(&receiver).Error; err != nil {
-			return errors.New("receiver not found")
+		// Check both users exist
+		var users []models.User
+		if err := dbTx.Select("id").Where("id IN ?", []uint{tx.SenderID, tx.ReceiverID}).Find(&users).Error; err != nil {
+			return err
 		}
-
-		// Check if sender has enough points
-		if sender.SkillPoints < tx.Amount {
-			return errors.New("insufficient skill points")
+		found := make(map[uint]bool, len(users))
+		for _, user := range users {
+			found[user.ID] = true
 		}
-
-		// Update sender's balance
-		if err := dbTx.Model(&sender).Update("skill_points", sender.SkillPoints-tx.Amount).Error; err != nil {
-			return err
+		if !found[tx.SenderID] {
+			return errors.New("sender not found")
 		}
-
-		// Update receiver's balance
-		if err := dbTx.Model(&receiver).Update("skill_points", receiver.SkillPoints+tx.Amount).Error; err != nil {
-			return err
+		if !found[tx.ReceiverID] {
+			return errors.New("receiver not found")
 		}
 
 		// Set creation timestamp
@@ -67,10 +64,50 @@ func (r *TransactionRepository) CreateTransaction(tx *models.Transaction) error
 			return err
 		}
 
-		return nil
+		// Move the points; fails with ErrInsufficientPoints if the sender can't cover them
+		journal := models.LedgerJournal{Kind: models.JournalTransfer, Note: tx.Note, TransactionID: &tx.ID, CreatedAt: tx.CreatedAt}
+		if tx.IsRefund() {
+			journal.Kind = models.JournalRefund
+		}
+		return NewLedgerRepository(dbTx).Post(&journal, UserPosting(tx.SenderID, -tx.Amount), UserPosting(tx.ReceiverID, tx.Amount))
 	})
 }
 
+// GetTransactionByID returns a transaction
+func (r *TransactionRepository) GetTransactionByID(id uint) (*models.Transaction, error) {
+	var tx models.Transaction
+	if err := r.DB.First(&tx, id).Error; err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// GetTransactionForUpdate returns a transaction, locking its row until the
+// surrounding transaction ends so refunds and disputes of it run in turn.
+// The row itself is never changed.
+func (r *TransactionRepository) GetTransactionForUpdate(id uint) (*models.Transaction, error) {
+	var tx models.Transaction
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tx, id).Error; err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// GetRefunds returns the refunds of a transaction, oldest first
+func (r *TransactionRepository) GetRefunds(id uint) ([]models.Transaction, error) {
+	var refunds []models.Transaction
+	err := r.DB.Where("refund_of = ?", id).Order("created_at, id").Find(&refunds).Error
+	return refunds, err
+}
+
+// RefundedAmount returns how many of a transaction's points were refunded
+func (r *TransactionRepository) RefundedAmount(id uint) (int, error) {
+	var refunded int
+	err := r.DB.Model(&models.Transaction{}).Where("refund_of = ?", id).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error
+	return refunded, err
+}
+
 // For backward compatibility with existing code
 func InsertTransaction(tx *models.Transaction) (*models.Transaction, error) {
 	// In a real implementation, you would use the repository pattern
@@ -1,11 +1,16 @@
 package repositories_test
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mplaczek99/SkillSwap/models"
 	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
 )
 
 func TestInsertTransaction(t *testing.T) {
@@ -162,3 +167,160 @@ func TestTransactionQueries(t *testing.T) {
 		}
 	})
 }
+
+func TestConcurrentTransfersConserveBalances(t *testing.T) {
+	db := connectTestDB(t)
+	users := createTransferUsers(t, db, 4)
+	repo := repositories.NewTransactionRepository(db)
+
+	// Workers send random amounts between the users in both directions at
+	// once, so some run out of points and pairs lock each other's rows
+	const workers, transfers = 8, 25
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed := 0
+	errs := make(chan error, workers*transfers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(seed))
+			for i := 0; i < transfers; i++ {
+				from := random.Intn(len(users))
+				to := (from + 1 + random.Intn(len(users)-1)) % len(users)
+				tx := models.Transaction{SenderID: users[from].ID, ReceiverID: users[to].ID, Amount: 1 + random.Intn(40)}
+				err := repo.CreateTransaction(&tx)
+				switch {
+				case err == nil:
+					mu.Lock()
+					completed++
+					mu.Unlock()
+				case !errors.Is(err, repositories.ErrInsufficientPoints):
+					errs <- err
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Transfer failed: %v", err)
+	}
+
+	ids := userIDs(users)
+	var reloaded []models.User
+	if err := db.Where("id IN ?", ids).Find(&reloaded).Error; err != nil {
+		t.Fatalf("Failed to reload users: %v", err)
+	}
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	total := 0
+	for _, user := range reloaded {
+		if user.SkillPoints < 0 {
+			t.Errorf("User %d overspent to %d", user.ID, user.SkillPoints)
+		}
+		balance, err := ledgerRepo.Balance(models.LedgerUser, user.ID)
+		if err != nil || balance != user.SkillPoints {
+			t.Errorf("User %d has %d skill_points but %d in the ledger (%v)", user.ID, user.SkillPoints, balance, err)
+		}
+		total += user.SkillPoints
+	}
+	if total != len(users)*models.SignupGrant {
+		t.Errorf("Expected %d points in total, got %d", len(users)*models.SignupGrant, total)
+	}
+
+	var recorded int64
+	if err := db.Model(&models.Transaction{}).Where("sender_id IN ?", ids).Count(&recorded).Error; err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if int(recorded) != completed {
+		t.Errorf("Expected %d recorded transactions, got %d", completed, recorded)
+	}
+}
+
+func TestConcurrentTransfersCannotOverspend(t *testing.T) {
+	db := connectTestDB(t)
+	users := createTransferUsers(t, db, 2)
+	sender, receiver := users[0], users[1]
+	repo := repositories.NewTransactionRepository(db)
+
+	// Twice as many transfers as the sender can cover, all at once
+	const attempts, amount = 20, models.SignupGrant / 10
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: amount}
+			results <- repo.CreateTransaction(&tx)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	completed := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			completed++
+		case !errors.Is(err, repositories.ErrInsufficientPoints):
+			t.Errorf("Transfer failed: %v", err)
+		}
+	}
+	if completed != 10 {
+		t.Errorf("Expected exactly 10 transfers to go through, got %d", completed)
+	}
+
+	var reloaded []models.User
+	if err := db.Where("id IN ?", userIDs(users)).Order("id").Find(&reloaded).Error; err != nil {
+		t.Fatalf("Failed to reload users: %v", err)
+	}
+	if reloaded[0].SkillPoints != 0 || reloaded[1].SkillPoints != 2*models.SignupGrant {
+		t.Errorf("Expected balances 0 and %d, got %d and %d", 2*models.SignupGrant, reloaded[0].SkillPoints, reloaded[1].SkillPoints)
+	}
+}
+
+// createTransferUsers creates n users with their signup grant, committed so
+// concurrent transactions see them, and deletes them and everything posted
+// for them when the test ends
+func createTransferUsers(t *testing.T, db *gorm.DB, n int) []models.User {
+	t.Helper()
+
+	users := make([]models.User, n)
+	for i := range users {
+		users[i] = models.User{
+			Name:     fmt.Sprintf("Transfer User %d", i),
+			Email:    fmt.Sprintf("transfer-%d-%d@example.com", time.Now().UnixNano(), i),
+			Password: "password123",
+		}
+		if err := repositories.NewUserRepository(db).CreateUser(&users[i]); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		// The ledger is append-only through the models, so clean up with plain SQL
+		ids := userIDs(users)
+		var journals []uint
+		db.Table("ledger_entries").
+			Joins("JOIN ledger_accounts ON ledger_accounts.id = ledger_entries.account_id").
+			Where("ledger_accounts.kind = ? AND ledger_accounts.user_id IN ?", models.LedgerUser, ids).
+			Distinct().Pluck("ledger_entries.journal_id", &journals)
+		if len(journals) > 0 {
+			db.Exec("DELETE FROM ledger_entries WHERE journal_id IN ?", journals)
+			db.Exec("DELETE FROM ledger_journals WHERE id IN ?", journals)
+		}
+		db.Exec("DELETE FROM ledger_accounts WHERE kind = ? AND user_id IN ?", models.LedgerUser, ids)
+		db.Exec("DELETE FROM transactions WHERE sender_id IN ? OR receiver_id IN ?", ids, ids)
+		db.Exec("DELETE FROM users WHERE id IN ?", ids)
+	})
+	return users
+}
+
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
 	"gorm.io/gorm"
 )
 
@@ -13,8 +14,22 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// CreateUser stores a new user and posts their signup grant from the mint,
+// which is all the SkillPoints they start with
 func (r *UserRepository) CreateUser(user *models.User) error {
-	return r.DB.Create(user).Error
+	return r.DB.Transaction(func(dbTx *gorm.DB) error {
+		user.SkillPoints = 0
+		if err := dbTx.Create(user).Error; err != nil {
+			return err
+		}
+		journal := models.LedgerJournal{Kind: models.JournalSignupGrant}
+		err := NewLedgerRepository(dbTx).Post(&journal, MintPosting(-models.SignupGrant), UserPosting(user.ID, models.SignupGrant))
+		if err != nil {
+			return err
+		}
+		user.SkillPoints = models.SignupGrant
+		return nil
+	})
 }
 
 func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
@@ -26,22 +41,83 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-// SearchUsers searches for users by name or email containing the search term
-func (r *UserRepository) SearchUsers(searchTerm string) ([]models.User, error) {
-	var users []models.User
+// SearchUsers returns one page of a ranked full-text search over the names and
+// bios of searchable users. Close trigram matches on the name are included so
+// typos still find people. Scopes such as CreatedAfter narrow the rows searched.
+func (r *UserRepository) SearchUsers(searchTerm string, page SearchPage, scopes ...func(*gorm.DB) *gorm.DB) ([]UserSearchResult, error) {
+	var users []UserSearchResult
 
-	// Use ILIKE for case-insensitive search in PostgreSQL
-	// Or you can use LOWER() function with LIKE for more database compatibility
-	err := r.DB.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?",
-		"%"+searchTerm+"%", "%"+searchTerm+"%").Find(&users).Error
+	if !isPostgres(r.DB) {
+		ranked, err := r.rankUsers(searchTerm, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		key := func(i int) (float64, uint) { return ranked[i].Rank, ranked[i].ID }
+		for _, i := range fallbackPage(len(ranked), key, page) {
+			user := ranked[i]
+			user.Snippet = utils.Highlight(user.Name+" "+user.Bio, utils.SearchTerms(searchTerm), snippetLength)
+			users = append(users, user)
+		}
+		return users, nil
+	}
 
+	err := withFuzzyThreshold(r.DB, func(tx *gorm.DB) error {
+		return fullTextSearch(tx, "users", "name", []string{"name", "bio"}, searchTerm, page, append(scopes, searchableUsers)...).
+			Scan(&users).Error
+	})
 	if err != nil {
 		return nil, err
 	}
+	return users, nil
+}
+
+// CountUsers counts the searchable users SearchUsers matches
+func (r *UserRepository) CountUsers(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) (SearchCounts, error) {
+	if !isPostgres(r.DB) {
+		ranked, err := r.rankUsers(searchTerm, scopes...)
+		return SearchCounts{Total: int64(len(ranked))}, err
+	}
+	return countSearch(r.DB, "users", "name", searchTerm, nil, append(scopes, searchableUsers)...)
+}
 
+// rankUsers scores searchable users against a search in Go, for databases
+// without full-text search. Users that don't match are left out.
+func (r *UserRepository) rankUsers(searchTerm string, scopes ...func(*gorm.DB) *gorm.DB) ([]UserSearchResult, error) {
+	var candidates []models.User
+	if err := r.DB.Scopes(append(scopes, searchableUsers)...).Limit(fallbackCandidateLimit).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var users []UserSearchResult
+	for _, user := range candidates {
+		if rank := fuzzyRank(searchTerm, user.Name, user.Bio); rank > 0 {
+			users = append(users, UserSearchResult{User: user, Rank: rank})
+		}
+	}
 	return users, nil
 }
 
+// SuggestUsers returns the names of searchable users completing the given prefix
+func (r *UserRepository) SuggestUsers(prefix string, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Suggestion, error) {
+	return suggest(r.DB, "users", "name", prefix, limit, append(scopes, searchableUsers)...)
+}
+
+// UpdatePrivacy saves a user's privacy settings
+func (r *UserRepository) UpdatePrivacy(user *models.User) error {
+	return r.DB.Model(user).Select("searchable", "show_email", "profile_visibility").Updates(user).Error
+}
+
+// searchableUsers limits a query to users who allow being found by search
+func searchableUsers(db *gorm.DB) *gorm.DB {
+	return db.Where("users.searchable = ?", true)
+}
+
+// PublicProfilesOnly limits a user query to profiles anyone may view,
+// for searches by people who are not logged in
+func PublicProfilesOnly(db *gorm.DB) *gorm.DB {
+	return db.Where("users.profile_visibility = ?", models.ProfilePublic)
+}
+
 // GetUserByID gets a user by their ID
 func (r *UserRepository) GetUserByID(id uint) (*models.User, error) {
 	var user models.User
@@ -51,3 +127,55 @@ func (r *UserRepository) GetUserByID(id uint) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// GetUsersByIDs returns the users with the given IDs, keyed by ID
+func (r *UserRepository) GetUsersByIDs(ids []uint) (map[uint]*models.User, error) {
+	users := map[uint]*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var found []models.User
+	if err := r.DB.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		users[found[i].ID] = &found[i]
+	}
+	return users, nil
+}
+
+// GetUserByCalendarToken gets the user whose calendar feed uses a token
+func (r *UserRepository) GetUserByCalendarToken(token string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Where("calendar_token = ? AND calendar_token <> ''", token).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateCalendarToken saves a user's calendar feed token
+func (r *UserRepository) UpdateCalendarToken(user *models.User) error {
+	return r.DB.Model(user).Update("calendar_token", user.CalendarToken).Error
+}
+
+// UpdateCalDAVPassword saves the hash of a user's CalDAV app password
+func (r *UserRepository) UpdateCalDAVPassword(user *models.User) error {
+	return r.DB.Model(user).Update("caldav_password_hash", user.CalDAVPasswordHash).Error
+}
+
+// UpdateTimeZone saves a user's time zone
+func (r *UserRepository) UpdateTimeZone(user *models.User) error {
+	return r.DB.Model(user).Update("time_zone", user.TimeZone).Error
+}
+
+// UpdateReminderSettings saves how a user is reminded of sessions
+func (r *UserRepository) UpdateReminderSettings(user *models.User) error {
+	return r.DB.Model(user).Select("remind_in_app", "remind_by_email").Updates(user).Error
+}
+
+// UpdateCancellationPolicy saves a user's cancellation policy as a teacher
+func (r *UserRepository) UpdateCancellationPolicy(user *models.User) error {
+	return r.DB.Model(user).Select("free_cancellation_hours", "late_cancellation_percent").Updates(user).Error
+}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WorkshopRepository handles database operations for workshops and their seats
+type WorkshopRepository struct {
+	DB *gorm.DB
+}
+
+// NewWorkshopRepository creates a new instance of WorkshopRepository
+func NewWorkshopRepository(db *gorm.DB) *WorkshopRepository {
+	return &WorkshopRepository{DB: db}
+}
+
+// CreateWorkshop stores a new workshop
+func (r *WorkshopRepository) CreateWorkshop(workshop *models.Workshop) error {
+	return r.DB.Create(workshop).Error
+}
+
+// GetWorkshopByID returns a workshop by ID
+func (r *WorkshopRepository) GetWorkshopByID(id uint) (*models.Workshop, error) {
+	var workshop models.Workshop
+	if err := r.DB.First(&workshop, id).Error; err != nil {
+		return nil, err
+	}
+	return &workshop, nil
+}
+
+// GetWorkshopForUpdate returns a workshop by ID, locking its row until the
+// surrounding transaction ends so seats are booked and freed one at a time
+func (r *WorkshopRepository) GetWorkshopForUpdate(id uint) (*models.Workshop, error) {
+	var workshop models.Workshop
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&workshop, id).Error; err != nil {
+		return nil, err
+	}
+	return &workshop, nil
+}
+
+// GetUpcomingWorkshops returns scheduled workshops starting after from, soonest
+// first. A non-zero skillID or teacherID narrows the list.
+func (r *WorkshopRepository) GetUpcomingWorkshops(from time.Time, skillID, teacherID uint, limit int) ([]models.Workshop, error) {
+	query := r.DB.Where("status = ? AND start_time > ?", models.WorkshopScheduled, from)
+	if skillID != 0 {
+		query = query.Where("skill_id = ?", skillID)
+	}
+	if teacherID != 0 {
+		query = query.Where("teacher_id = ?", teacherID)
+	}
+
+	var workshops []models.Workshop
+	err := query.Order("start_time, id").Limit(limit).Find(&workshops).Error
+	return workshops, err
+}
+
+// UpdateWorkshop saves a workshop
+func (r *WorkshopRepository) UpdateWorkshop(workshop *models.Workshop) error {
+	return r.DB.Save(workshop).Error
+}
+
+// FindOverlapping returns the scheduled workshops, other than the one
+// excluded, overlapping the given times that any of the users teaches or has a
+// booked seat in
+func (r *WorkshopRepository) FindOverlapping(userIDs []uint, start, end time.Time, excludeID uint) ([]models.Workshop, error) {
+	var workshops []models.Workshop
+	if len(userIDs) == 0 {
+		return workshops, nil
+	}
+	attending := r.DB.Model(&models.WorkshopSeat{}).Select("workshop_id").
+		Where("status = ? AND user_id IN ?", models.SeatBooked, userIDs)
+	err := r.DB.
+		Where("id <> ? AND status = ?", excludeID, models.WorkshopScheduled).
+		Where("teacher_id IN ? OR id IN (?)", userIDs, attending).
+		Where("start_time < ? AND end_time > ?", end, start).
+		Order("start_time").
+		Find(&workshops).Error
+	return workshops, err
+}
+
+// CountBookedSeats returns how many seats of a workshop are booked
+func (r *WorkshopRepository) CountBookedSeats(workshopID uint) (int, error) {
+	var count int64
+	err := r.DB.Model(&models.WorkshopSeat{}).
+		Where("workshop_id = ? AND status = ?", workshopID, models.SeatBooked).
+		Count(&count).Error
+	return int(count), err
+}
+
+// GetSeatByID returns a seat by ID
+func (r *WorkshopRepository) GetSeatByID(id uint) (*models.WorkshopSeat, error) {
+	var seat models.WorkshopSeat
+	if err := r.DB.First(&seat, id).Error; err != nil {
+		return nil, err
+	}
+	return &seat, nil
+}
+
+// GetActiveSeat returns a user's booked seat or waitlist place in a workshop
+func (r *WorkshopRepository) GetActiveSeat(workshopID, userID uint) (*models.WorkshopSeat, error) {
+	var seat models.WorkshopSeat
+	err := r.DB.Where("workshop_id = ? AND user_id = ? AND status IN ?", workshopID, userID,
+		[]string{models.SeatBooked, models.SeatWaitlisted}).First(&seat).Error
+	if err != nil {
+		return nil, err
+	}
+	return &seat, nil
+}
+
+// GetSeatByCode returns the booked seat of a workshop with a check-in code
+func (r *WorkshopRepository) GetSeatByCode(workshopID uint, code string) (*models.WorkshopSeat, error) {
+	var seat models.WorkshopSeat
+	err := r.DB.Where("workshop_id = ? AND status = ? AND check_in_code = ?", workshopID, models.SeatBooked, code).
+		First(&seat).Error
+	if err != nil {
+		return nil, err
+	}
+	return &seat, nil
+}
+
+// GetSeats returns a workshop's seats with the given statuses, in the order
+// they were taken, which is the waitlist order
+func (r *WorkshopRepository) GetSeats(workshopID uint, statuses ...string) ([]models.WorkshopSeat, error) {
+	var seats []models.WorkshopSeat
+	err := r.DB.Where("workshop_id = ? AND status IN ?", workshopID, statuses).
+		Order("created_at, id").Find(&seats).Error
+	return seats, err
+}
+
+// CreateSeat stores a new seat or waitlist place
+func (r *WorkshopRepository) CreateSeat(seat *models.WorkshopSeat) error {
+	return r.DB.Create(seat).Error
+}
+
+// UpdateSeat saves a seat
+func (r *WorkshopRepository) UpdateSeat(seat *models.WorkshopSeat) error {
+	return r.DB.Save(seat).Error
+}
@@ -0,0 +1,60 @@
+package repositories_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+)
+
+func TestWorkshopSeats(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewWorkshopRepository(db)
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Minute)
+	workshop := models.Workshop{TeacherID: 1, SkillID: 1, Title: "Guitar", StartTime: start, EndTime: start.Add(2 * time.Hour), Capacity: 1, Status: models.WorkshopScheduled}
+	if err := repo.CreateWorkshop(&workshop); err != nil {
+		t.Fatalf("CreateWorkshop failed: %v", err)
+	}
+
+	booked := models.WorkshopSeat{WorkshopID: workshop.ID, UserID: 2, Status: models.SeatBooked, CheckInCode: "ABCD2345"}
+	first := models.WorkshopSeat{WorkshopID: workshop.ID, UserID: 3, Status: models.SeatWaitlisted}
+	second := models.WorkshopSeat{WorkshopID: workshop.ID, UserID: 4, Status: models.SeatWaitlisted}
+	for _, seat := range []*models.WorkshopSeat{&booked, &first, &second} {
+		if err := repo.CreateSeat(seat); err != nil {
+			t.Fatalf("CreateSeat failed: %v", err)
+		}
+	}
+
+	if count, err := repo.CountBookedSeats(workshop.ID); err != nil || count != 1 {
+		t.Errorf("Expected 1 booked seat, got %d (%v)", count, err)
+	}
+	waitlist, err := repo.GetSeats(workshop.ID, models.SeatWaitlisted)
+	if err != nil || len(waitlist) != 2 || waitlist[0].UserID != 3 || waitlist[1].UserID != 4 {
+		t.Errorf("Expected the waitlist in the order it was joined, got %+v (%v)", waitlist, err)
+	}
+	if seat, err := repo.GetSeatByCode(workshop.ID, "ABCD2345"); err != nil || seat.ID != booked.ID {
+		t.Errorf("Expected the booked seat for its code, got %+v (%v)", seat, err)
+	}
+	if seat, err := repo.GetActiveSeat(workshop.ID, 3); err != nil || seat.ID != first.ID {
+		t.Errorf("Expected the waitlist place as an active seat, got %+v (%v)", seat, err)
+	}
+
+	// Teachers and attendees are busy during the workshop, waitlisted learners aren't
+	for _, tt := range []struct {
+		userID uint
+		want   int
+	}{{1, 1}, {2, 1}, {3, 0}} {
+		overlapping, err := repo.FindOverlapping([]uint{tt.userID}, start.Add(time.Hour), start.Add(3*time.Hour), 0)
+		if err != nil || len(overlapping) != tt.want {
+			t.Errorf("User %d: expected %d overlapping workshops, got %d (%v)", tt.userID, tt.want, len(overlapping), err)
+		}
+	}
+	if overlapping, _ := repo.FindOverlapping([]uint{1}, start.Add(2*time.Hour), start.Add(3*time.Hour), 0); len(overlapping) != 0 {
+		t.Errorf("Expected a workshop ending as another starts not to overlap, got %d", len(overlapping))
+	}
+	if overlapping, _ := repo.FindOverlapping([]uint{1}, start, start.Add(time.Hour), workshop.ID); len(overlapping) != 0 {
+		t.Errorf("Expected the excluded workshop to be left out, got %d", len(overlapping))
+	}
+}
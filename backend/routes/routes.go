@@ -12,6 +12,17 @@ func SetupRoutes(router *gin.Engine, authController *controllers.AuthController)
 	// Serve static files at the router level, not inside the API group
 	router.StaticFS("/uploads", http.Dir("./uploads"))
 
+	// CalDAV sync for calendar clients, signed in with their email and a revocable
+	// CalDAV app password. The read-only feed token is not accepted here.
+	for _, method := range []string{http.MethodGet, "PROPFIND", http.MethodOptions} {
+		router.Handle(method, "/.well-known/caldav", controllers.CalDAVWellKnown)
+	}
+	caldav := router.Group("/caldav")
+	caldav.Use(middleware.CalDAVAuthMiddleware())
+	for _, method := range controllers.CalDAVMethods {
+		caldav.Handle(method, "/*path", controllers.CalDAV)
+	}
+
 	// Create an API group for all API routes.
 	api := router.Group("/api")
 	{
@@ -22,33 +33,131 @@ func SetupRoutes(router *gin.Engine, authController *controllers.AuthController)
 			auth.POST("/login", authController.Login)
 		}
 
-		// Search endpoint.
-		api.GET("/search", controllers.Search)
+		// Public endpoints that show logged-in users more.
+		public := api.Group("/")
+		public.Use(middleware.OptionalAuthMiddleware())
+		{
+			// Search endpoint.
+			public.GET("/search", controllers.Search)
+			public.GET("/search/suggest", controllers.SearchSuggest)
+
+			// Public profiles.
+			public.GET("/users/:id", controllers.GetUserProfile)
+			public.GET("/users/:id/slots", controllers.GetUserSlots)
+
+			// Calendar feeds, authorized by the secret token in their URL.
+			public.GET("/calendar/feeds/:token", controllers.GetCalendarFeed)
+		}
 
 		// Protected endpoints.
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware())
 		{
+			// Makes POSTs that move points or create things safe for clients to retry
+			idempotent := middleware.IdempotencyMiddleware()
+
 			protected.GET("/protected", func(ctx *gin.Context) {
 				ctx.JSON(200, gin.H{"message": "You are authenticated"})
 			})
 
 			// Video upload endpoint.
-			protected.POST("/videos/upload", controllers.VideoUpload)
+			protected.POST("/videos/upload", idempotent, controllers.VideoUpload)
 			protected.GET("/videos", controllers.GetVideosList)
 
 			// New schedule endpoints.
-			protected.POST("/schedule", controllers.CreateSchedule)
+			protected.POST("/schedule", idempotent, controllers.CreateSchedule)
 			protected.GET("/schedule", controllers.GetSchedules)
+			protected.GET("/schedule/:id", controllers.GetSchedule)
+			protected.PUT("/schedule/:id", controllers.UpdateSchedule)
+			protected.DELETE("/schedule/:id", controllers.DeleteSchedule)
+			protected.GET("/schedule/:id/events", controllers.GetScheduleEvents)
+			protected.GET("/schedule/:id/ics", controllers.GetScheduleCalendar)
+			protected.POST("/schedule/:id/propose", idempotent, controllers.UpdateSchedule)
+			protected.POST("/schedule/:id/accept", idempotent, controllers.AcceptSchedule)
+			protected.POST("/schedule/:id/decline", idempotent, controllers.DeclineSchedule)
+			protected.POST("/schedule/:id/cancel", idempotent, controllers.DeleteSchedule)
+			protected.POST("/schedule/:id/complete", idempotent, controllers.CompleteSchedule)
+			protected.POST("/schedule/:id/no-show", idempotent, controllers.ReportScheduleNoShow)
+			protected.POST("/schedule/:id/dispute", idempotent, controllers.DisputeScheduleNoShow)
+
+			// Recurring series endpoints
+			protected.POST("/schedule/series", idempotent, controllers.CreateSeries)
+			protected.GET("/schedule/series/:id", controllers.GetSeries)
+			protected.POST("/schedule/series/:id/propose", idempotent, controllers.RescheduleSeries)
+			protected.POST("/schedule/series/:id/accept", idempotent, controllers.AcceptSeries)
+			protected.POST("/schedule/series/:id/decline", idempotent, controllers.DeclineSeries)
+			protected.POST("/schedule/series/:id/cancel", idempotent, controllers.CancelSeries)
+
+			// Workshop endpoints
+			protected.GET("/workshops", controllers.GetWorkshops)
+			protected.POST("/workshops", idempotent, controllers.CreateWorkshop)
+			protected.GET("/workshops/:id", controllers.GetWorkshop)
+			protected.POST("/workshops/:id/cancel", controllers.CancelWorkshop)
+			protected.POST("/workshops/:id/seats", idempotent, controllers.BookWorkshopSeat)
+			protected.DELETE("/workshops/:id/seats", controllers.CancelWorkshopSeat)
+			protected.GET("/workshops/:id/roster", controllers.GetWorkshopRoster)
+			protected.POST("/workshops/:id/check-in", controllers.CheckInWorkshopSeat)
+
+			// Teacher availability endpoints
+			protected.GET("/availability", controllers.GetAvailability)
+			protected.POST("/availability", idempotent, controllers.CreateAvailability)
+			protected.PUT("/availability/:id", controllers.UpdateAvailability)
+			protected.DELETE("/availability/:id", controllers.DeleteAvailability)
+
+			// Calendar feed and import endpoints
+			protected.GET("/calendar/feed", controllers.GetCalendarFeedURL)
+			protected.POST("/calendar/feed/reset", controllers.ResetCalendarFeedURL)
+			protected.POST("/calendar/caldav-password", controllers.ResetCalDAVPassword)
+			protected.DELETE("/calendar/caldav-password", controllers.RevokeCalDAVPassword)
+			protected.POST("/calendar/import", idempotent, controllers.ImportCalendar)
+			protected.GET("/calendar/busy", controllers.GetBusyBlocks)
+			protected.DELETE("/calendar/busy/:id", controllers.DeleteBusyBlock)
+
+			// Skill pricing endpoints
+			protected.PUT("/skills/:id/pricing", controllers.SetSkillPricing)
+			protected.GET("/skills/:id/quote", controllers.GetSkillQuote)
+			protected.PUT("/skills/:id/session-limits", controllers.SetSkillSessionLimits)
 
 			// Transactions endpoints
 			protected.GET("/transactions", controllers.GetTransactions)
-			protected.POST("/transactions", controllers.CreateTransaction) // New endpoint for creating transactions
+			protected.GET("/transactions/balance", controllers.GetBalance)
+			protected.GET("/transactions/export", controllers.ExportTransactions)
+			protected.POST("/transactions", idempotent, controllers.CreateTransaction) // New endpoint for creating transactions
+			protected.POST("/transactions/:id/refund", idempotent, controllers.RefundTransaction)
+			protected.POST("/transactions/:id/disputes", idempotent, controllers.OpenTransactionDispute)
+
+			// Dispute endpoints
+			protected.GET("/disputes/:id", controllers.GetDispute)
+			protected.POST("/disputes/:id/messages", idempotent, controllers.AddDisputeMessage)
+			protected.POST("/disputes/:id/evidence", idempotent, controllers.AddDisputeEvidence)
+			protected.GET("/disputes/:id/evidence/:evidenceId", controllers.GetDisputeEvidence)
+
+			// Privacy settings of the logged-in user
+			protected.GET("/users/me/privacy", controllers.GetPrivacySettings)
+			protected.PUT("/users/me/privacy", controllers.UpdatePrivacySettings)
+			protected.GET("/users/me/timezone", controllers.GetTimeZone)
+			protected.PUT("/users/me/timezone", controllers.UpdateTimeZone)
+			protected.GET("/users/me/reminders", controllers.GetReminderSettings)
+			protected.PUT("/users/me/reminders", controllers.UpdateReminderSettings)
+			protected.GET("/users/me/cancellation-policy", controllers.GetCancellationPolicy)
+			protected.PUT("/users/me/cancellation-policy", controllers.UpdateCancellationPolicy)
+
+			// Saved search endpoints
+			protected.GET("/saved-searches", controllers.GetSavedSearches)
+			protected.POST("/saved-searches", controllers.CreateSavedSearch)
+			protected.GET("/saved-searches/:id", controllers.GetSavedSearch)
+			protected.PUT("/saved-searches/:id", controllers.UpdateSavedSearch)
+			protected.DELETE("/saved-searches/:id", controllers.DeleteSavedSearch)
+
+			// Notification endpoints
+			protected.GET("/notifications", controllers.GetNotifications)
+			protected.PUT("/notifications/read", controllers.MarkAllNotificationsRead)
+			protected.PUT("/notifications/:id/read", controllers.MarkNotificationRead)
 
 			// Job endpoints
 			protected.GET("/jobs", controllers.GetJobs)
 			protected.GET("/jobs/:id", controllers.GetJob)
-			protected.POST("/jobs", controllers.CreateJob)
+			protected.POST("/jobs", idempotent, controllers.CreateJob)
 			protected.PUT("/jobs/:id", controllers.UpdateJob)
 			protected.DELETE("/jobs/:id", controllers.DeleteJob)
 		}
@@ -60,6 +169,9 @@ func SetupRoutes(router *gin.Engine, authController *controllers.AuthController)
 			admin.GET("/dashboard", func(ctx *gin.Context) {
 				ctx.JSON(200, gin.H{"message": "Welcome Admin"})
 			})
+			admin.POST("/schedule/:id/no-show", controllers.ResolveScheduleNoShow)
+			admin.GET("/disputes", controllers.GetDisputes)
+			admin.POST("/disputes/:id/resolve", middleware.IdempotencyMiddleware(), controllers.ResolveDispute)
 		}
 	}
 }
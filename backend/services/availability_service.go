@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+// ErrOutsideAvailability is returned when a learner asks for a time the teacher hasn't made available
+var ErrOutsideAvailability = errors.New("the teacher is not available at that time")
+
+// AvailabilityService works out when teachers can take sessions
+type AvailabilityService struct {
+	DB *gorm.DB
+}
+
+// NewAvailabilityService creates a new availability service backed by the given database
+func NewAvailabilityService(db *gorm.DB) *AvailabilityService {
+	return &AvailabilityService{DB: db}
+}
+
+// FreeTime returns the teacher's free time between from and to for sessions of a skill:
+// their availability windows less their confirmed sessions, other than the one
+// excluded, the busy times imported from their calendar, and the buffer around
+// them. ok is false when the teacher hasn't published availability for the skill.
+func (s *AvailabilityService) FreeTime(teacherID, skillID uint, from, to time.Time, excludeID uint) (free []models.TimeRange, ok bool, err error) {
+	availability, err := repositories.NewAvailabilityRepository(s.DB).GetAvailabilityByTeacher(teacherID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var covering []models.Availability
+	maxBuffer := time.Duration(0)
+	for _, a := range availability {
+		if a.Covers(skillID) {
+			covering = append(covering, a)
+			if a.Buffer() > maxBuffer {
+				maxBuffer = a.Buffer()
+			}
+		}
+	}
+	if len(covering) == 0 {
+		return nil, false, nil
+	}
+
+	sessions, err := repositories.NewScheduleRepository(s.DB).
+		GetSchedulesByUser(teacherID, from.Add(-maxBuffer), to.Add(maxBuffer), models.StatusConfirmed)
+	if err != nil {
+		return nil, true, err
+	}
+	var busy []models.TimeRange
+	for _, session := range sessions {
+		if session.ID != excludeID {
+			busy = append(busy, models.TimeRange{Start: session.StartTime, End: session.EndTime})
+		}
+	}
+
+	blocks, err := repositories.NewBusyBlockRepository(s.DB).GetBusyBlocksByUser(teacherID, from.Add(-maxBuffer), to.Add(maxBuffer))
+	if err != nil {
+		return nil, true, err
+	}
+	for _, block := range blocks {
+		busy = append(busy, models.TimeRange{Start: block.StartTime, End: block.EndTime})
+	}
+
+	free, err = FreeTime(covering, busy, from, to)
+	return free, true, err
+}
+
+// OpenSlots returns the bookable slots of the given length in the teacher's free
+// time between from and to that start after now.
+func (s *AvailabilityService) OpenSlots(teacherID, skillID uint, from, to time.Time, length time.Duration, now time.Time) ([]models.TimeRange, error) {
+	free, _, err := s.FreeTime(teacherID, skillID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	return SplitSlots(free, length, now), nil
+}
+
+// IsAvailable reports whether a session moving to start and end fits in its
+// teacher's free time. Teachers who haven't published availability for the
+// skill take sessions at any time.
+func (s *AvailabilityService) IsAvailable(session *models.Schedule, start, end time.Time) (bool, error) {
+	free, ok, err := s.FreeTime(session.TeacherID, session.SkillID, start, end, session.ID)
+	if err != nil || !ok {
+		return !ok, err
+	}
+	for _, r := range free {
+		if !r.Start.After(start) && !r.End.Before(end) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FreeTime returns the parts of the availability windows between from and to
+// that are clear of the busy times and each window's buffer around them, merged
+// and in start order.
+func FreeTime(availability []models.Availability, busy []models.TimeRange, from, to time.Time) ([]models.TimeRange, error) {
+	var free []models.TimeRange
+	for _, a := range availability {
+		windows, err := a.Windows(from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		blocked := make([]models.TimeRange, len(busy))
+		for i, b := range busy {
+			blocked[i] = models.TimeRange{Start: b.Start.Add(-a.Buffer()), End: b.End.Add(a.Buffer())}
+		}
+
+		for _, window := range windows {
+			window = clip(window, from, to)
+			free = append(free, subtract(window, blocked)...)
+		}
+	}
+	return merge(free), nil
+}
+
+// SplitSlots cuts free time into back-to-back slots of the given length,
+// leaving out slots starting before notBefore.
+func SplitSlots(free []models.TimeRange, length time.Duration, notBefore time.Time) []models.TimeRange {
+	slots := []models.TimeRange{}
+	if length <= 0 {
+		return slots
+	}
+	for _, r := range free {
+		for start := r.Start; !start.Add(length).After(r.End); start = start.Add(length) {
+			if start.Before(notBefore) {
+				continue
+			}
+			slots = append(slots, models.TimeRange{Start: start, End: start.Add(length)})
+		}
+	}
+	return slots
+}
+
+// clip narrows r to the span from up to to
+func clip(r models.TimeRange, from, to time.Time) models.TimeRange {
+	if r.Start.Before(from) {
+		r.Start = from
+	}
+	if r.End.After(to) {
+		r.End = to
+	}
+	return r
+}
+
+// subtract returns the parts of r not covered by any of the blocked ranges
+func subtract(r models.TimeRange, blocked []models.TimeRange) []models.TimeRange {
+	remaining := []models.TimeRange{r}
+	for _, b := range blocked {
+		var next []models.TimeRange
+		for _, part := range remaining {
+			if !b.Start.Before(part.End) || !b.End.After(part.Start) {
+				next = append(next, part)
+				continue
+			}
+			if b.Start.After(part.Start) {
+				next = append(next, models.TimeRange{Start: part.Start, End: b.Start})
+			}
+			if b.End.Before(part.End) {
+				next = append(next, models.TimeRange{Start: b.End, End: part.End})
+			}
+		}
+		remaining = next
+	}
+
+	var nonEmpty []models.TimeRange
+	for _, part := range remaining {
+		if part.End.After(part.Start) {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return nonEmpty
+}
+
+// merge sorts ranges and joins those that overlap or touch
+func merge(ranges []models.TimeRange) []models.TimeRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+
+	var merged []models.TimeRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && !r.Start.After(merged[n-1].End) {
+			if r.End.After(merged[n-1].End) {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,88 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+)
+
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestFreeTimeAcrossDST(t *testing.T) {
+	availability := []models.Availability{{
+		TeacherID: 1,
+		TimeZone:  "America/New_York",
+		StartDate: "2026-03-02",
+		StartsAt:  "09:00",
+		EndsAt:    "10:00",
+		RRule:     "FREQ=WEEKLY",
+	}}
+
+	free, err := services.FreeTime(availability, nil, utc(2026, 3, 1, 0, 0), utc(2026, 3, 15, 0, 0))
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	// New York moves from UTC-5 to UTC-4 on 2026-03-08
+	expected := []models.TimeRange{
+		{Start: utc(2026, 3, 2, 14, 0), End: utc(2026, 3, 2, 15, 0)},
+		{Start: utc(2026, 3, 9, 13, 0), End: utc(2026, 3, 9, 14, 0)},
+	}
+	if len(free) != len(expected) {
+		t.Fatalf("Expected %d windows, got %d: %v", len(expected), len(free), free)
+	}
+	for i := range expected {
+		if !free[i].Start.Equal(expected[i].Start) || !free[i].End.Equal(expected[i].End) {
+			t.Errorf("Expected %v, got %v", expected[i], free[i])
+		}
+	}
+}
+
+func TestFreeTimeBuffersAndExceptions(t *testing.T) {
+	availability := []models.Availability{{
+		TeacherID:      1,
+		TimeZone:       "UTC",
+		StartDate:      "2026-03-02",
+		StartsAt:       "09:00",
+		EndsAt:         "13:00",
+		RRule:          "FREQ=DAILY",
+		ExceptionDates: models.StringArray{"2026-03-03"},
+		BufferMinutes:  15,
+	}}
+	busy := []models.TimeRange{{Start: utc(2026, 3, 2, 10, 0), End: utc(2026, 3, 2, 11, 0)}}
+
+	free, err := services.FreeTime(availability, busy, utc(2026, 3, 2, 0, 0), utc(2026, 3, 4, 0, 0))
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	expected := []models.TimeRange{
+		{Start: utc(2026, 3, 2, 9, 0), End: utc(2026, 3, 2, 9, 45)},
+		{Start: utc(2026, 3, 2, 11, 15), End: utc(2026, 3, 2, 13, 0)},
+	}
+	if len(free) != len(expected) {
+		t.Fatalf("Expected %d ranges, got %d: %v", len(expected), len(free), free)
+	}
+	for i := range expected {
+		if !free[i].Start.Equal(expected[i].Start) || !free[i].End.Equal(expected[i].End) {
+			t.Errorf("Expected %v, got %v", expected[i], free[i])
+		}
+	}
+}
+
+func TestSplitSlots(t *testing.T) {
+	free := []models.TimeRange{{Start: utc(2026, 3, 2, 9, 0), End: utc(2026, 3, 2, 11, 30)}}
+
+	slots := services.SplitSlots(free, time.Hour, utc(2026, 3, 2, 9, 30))
+	if len(slots) != 1 || !slots[0].Start.Equal(utc(2026, 3, 2, 10, 0)) {
+		t.Errorf("Expected one slot at 10:00, got %v", slots)
+	}
+
+	if slots := services.SplitSlots(nil, time.Hour, time.Time{}); slots == nil || len(slots) != 0 {
+		t.Errorf("Expected an empty list, got %v", slots)
+	}
+}
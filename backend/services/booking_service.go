@@ -0,0 +1,789 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSkillNotFound is returned when a session is booked for an unknown skill
+	ErrSkillNotFound = errors.New("skill not found")
+	// ErrOwnSkill is returned when a teacher tries to book their own skill
+	ErrOwnSkill = errors.New("cannot book a session of your own skill")
+	// ErrSessionNotFound is returned for sessions that don't exist or that the user doesn't take part in
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionStarted is returned when changing a session that has already started
+	ErrSessionStarted = errors.New("session has already started")
+	// ErrSessionNotStarted is returned when reporting a no-show before the session started
+	ErrSessionNotStarted = errors.New("session has not started yet")
+	// ErrSessionNotOver is returned when completing a session before it ended
+	ErrSessionNotOver = errors.New("session has not ended yet")
+	// ErrAwaitingOtherParty is returned when a participant answers their own proposal
+	ErrAwaitingOtherParty = errors.New("waiting for the other participant to answer")
+	// ErrNoProposal is returned when accepting or declining a session with nothing to answer
+	ErrNoProposal = errors.New("no proposed time to answer")
+	// ErrPaidSessionLength is returned when rescheduling would change what a paid session cost
+	ErrPaidSessionLength = errors.New("a paid session must keep its length")
+	// ErrScheduleConflict is returned when a participant already has a session at that time
+	ErrScheduleConflict = errors.New("session conflicts with an existing session")
+	// ErrParticipantBusy is returned when a participant's imported calendar shows them busy at that time
+	ErrParticipantBusy = errors.New("a participant is busy at that time")
+	// ErrNotDisputable is returned when disputing a no-show report that isn't about the user or whose window closed
+	ErrNotDisputable = errors.New("no-show report can't be disputed")
+	// ErrNoDispute is returned when ruling on a session without an open no-show dispute
+	ErrNoDispute = errors.New("no open no-show dispute")
+)
+
+// NoShowDisputeWindow is how long a participant reported absent has to dispute
+// the report before it is settled as reported
+const NoShowDisputeWindow = 48 * time.Hour
+
+// Session actions recorded in a session's history
+const (
+	ActionRequested   = "requested"
+	ActionProposed    = "proposed"
+	ActionAccepted    = "accepted"
+	ActionRescheduled = "rescheduled"
+	ActionDeclined    = "declined"
+	ActionCancelled   = "cancelled"
+	ActionCompleted   = "completed"
+	ActionNoShow      = "no_show"
+	ActionDisputed    = "disputed"
+	ActionNoShowRuled = "no_show_ruled"
+)
+
+// sessionNotificationTitles are the titles of the notifications sent for each action
+var sessionNotificationTitles = map[string]string{
+	ActionRequested:   "New session request",
+	ActionProposed:    "New time proposed",
+	ActionAccepted:    "Session confirmed",
+	ActionRescheduled: "Session rescheduled",
+	ActionDeclined:    "Session declined",
+	ActionCancelled:   "Session cancelled",
+	ActionCompleted:   "Session completed",
+	ActionNoShow:      "Session marked as no-show",
+	ActionDisputed:    "No-show report disputed",
+	ActionNoShowRuled: "No-show report settled",
+}
+
+// InvalidSessionError is returned when a session breaks the schedule rules,
+// such as the skill's length limits. Its message can be shown to users.
+type InvalidSessionError struct {
+	Err error
+}
+
+func (e *InvalidSessionError) Error() string { return e.Err.Error() }
+
+func (e *InvalidSessionError) Unwrap() error { return e.Err }
+
+// exclusionViolation is the Postgres error code raised by the schedules'
+// no-overlap exclusion constraints
+const exclusionViolation = "23P01"
+
+// checkSchedule validates a session against its skill and takes the
+// participants' calendar locks before looking for conflicting sessions,
+// workshops and busy times imported from their own calendars.
+// It must run inside the transaction that then writes the session.
+func checkSchedule(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) error {
+	if err := schedule.Validate(skill); err != nil {
+		return &InvalidSessionError{Err: err}
+	}
+	return checkCalendars(dbTx, schedule, 0)
+}
+
+// checkCalendars takes the calendar locks of a session's participants and
+// looks for their confirmed sessions, other than the session itself, their
+// workshops, other than the one excluded, and their imported busy times
+// overlapping it
+func checkCalendars(dbTx *gorm.DB, schedule *models.Schedule, excludeWorkshopID uint) error {
+	scheduleRepo := repositories.NewScheduleRepository(dbTx)
+	if err := scheduleRepo.LockParticipants(schedule.Participants()...); err != nil {
+		return err
+	}
+	conflicts, err := scheduleRepo.FindConflicts(schedule, schedule.ID)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return ErrScheduleConflict
+	}
+	workshops, err := repositories.NewWorkshopRepository(dbTx).FindOverlapping(schedule.Participants(), schedule.StartTime, schedule.EndTime, excludeWorkshopID)
+	if err != nil {
+		return err
+	}
+	if len(workshops) > 0 {
+		return ErrScheduleConflict
+	}
+
+	busy, err := repositories.NewBusyBlockRepository(dbTx).FindOverlapping(schedule.Participants(), schedule.StartTime, schedule.EndTime)
+	if err != nil {
+		return err
+	}
+	if len(busy) > 0 {
+		return ErrParticipantBusy
+	}
+	return nil
+}
+
+// checkAvailability keeps the times a learner asks for within the teacher's published availability
+func checkAvailability(dbTx *gorm.DB, schedule *models.Schedule, start, end time.Time) error {
+	available, err := NewAvailabilityService(dbTx).IsAvailable(schedule, start, end)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return ErrOutsideAvailability
+	}
+	return nil
+}
+
+// translateScheduleError reports exclusion constraint violations as conflicts
+func translateScheduleError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation {
+		return ErrScheduleConflict
+	}
+	return err
+}
+
+// BookingService prices, books and manages skill sessions
+type BookingService struct {
+	DB *gorm.DB
+}
+
+// NewBookingService creates a new booking service backed by the given database
+func NewBookingService(db *gorm.DB) *BookingService {
+	return &BookingService{DB: db}
+}
+
+// QuoteSession prices a session of a skill for the given learner
+func (s *BookingService) QuoteSession(learnerID uint, skill *models.Skill, start, end time.Time) (models.PriceQuote, error) {
+	booked, err := repositories.NewScheduleRepository(s.DB).HasBookedSkill(learnerID, skill.ID)
+	if err != nil {
+		return models.PriceQuote{}, err
+	}
+	return skill.Quote(start, end, !booked)
+}
+
+// RequestSession records a learner's request for a session of a skill and
+// notifies the teacher. The learner is charged once the session is confirmed.
+func (s *BookingService) RequestSession(schedule *models.Schedule) (*models.Schedule, error) {
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		skill, err := repositories.NewSkillRepository(dbTx).GetSkillByID(schedule.SkillID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSkillNotFound
+			}
+			return err
+		}
+		if skill.UserID == schedule.LearnerID {
+			return ErrOwnSkill
+		}
+		schedule.TeacherID = skill.UserID
+
+		if err := checkSchedule(dbTx, schedule, skill); err != nil {
+			return err
+		}
+		if err := checkAvailability(dbTx, schedule, schedule.StartTime, schedule.EndTime); err != nil {
+			return err
+		}
+
+		quote, err := (&BookingService{DB: dbTx}).QuoteSession(schedule.LearnerID, skill, schedule.StartTime, schedule.EndTime)
+		if err != nil {
+			return err
+		}
+		schedule.Price = quote.Total
+		schedule.Status = models.StatusRequested
+		schedule.ProposedByID = schedule.LearnerID
+
+		if err := repositories.NewScheduleRepository(dbTx).CreateSchedule(schedule); err != nil {
+			return err
+		}
+		return recordSessionChange(dbTx, schedule, skill, schedule.LearnerID, ActionRequested, "")
+	})
+	if err != nil {
+		return nil, translateScheduleError(err)
+	}
+	return schedule, nil
+}
+
+// ProposeTime proposes new times for a session. While it is requested the new
+// times replace the request, and the other participant answers them. Once it is
+// confirmed the session keeps its time until the other participant accepts.
+// Sessions charged by the hour keep their length so the price paid still holds,
+// and learners can only propose times the teacher has made available.
+func (s *BookingService) ProposeTime(id, actorID uint, start, end time.Time) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		moved := *schedule
+		moved.StartTime = start
+		moved.EndTime = end
+		learner := actorID == schedule.LearnerID
+
+		switch schedule.Status {
+		case models.StatusRequested:
+			if err := checkSchedule(dbTx, &moved, skill); err != nil {
+				return "", err
+			}
+			if learner {
+				if err := checkAvailability(dbTx, schedule, start, end); err != nil {
+					return "", err
+				}
+			}
+			if skill != nil {
+				quote, err := (&BookingService{DB: dbTx}).QuoteSession(schedule.LearnerID, skill, start, end)
+				if err != nil {
+					return "", err
+				}
+				schedule.Price = quote.Total
+			}
+			schedule.StartTime = start
+			schedule.EndTime = end
+
+		case models.StatusConfirmed:
+			if !schedule.StartTime.After(time.Now()) {
+				return "", ErrSessionStarted
+			}
+			if err := checkPaidLength(schedule, skill, start, end); err != nil {
+				return "", err
+			}
+			if err := checkSchedule(dbTx, &moved, skill); err != nil {
+				return "", err
+			}
+			if learner {
+				if err := checkAvailability(dbTx, schedule, start, end); err != nil {
+					return "", err
+				}
+			}
+			schedule.ProposedStartTime = &start
+			schedule.ProposedEndTime = &end
+
+		default:
+			return "", fmt.Errorf("%w: %s session", models.ErrIllegalTransition, schedule.Status)
+		}
+
+		schedule.ProposedByID = actorID
+		return ActionProposed, nil
+	})
+}
+
+// AcceptSession accepts the time the other participant proposed. Accepting a
+// request confirms the session and holds the learner's payment in escrow, at
+// its price when confirmed; accepting a new time for a confirmed session moves it.
+func (s *BookingService) AcceptSession(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		switch {
+		case schedule.Status == models.StatusRequested:
+			if schedule.ProposedByID == actorID {
+				return "", ErrAwaitingOtherParty
+			}
+			if err := checkSchedule(dbTx, schedule, skill); err != nil {
+				return "", err
+			}
+			// Quoted again now the participants' calendars are locked: of the
+			// learner's requests for a skill, only the first confirmed gets
+			// the first-session discount
+			if skill != nil {
+				quote, err := (&BookingService{DB: dbTx}).QuoteSession(schedule.LearnerID, skill, schedule.StartTime, schedule.EndTime)
+				if err != nil {
+					return "", err
+				}
+				schedule.Price = quote.Total
+			}
+			if err := schedule.TransitionTo(models.StatusConfirmed); err != nil {
+				return "", err
+			}
+			if err := holdPayment(dbTx, schedule); err != nil {
+				return "", err
+			}
+			schedule.ClearProposal()
+			return ActionAccepted, nil
+
+		case schedule.Status == models.StatusConfirmed && schedule.HasProposal():
+			if schedule.ProposedByID == actorID {
+				return "", ErrAwaitingOtherParty
+			}
+			start, end := *schedule.ProposedStartTime, *schedule.ProposedEndTime
+			if err := checkPaidLength(schedule, skill, start, end); err != nil {
+				return "", err
+			}
+			moved := *schedule
+			moved.StartTime = start
+			moved.EndTime = end
+			if err := checkSchedule(dbTx, &moved, skill); err != nil {
+				return "", err
+			}
+			schedule.StartTime = start
+			schedule.EndTime = end
+			schedule.ClearProposal()
+			return ActionRescheduled, nil
+
+		case schedule.Status == models.StatusConfirmed:
+			return "", ErrNoProposal
+
+		default:
+			return "", fmt.Errorf("%w: %s session", models.ErrIllegalTransition, schedule.Status)
+		}
+	})
+}
+
+// DeclineSession turns down the time the other participant proposed. Declining
+// a request cancels it; declining a new time keeps a confirmed session as it was.
+func (s *BookingService) DeclineSession(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		switch {
+		case schedule.Status == models.StatusRequested:
+			if schedule.ProposedByID == actorID {
+				return "", ErrAwaitingOtherParty
+			}
+			if err := schedule.TransitionTo(models.StatusCancelled); err != nil {
+				return "", err
+			}
+			schedule.ClearProposal()
+			return ActionDeclined, nil
+
+		case schedule.Status == models.StatusConfirmed && schedule.HasProposal():
+			if schedule.ProposedByID == actorID {
+				return "", ErrAwaitingOtherParty
+			}
+			schedule.ClearProposal()
+			return ActionDeclined, nil
+
+		case schedule.Status == models.StatusConfirmed:
+			return "", ErrNoProposal
+
+		default:
+			return "", fmt.Errorf("%w: %s session", models.ErrIllegalTransition, schedule.Status)
+		}
+	})
+}
+
+// CancelSession cancels a requested session, or a confirmed one that has not
+// started yet. A teacher cancelling refunds the learner's payment in full; a
+// learner cancelling pays the teacher's late cancellation fee, if the policy
+// in place when the session was booked has one, and gets the rest back.
+func (s *BookingService) CancelSession(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		now := time.Now()
+		wasConfirmed := schedule.Status == models.StatusConfirmed
+		if wasConfirmed && !schedule.StartTime.After(now) {
+			return "", ErrSessionStarted
+		}
+		if err := schedule.TransitionTo(models.StatusCancelled); err != nil {
+			return "", err
+		}
+		if wasConfirmed {
+			if err := settleCancellation(dbTx, schedule, skill, actorID, now); err != nil {
+				return "", err
+			}
+		}
+		schedule.ClearProposal()
+		return ActionCancelled, nil
+	})
+}
+
+// CompleteSession records that a participant confirms a session that has
+// ended took place. The first confirmation marks it completed, and once both
+// participants confirmed it the payment held in escrow is released to the
+// teacher. Unconfirmed payments are released by the EscrowService later.
+func (s *BookingService) CompleteSession(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		wasCompleted := schedule.Status == models.StatusCompleted
+		if !wasCompleted {
+			if schedule.Status == models.StatusConfirmed && time.Now().Before(schedule.EndTime) {
+				return "", ErrSessionNotOver
+			}
+			if err := schedule.TransitionTo(models.StatusCompleted); err != nil {
+				return "", err
+			}
+		}
+
+		confirmed, err := confirmCompletion(dbTx, schedule, skill, actorID)
+		if err != nil {
+			return "", err
+		}
+		if wasCompleted && !confirmed {
+			// Only the participant who hasn't confirmed a held payment yet can complete it again
+			return "", fmt.Errorf("%w: %s session", models.ErrIllegalTransition, schedule.Status)
+		}
+		schedule.ClearProposal()
+		return ActionCompleted, nil
+	})
+}
+
+// ReportNoShow marks a confirmed session that has started as a no-show by
+// the other participant. They have NoShowDisputeWindow to dispute it; until
+// then, or until a dispute is ruled on, the payment stays in escrow.
+func (s *BookingService) ReportNoShow(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		now := time.Now()
+		if schedule.Status == models.StatusConfirmed && now.Before(schedule.StartTime) {
+			return "", ErrSessionNotStarted
+		}
+		if err := schedule.TransitionTo(models.StatusNoShow); err != nil {
+			return "", err
+		}
+		deadline := now.Add(NoShowDisputeWindow)
+		schedule.NoShowUserID = schedule.OtherParticipant(actorID)
+		schedule.DisputeDeadline = &deadline
+		schedule.ClearProposal()
+		return ActionNoShow, nil
+	})
+}
+
+// DisputeNoShow lets the participant reported absent dispute the report within
+// NoShowDisputeWindow. The payment then stays in escrow until an admin rules.
+func (s *BookingService) DisputeNoShow(id, actorID uint) (*models.Schedule, error) {
+	return s.changeSession(id, actorID, func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error) {
+		now := time.Now()
+		if schedule.NoShowUserID != actorID || !schedule.NoShowDisputable(now) {
+			return "", ErrNotDisputable
+		}
+		schedule.DisputedAt = &now
+		return ActionDisputed, nil
+	})
+}
+
+// ResolveNoShow settles a disputed no-show report on an admin's ruling. An
+// upheld report is settled as reported; a rejected one means the session took
+// place, so the teacher is paid in full.
+func (s *BookingService) ResolveNoShow(id, adminID uint, upheld bool) (*models.Schedule, error) {
+	var resolved *models.Schedule
+
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		scheduleRepo := repositories.NewScheduleRepository(dbTx)
+		schedule, err := scheduleRepo.GetScheduleForUpdate(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+		if schedule.Status != models.StatusNoShow || schedule.DisputedAt == nil || schedule.NoShowResolution != "" {
+			return ErrNoDispute
+		}
+
+		skill, err := repositories.NewSkillRepository(dbTx).GetSkillByID(schedule.SkillID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			skill = nil
+		} else if err != nil {
+			return err
+		}
+
+		resolution := models.NoShowRejected
+		if upheld {
+			resolution = models.NoShowUpheld
+		}
+		if err := settleNoShow(dbTx, schedule, skill, resolution, time.Now()); err != nil {
+			return err
+		}
+		resolved = schedule
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// changeSession applies one participant's change to a session in a transaction.
+// The session's row stays locked while change runs, so concurrent changes are
+// applied in turn. The change is saved, recorded in the session's history and
+// sent to the other participant. A nil skill means it was removed since booking.
+func (s *BookingService) changeSession(id, actorID uint, change func(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill) (string, error)) (*models.Schedule, error) {
+	var changed *models.Schedule
+
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		scheduleRepo := repositories.NewScheduleRepository(dbTx)
+		schedule, err := scheduleRepo.GetScheduleForUpdate(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+		if !schedule.IsParticipant(actorID) {
+			return ErrSessionNotFound
+		}
+
+		skill, err := repositories.NewSkillRepository(dbTx).GetSkillByID(schedule.SkillID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			skill = nil
+		} else if err != nil {
+			return err
+		}
+
+		fromStatus := schedule.Status
+		action, err := change(dbTx, schedule, skill)
+		if err != nil {
+			return err
+		}
+
+		schedule.Sequence++
+		if err := scheduleRepo.UpdateSchedule(schedule); err != nil {
+			return err
+		}
+		changed = schedule
+		return recordSessionChange(dbTx, schedule, skill, actorID, action, fromStatus)
+	})
+	if err != nil {
+		return nil, translateScheduleError(err)
+	}
+	return changed, nil
+}
+
+// checkPaidLength keeps sessions paid by the hour at the length that was paid for
+func checkPaidLength(schedule *models.Schedule, skill *models.Skill, start, end time.Time) error {
+	if schedule.Price == 0 || end.Sub(start) == schedule.EndTime.Sub(schedule.StartTime) {
+		return nil
+	}
+	if skill == nil || skill.PriceUnit == models.PricePerHour {
+		return ErrPaidSessionLength
+	}
+	return nil
+}
+
+// holdPayment holds the session's price in escrow out of the learner's
+// balance, under the teacher's current cancellation policy
+func holdPayment(dbTx *gorm.DB, schedule *models.Schedule) error {
+	if schedule.Price == 0 {
+		return nil
+	}
+	policy, err := teacherPolicy(dbTx, schedule.TeacherID)
+	if err != nil {
+		return err
+	}
+
+	escrow := models.Escrow{
+		ScheduleID:         schedule.ID,
+		LearnerID:          schedule.LearnerID,
+		TeacherID:          schedule.TeacherID,
+		Amount:             schedule.Price,
+		CancellationPolicy: policy,
+	}
+	return repositories.NewEscrowRepository(dbTx).HoldEscrow(&escrow)
+}
+
+// teacherPolicy returns a teacher's current cancellation policy, or the
+// default one for teachers who no longer exist
+func teacherPolicy(dbTx *gorm.DB, teacherID uint) (models.CancellationPolicy, error) {
+	teacher, err := repositories.NewUserRepository(dbTx).GetUserByID(teacherID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.CancellationPolicy{}, nil
+	}
+	if err != nil {
+		return models.CancellationPolicy{}, err
+	}
+	return teacher.CancellationPolicy, nil
+}
+
+// refundPayment returns the learner's payment for a session: from escrow, or
+// for sessions paid before escrow, back from the teacher
+func refundPayment(dbTx *gorm.DB, schedule *models.Schedule) error {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetEscrowForUpdate(schedule.ID)
+	if err == nil {
+		if escrow.Status != models.EscrowHeld {
+			return nil
+		}
+		return escrowRepo.RefundEscrow(escrow, time.Now())
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if schedule.TransactionID == nil || schedule.Price == 0 {
+		return nil
+	}
+	var payment models.Transaction
+	if err := dbTx.First(&payment, *schedule.TransactionID).Error; err != nil {
+		return err
+	}
+	refund := models.Transaction{
+		SenderID:   payment.ReceiverID,
+		ReceiverID: payment.SenderID,
+		Amount:     payment.Amount,
+		Note:       fmt.Sprintf("Refund: cancelled session %d", schedule.ID),
+	}
+	return repositories.NewTransactionRepository(dbTx).CreateTransaction(&refund)
+}
+
+// settleCancellation settles the escrow of a confirmed session being cancelled
+// by actorID, charging learners the late cancellation fee of the policy the
+// session was booked under. Sessions paid before escrow are refunded in full.
+func settleCancellation(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill, actorID uint, now time.Time) error {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetEscrowForUpdate(schedule.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return refundPayment(dbTx, schedule)
+	}
+	if err != nil {
+		return err
+	}
+	if escrow.Status != models.EscrowHeld {
+		return nil
+	}
+
+	fee := 0
+	if actorID == schedule.LearnerID {
+		fee = escrow.CancellationPolicy.Fee(escrow.Amount, schedule.StartTime, now)
+	}
+	return escrowRepo.SettleEscrow(escrow, fee, fmt.Sprintf("Late cancellation: %s", skillName(skill)), now)
+}
+
+// settleNoShow settles a no-show report with the given resolution and saves
+// the session. Upheld and accepted reports charge an absent learner the late
+// cancellation fee and refund the learner of an absent teacher in full;
+// rejected ones pay the teacher in full. Both participants are told.
+func settleNoShow(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill, resolution string, now time.Time) error {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetEscrowForUpdate(schedule.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if escrow != nil && escrow.Status == models.EscrowHeld {
+		toTeacher, note := escrow.Amount, sessionPaymentNote(skill)
+		if resolution != models.NoShowRejected {
+			toTeacher = 0
+			if schedule.NoShowUserID == schedule.LearnerID {
+				toTeacher = escrow.CancellationPolicy.LateFee(escrow.Amount)
+				note = fmt.Sprintf("No-show: %s", skillName(skill))
+			}
+		}
+		if err := escrowRepo.SettleEscrow(escrow, toTeacher, note, now); err != nil {
+			return err
+		}
+	}
+
+	schedule.NoShowResolution = resolution
+	schedule.Sequence++
+	if err := repositories.NewScheduleRepository(dbTx).UpdateSchedule(schedule); err != nil {
+		return err
+	}
+
+	event, err := recordSessionEvent(dbTx, schedule, 0, ActionNoShowRuled, schedule.Status)
+	if err != nil {
+		return err
+	}
+	for _, userID := range []uint{schedule.LearnerID, schedule.TeacherID} {
+		if err := notifySessionChange(dbTx, event, skill, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confirmCompletion records a participant's confirmation that a session took
+// place against its escrow, releasing it once both confirmed. It reports
+// false when there is no held payment or the participant already confirmed.
+func confirmCompletion(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill, actorID uint) (bool, error) {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetEscrowForUpdate(schedule.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if escrow.Status != models.EscrowHeld {
+		return false, nil
+	}
+
+	now := time.Now()
+	if !escrow.ConfirmCompletion(actorID, now) {
+		return false, nil
+	}
+	if err := escrowRepo.UpdateEscrowConfirmations(escrow); err != nil {
+		return false, err
+	}
+	if escrow.BothConfirmed() {
+		if err := escrowRepo.ReleaseEscrow(escrow, sessionPaymentNote(skill), now); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// sessionPaymentNote describes a session's payment in transaction histories
+func sessionPaymentNote(skill *models.Skill) string {
+	return fmt.Sprintf("Session: %s", skillName(skill))
+}
+
+// recordSessionChange adds a change to the session's history and notifies the
+// participant who didn't make it
+func recordSessionChange(dbTx *gorm.DB, schedule *models.Schedule, skill *models.Skill, actorID uint, action, fromStatus string) error {
+	event, err := recordSessionEvent(dbTx, schedule, actorID, action, fromStatus)
+	if err != nil {
+		return err
+	}
+	return notifySessionChange(dbTx, event, skill, schedule.OtherParticipant(actorID))
+}
+
+// recordSessionEvent adds a change to the session's history. Changes made by
+// the system rather than a participant have no actor.
+func recordSessionEvent(dbTx *gorm.DB, schedule *models.Schedule, actorID uint, action, fromStatus string) (*models.ScheduleEvent, error) {
+	event := models.ScheduleEvent{
+		ScheduleID: schedule.ID,
+		ActorID:    actorID,
+		Action:     action,
+		FromStatus: fromStatus,
+		ToStatus:   schedule.Status,
+		StartTime:  schedule.StartTime,
+		EndTime:    schedule.EndTime,
+	}
+	if action == ActionProposed && schedule.HasProposal() {
+		event.StartTime = *schedule.ProposedStartTime
+		event.EndTime = *schedule.ProposedEndTime
+	}
+	if err := repositories.NewScheduleRepository(dbTx).CreateScheduleEvent(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// notifySessionChange tells a participant about a change in a session's history
+func notifySessionChange(dbTx *gorm.DB, event *models.ScheduleEvent, skill *models.Skill, recipientID uint) error {
+	loc, err := userLocation(dbTx, recipientID)
+	if err != nil {
+		return err
+	}
+
+	notification := models.Notification{
+		UserID:  recipientID,
+		Kind:    models.NotificationSessionUpdate,
+		Title:   sessionNotificationTitles[event.Action],
+		Message: fmt.Sprintf("%s on %s", skillName(skill), FormatSessionTime(event.StartTime, loc)),
+		Link:    "/schedule",
+	}
+	return repositories.NewNotificationRepository(dbTx).CreateNotification(&notification)
+}
+
+// FormatSessionTime formats a session's start for messages, in the reader's time zone
+func FormatSessionTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("Mon Jan 2 at 15:04 MST")
+}
+
+// userLocation returns a user's time zone, or UTC for users who no longer exist
+func userLocation(db *gorm.DB, userID uint) (*time.Location, error) {
+	user, err := repositories.NewUserRepository(db).GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user.Location(), nil
+}
+
+// skillName names a session's skill in messages
+func skillName(skill *models.Skill) string {
+	if skill == nil {
+		return "A removed skill"
+	}
+	return skill.Name
+}
@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"gorm.io/gorm"
+)
+
+func TestAcceptedSessionIsStoredAndCharged(t *testing.T) {
+	db := openTestDB(t)
+	learner, skill := createBookingUsers(t, db, 0)
+	booking := services.NewBookingService(db)
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	requested, err := booking.RequestSession(&models.Schedule{LearnerID: learner.ID, SkillID: skill.ID, StartTime: start, EndTime: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("RequestSession failed: %v", err)
+	}
+	if _, err := booking.AcceptSession(requested.ID, skill.UserID); err != nil {
+		t.Fatalf("AcceptSession failed: %v", err)
+	}
+
+	stored, err := repositories.NewScheduleRepository(db).GetScheduleByID(requested.ID)
+	if err != nil || stored.Status != models.StatusConfirmed {
+		t.Fatalf("Expected the session to be stored as confirmed, got %+v (%v)", stored, err)
+	}
+	var escrow models.Escrow
+	if err := db.Where("schedule_id = ?", stored.ID).First(&escrow).Error; err != nil || escrow.Amount != skill.Price {
+		t.Errorf("Expected the payment to be held for the stored session, got %+v (%v)", escrow, err)
+	}
+	charged, err := repositories.NewUserRepository(db).GetUserByID(learner.ID)
+	if err != nil || charged.SkillPoints != models.SignupGrant-skill.Price {
+		t.Errorf("Expected the learner to have %d points left, got %+v (%v)", models.SignupGrant-skill.Price, charged, err)
+	}
+	if booked, err := repositories.NewScheduleRepository(db).HasBookedSkill(learner.ID, skill.ID); err != nil || !booked {
+		t.Errorf("Expected the booking to count as the learner's first session, got %v (%v)", booked, err)
+	}
+}
+
+func TestFirstSessionDiscountAppliesOnce(t *testing.T) {
+	db := openTestDB(t)
+	learner, skill := createBookingUsers(t, db, 50)
+	booking := services.NewBookingService(db)
+
+	// Both requests are quoted as the first session while neither is confirmed
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	var requests []*models.Schedule
+	for i := 0; i < 2; i++ {
+		at := start.Add(time.Duration(i) * 24 * time.Hour)
+		requested, err := booking.RequestSession(&models.Schedule{LearnerID: learner.ID, SkillID: skill.ID, StartTime: at, EndTime: at.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("RequestSession failed: %v", err)
+		}
+		if requested.Price != skill.Price/2 {
+			t.Fatalf("Expected the request to be quoted at %d, got %d", skill.Price/2, requested.Price)
+		}
+		requests = append(requests, requested)
+	}
+
+	for i, want := range []int{skill.Price / 2, skill.Price} {
+		accepted, err := booking.AcceptSession(requests[i].ID, skill.UserID)
+		if err != nil {
+			t.Fatalf("AcceptSession failed: %v", err)
+		}
+		if accepted.Price != want {
+			t.Errorf("Expected session %d to be confirmed at %d, got %d", i+1, want, accepted.Price)
+		}
+	}
+}
+
+// createBookingUsers creates a learner and a teacher's skill costing 20 points
+// a session, with the given first-session discount
+func createBookingUsers(t *testing.T, db *gorm.DB, discount int) (models.User, models.Skill) {
+	t.Helper()
+
+	userRepo := repositories.NewUserRepository(db)
+	learner := models.User{Name: "Learner", Email: "booking-learner@example.com", Password: "password123"}
+	teacher := models.User{Name: "Teacher", Email: "booking-teacher@example.com", Password: "password123"}
+	for _, user := range []*models.User{&learner, &teacher} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	skill := models.Skill{Name: "Guitar", UserID: teacher.ID, Price: 20, PriceUnit: models.PricePerSession, FirstSessionDiscount: discount}
+	if err := db.Create(&skill).Error; err != nil {
+		t.Fatalf("Failed to create skill: %v", err)
+	}
+	return learner, skill
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// FeedHistory is how far back calendar feeds list sessions
+	FeedHistory = 90 * 24 * time.Hour
+	// ImportHorizon is how far ahead imported events are turned into busy times
+	ImportHorizon = 365 * 24 * time.Hour
+)
+
+// ImportResult counts the events of an imported calendar
+type ImportResult struct {
+	Imported int `json:"imported"` // Events saved as busy times
+	Skipped  int `json:"skipped"`  // Free, cancelled or past events, and repeats that can't be read
+}
+
+// CalendarService publishes users' sessions as iCalendar and imports their busy times
+type CalendarService struct {
+	DB *gorm.DB
+}
+
+// NewCalendarService creates a new calendar service backed by the given database
+func NewCalendarService(db *gorm.DB) *CalendarService {
+	return &CalendarService{DB: db}
+}
+
+// FeedToken returns the secret token of the user's calendar feed, creating it
+// the first time it is asked for
+func (s *CalendarService) FeedToken(user *models.User) (string, error) {
+	if user.CalendarToken != "" {
+		return user.CalendarToken, nil
+	}
+	return s.ResetFeedToken(user)
+}
+
+// ResetFeedToken gives the user's calendar feed a new token, so links to the old
+// one stop working
+func (s *CalendarService) ResetFeedToken(user *models.User) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	user.CalendarToken = hex.EncodeToString(buf)
+	if err := repositories.NewUserRepository(s.DB).UpdateCalendarToken(user); err != nil {
+		return "", err
+	}
+	return user.CalendarToken, nil
+}
+
+// ResetCalDAVPassword gives the user a new app password for CalDAV clients,
+// signing out those using the old one. Only its hash is kept, so it can be
+// shown once.
+func (s *CalendarService) ResetCalDAVPassword(user *models.User) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	password := hex.EncodeToString(buf)
+	user.CalDAVPasswordHash = models.HashCalDAVPassword(password)
+	if err := repositories.NewUserRepository(s.DB).UpdateCalDAVPassword(user); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// RevokeCalDAVPassword removes the user's CalDAV app password, signing out their calendar clients
+func (s *CalendarService) RevokeCalDAVPassword(user *models.User) error {
+	user.CalDAVPasswordHash = ""
+	return repositories.NewUserRepository(s.DB).UpdateCalDAVPassword(user)
+}
+
+// Sessions returns the user's sessions calendars show: those ending after
+// FeedHistory before now, in start time order
+func (s *CalendarService) Sessions(userID uint, now time.Time) ([]models.Schedule, error) {
+	return repositories.NewScheduleRepository(s.DB).GetSchedulesByUser(userID, now.Add(-FeedHistory), time.Time{})
+}
+
+// Feed returns the user's sessions from FeedHistory before now onwards as an
+// iCalendar feed in their time zone. Cancelled sessions stay in it so
+// subscribers remove them.
+func (s *CalendarService) Feed(user *models.User, now time.Time) (string, error) {
+	schedules, err := s.Sessions(user.ID, now)
+	if err != nil {
+		return "", err
+	}
+	events, err := s.sessionEvents(user.ID, schedules)
+	if err != nil {
+		return "", err
+	}
+	return utils.WriteICalendar("SkillSwap sessions", user.Location(), events), nil
+}
+
+// SessionCalendar returns one session as an iCalendar file for a participant, in their time zone
+func (s *CalendarService) SessionCalendar(schedule *models.Schedule, userID uint) (string, error) {
+	calendars, err := s.SessionCalendars([]models.Schedule{*schedule}, userID)
+	if err != nil {
+		return "", err
+	}
+	return calendars[schedule.ID], nil
+}
+
+// SessionCalendars returns each session as an iCalendar object for a participant,
+// in their time zone, keyed by session ID
+func (s *CalendarService) SessionCalendars(schedules []models.Schedule, userID uint) (map[uint]string, error) {
+	events, err := s.sessionEvents(userID, schedules)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	calendars := make(map[uint]string, len(schedules))
+	for i, event := range events {
+		calendars[schedules[i].ID] = utils.WriteICalendar("SkillSwap session", loc, []utils.ICalEvent{event})
+	}
+	return calendars, nil
+}
+
+// ApplyEventChange applies a participant's edit of a session's event in their
+// calendar client. A cancelled event cancels the session, and new times are
+// proposed for it like any other change, so they pass the same checks as a
+// booking. Other edits, such as to the summary, are ignored.
+func (s *CalendarService) ApplyEventChange(schedule *models.Schedule, userID uint, event utils.ICalEvent) (*models.Schedule, error) {
+	booking := NewBookingService(s.DB)
+
+	if event.Status == utils.ICalCancelled {
+		if schedule.Status == models.StatusCancelled {
+			return schedule, nil
+		}
+		return booking.CancelSession(schedule.ID, userID)
+	}
+
+	start, end := event.Start.UTC(), event.End.UTC()
+	if start.Equal(schedule.StartTime) && end.Equal(schedule.EndTime) {
+		return schedule, nil
+	}
+	if schedule.HasProposal() && start.Equal(*schedule.ProposedStartTime) && end.Equal(*schedule.ProposedEndTime) {
+		if schedule.ProposedByID == userID {
+			return schedule, nil
+		}
+		// Moving the event to the time the other participant proposed accepts it
+		return booking.AcceptSession(schedule.ID, userID)
+	}
+	return booking.ProposeTime(schedule.ID, userID, start, end)
+}
+
+// SessionETag identifies a revision of a session for calendar clients
+func SessionETag(schedule *models.Schedule) string {
+	return fmt.Sprintf(`"%d-%d"`, schedule.ID, schedule.Sequence)
+}
+
+// CalendarTag identifies a revision of a list of sessions; it changes when any
+// session is added, removed or changed
+func CalendarTag(schedules []models.Schedule) string {
+	hash := sha256.New()
+	for _, schedule := range schedules {
+		fmt.Fprintf(hash, "%d-%d;", schedule.ID, schedule.Sequence)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// SessionResourceName is the name of a session's event in calendar collections
+func SessionResourceName(id uint) string {
+	return fmt.Sprintf("schedule-%d.ics", id)
+}
+
+// ParseSessionResourceName returns the session ID of an event name made by SessionResourceName
+func ParseSessionResourceName(name string) (uint, bool) {
+	digits, ok := strings.CutPrefix(name, "schedule-")
+	if !ok {
+		return 0, false
+	}
+	digits, ok = strings.CutSuffix(digits, ".ics")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// sessionEvents turns sessions into calendar events for one of their participants
+func (s *CalendarService) sessionEvents(userID uint, schedules []models.Schedule) ([]utils.ICalEvent, error) {
+	ids := make([]uint, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.SkillID
+	}
+	skills, err := repositories.NewSkillRepository(s.DB).GetSkillsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]utils.ICalEvent, len(schedules))
+	for i := range schedules {
+		events[i] = SessionEvent(&schedules[i], skills[schedules[i].SkillID], userID)
+	}
+	return events, nil
+}
+
+// SessionUID is the UID of a session's calendar event, which stays the same across changes
+func SessionUID(id uint) string {
+	return fmt.Sprintf("schedule-%d@skillswap", id)
+}
+
+// SessionEvent describes a session as a calendar event for one of its participants.
+// Its UID stays the same across changes, and its sequence grows with each one.
+func SessionEvent(schedule *models.Schedule, skill *models.Skill, userID uint) utils.ICalEvent {
+	role := "Learning"
+	if schedule.TeacherID == userID {
+		role = "Teaching"
+	}
+
+	status := utils.ICalConfirmed
+	switch schedule.Status {
+	case models.StatusRequested:
+		status = utils.ICalTentative
+	case models.StatusCancelled:
+		status = utils.ICalCancelled
+	}
+
+	stamp := schedule.UpdatedAt
+	if stamp.IsZero() {
+		stamp = schedule.CreatedAt
+	}
+
+	return utils.ICalEvent{
+		UID:         SessionUID(schedule.ID),
+		Summary:     fmt.Sprintf("%s: %s", role, skillName(skill)),
+		Description: fmt.Sprintf("SkillSwap session, %s", schedule.Status),
+		Start:       schedule.StartTime,
+		End:         schedule.EndTime,
+		Status:      status,
+		Sequence:    schedule.Sequence,
+		Stamp:       stamp,
+	}
+}
+
+// ImportBusyTimes saves the events of an iCalendar file as the user's busy times
+// from now until ImportHorizon ahead, reading times without a time zone in the
+// user's. Importing a file again replaces the busy times of the events it
+// contains, so updated and cancelled events are picked up.
+func (s *CalendarService) ImportBusyTimes(userID uint, r io.Reader, now time.Time) (ImportResult, error) {
+	loc, err := userLocation(s.DB, userID)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	events, err := utils.ParseICalendar(r, loc)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	var (
+		result ImportResult
+		uids   []string
+		blocks []models.BusyBlock
+	)
+	for _, event := range events {
+		if event.UID != "" {
+			uids = append(uids, event.UID)
+		}
+		if event.Transparent || event.Status == utils.ICalCancelled {
+			result.Skipped++
+			continue
+		}
+
+		occurrences, err := event.Occurrences(now, now.Add(ImportHorizon))
+		if err != nil || len(occurrences) == 0 {
+			result.Skipped++
+			continue
+		}
+		for _, o := range occurrences {
+			blocks = append(blocks, models.BusyBlock{
+				UserID:    userID,
+				UID:       event.UID,
+				Summary:   event.Summary,
+				StartTime: o.Start.UTC(),
+				EndTime:   o.End.UTC(),
+			})
+		}
+		result.Imported++
+	}
+
+	if err := repositories.NewBusyBlockRepository(s.DB).ReplaceBusyBlocks(userID, uids, blocks); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func TestSessionEvent(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	schedule := &models.Schedule{
+		ID:        7,
+		LearnerID: 1,
+		TeacherID: 2,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Status:    models.StatusRequested,
+		Sequence:  2,
+	}
+	skill := &models.Skill{Name: "Go"}
+
+	event := services.SessionEvent(schedule, skill, 2)
+	if event.UID != "schedule-7@skillswap" || event.Sequence != 2 {
+		t.Errorf("Expected a stable UID and the session's sequence, got %+v", event)
+	}
+	if event.Summary != "Teaching: Go" || event.Status != utils.ICalTentative {
+		t.Errorf("Expected a tentative teaching event, got %+v", event)
+	}
+
+	schedule.Status = models.StatusCancelled
+	event = services.SessionEvent(schedule, nil, 1)
+	if event.Summary != "Learning: A removed skill" || event.Status != utils.ICalCancelled {
+		t.Errorf("Expected a cancelled learning event, got %+v", event)
+	}
+}
+
+func TestSessionResourceNames(t *testing.T) {
+	name := services.SessionResourceName(42)
+	if id, ok := services.ParseSessionResourceName(name); !ok || id != 42 {
+		t.Errorf("Expected %q to name session 42, got %d", name, id)
+	}
+	for _, name := range []string{"schedule-.ics", "schedule-0.ics", "schedule-1", "event-1.ics", "schedule-x.ics"} {
+		if _, ok := services.ParseSessionResourceName(name); ok {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+
+	schedules := []models.Schedule{{ID: 1, Sequence: 1}}
+	before := services.CalendarTag(schedules)
+	schedules[0].Sequence++
+	if services.CalendarTag(schedules) == before {
+		t.Error("Expected the calendar tag to change with a session")
+	}
+	if services.SessionETag(&schedules[0]) != `"1-2"` {
+		t.Errorf("Unexpected ETag %s", services.SessionETag(&schedules[0]))
+	}
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+// disputeListLimit is the most disputes listed at once
+const disputeListLimit = 100
+
+var (
+	// ErrTransactionNotFound is returned when a transaction doesn't exist or the user isn't part of it
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrNotReceiver is returned when someone other than the receiver refunds a transaction
+	ErrNotReceiver = errors.New("only the receiver can refund a transaction")
+	// ErrNotSender is returned when someone other than the sender disputes a transaction
+	ErrNotSender = errors.New("only the sender can dispute a transaction")
+	// ErrRefundOfRefund is returned when refunding or disputing a refund
+	ErrRefundOfRefund = errors.New("refunds can't be refunded or disputed")
+	// ErrNothingToRefund is returned when a transaction was already refunded in full
+	ErrNothingToRefund = errors.New("the transaction was already refunded in full")
+	// ErrInvalidRefund is returned for a negative refund amount
+	ErrInvalidRefund = errors.New("refund amount can't be negative")
+	// ErrRefundTooLarge is returned when refunding more than is left of a transaction
+	ErrRefundTooLarge = errors.New("refund is larger than what is left of the transaction")
+	// ErrDisputeNotFound is returned when a dispute doesn't exist or the user can't see it
+	ErrDisputeNotFound = errors.New("dispute not found")
+	// ErrDisputeOpen is returned when disputing a transaction that already has an open dispute
+	ErrDisputeOpen = errors.New("the transaction already has an open dispute")
+	// ErrDisputeClosed is returned when changing a dispute that was resolved
+	ErrDisputeClosed = errors.New("the dispute has been resolved")
+	// ErrEvidenceNotFound is returned when a dispute has no such evidence
+	ErrEvidenceNotFound = errors.New("evidence not found")
+	// ErrEvidenceTooLarge is returned for evidence files over models.MaxEvidenceSize
+	ErrEvidenceTooLarge = errors.New("evidence file is too large")
+	// ErrEvidenceType is returned for evidence files of a type not in models.EvidenceTypes
+	ErrEvidenceType = errors.New("evidence must be an image, PDF or text file")
+)
+
+// InvalidDisputeError is returned when a dispute, message or resolution
+// breaks the rules, such as being too long. Its message can be shown to users.
+type InvalidDisputeError struct {
+	Err error
+}
+
+func (e *InvalidDisputeError) Error() string { return e.Err.Error() }
+
+func (e *InvalidDisputeError) Unwrap() error { return e.Err }
+
+// DisputeDetails is a dispute with the transaction it is about and how much
+// of it was refunded so far
+type DisputeDetails struct {
+	models.TransactionDispute
+	Transaction models.Transaction `json:"transaction"`
+	Refunded    int                `json:"refunded"`
+}
+
+// DisputeService handles refunds of transactions and disputes over them.
+// Transactions are never changed: points go back in new transactions linked
+// to the original.
+type DisputeService struct {
+	DB *gorm.DB
+	// EvidenceDir is where evidence files are stored
+	EvidenceDir string
+}
+
+// NewDisputeService creates a dispute service storing evidence in evidenceDir
+func NewDisputeService(db *gorm.DB, evidenceDir string) *DisputeService {
+	return &DisputeService{DB: db, EvidenceDir: evidenceDir}
+}
+
+// Refund gives back amount of a transaction's points from its receiver to
+// its sender, or all that is left when amount is 0. A transaction can be
+// refunded in parts, but never by more than its amount in total.
+func (s *DisputeService) Refund(transactionID, actorID uint, amount int, note string) (*models.Transaction, error) {
+	if amount < 0 {
+		return nil, ErrInvalidRefund
+	}
+
+	var refund *models.Transaction
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		original, refunded, err := lockTransaction(dbTx, transactionID, actorID)
+		if err != nil {
+			return err
+		}
+		if original.ReceiverID != actorID {
+			return ErrNotReceiver
+		}
+		if amount == 0 {
+			amount = original.Amount - refunded
+		}
+		if refund, err = refundTransaction(dbTx, original, refunded, amount, refundNote(original, note)); err != nil {
+			return err
+		}
+		return notifyTransaction(dbTx, original.SenderID, "Points refunded to you",
+			fmt.Sprintf("%d SkillPoints of transaction %d came back", amount, original.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// OpenDispute lets a transaction's sender claim its points back, with the
+// reason why. An admin resolves it.
+func (s *DisputeService) OpenDispute(transactionID, actorID uint, reason string) (*DisputeDetails, error) {
+	reason, err := models.ValidateDisputeText("reason", reason)
+	if err != nil {
+		return nil, &InvalidDisputeError{Err: err}
+	}
+
+	var details *DisputeDetails
+	err = s.DB.Transaction(func(dbTx *gorm.DB) error {
+		original, refunded, err := lockTransaction(dbTx, transactionID, actorID)
+		if err != nil {
+			return err
+		}
+		if original.SenderID != actorID {
+			return ErrNotSender
+		}
+		if refunded >= original.Amount {
+			return ErrNothingToRefund
+		}
+
+		disputeRepo := repositories.NewDisputeRepository(dbTx)
+		open, err := disputeRepo.HasOpenDispute(original.ID)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrDisputeOpen
+		}
+		dispute := models.TransactionDispute{
+			TransactionID: original.ID,
+			OpenedByID:    actorID,
+			Reason:        reason,
+			Status:        models.DisputeOpen,
+		}
+		if err := disputeRepo.CreateDispute(&dispute); err != nil {
+			return err
+		}
+		details = &DisputeDetails{TransactionDispute: dispute, Transaction: *original, Refunded: refunded}
+		return notifyTransaction(dbTx, original.ReceiverID, "A transaction to you was disputed",
+			fmt.Sprintf("Transaction %d of %d SkillPoints: %s", original.ID, original.Amount, reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// Dispute returns a dispute with its messages and evidence to one of the
+// transaction's participants or an admin
+func (s *DisputeService) Dispute(id, userID uint, admin bool) (*DisputeDetails, error) {
+	dispute, err := repositories.NewDisputeRepository(s.DB).GetDisputeWithThread(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDisputeNotFound
+		}
+		return nil, err
+	}
+	original, refunded, err := disputedTransaction(s.DB, dispute, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+	return &DisputeDetails{TransactionDispute: *dispute, Transaction: *original, Refunded: refunded}, nil
+}
+
+// Disputes lists disputes with a status, or of any status when it is empty,
+// oldest first, for admins
+func (s *DisputeService) Disputes(status string) ([]models.TransactionDispute, error) {
+	return repositories.NewDisputeRepository(s.DB).GetDisputes(status, disputeListLimit)
+}
+
+// AddMessage adds a participant's or admin's message to an open dispute and
+// tells the others in it
+func (s *DisputeService) AddMessage(id, userID uint, admin bool, body string) (*models.DisputeMessage, error) {
+	body, err := models.ValidateDisputeText("message", body)
+	if err != nil {
+		return nil, &InvalidDisputeError{Err: err}
+	}
+
+	var message *models.DisputeMessage
+	err = s.changeDispute(id, userID, admin, func(dbTx *gorm.DB, dispute *models.TransactionDispute, original *models.Transaction) error {
+		message = &models.DisputeMessage{DisputeID: dispute.ID, AuthorID: userID, Body: body}
+		if err := repositories.NewDisputeRepository(dbTx).CreateMessage(message); err != nil {
+			return err
+		}
+		return notifyDispute(dbTx, dispute, original, userID, "New message in a dispute", body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// AddEvidence stores a file a participant or admin attaches to an open
+// dispute. Only images, PDFs and text files of up to models.MaxEvidenceSize
+// are accepted, judged by their content rather than their name.
+func (s *DisputeService) AddEvidence(id, userID uint, admin bool, fileName string, content io.Reader) (*models.DisputeEvidence, error) {
+	data, err := io.ReadAll(io.LimitReader(content, models.MaxEvidenceSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > models.MaxEvidenceSize {
+		return nil, ErrEvidenceTooLarge
+	}
+	contentType := strings.TrimSpace(strings.SplitN(http.DetectContentType(data), ";", 2)[0])
+	ext, allowed := models.EvidenceTypes[contentType]
+	if !allowed || len(data) == 0 {
+		return nil, ErrEvidenceType
+	}
+
+	var evidence *models.DisputeEvidence
+	err = s.changeDispute(id, userID, admin, func(dbTx *gorm.DB, dispute *models.TransactionDispute, original *models.Transaction) error {
+		storedName, err := newEvidenceName(ext)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(s.EvidenceDir, 0o750); err != nil {
+			return err
+		}
+		path := filepath.Join(s.EvidenceDir, storedName)
+		if err := os.WriteFile(path, data, 0o640); err != nil {
+			return err
+		}
+
+		evidence = &models.DisputeEvidence{
+			DisputeID:   dispute.ID,
+			UploaderID:  userID,
+			FileName:    filepath.Base(fileName),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+			StoredName:  storedName,
+		}
+		err = repositories.NewDisputeRepository(dbTx).CreateEvidence(evidence)
+		if err == nil {
+			err = notifyDispute(dbTx, dispute, original, userID, "New evidence in a dispute", evidence.FileName)
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// Evidence returns one of a dispute's evidence files and its contents to one
+// of the transaction's participants or an admin
+func (s *DisputeService) Evidence(id, evidenceID, userID uint, admin bool) (*models.DisputeEvidence, []byte, error) {
+	dispute, err := repositories.NewDisputeRepository(s.DB).GetDisputeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrDisputeNotFound
+		}
+		return nil, nil, err
+	}
+	if _, _, err := disputedTransaction(s.DB, dispute, userID, admin); err != nil {
+		return nil, nil, err
+	}
+
+	evidence, err := repositories.NewDisputeRepository(s.DB).GetEvidence(dispute.ID, evidenceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrEvidenceNotFound
+		}
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.EvidenceDir, evidence.StoredName))
+	if err != nil {
+		return nil, nil, err
+	}
+	return evidence, data, nil
+}
+
+// ResolveDispute closes an open dispute on an admin's ruling. A refund above
+// zero is posted as a compensating transaction from the receiver back to the
+// sender, linked to the original; with none the dispute is rejected.
+func (s *DisputeService) ResolveDispute(id, adminID uint, refund int, resolution string) (*DisputeDetails, error) {
+	resolution, err := models.ValidateDisputeText("resolution", resolution)
+	if err != nil {
+		return nil, &InvalidDisputeError{Err: err}
+	}
+	if refund < 0 {
+		return nil, ErrInvalidRefund
+	}
+
+	err = s.changeDispute(id, adminID, true, func(dbTx *gorm.DB, dispute *models.TransactionDispute, original *models.Transaction) error {
+		refunded, err := repositories.NewTransactionRepository(dbTx).RefundedAmount(original.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		dispute.Status = models.DisputeRejected
+		dispute.ResolvedByID = &adminID
+		dispute.Resolution = resolution
+		dispute.ResolvedAt = &now
+		if refund > 0 {
+			note := fmt.Sprintf("Dispute %d resolution", dispute.ID)
+			compensation, err := refundTransaction(dbTx, original, refunded, refund, note)
+			if err != nil {
+				return err
+			}
+			dispute.Status = models.DisputeRefunded
+			dispute.RefundTransactionID = &compensation.ID
+		}
+		if err := repositories.NewDisputeRepository(dbTx).UpdateDisputeResolution(dispute); err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("Transaction %d: no points were returned", original.ID)
+		if refund > 0 {
+			message = fmt.Sprintf("Transaction %d: %d SkillPoints were returned to the sender", original.ID, refund)
+		}
+		return notifyDispute(dbTx, dispute, original, adminID, "A dispute was resolved", message)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Dispute(id, adminID, true)
+}
+
+// changeDispute runs a change to an open dispute in a transaction, with the
+// disputed transaction's row locked before the dispute's, in the same order
+// as refunds and new disputes lock them
+func (s *DisputeService) changeDispute(id, userID uint, admin bool, change func(dbTx *gorm.DB, dispute *models.TransactionDispute, original *models.Transaction) error) error {
+	return s.DB.Transaction(func(dbTx *gorm.DB) error {
+		disputeRepo := repositories.NewDisputeRepository(dbTx)
+		dispute, err := disputeRepo.GetDisputeByID(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDisputeNotFound
+			}
+			return err
+		}
+		original, err := repositories.NewTransactionRepository(dbTx).GetTransactionForUpdate(dispute.TransactionID)
+		if err != nil {
+			return err
+		}
+		if !admin && !original.IsParticipant(userID) {
+			return ErrDisputeNotFound
+		}
+		if dispute, err = disputeRepo.GetDisputeForUpdate(id); err != nil {
+			return err
+		}
+		if !dispute.IsOpen() {
+			return ErrDisputeClosed
+		}
+		return change(dbTx, dispute, original)
+	})
+}
+
+// lockTransaction locks a transaction the actor sent or received and
+// returns it with how much of it was refunded so far. Refunds themselves
+// can't be refunded or disputed.
+func lockTransaction(dbTx *gorm.DB, id, actorID uint) (*models.Transaction, int, error) {
+	txRepo := repositories.NewTransactionRepository(dbTx)
+	original, err := txRepo.GetTransactionForUpdate(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrTransactionNotFound
+		}
+		return nil, 0, err
+	}
+	if !original.IsParticipant(actorID) {
+		return nil, 0, ErrTransactionNotFound
+	}
+	if original.IsRefund() {
+		return nil, 0, ErrRefundOfRefund
+	}
+	refunded, err := txRepo.RefundedAmount(original.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return original, refunded, nil
+}
+
+// disputedTransaction returns the transaction a dispute is about, and how
+// much of it was refunded, if the user took part in it or is an admin
+func disputedTransaction(db *gorm.DB, dispute *models.TransactionDispute, userID uint, admin bool) (*models.Transaction, int, error) {
+	txRepo := repositories.NewTransactionRepository(db)
+	original, err := txRepo.GetTransactionByID(dispute.TransactionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !admin && !original.IsParticipant(userID) {
+		return nil, 0, ErrDisputeNotFound
+	}
+	refunded, err := txRepo.RefundedAmount(original.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return original, refunded, nil
+}
+
+// refundTransaction posts amount of a transaction, of which refunded were
+// already given back, from its receiver back to its sender
+func refundTransaction(dbTx *gorm.DB, original *models.Transaction, refunded, amount int, note string) (*models.Transaction, error) {
+	left := original.Amount - refunded
+	if left <= 0 {
+		return nil, ErrNothingToRefund
+	}
+	if amount <= 0 || amount > left {
+		return nil, ErrRefundTooLarge
+	}
+	refund := models.Transaction{
+		SenderID:   original.ReceiverID,
+		ReceiverID: original.SenderID,
+		Amount:     amount,
+		Note:       note,
+		RefundOf:   &original.ID,
+	}
+	if err := repositories.NewTransactionRepository(dbTx).CreateTransaction(&refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// refundNote describes a refund in transaction histories
+func refundNote(original *models.Transaction, note string) string {
+	if note = strings.TrimSpace(note); note != "" {
+		return note
+	}
+	return fmt.Sprintf("Refund of transaction %d", original.ID)
+}
+
+// notifyDispute tells the participants of a disputed transaction, other than
+// the one who acted, about a change to the dispute
+func notifyDispute(dbTx *gorm.DB, dispute *models.TransactionDispute, original *models.Transaction, actorID uint, title, message string) error {
+	for _, userID := range []uint{original.SenderID, original.ReceiverID} {
+		if userID == actorID {
+			continue
+		}
+		notification := models.Notification{
+			UserID:  userID,
+			Kind:    models.NotificationTransaction,
+			Title:   title,
+			Message: message,
+			Link:    fmt.Sprintf("/disputes/%d", dispute.ID),
+		}
+		if err := repositories.NewNotificationRepository(dbTx).CreateNotification(&notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyTransaction tells a user about a refund or dispute of a transaction
+func notifyTransaction(dbTx *gorm.DB, userID uint, title, message string) error {
+	notification := models.Notification{
+		UserID:  userID,
+		Kind:    models.NotificationTransaction,
+		Title:   title,
+		Message: message,
+		Link:    "/transactions",
+	}
+	return repositories.NewNotificationRepository(dbTx).CreateNotification(&notification)
+}
+
+// newEvidenceName returns a random name to store an evidence file under
+func newEvidenceName(ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf) + ext, nil
+}
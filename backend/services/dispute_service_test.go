@@ -0,0 +1,183 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+	"gorm.io/gorm"
+)
+
+func TestRefunds(t *testing.T) {
+	db := openTestDB(t)
+	sender, receiver, original := createDisputedTransaction(t, db, 40)
+	service := services.NewDisputeService(db, t.TempDir())
+
+	if _, err := service.Refund(original.ID, sender.ID, 10, ""); !errors.Is(err, services.ErrNotReceiver) {
+		t.Errorf("Expected the sender to be refused a refund, got %v", err)
+	}
+	if _, err := service.Refund(original.ID, receiver.ID, -5, ""); !errors.Is(err, services.ErrInvalidRefund) {
+		t.Errorf("Expected a negative refund to be invalid, got %v", err)
+	}
+
+	// Refunds can be given in parts, up to what is left
+	refund, err := service.Refund(original.ID, receiver.ID, 15, "")
+	if err != nil {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if refund.Amount != 15 || refund.SenderID != receiver.ID || refund.ReceiverID != sender.ID ||
+		refund.RefundOf == nil || *refund.RefundOf != original.ID {
+		t.Errorf("Expected 15 points back to the sender linked to the original, got %+v", refund)
+	}
+	if _, err := service.Refund(original.ID, receiver.ID, 10, "Part two"); err != nil {
+		t.Fatalf("Second refund failed: %v", err)
+	}
+	if _, err := service.Refund(original.ID, receiver.ID, 16, ""); !errors.Is(err, services.ErrRefundTooLarge) {
+		t.Errorf("Expected refunding more than the 15 left to fail, got %v", err)
+	}
+	if _, err := service.Refund(refund.ID, sender.ID, 5, ""); !errors.Is(err, services.ErrRefundOfRefund) {
+		t.Errorf("Expected a refund not to be refundable, got %v", err)
+	}
+
+	// 0 refunds the rest, after which nothing is left
+	rest, err := service.Refund(original.ID, receiver.ID, 0, "")
+	if err != nil || rest.Amount != 15 {
+		t.Fatalf("Expected the remaining 15 points refunded, got %+v (%v)", rest, err)
+	}
+	if _, err := service.Refund(original.ID, receiver.ID, 0, ""); !errors.Is(err, services.ErrNothingToRefund) {
+		t.Errorf("Expected nothing left to refund, got %v", err)
+	}
+	if _, err := service.OpenDispute(original.ID, sender.ID, "Never happened"); !errors.Is(err, services.ErrNothingToRefund) {
+		t.Errorf("Expected a fully refunded transaction not to be disputable, got %v", err)
+	}
+
+	refunded, err := repositories.NewTransactionRepository(db).RefundedAmount(original.ID)
+	if err != nil || refunded != 40 {
+		t.Errorf("Expected 40 points refunded in total, got %d (%v)", refunded, err)
+	}
+	expectBalances(t, db, map[*models.User]int{&sender: 100, &receiver: 100})
+}
+
+func TestDisputeResolution(t *testing.T) {
+	db := openTestDB(t)
+	sender, receiver, original := createDisputedTransaction(t, db, 50)
+	admin := models.User{Name: "Admin", Email: "dispute-service-admin@example.com", Password: "password123", Role: "Admin"}
+	if err := repositories.NewUserRepository(db).CreateUser(&admin); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	service := services.NewDisputeService(db, t.TempDir())
+
+	if _, err := service.OpenDispute(original.ID, receiver.ID, "Wrong amount"); !errors.Is(err, services.ErrNotSender) {
+		t.Errorf("Expected the receiver to be refused a dispute, got %v", err)
+	}
+	dispute, err := service.OpenDispute(original.ID, sender.ID, "Lesson was cut short")
+	if err != nil {
+		t.Fatalf("OpenDispute failed: %v", err)
+	}
+	if _, err := service.OpenDispute(original.ID, sender.ID, "Again"); !errors.Is(err, services.ErrDisputeOpen) {
+		t.Errorf("Expected a second open dispute to be refused, got %v", err)
+	}
+	if _, err := service.ResolveDispute(dispute.ID, admin.ID, -1, "Nope"); !errors.Is(err, services.ErrInvalidRefund) {
+		t.Errorf("Expected a negative resolution refund to be invalid, got %v", err)
+	}
+	if _, err := service.ResolveDispute(dispute.ID, admin.ID, 51, "Too much"); !errors.Is(err, services.ErrRefundTooLarge) {
+		t.Errorf("Expected refunding more than the transaction to fail, got %v", err)
+	}
+
+	resolved, err := service.ResolveDispute(dispute.ID, admin.ID, 30, "Half the lesson happened")
+	if err != nil {
+		t.Fatalf("ResolveDispute failed: %v", err)
+	}
+	if resolved.Status != models.DisputeRefunded || resolved.RefundTransactionID == nil || resolved.Refunded != 30 {
+		t.Fatalf("Expected the dispute refunded with 30 points, got %+v", resolved)
+	}
+
+	// The compensation is a refund journal moving 30 points from the receiver to the sender
+	var journal models.LedgerJournal
+	if err := db.Where("transaction_id = ?", *resolved.RefundTransactionID).First(&journal).Error; err != nil || journal.Kind != models.JournalRefund {
+		t.Fatalf("Expected the compensation posted as a refund journal, got %+v (%v)", journal, err)
+	}
+	var entries []models.LedgerEntry
+	if err := db.Where("journal_id = ?", journal.ID).Find(&entries).Error; err != nil {
+		t.Fatalf("Failed to load ledger entries: %v", err)
+	}
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	want := map[uint]int{}
+	for user, amount := range map[*models.User]int{&sender: 30, &receiver: -30} {
+		account, err := ledgerRepo.Account(models.LedgerUser, user.ID)
+		if err != nil {
+			t.Fatalf("Account failed: %v", err)
+		}
+		want[account.ID] = amount
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 ledger entries, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if want[entry.AccountID] != entry.Amount {
+			t.Errorf("Expected entry %+v to move %d points", entry, want[entry.AccountID])
+		}
+	}
+	if unbalanced, err := ledgerRepo.UnbalancedJournals(); err != nil || len(unbalanced) != 0 {
+		t.Errorf("Expected every journal to balance, got %v (%v)", unbalanced, err)
+	}
+	expectBalances(t, db, map[*models.User]int{&sender: 80, &receiver: 120})
+
+	if _, err := service.ResolveDispute(dispute.ID, admin.ID, 10, "Changed my mind"); !errors.Is(err, services.ErrDisputeClosed) {
+		t.Errorf("Expected a resolved dispute to stay closed, got %v", err)
+	}
+
+	// A later dispute can be rejected without moving points
+	second, err := service.OpenDispute(original.ID, sender.ID, "The rest too")
+	if err != nil {
+		t.Fatalf("OpenDispute after resolution failed: %v", err)
+	}
+	rejected, err := service.ResolveDispute(second.ID, admin.ID, 0, "The rest was delivered")
+	if err != nil {
+		t.Fatalf("ResolveDispute failed: %v", err)
+	}
+	if rejected.Status != models.DisputeRejected || rejected.RefundTransactionID != nil || rejected.Refunded != 30 {
+		t.Errorf("Expected the dispute rejected with nothing more refunded, got %+v", rejected)
+	}
+	expectBalances(t, db, map[*models.User]int{&sender: 80, &receiver: 120})
+}
+
+// createDisputedTransaction creates a sender and receiver who start with
+// 100 points and a transaction of amount between them
+func createDisputedTransaction(t *testing.T, db *gorm.DB, amount int) (models.User, models.User, models.Transaction) {
+	t.Helper()
+
+	userRepo := repositories.NewUserRepository(db)
+	sender := models.User{Name: "Sender", Email: "dispute-service-sender@example.com", Password: "password123"}
+	receiver := models.User{Name: "Receiver", Email: "dispute-service-receiver@example.com", Password: "password123"}
+	for _, user := range []*models.User{&sender, &receiver} {
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	original := models.Transaction{SenderID: sender.ID, ReceiverID: receiver.ID, Amount: amount, Note: "Guitar lesson"}
+	if err := repositories.NewTransactionRepository(db).CreateTransaction(&original); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	return sender, receiver, original
+}
+
+// expectBalances checks users' balances in the ledger and on their rows
+func expectBalances(t *testing.T, db *gorm.DB, balances map[*models.User]int) {
+	t.Helper()
+
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	for user, want := range balances {
+		balance, err := ledgerRepo.Balance(models.LedgerUser, user.ID)
+		if err != nil || balance != want {
+			t.Errorf("Expected %s to have %d in the ledger, got %d (%v)", user.Name, want, balance, err)
+		}
+		var stored models.User
+		if err := db.First(&stored, user.ID).Error; err != nil || stored.SkillPoints != want {
+			t.Errorf("Expected %s to have %d SkillPoints, got %d (%v)", user.Name, want, stored.SkillPoints, err)
+		}
+	}
+}
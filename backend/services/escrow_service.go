@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// escrowBatchSize is the number of releasable escrows claimed at a time
+const escrowBatchSize = 50
+
+// Balance is a user's SkillPoints, split into what they can spend and what is
+// held in escrow for sessions
+type Balance struct {
+	Available int `json:"available"` // spendable points
+	Held      int `json:"held"`      // paid for sessions as a learner, not yet released
+	Pending   int `json:"pending"`   // held for the user's sessions as a teacher, not yet released to them
+}
+
+// EscrowService releases session payments held in escrow that neither
+// participant settled, and reports balances including held points
+type EscrowService struct {
+	DB *gorm.DB
+	// ReleaseAfter is how long after a session ends its payment is released
+	// to the teacher without both participants confirming it took place
+	ReleaseAfter time.Duration
+}
+
+// NewEscrowService creates an escrow service releasing payments the given time after sessions end
+func NewEscrowService(db *gorm.DB, releaseAfter time.Duration) *EscrowService {
+	return &EscrowService{DB: db, ReleaseAfter: releaseAfter}
+}
+
+// Run releases due payments and settles undisputed no-show reports every
+// interval until stop is closed
+func (s *EscrowService) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			if _, err := s.ReleaseDue(now); err != nil {
+				utils.Error("Failed to release escrowed payments: " + err.Error())
+			}
+			if _, err := s.SettleNoShows(now); err != nil {
+				utils.Error("Failed to settle no-show reports: " + err.Error())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ReleaseDue pays teachers the escrowed payments of confirmed or completed
+// sessions, and of workshop seats nobody checked in, that ended ReleaseAfter
+// before now, and returns how many were released. Payments of sessions
+// reported as no-shows are settled by SettleNoShows instead.
+func (s *EscrowService) ReleaseDue(now time.Time) (int, error) {
+	endedBefore := now.Add(-s.ReleaseAfter)
+	sessions, err := s.releaseClaimed(now, func(repo *repositories.EscrowRepository) ([]models.Escrow, error) {
+		return repo.ClaimReleasableEscrows(endedBefore, escrowBatchSize)
+	})
+	if err != nil {
+		return sessions, err
+	}
+	seats, err := s.releaseClaimed(now, func(repo *repositories.EscrowRepository) ([]models.Escrow, error) {
+		return repo.ClaimReleasableSeatEscrows(endedBefore, escrowBatchSize)
+	})
+	return sessions + seats, err
+}
+
+// releaseClaimed releases the escrows claim returns, a batch per transaction,
+// until it returns a short batch
+func (s *EscrowService) releaseClaimed(now time.Time, claim func(repo *repositories.EscrowRepository) ([]models.Escrow, error)) (int, error) {
+	released := 0
+	for {
+		claimed := 0
+		err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+			due, err := claim(repositories.NewEscrowRepository(dbTx))
+			if err != nil {
+				return err
+			}
+			claimed = len(due)
+
+			for i := range due {
+				if err := s.release(dbTx, &due[i], now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return released, err
+		}
+		released += claimed
+		if claimed < escrowBatchSize {
+			return released, nil
+		}
+	}
+}
+
+// SettleNoShows settles the no-show reports nobody disputed within
+// NoShowDisputeWindow as reported, and returns how many were settled
+func (s *EscrowService) SettleNoShows(now time.Time) (int, error) {
+	settled := 0
+	for {
+		claimed := 0
+		err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+			due, err := repositories.NewScheduleRepository(dbTx).ClaimSettleableNoShows(now, escrowBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(due)
+
+			skillIDs := make([]uint, len(due))
+			for i, schedule := range due {
+				skillIDs[i] = schedule.SkillID
+			}
+			skills, err := repositories.NewSkillRepository(dbTx).GetSkillsByIDs(skillIDs)
+			if err != nil {
+				return err
+			}
+
+			for i := range due {
+				if err := settleNoShow(dbTx, &due[i], skills[due[i].SkillID], models.NoShowAccepted, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return settled, err
+		}
+		settled += claimed
+		if claimed < escrowBatchSize {
+			return settled, nil
+		}
+	}
+}
+
+// release pays one escrow to the teacher and lets them know
+func (s *EscrowService) release(dbTx *gorm.DB, escrow *models.Escrow, now time.Time) error {
+	name, note, start, err := escrowSubject(dbTx, escrow)
+	if err != nil {
+		return err
+	}
+	if err := repositories.NewEscrowRepository(dbTx).ReleaseEscrow(escrow, note, now); err != nil {
+		return err
+	}
+
+	loc, err := userLocation(dbTx, escrow.TeacherID)
+	if err != nil {
+		return err
+	}
+	notification := models.Notification{
+		UserID:  escrow.TeacherID,
+		Kind:    models.NotificationSessionUpdate,
+		Title:   "Payment released",
+		Message: fmt.Sprintf("%d SkillPoints for %s on %s", escrow.Amount, name, FormatSessionTime(start, loc)),
+		Link:    "/transactions",
+	}
+	return repositories.NewNotificationRepository(dbTx).CreateNotification(&notification)
+}
+
+// escrowSubject returns the name, payment note and start time of the session
+// or workshop an escrow pays for
+func escrowSubject(dbTx *gorm.DB, escrow *models.Escrow) (string, string, time.Time, error) {
+	if escrow.SeatID != 0 {
+		workshopRepo := repositories.NewWorkshopRepository(dbTx)
+		seat, err := workshopRepo.GetSeatByID(escrow.SeatID)
+		if err != nil {
+			return "", "", time.Time{}, err
+		}
+		workshop, err := workshopRepo.GetWorkshopByID(seat.WorkshopID)
+		if err != nil {
+			return "", "", time.Time{}, err
+		}
+		return workshop.Title, workshopPaymentNote(workshop), workshop.StartTime, nil
+	}
+
+	schedule, err := repositories.NewScheduleRepository(dbTx).GetScheduleByID(escrow.ScheduleID)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	skills, err := repositories.NewSkillRepository(dbTx).GetSkillsByIDs([]uint{schedule.SkillID})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	skill := skills[schedule.SkillID]
+	return skillName(skill), sessionPaymentNote(skill), schedule.StartTime, nil
+}
+
+// Balance returns a user's spendable and held SkillPoints, the spendable
+// ones read from their ledger account
+func (s *EscrowService) Balance(userID uint) (Balance, error) {
+	if _, err := repositories.NewUserRepository(s.DB).GetUserByID(userID); err != nil {
+		return Balance{}, err
+	}
+	available, err := repositories.NewLedgerRepository(s.DB).Balance(models.LedgerUser, userID)
+	if err != nil {
+		return Balance{}, err
+	}
+	held, pending, err := repositories.NewEscrowRepository(s.DB).HeldPoints(userID)
+	if err != nil {
+		return Balance{}, err
+	}
+	return Balance{Available: available, Held: held, Pending: pending}, nil
+}
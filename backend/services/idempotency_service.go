@@ -0,0 +1,37 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyPurger deletes idempotency keys once their responses are no
+// longer kept for retries
+type IdempotencyKeyPurger struct {
+	DB *gorm.DB
+}
+
+// NewIdempotencyKeyPurger creates a purger of expired idempotency keys
+func NewIdempotencyKeyPurger(db *gorm.DB) *IdempotencyKeyPurger {
+	return &IdempotencyKeyPurger{DB: db}
+}
+
+// Run deletes expired keys every interval until stop is closed
+func (p *IdempotencyKeyPurger) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := repositories.NewIdempotencyRepository(p.DB).DeleteExpired(time.Now()); err != nil {
+				utils.Error("Failed to delete expired idempotency keys: " + err.Error())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer writes email to the log instead of sending it, for development
+// and deployments without an SMTP server.
+type LogMailer struct{}
+
+// Send logs the email
+func (LogMailer) Send(to, subject, body string) error {
+	utils.Info(fmt.Sprintf("Email to %s: %s\n%s", to, subject, body))
+	return nil
+}
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the email, authenticating when a username is configured
+func (m SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// Header values must not carry line breaks, or they could inject headers
+	clean := strings.NewReplacer("\r", " ", "\n", " ")
+	msg := "From: " + clean.Replace(m.From) + "\r\n" +
+		"To: " + clean.Replace(to) + "\r\n" +
+		"Subject: " + clean.Replace(subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,559 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// BM25 parameters: k1 controls term frequency saturation, b length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field weights, mirroring the A and B weights of the Postgres search columns
+const (
+	titleWeight = 2.0
+	bodyWeight  = 1.0
+)
+
+// fuzzyTermWeight scales typo matches so they rank below exact ones
+const fuzzyTermWeight = 0.5
+
+// memorySnapshotVersion is bumped whenever the snapshot format changes
+const memorySnapshotVersion = 2
+
+// memorySnippetLength is the approximate length of snippets built by the index
+const memorySnippetLength = 200
+
+// rebuildBatchSize is the number of rows loaded at a time while rebuilding
+const rebuildBatchSize = 500
+
+// indexedDocument is a document with its weighted term frequencies
+type indexedDocument struct {
+	SearchDocument
+	terms  map[string]float64
+	length float64
+}
+
+// memoryIndexState holds the inverted index. It is replaced wholesale on rebuild.
+type memoryIndexState struct {
+	docs        map[string]*indexedDocument    // keyed by type:id
+	postings    map[string]map[string]float64  // term to document key to weighted frequency
+	totalLength map[string]float64             // sum of document lengths per type
+	counts      map[string]int                 // documents per type
+	terms       map[string]map[string]struct{} // vocabulary per type, for typo expansion
+}
+
+// MemorySearchIndex is an in-process inverted index scored with BM25, for
+// deployments whose database lacks Postgres full-text search or pg_trgm.
+// Terms are stemmed, so "teaching" matches "teaches", and query terms missing
+// from the index are expanded to close trigram matches so typos still find results.
+type MemorySearchIndex struct {
+	mu    sync.RWMutex
+	state *memoryIndexState
+	dirty bool
+
+	// While a rebuild runs, changes are also queued here and replayed onto
+	// the rebuilt state, since the rebuild may have read the rows before them.
+	rebuilding bool
+	pending    []SearchDocument // documents to index, or to remove when Data is nil
+}
+
+// memorySnapshot is the on-disk form of the index
+type memorySnapshot struct {
+	Version   int              `json:"version"`
+	SavedAt   time.Time        `json:"saved_at"`
+	Documents []SearchDocument `json:"documents"`
+}
+
+// NewMemorySearchIndex creates an empty in-memory search index
+func NewMemorySearchIndex() *MemorySearchIndex {
+	return &MemorySearchIndex{state: newMemoryIndexState()}
+}
+
+func newMemoryIndexState() *memoryIndexState {
+	return &memoryIndexState{
+		docs:        make(map[string]*indexedDocument),
+		postings:    make(map[string]map[string]float64),
+		totalLength: make(map[string]float64),
+		counts:      make(map[string]int),
+		terms:       make(map[string]map[string]struct{}),
+	}
+}
+
+// documentKey identifies a document across types
+func documentKey(searchType string, id uint) string {
+	return searchType + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// Len returns the number of indexed documents
+func (idx *MemorySearchIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.state.docs)
+}
+
+// Index adds or replaces a document
+func (idx *MemorySearchIndex) Index(doc SearchDocument) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.state.remove(documentKey(doc.Type, doc.ID))
+	idx.state.add(doc)
+	idx.dirty = true
+	if idx.rebuilding {
+		idx.pending = append(idx.pending, doc)
+	}
+	return nil
+}
+
+// Remove drops a document; removing a document that is not indexed does nothing
+func (idx *MemorySearchIndex) Remove(searchType string, id uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.state.remove(documentKey(searchType, id)) {
+		idx.dirty = true
+	}
+	if idx.rebuilding {
+		idx.pending = append(idx.pending, SearchDocument{Type: searchType, ID: id})
+	}
+	return nil
+}
+
+// add indexes a document that is not yet in the state
+func (s *memoryIndexState) add(doc SearchDocument) {
+	key := documentKey(doc.Type, doc.ID)
+	indexed := &indexedDocument{SearchDocument: doc, terms: make(map[string]float64)}
+
+	addField := func(text string, weight float64) {
+		for _, term := range utils.Tokenize(text) {
+			indexed.terms[term] += weight
+			indexed.length += weight
+		}
+	}
+	addField(doc.Title, titleWeight)
+	for _, keyword := range doc.Keywords {
+		addField(keyword, titleWeight)
+	}
+	for _, body := range doc.Body {
+		addField(body, bodyWeight)
+	}
+
+	vocabulary := s.terms[doc.Type]
+	if vocabulary == nil {
+		vocabulary = make(map[string]struct{})
+		s.terms[doc.Type] = vocabulary
+	}
+	for term, frequency := range indexed.terms {
+		postings := s.postings[term]
+		if postings == nil {
+			postings = make(map[string]float64)
+			s.postings[term] = postings
+		}
+		postings[key] = frequency
+		vocabulary[term] = struct{}{}
+	}
+
+	s.docs[key] = indexed
+	s.counts[doc.Type]++
+	s.totalLength[doc.Type] += indexed.length
+}
+
+// remove drops a document from the state and reports whether it was present
+func (s *memoryIndexState) remove(key string) bool {
+	indexed, ok := s.docs[key]
+	if !ok {
+		return false
+	}
+
+	for term := range indexed.terms {
+		postings := s.postings[term]
+		delete(postings, key)
+		if len(postings) == 0 {
+			delete(s.postings, term)
+		}
+		if !s.typeHasTerm(indexed.Type, term) {
+			delete(s.terms[indexed.Type], term)
+		}
+	}
+
+	delete(s.docs, key)
+	s.counts[indexed.Type]--
+	s.totalLength[indexed.Type] -= indexed.length
+	return true
+}
+
+// typeHasTerm reports whether any document of the type still contains the term
+func (s *memoryIndexState) typeHasTerm(searchType, term string) bool {
+	prefix := searchType + ":"
+	for key := range s.postings[term] {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scoredDocument is a document matching a search, with its BM25 score
+type scoredDocument struct {
+	*indexedDocument
+	score float64
+}
+
+// Search returns one page of the documents of the type matching the query, scored with BM25
+func (idx *MemorySearchIndex) Search(searchType, text string, opts IndexOptions) ([]SearchHit, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scored, highlightTerms := idx.state.score(searchType, text, opts)
+
+	page := make([]scoredDocument, 0, len(scored))
+	for _, doc := range scored {
+		if opts.After.includes(doc.score, doc.ID) {
+			page = append(page, doc)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool {
+		if page[i].score != page[j].score {
+			return page[i].score > page[j].score
+		}
+		return page[i].ID < page[j].ID
+	})
+	if opts.Limit > 0 && len(page) > opts.Limit {
+		page = page[:opts.Limit]
+	}
+
+	hits := make([]SearchHit, 0, len(page))
+	for _, doc := range page {
+		snippet := ""
+		if body := strings.TrimSpace(strings.Join(doc.Body, " ")); body != "" {
+			snippet = utils.Highlight(body, highlightTerms, memorySnippetLength)
+		}
+
+		hits = append(hits, SearchHit{
+			Type:    doc.Type,
+			ID:      strconv.FormatUint(uint64(doc.ID), 10),
+			Title:   doc.Title,
+			Snippet: snippet,
+			Rank:    doc.score,
+			Data:    doc.Data,
+			Facets:  doc.Facets,
+		})
+	}
+	return hits, nil
+}
+
+// Count counts the documents of the type matching the query and their facet values
+func (idx *MemorySearchIndex) Count(searchType, text string, opts IndexOptions) (SearchCount, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scored, _ := idx.state.score(searchType, text, opts)
+	count := SearchCount{Total: len(scored), Facets: make(map[string]map[string]int)}
+	for _, doc := range scored {
+		for name, value := range doc.Facets {
+			if count.Facets[name] == nil {
+				count.Facets[name] = make(map[string]int)
+			}
+			count.Facets[name][value]++
+		}
+	}
+	return count, nil
+}
+
+// score scores every document of the type against the query with BM25 and
+// returns those matching it and the options, unordered, with the index terms
+// to highlight in their snippets.
+func (s *memoryIndexState) score(searchType, text string, opts IndexOptions) ([]scoredDocument, []string) {
+	queryTerms := utils.Tokenize(text)
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+
+	n := float64(s.counts[searchType])
+	if n == 0 {
+		return nil, nil
+	}
+	avgLength := s.totalLength[searchType] / n
+	if avgLength == 0 {
+		avgLength = 1
+	}
+	prefix := searchType + ":"
+
+	scores := make(map[string]float64)
+	highlightTerms := utils.SearchTerms(text)
+	for _, queryTerm := range queryTerms {
+		for term, weight := range s.expandTerm(searchType, queryTerm) {
+			postings := s.postings[term]
+
+			df := 0.0
+			for key := range postings {
+				if strings.HasPrefix(key, prefix) {
+					df++
+				}
+			}
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+			for key, tf := range postings {
+				if !strings.HasPrefix(key, prefix) {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*s.docs[key].length/avgLength
+				scores[key] += weight * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			}
+			// Stems and typo expansions highlight the words they matched
+			highlightTerms = append(highlightTerms, term)
+		}
+	}
+
+	scored := make([]scoredDocument, 0, len(scores))
+	for key, score := range scores {
+		doc := s.docs[key]
+		if !opts.CreatedAfter.IsZero() && !doc.CreatedAt.After(opts.CreatedAfter) {
+			continue
+		}
+		if doc.MembersOnly && !opts.Authenticated {
+			continue
+		}
+		if !facetsMatch(doc.Facets, opts.Filters) {
+			continue
+		}
+		scored = append(scored, scoredDocument{indexedDocument: doc, score: score})
+	}
+	return scored, highlightTerms
+}
+
+// expandTerm returns the index terms a query term matches, with their weights.
+// A term present in the index matches itself; otherwise it matches close
+// trigram neighbours, weighted by similarity so typo matches rank lower.
+func (s *memoryIndexState) expandTerm(searchType, queryTerm string) map[string]float64 {
+	vocabulary := s.terms[searchType]
+	if _, ok := vocabulary[queryTerm]; ok {
+		return map[string]float64{queryTerm: 1}
+	}
+
+	expanded := make(map[string]float64)
+	for term := range vocabulary {
+		if similarity := utils.TrigramSimilarity(queryTerm, term); similarity >= utils.FuzzyThreshold {
+			expanded[term] = similarity * fuzzyTermWeight
+		}
+	}
+	return expanded
+}
+
+// Suggest completes a prefix from the titles of one type
+func (idx *MemorySearchIndex) Suggest(searchType, prefix string, limit int, opts IndexOptions) ([]SearchSuggestion, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var suggestions []SearchSuggestion
+	for _, doc := range idx.state.docs {
+		if doc.Type != searchType || (doc.MembersOnly && !opts.Authenticated) {
+			continue
+		}
+		if score := utils.SuggestionScore(prefix, doc.Title); score > 0 {
+			suggestions = append(suggestions, SearchSuggestion{
+				Type:  doc.Type,
+				ID:    doc.ID,
+				Text:  doc.Title,
+				Score: score,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].ID < suggestions[j].ID
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// SaveSnapshot writes the index to path. The file is replaced atomically so
+// a crash mid-write never leaves a truncated snapshot behind.
+func (idx *MemorySearchIndex) SaveSnapshot(path string) error {
+	idx.mu.RLock()
+	snapshot := memorySnapshot{
+		Version:   memorySnapshotVersion,
+		SavedAt:   time.Now(),
+		Documents: make([]SearchDocument, 0, len(idx.state.docs)),
+	}
+	for _, doc := range idx.state.docs {
+		snapshot.Documents = append(snapshot.Documents, doc.SearchDocument)
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(snapshot.Documents, func(i, j int) bool {
+		if snapshot.Documents[i].Type != snapshot.Documents[j].Type {
+			return snapshot.Documents[i].Type < snapshot.Documents[j].Type
+		}
+		return snapshot.Documents[i].ID < snapshot.Documents[j].ID
+	})
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(snapshot); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot replaces the index with the documents saved at path
+func (idx *MemorySearchIndex) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("decode search snapshot: %w", err)
+	}
+	if snapshot.Version != memorySnapshotVersion {
+		return fmt.Errorf("unsupported search snapshot version %d", snapshot.Version)
+	}
+
+	state := newMemoryIndexState()
+	for _, doc := range snapshot.Documents {
+		state.add(doc)
+	}
+
+	idx.mu.Lock()
+	idx.state = state
+	idx.dirty = false
+	idx.mu.Unlock()
+	return nil
+}
+
+// Rebuild reindexes every user, skill and job from the database. The new index
+// is built on the side and swapped in, so searches keep working meanwhile.
+func (idx *MemorySearchIndex) Rebuild(db *gorm.DB) error {
+	idx.mu.Lock()
+	idx.rebuilding = true
+	idx.pending = nil
+	idx.mu.Unlock()
+
+	state, err := buildMemoryIndexState(db)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err == nil {
+		for _, doc := range idx.pending {
+			state.remove(documentKey(doc.Type, doc.ID))
+			if doc.Data != nil {
+				state.add(doc)
+			}
+		}
+		idx.state = state
+		idx.dirty = true
+	}
+	idx.rebuilding = false
+	idx.pending = nil
+	return err
+}
+
+// buildMemoryIndexState reads every user, skill and job into a new index state
+func buildMemoryIndexState(db *gorm.DB) (*memoryIndexState, error) {
+	state := newMemoryIndexState()
+
+	var users []models.User
+	err := db.FindInBatches(&users, rebuildBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			if user.Searchable {
+				state.add(UserDocument(user))
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var skills []models.Skill
+	err = db.FindInBatches(&skills, rebuildBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, skill := range skills {
+			state.add(SkillDocument(skill))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []models.Job
+	err = db.FindInBatches(&jobs, rebuildBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, job := range jobs {
+			state.add(JobDocument(job))
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// RunSnapshots saves the index to path every interval while it has unsaved
+// changes, until stop is closed. A final snapshot is written on stop.
+func (idx *MemorySearchIndex) RunSnapshots(path string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	save := func() {
+		idx.mu.Lock()
+		dirty := idx.dirty
+		idx.dirty = false
+		idx.mu.Unlock()
+		if !dirty {
+			return
+		}
+
+		if err := idx.SaveSnapshot(path); err != nil {
+			log.Printf("Failed to save search index snapshot: %v", err)
+			idx.mu.Lock()
+			idx.dirty = true
+			idx.mu.Unlock()
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			save()
+		case <-stop:
+			save()
+			return
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package services_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+)
+
+func newTestIndex(t *testing.T) *services.MemorySearchIndex {
+	t.Helper()
+	index := services.NewMemorySearchIndex()
+	docs := []services.SearchDocument{
+		services.SkillDocument(models.Skill{ID: 1, Name: "Guitar", Description: "Teaching acoustic guitar to beginners", Category: "Music"}),
+		services.SkillDocument(models.Skill{ID: 2, Name: "Python Programming", Description: "Learn Python from scratch", Category: "Technology"}),
+		services.SkillDocument(models.Skill{ID: 3, Name: "Cooking", Description: "Italian cuisine and a little guitar while the pasta boils", Category: "Food"}),
+		services.UserDocument(models.User{ID: 1, Name: "Alice Smith", Bio: "Guitar teacher"}),
+		services.JobDocument(models.Job{ID: 1, Title: "Backend Developer", SkillsRequired: models.StringArray{"Go", "Python"}, Location: "Remote", JobType: "Full-time"}),
+	}
+	for _, doc := range docs {
+		if err := index.Index(doc); err != nil {
+			t.Fatalf("Index failed: %v", err)
+		}
+	}
+	return index
+}
+
+func TestMemorySearchIndexRanksTitleMatchesFirst(t *testing.T) {
+	index := newTestIndex(t)
+
+	hits, err := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %d", len(hits))
+	}
+
+	response, _ := services.BuildSearchResponse(services.SearchQuery{Text: "guitar"}, hits)
+	if response.Hits[0].ID != "1" {
+		t.Errorf("Expected the Guitar skill to rank first, got %s", response.Hits[0].Title)
+	}
+	if response.Hits[0].Facets[services.FacetCategory] != "Music" {
+		t.Errorf("Expected category facet Music, got %q", response.Hits[0].Facets[services.FacetCategory])
+	}
+}
+
+func TestMemorySearchIndexStemming(t *testing.T) {
+	index := newTestIndex(t)
+
+	hits, err := index.Search(services.SearchTypeSkill, "teaches beginner", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != "1" {
+		t.Fatalf("Expected the Guitar skill, got %v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "<mark>") {
+		t.Errorf("Expected a highlighted snippet, got %q", hits[0].Snippet)
+	}
+}
+
+func TestMemorySearchIndexTypoTolerance(t *testing.T) {
+	index := newTestIndex(t)
+
+	hits, err := index.Search(services.SearchTypeSkill, "pyhton", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != "2" {
+		t.Fatalf("Expected the Python skill, got %v", hits)
+	}
+}
+
+func TestMemorySearchIndexKeywords(t *testing.T) {
+	index := newTestIndex(t)
+
+	hits, err := index.Search(services.SearchTypeJob, "python", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Title != "Backend Developer" {
+		t.Fatalf("Expected the job requiring Python, got %v", hits)
+	}
+}
+
+func TestMemorySearchIndexUpdates(t *testing.T) {
+	index := newTestIndex(t)
+
+	// Renaming a skill replaces its terms
+	index.Index(services.SkillDocument(models.Skill{ID: 2, Name: "Rust Programming", Description: "Systems programming"}))
+	if hits, _ := index.Search(services.SearchTypeSkill, "python", services.IndexOptions{}); len(hits) != 0 {
+		t.Errorf("Expected no hits for the old name, got %d", len(hits))
+	}
+	if hits, _ := index.Search(services.SearchTypeSkill, "rust", services.IndexOptions{}); len(hits) != 1 {
+		t.Errorf("Expected 1 hit for the new name, got %d", len(hits))
+	}
+
+	index.Remove(services.SearchTypeSkill, 1)
+	hits, _ := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{})
+	if len(hits) != 1 || hits[0].ID != "3" {
+		t.Errorf("Expected only the Cooking skill after removal, got %v", hits)
+	}
+	if index.Len() != 4 {
+		t.Errorf("Expected 4 documents, got %d", index.Len())
+	}
+}
+
+func TestMemorySearchIndexSuggest(t *testing.T) {
+	index := newTestIndex(t)
+
+	suggestions, err := index.Suggest(services.SearchTypeSkill, "gui", 5, services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Text != "Guitar" {
+		t.Errorf("Expected Guitar, got %v", suggestions)
+	}
+}
+
+func TestMemorySearchIndexSnapshot(t *testing.T) {
+	index := newTestIndex(t)
+	path := filepath.Join(t.TempDir(), "index", "search.json")
+
+	if err := index.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	restored := services.NewMemorySearchIndex()
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.Len() != index.Len() {
+		t.Fatalf("Expected %d documents, got %d", index.Len(), restored.Len())
+	}
+
+	hits, _ := restored.Search(services.SearchTypeUser, "guitar", services.IndexOptions{})
+	if len(hits) != 1 || hits[0].Title != "Alice Smith" {
+		t.Errorf("Expected Alice Smith after restore, got %v", hits)
+	}
+
+	if err := restored.LoadSnapshot(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error loading a missing snapshot")
+	}
+}
+
+func TestSearchServiceUsesIndex(t *testing.T) {
+	service := services.NewSearchService(newTestIndex(t))
+	service.UploadDir = t.TempDir()
+
+	response, err := service.Search(services.SearchQuery{Text: "guitar"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if response.Counts[services.SearchTypeSkill] != 2 || response.Counts[services.SearchTypeUser] != 1 {
+		t.Errorf("Unexpected counts: %v", response.Counts)
+	}
+}
+
+func TestMemorySearchIndexCreatedAfter(t *testing.T) {
+	index := services.NewMemorySearchIndex()
+	lastRun := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	index.Index(services.SkillDocument(models.Skill{ID: 1, Name: "Guitar", CreatedAt: lastRun.Add(-time.Hour)}))
+	index.Index(services.SkillDocument(models.Skill{ID: 2, Name: "Jazz Guitar", CreatedAt: lastRun.Add(time.Hour)}))
+
+	hits, err := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{CreatedAfter: lastRun})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != "2" {
+		t.Errorf("Expected only the skill created after the last run, got %v", hits)
+	}
+}
+
+func TestMemorySearchIndexMembersOnlyProfiles(t *testing.T) {
+	index := services.NewMemorySearchIndex()
+	index.Index(services.UserDocument(models.User{ID: 1, Name: "Alice Smith", Email: "alice@example.com", ProfileVisibility: models.ProfileMembers}))
+
+	if hits, _ := index.Search(services.SearchTypeUser, "alice", services.IndexOptions{}); len(hits) != 0 {
+		t.Errorf("Expected members-only profile to be hidden from anonymous searches, got %v", hits)
+	}
+
+	hits, _ := index.Search(services.SearchTypeUser, "alice", services.IndexOptions{Authenticated: true})
+	if len(hits) != 1 {
+		t.Fatalf("Expected 1 hit for a logged-in search, got %d", len(hits))
+	}
+	if strings.Contains(string(hits[0].Data.(json.RawMessage)), "alice@example.com") {
+		t.Errorf("Expected hidden email to be left out of the hit, got %s", hits[0].Data)
+	}
+	if hits, _ := index.Search(services.SearchTypeUser, "example", services.IndexOptions{Authenticated: true}); len(hits) != 0 {
+		t.Errorf("Expected email not to be searchable, got %v", hits)
+	}
+}
+
+func TestMemorySearchIndexHiddenEmail(t *testing.T) {
+	index := services.NewMemorySearchIndex()
+	index.Index(services.UserDocument(models.User{ID: 1, Name: "Bob Jones", Bio: "Chess coach", Email: "zephyrquill@lanternbox.org"}))
+
+	for _, text := range []string{"zephyrquill@lanternbox.org", "zephyrquill", "lanternbox"} {
+		hits, err := index.Search(services.SearchTypeUser, text, services.IndexOptions{Authenticated: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(hits) != 0 {
+			t.Errorf("Expected hidden email not to match %q, got %v", text, hits)
+		}
+	}
+
+	hits, _ := index.Search(services.SearchTypeUser, "chess", services.IndexOptions{Authenticated: true})
+	if len(hits) != 1 {
+		t.Fatalf("Expected 1 hit for the bio, got %d", len(hits))
+	}
+	if strings.Contains(hits[0].Snippet, "zephyrquill") || strings.Contains(string(hits[0].Data.(json.RawMessage)), "zephyrquill") {
+		t.Errorf("Expected hidden email to be left out of the hit, got %v", hits[0])
+	}
+}
+
+func TestMemorySearchIndexPages(t *testing.T) {
+	index := newTestIndex(t)
+
+	all, _ := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{})
+	first, _ := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{Limit: 1})
+	if len(first) != 1 || first[0].ID != all[0].ID {
+		t.Fatalf("Expected the first page to hold the best hit, got %v", first)
+	}
+
+	after := &services.SearchPosition{Rank: first[0].Rank, ID: 1}
+	rest, _ := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{After: after, Limit: 10})
+	if len(rest) != 1 || rest[0].ID != all[1].ID {
+		t.Errorf("Expected the second page to hold the remaining hit, got %v", rest)
+	}
+}
+
+func TestMemorySearchIndexCount(t *testing.T) {
+	index := newTestIndex(t)
+
+	count, err := index.Count(services.SearchTypeSkill, "guitar", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count.Total != 2 || count.Facets[services.FacetCategory]["Music"] != 1 || count.Facets[services.FacetCategory]["Food"] != 1 {
+		t.Errorf("Unexpected count: %+v", count)
+	}
+
+	filters := map[string]string{services.FacetCategory: "music"}
+	count, _ = index.Count(services.SearchTypeSkill, "guitar", services.IndexOptions{Filters: filters})
+	hits, _ := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{Filters: filters})
+	if count.Total != 1 || len(hits) != 1 || hits[0].ID != "1" {
+		t.Errorf("Expected only the Music skill to match the filter, got %+v and %v", count, hits)
+	}
+}
+
+func TestSearchServicePagesWithCursor(t *testing.T) {
+	// Identical documents rank the same within and across types, so every
+	// page boundary falls on a tie
+	index := services.NewMemorySearchIndex()
+	for _, id := range []uint{2, 10, 3} {
+		index.Index(services.UserDocument(models.User{ID: id, Name: "Guitar lessons"}))
+		index.Index(services.SkillDocument(models.Skill{ID: id, Name: "Guitar lessons"}))
+		index.Index(services.JobDocument(models.Job{ID: id, Title: "Guitar lessons"}))
+	}
+	service := services.NewSearchService(index)
+	service.UploadDir = t.TempDir()
+
+	var got []string
+	query := services.SearchQuery{Text: "guitar", Limit: 2}
+	for page := 0; page < 10; page++ {
+		response, err := service.Search(query)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if response.Total != 9 {
+			t.Errorf("Expected every page to count 9 hits, got %d", response.Total)
+		}
+		for _, hit := range response.Hits {
+			got = append(got, hit.Type+":"+hit.ID)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		query.Cursor = response.NextCursor
+	}
+
+	want := []string{"job:2", "job:3", "job:10", "skill:2", "skill:3", "skill:10", "user:2", "user:3", "user:10"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected pages to hold %v, got %v", want, got)
+	}
+}
+
+func TestMemorySearchIndexSnippetOfTextChangingLengthWhenLowercased(t *testing.T) {
+	index := services.NewMemorySearchIndex()
+	index.Index(services.SkillDocument(models.Skill{ID: 1, Name: "Guitar", Description: "ẞẞẞẞẞẞẞẞẞẞ guitar lessons"}))
+
+	hits, err := index.Search(services.SearchTypeSkill, "guitar", services.IndexOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Snippet != "ẞẞẞẞẞẞẞẞẞẞ <mark>guitar</mark> lessons" {
+		t.Errorf("Expected the match highlighted in the snippet, got %v", hits)
+	}
+}
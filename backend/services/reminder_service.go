@@ -0,0 +1,261 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// reminderBatchSize is the number of due reminders claimed at a time
+const reminderBatchSize = 50
+
+// ReminderScheduler reminds participants of their confirmed sessions in-app and
+// by email at fixed lead times before they start. Reminders are queued in the
+// database, so they survive restarts, and claimed with SKIP LOCKED, so every
+// server may run a scheduler and each reminder is still sent once.
+type ReminderScheduler struct {
+	DB        *gorm.DB
+	Mailer    Mailer
+	LeadTimes []time.Duration // longest first
+}
+
+// NewReminderScheduler creates a scheduler sending reminders at the given lead
+// times, rounded to whole minutes
+func NewReminderScheduler(db *gorm.DB, mailer Mailer, leadTimes []time.Duration) *ReminderScheduler {
+	leads := make([]time.Duration, 0, len(leadTimes))
+	for _, lead := range leadTimes {
+		if lead = lead.Round(time.Minute); lead > 0 {
+			leads = append(leads, lead)
+		}
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i] > leads[j] })
+	return &ReminderScheduler{DB: db, Mailer: mailer, LeadTimes: leads}
+}
+
+// Run queues and sends reminders every interval until stop is closed
+func (s *ReminderScheduler) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			if _, err := s.QueueReminders(now); err != nil {
+				utils.Error("Failed to queue session reminders: " + err.Error())
+			}
+			if _, err := s.SendDueReminders(now); err != nil {
+				utils.Error("Failed to send session reminders: " + err.Error())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// QueueReminders queues reminders for both participants of the confirmed
+// sessions starting within the longest lead time after now, and returns how
+// many were new. Reminders already queued for a session's start are kept.
+func (s *ReminderScheduler) QueueReminders(now time.Time) (int, error) {
+	if len(s.LeadTimes) == 0 {
+		return 0, nil
+	}
+
+	schedules, err := repositories.NewScheduleRepository(s.DB).GetConfirmedStartingBetween(now, now.Add(s.LeadTimes[0]))
+	if err != nil {
+		return 0, err
+	}
+
+	var reminders []models.SessionReminder
+	for _, schedule := range schedules {
+		for _, userID := range []uint{schedule.LearnerID, schedule.TeacherID} {
+			if userID == 0 {
+				continue
+			}
+			for _, lead := range s.LeadTimes {
+				reminders = append(reminders, models.SessionReminder{
+					ScheduleID:  schedule.ID,
+					UserID:      userID,
+					LeadMinutes: int(lead / time.Minute),
+					StartTime:   schedule.StartTime,
+					SendAt:      schedule.StartTime.Add(-lead),
+					Status:      models.ReminderPending,
+				})
+			}
+		}
+	}
+
+	queued, err := repositories.NewReminderRepository(s.DB).QueueReminders(reminders)
+	return int(queued), err
+}
+
+// reminderEmail is a reminder email to send once its reminder is marked sent
+type reminderEmail struct {
+	reminderID uint
+	userID     uint
+	to         string
+	subject    string
+	body       string
+}
+
+// SendDueReminders sends the reminders due by now and returns how many were
+// sent. Each batch is claimed, notified in-app and marked in one transaction,
+// and its emails are sent once that commits, so a server stopping mid-batch
+// leaves its reminders to be claimed again without having emailed anyone.
+func (s *ReminderScheduler) SendDueReminders(now time.Time) (int, error) {
+	sent := 0
+	for {
+		var (
+			claimed, delivered int
+			emails             []reminderEmail
+		)
+		err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+			delivered, emails = 0, nil
+			repo := repositories.NewReminderRepository(dbTx)
+			due, err := repo.ClaimDueReminders(now, reminderBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(due)
+
+			for i := range due {
+				status, email, err := s.deliver(dbTx, &due[i], now)
+				if err != nil {
+					return err
+				}
+				if status == models.ReminderSent {
+					delivered++
+				}
+				if email != nil {
+					emails = append(emails, *email)
+				}
+				if err := repo.MarkReminder(due[i].ID, status, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return sent, err
+		}
+
+		for _, email := range emails {
+			// The in-app notification still reaches the user, and a late email is of little use
+			if err := s.Mailer.Send(email.to, email.subject, email.body); err != nil {
+				utils.Error(fmt.Sprintf("Failed to email reminder %d to user %d: %v", email.reminderID, email.userID, err))
+			}
+		}
+		sent += delivered
+		if claimed < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver notifies a participant of one due reminder in-app and returns
+// whether it was sent or skipped, with the email to send when they want one.
+// Reminders for sessions no longer confirmed at the reminded time, those a
+// shorter reminder has caught up with, and those of users who opted out of
+// both channels are skipped.
+func (s *ReminderScheduler) deliver(dbTx *gorm.DB, reminder *models.SessionReminder, now time.Time) (string, *reminderEmail, error) {
+	if s.Superseded(reminder, now) {
+		return models.ReminderSkipped, nil, nil
+	}
+
+	schedule, err := repositories.NewScheduleRepository(dbTx).GetScheduleByID(reminder.ScheduleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ReminderSkipped, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if schedule.Status != models.StatusConfirmed || !schedule.StartTime.Equal(reminder.StartTime) || !schedule.StartTime.After(now) {
+		return models.ReminderSkipped, nil, nil
+	}
+
+	user, err := repositories.NewUserRepository(dbTx).GetUserByID(reminder.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ReminderSkipped, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !user.RemindInApp && !user.RemindByEmail {
+		return models.ReminderSkipped, nil, nil
+	}
+
+	skills, err := repositories.NewSkillRepository(dbTx).GetSkillsByIDs([]uint{schedule.SkillID})
+	if err != nil {
+		return "", nil, err
+	}
+	title, message := reminderMessage(schedule, skills[schedule.SkillID], user, now)
+
+	if user.RemindInApp {
+		notification := models.Notification{
+			UserID:  user.ID,
+			Kind:    models.NotificationSessionReminder,
+			Title:   title,
+			Message: message,
+			Link:    "/schedule",
+		}
+		if err := repositories.NewNotificationRepository(dbTx).CreateNotification(&notification); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var email *reminderEmail
+	if user.RemindByEmail {
+		email = &reminderEmail{
+			reminderID: reminder.ID,
+			userID:     user.ID,
+			to:         user.Email,
+			subject:    title,
+			body:       fmt.Sprintf("Hi %s,\n\n%s.\n\nYou can turn off session reminders in your settings.\n", user.Name, message),
+		}
+	}
+	return models.ReminderSent, email, nil
+}
+
+// Superseded reports whether a shorter reminder for the same start is also
+// due, as after a late booking or an outage; only the latest one is sent.
+func (s *ReminderScheduler) Superseded(reminder *models.SessionReminder, now time.Time) bool {
+	for _, lead := range s.LeadTimes {
+		if lead < reminder.Lead() && !reminder.StartTime.Add(-lead).After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// reminderMessage writes a reminder's title and message for a participant, in their time zone
+func reminderMessage(schedule *models.Schedule, skill *models.Skill, user *models.User, now time.Time) (string, string) {
+	role := "learning"
+	if schedule.TeacherID == user.ID {
+		role = "teaching"
+	}
+	title := fmt.Sprintf("Session starts in %s", formatLead(schedule.StartTime.Sub(now)))
+	message := fmt.Sprintf("You're %s %s on %s", role, skillName(skill), FormatSessionTime(schedule.StartTime, user.Location()))
+	return title, message
+}
+
+// formatLead describes how long until a session starts, rounded to minutes or hours
+func formatLead(d time.Duration) string {
+	if d < time.Hour {
+		minutes := int((d + time.Minute/2) / time.Minute)
+		if minutes <= 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	hours := int((d + time.Hour/2) / time.Hour)
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
@@ -0,0 +1,48 @@
+package services_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+)
+
+func TestNewReminderSchedulerLeadTimes(t *testing.T) {
+	scheduler := services.NewReminderScheduler(nil, services.LogMailer{},
+		[]time.Duration{15 * time.Minute, 24*time.Hour + 10*time.Second, 0, -time.Hour})
+
+	want := []time.Duration{24 * time.Hour, 15 * time.Minute}
+	if !reflect.DeepEqual(scheduler.LeadTimes, want) {
+		t.Errorf("Expected lead times %v, got %v", want, scheduler.LeadTimes)
+	}
+}
+
+func TestReminderSuperseded(t *testing.T) {
+	scheduler := services.NewReminderScheduler(nil, services.LogMailer{}, []time.Duration{24 * time.Hour, 15 * time.Minute})
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	dayBefore := &models.SessionReminder{LeadMinutes: 24 * 60, StartTime: start}
+	quarterBefore := &models.SessionReminder{LeadMinutes: 15, StartTime: start}
+
+	tests := []struct {
+		name     string
+		reminder *models.SessionReminder
+		now      time.Time
+		want     bool
+	}{
+		{"day before on time", dayBefore, start.Add(-24 * time.Hour), false},
+		{"day before caught up by the shorter reminder", dayBefore, start.Add(-10 * time.Minute), true},
+		{"day before just before the shorter reminder", dayBefore, start.Add(-16 * time.Minute), false},
+		{"shortest is never superseded", quarterBefore, start.Add(-time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scheduler.Superseded(tt.reminder, tt.now); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
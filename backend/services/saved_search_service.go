@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// savedSearchBatchSize is the number of saved searches loaded at a time
+const savedSearchBatchSize = 100
+
+// alertOverlap widens each run's window back past the previous run, so records
+// committed late with an earlier creation time are still seen. Matches already
+// alerted are skipped by the notifications' unique index.
+const alertOverlap = time.Minute
+
+// SavedSearchAlerter evaluates saved searches against newly created records and
+// alerts their owners in-app, and by email digest when they asked for it.
+type SavedSearchAlerter struct {
+	DB     *gorm.DB
+	Search *SearchService
+	Mailer Mailer
+}
+
+// NewSavedSearchAlerter creates an alerter that runs searches through the given index
+func NewSavedSearchAlerter(db *gorm.DB, index SearchIndex, mailer Mailer) *SavedSearchAlerter {
+	return &SavedSearchAlerter{DB: db, Search: NewSearchService(index), Mailer: mailer}
+}
+
+// Run evaluates saved searches every alertInterval and sends digests every
+// digestInterval until stop is closed.
+func (a *SavedSearchAlerter) Run(alertInterval, digestInterval time.Duration, stop <-chan struct{}) {
+	alerts := time.NewTicker(alertInterval)
+	defer alerts.Stop()
+	digests := time.NewTicker(digestInterval)
+	defer digests.Stop()
+
+	for {
+		select {
+		case <-alerts.C:
+			if _, err := a.EvaluateSavedSearches(time.Now()); err != nil {
+				utils.Error("Failed to evaluate saved searches: " + err.Error())
+			}
+		case <-digests.C:
+			if err := a.SendDigests(time.Now()); err != nil {
+				utils.Error("Failed to send saved search digests: " + err.Error())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// EvaluateSavedSearches runs every saved search last run before now against the
+// records created since, and returns how many new matches were alerted.
+// A search that fails is logged and retried on the next run.
+func (a *SavedSearchAlerter) EvaluateSavedSearches(now time.Time) (int, error) {
+	repo := repositories.NewSavedSearchRepository(a.DB)
+	alerted := 0
+
+	var afterID uint
+	for {
+		due, err := repo.GetSavedSearchesDue(now, afterID, savedSearchBatchSize)
+		if err != nil {
+			return alerted, err
+		}
+
+		for _, search := range due {
+			afterID = search.ID
+			n, err := a.evaluate(search, now)
+			if err != nil {
+				utils.Error(fmt.Sprintf("Failed to evaluate saved search %d: %v", search.ID, err))
+				continue
+			}
+			alerted += n
+		}
+
+		if len(due) < savedSearchBatchSize {
+			return alerted, nil
+		}
+	}
+}
+
+// evaluate alerts the owner of one saved search about its new matches
+func (a *SavedSearchAlerter) evaluate(search models.SavedSearch, now time.Time) (int, error) {
+	query := SearchQuery{
+		Text:         search.Query,
+		Types:        search.Types,
+		Filters:      search.Filters,
+		Limit:        MaxSearchLimit,
+		CreatedAfter: search.LastRunAt.Add(-alertOverlap),
+		// The owner is logged in whenever they look at their alerts
+		Authenticated: true,
+	}
+
+	var notifications []models.Notification
+	for {
+		response, err := a.Search.Search(query)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, hit := range response.Hits {
+			if hit.Type == SearchTypeUser && hit.ID == strconv.FormatUint(uint64(search.UserID), 10) {
+				// People don't need alerts about themselves
+				continue
+			}
+			notifications = append(notifications, savedSearchNotification(search, hit))
+		}
+
+		if response.NextCursor == "" {
+			break
+		}
+		query.Cursor = response.NextCursor
+	}
+
+	created, err := repositories.NewNotificationRepository(a.DB).CreateNotificationsOnce(notifications)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := repositories.NewSavedSearchRepository(a.DB).MarkSavedSearchRun(search.ID, now); err != nil {
+		return 0, err
+	}
+	return int(created), nil
+}
+
+// savedSearchNotification builds the alert for one new match of a saved search
+func savedSearchNotification(search models.SavedSearch, hit SearchHit) models.Notification {
+	searchID := search.ID
+
+	link := "/search?q=" + url.QueryEscape(search.Query) + "&type=" + hit.Type
+	if hit.Type == SearchTypeJob {
+		link = "/jobs/" + hit.ID
+	}
+
+	return models.Notification{
+		UserID:        search.UserID,
+		Kind:          models.NotificationSavedSearchMatch,
+		Title:         fmt.Sprintf("New %s matching %q", hit.Type, search.Name),
+		Message:       hit.Title,
+		Link:          link,
+		SavedSearchID: &searchID,
+		ResultType:    hit.Type,
+		ResultID:      hit.ID,
+		EmailPending:  search.Alert == models.AlertEmail,
+	}
+}
+
+// SendDigests emails each user one summary of the alerts waiting for a digest.
+// A failed email is logged and its alerts stay pending for the next digest.
+func (a *SavedSearchAlerter) SendDigests(now time.Time) error {
+	notificationRepo := repositories.NewNotificationRepository(a.DB)
+	userRepo := repositories.NewUserRepository(a.DB)
+
+	userIDs, err := notificationRepo.GetUsersWithPendingEmail()
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		user, err := userRepo.GetUserByID(userID)
+		if err != nil {
+			utils.Error(fmt.Sprintf("Failed to load user %d for digest: %v", userID, err))
+			continue
+		}
+
+		pending, err := notificationRepo.GetPendingEmail(userID)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		subject, body := savedSearchDigest(user.Name, pending)
+		if err := a.Mailer.Send(user.Email, subject, body); err != nil {
+			utils.Error(fmt.Sprintf("Failed to email digest to user %d: %v", userID, err))
+			continue
+		}
+
+		ids := make([]uint, len(pending))
+		for i, notification := range pending {
+			ids[i] = notification.ID
+		}
+		if err := notificationRepo.MarkEmailed(ids, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savedSearchDigest writes the email summarizing a user's pending alerts
+func savedSearchDigest(name string, notifications []models.Notification) (string, string) {
+	subject := "1 new match for your saved searches"
+	if len(notifications) != 1 {
+		subject = fmt.Sprintf("%d new matches for your saved searches", len(notifications))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\nThese new results match your saved searches on SkillSwap:\n\n", name)
+	for _, notification := range notifications {
+		fmt.Fprintf(&b, "- %s: %s\n", notification.Title, notification.Message)
+	}
+	b.WriteString("\nYou can change how you're alerted from your saved searches.\n")
+	return subject, b.String()
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+// Search index backends
+const (
+	SearchBackendPostgres = "postgres"
+	SearchBackendMemory   = "memory"
+)
+
+// SearchIndex finds users, skills and jobs matching a query.
+// Videos are not indexed; the search service reads them from the upload directory.
+type SearchIndex interface {
+	// Search returns the documents of one type matching the text, ranked best
+	// first with ties broken by ID, after opts.After and up to opts.Limit
+	Search(searchType, text string, opts IndexOptions) ([]SearchHit, error)
+	// Count counts the documents of one type matching the text and their
+	// facet values. It ignores opts.After and opts.Limit.
+	Count(searchType, text string, opts IndexOptions) (SearchCount, error)
+	// Suggest returns up to limit completions of a prefix from titles of one type
+	Suggest(searchType, prefix string, limit int, opts IndexOptions) ([]SearchSuggestion, error)
+	// Index adds a document, replacing any earlier version with the same type and ID
+	Index(doc SearchDocument) error
+	// Remove drops a document from the index
+	Remove(searchType string, id uint) error
+}
+
+// IndexOptions narrow the documents a search index returns
+type IndexOptions struct {
+	CreatedAfter  time.Time // when set, only documents created after it match
+	Authenticated bool      // whether the searcher is logged in, as members-only profiles require
+
+	Filters map[string]string // facet name to required value, ignoring case
+	After   *SearchPosition   // when set, only documents ranked after it are returned
+	Limit   int               // when positive, at most Limit documents are returned
+}
+
+// SearchPosition is a place in the ranked documents of one type, ordered by
+// rank descending and then ID. Documents after it rank lower, or rank the
+// same with a greater ID.
+type SearchPosition struct {
+	Rank float64
+	ID   uint
+}
+
+// includes reports whether a document with the rank and ID comes after the position
+func (p *SearchPosition) includes(rank float64, id uint) bool {
+	return p == nil || rank < p.Rank || (rank == p.Rank && id > p.ID)
+}
+
+// SearchCount is the number of documents of one type matching a search, and
+// how many of them carry each facet value
+type SearchCount struct {
+	Total  int
+	Facets map[string]map[string]int // facet name to value to count
+}
+
+// typeFacets lists the facets each type's documents carry. Filtering on any
+// other facet matches none of them.
+var typeFacets = map[string][]string{
+	SearchTypeSkill: {FacetCategory},
+	SearchTypeJob:   {FacetExperienceLevel, FacetJobType, FacetLocation},
+}
+
+// supportsFilters reports whether documents of the type can match the filters
+func supportsFilters(searchType string, filters map[string]string) bool {
+	for name, value := range filters {
+		if value != "" && !containsFacet(typeFacets[searchType], name) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFacet reports whether facets includes name
+func containsFacet(facets []string, name string) bool {
+	for _, facet := range facets {
+		if facet == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchDocument is the searchable form of a user, skill or job
+type SearchDocument struct {
+	Type        string            `json:"type"`
+	ID          uint              `json:"id"`
+	Title       string            `json:"title"`
+	Keywords    []string          `json:"keywords,omitempty"` // weighted like the title but not displayed
+	Body        []string          `json:"body,omitempty"`     // weighted below the title and used for snippets
+	Facets      map[string]string `json:"facets,omitempty"`
+	Data        json.RawMessage   `json:"data"` // the public form of the model, as returned in search hits
+	CreatedAt   time.Time         `json:"created_at"`
+	MembersOnly bool              `json:"members_only,omitempty"` // only shown to logged-in searchers
+}
+
+// UserDocument builds the search document of a user. Users who opted out of
+// search should be removed from the index rather than indexed.
+func UserDocument(user models.User) SearchDocument {
+	return SearchDocument{
+		Type:        SearchTypeUser,
+		ID:          user.ID,
+		Title:       user.Name,
+		Body:        []string{user.Bio},
+		Data:        mustMarshal(user.PublicProfile()),
+		CreatedAt:   user.CreatedAt,
+		MembersOnly: !user.VisibleTo(false),
+	}
+}
+
+// SkillDocument builds the search document of a skill
+func SkillDocument(skill models.Skill) SearchDocument {
+	return SearchDocument{
+		Type:      SearchTypeSkill,
+		ID:        skill.ID,
+		Title:     skill.Name,
+		Body:      []string{skill.Description},
+		Facets:    map[string]string{FacetCategory: skill.Category},
+		Data:      mustMarshal(skill),
+		CreatedAt: skill.CreatedAt,
+	}
+}
+
+// JobDocument builds the search document of a job posting
+func JobDocument(job models.Job) SearchDocument {
+	return SearchDocument{
+		Type:     SearchTypeJob,
+		ID:       job.ID,
+		Title:    job.Title,
+		Keywords: job.SkillsRequired,
+		Body:     []string{job.Company + " " + job.Location, job.Description},
+		Facets: map[string]string{
+			FacetExperienceLevel: job.ExperienceLevel,
+			FacetJobType:         job.JobType,
+			FacetLocation:        job.Location,
+		},
+		Data:      mustMarshal(job),
+		CreatedAt: job.CreatedAt,
+	}
+}
+
+// mustMarshal encodes a model as JSON; the models here always encode
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// DatabaseSearchIndex searches the database directly. On Postgres it uses the
+// generated tsvector columns and pg_trgm; other databases are scored in Go.
+// The database is the index, so Index and Remove have nothing to do.
+type DatabaseSearchIndex struct {
+	DB *gorm.DB
+}
+
+// NewDatabaseSearchIndex creates a search index backed by the given database
+func NewDatabaseSearchIndex(db *gorm.DB) *DatabaseSearchIndex {
+	return &DatabaseSearchIndex{DB: db}
+}
+
+// Search runs one page of a ranked search over one type
+func (idx *DatabaseSearchIndex) Search(searchType, text string, opts IndexOptions) ([]SearchHit, error) {
+	var hits []SearchHit
+	if !supportsFilters(searchType, opts.Filters) {
+		return hits, nil
+	}
+
+	scopes := searchScopes(searchType, opts)
+	page := repositories.SearchPage{Limit: opts.Limit}
+	if opts.After != nil {
+		page.After = &repositories.SearchAfter{Rank: opts.After.Rank, ID: opts.After.ID}
+	}
+
+	switch searchType {
+	case SearchTypeUser:
+		users, err := repositories.NewUserRepository(idx.DB).SearchUsers(text, page, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			hits = append(hits, SearchHit{
+				Type:    SearchTypeUser,
+				ID:      strconv.FormatUint(uint64(user.ID), 10),
+				Title:   user.Name,
+				Snippet: user.Snippet,
+				Rank:    user.Rank,
+				Data:    user.PublicProfile(),
+			})
+		}
+
+	case SearchTypeSkill:
+		skills, err := repositories.NewSkillRepository(idx.DB).SearchSkills(text, page, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		for _, skill := range skills {
+			hits = append(hits, SearchHit{
+				Type:    SearchTypeSkill,
+				ID:      strconv.FormatUint(uint64(skill.ID), 10),
+				Title:   skill.Name,
+				Snippet: skill.Snippet,
+				Rank:    skill.Rank,
+				Data:    skill.Skill,
+				Facets:  map[string]string{FacetCategory: skill.Category},
+			})
+		}
+
+	case SearchTypeJob:
+		jobs, err := repositories.NewJobRepository(idx.DB).SearchJobs(text, page, scopes...)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs {
+			hits = append(hits, SearchHit{
+				Type:    SearchTypeJob,
+				ID:      strconv.FormatUint(uint64(job.ID), 10),
+				Title:   job.Title,
+				Snippet: job.Snippet,
+				Rank:    job.Rank,
+				Data:    job.Job,
+				Facets: map[string]string{
+					FacetExperienceLevel: job.ExperienceLevel,
+					FacetJobType:         job.JobType,
+					FacetLocation:        job.Location,
+				},
+			})
+		}
+	}
+
+	return hits, nil
+}
+
+// Count counts the matches of one type with aggregate queries. Facet names
+// are the names of the columns they are read from.
+func (idx *DatabaseSearchIndex) Count(searchType, text string, opts IndexOptions) (SearchCount, error) {
+	if !supportsFilters(searchType, opts.Filters) {
+		return SearchCount{}, nil
+	}
+
+	scopes := searchScopes(searchType, opts)
+	var counts repositories.SearchCounts
+	var err error
+	switch searchType {
+	case SearchTypeUser:
+		counts, err = repositories.NewUserRepository(idx.DB).CountUsers(text, scopes...)
+	case SearchTypeSkill:
+		counts, err = repositories.NewSkillRepository(idx.DB).CountSkills(text, scopes...)
+	case SearchTypeJob:
+		counts, err = repositories.NewJobRepository(idx.DB).CountJobs(text, scopes...)
+	}
+	if err != nil {
+		return SearchCount{}, err
+	}
+
+	count := SearchCount{Total: int(counts.Total), Facets: make(map[string]map[string]int)}
+	for name, values := range counts.Values {
+		count.Facets[name] = make(map[string]int, len(values))
+		for value, n := range values {
+			count.Facets[name][value] = int(n)
+		}
+	}
+	return count, nil
+}
+
+// searchScopes narrows the rows of one type searched in the database to the options
+func searchScopes(searchType string, opts IndexOptions) []func(*gorm.DB) *gorm.DB {
+	table := searchType + "s"
+
+	var scopes []func(*gorm.DB) *gorm.DB
+	if !opts.CreatedAfter.IsZero() {
+		scopes = append(scopes, repositories.CreatedAfter(table, opts.CreatedAfter))
+	}
+	if searchType == SearchTypeUser && !opts.Authenticated {
+		scopes = append(scopes, repositories.PublicProfilesOnly)
+	}
+	for name, value := range opts.Filters {
+		if value != "" {
+			scopes = append(scopes, repositories.ColumnEquals(table, name, value))
+		}
+	}
+	return scopes
+}
+
+// Suggest completes a prefix from skill names, job titles or user names
+func (idx *DatabaseSearchIndex) Suggest(searchType, prefix string, limit int, opts IndexOptions) ([]SearchSuggestion, error) {
+	var found []repositories.Suggestion
+	var err error
+
+	switch searchType {
+	case SearchTypeSkill:
+		found, err = repositories.NewSkillRepository(idx.DB).SuggestSkills(prefix, limit)
+	case SearchTypeJob:
+		found, err = repositories.NewJobRepository(idx.DB).SuggestJobs(prefix, limit)
+	case SearchTypeUser:
+		var scopes []func(*gorm.DB) *gorm.DB
+		if !opts.Authenticated {
+			scopes = append(scopes, repositories.PublicProfilesOnly)
+		}
+		found, err = repositories.NewUserRepository(idx.DB).SuggestUsers(prefix, limit, scopes...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]SearchSuggestion, 0, len(found))
+	for _, suggestion := range found {
+		suggestions = append(suggestions, SearchSuggestion{
+			Type:  searchType,
+			ID:    suggestion.ID,
+			Text:  suggestion.Text,
+			Score: suggestion.Score,
+		})
+	}
+	return suggestions, nil
+}
+
+// Index is a no-op; Postgres keeps the search columns current itself
+func (idx *DatabaseSearchIndex) Index(doc SearchDocument) error {
+	return nil
+}
+
+// Remove is a no-op; deleted rows drop out of the search columns with them
+func (idx *DatabaseSearchIndex) Remove(searchType string, id uint) error {
+	return nil
+}
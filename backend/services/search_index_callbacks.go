@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// searchableTables maps each indexed table to its search type
+var searchableTables = map[string]string{
+	"users":  SearchTypeUser,
+	"skills": SearchTypeSkill,
+	"jobs":   SearchTypeJob,
+}
+
+// searchableColumns lists the columns each search document is built from.
+// Updates that touch none of them, such as skill point balances, skip reindexing.
+// A user's email is never indexed, and is only shown in hits when show_email is set.
+var searchableColumns = map[string][]string{
+	SearchTypeUser:  {"name", "bio", "searchable", "show_email", "profile_visibility"},
+	SearchTypeSkill: {"name", "description", "category", "price", "price_unit", "first_session_discount"},
+	SearchTypeJob: {"title", "company", "location", "description", "skills_required", "experience_level",
+		"job_type", "salary_range", "contact_email"},
+}
+
+// RegisterSearchIndexCallbacks keeps the index current as users, skills and jobs
+// are created, updated and deleted through db. Changes made in a transaction
+// are held back until it commits, and dropped if it rolls back.
+func RegisterSearchIndexCallbacks(db *gorm.DB, index SearchIndex) error {
+	// Transactions begun through db hold their pending changes
+	pool := &searchIndexConnPool{ConnPool: db.ConnPool}
+	if db.Statement.ConnPool == db.ConnPool {
+		db.Statement.ConnPool = pool
+	}
+	db.ConnPool = pool
+
+	callbacks := db.Callback()
+
+	err := callbacks.Create().After("gorm:create").Register("search_index:create", func(tx *gorm.DB) {
+		reindexStatement(tx, db, index, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = callbacks.Update().After("gorm:update").Register("search_index:update", func(tx *gorm.DB) {
+		reindexStatement(tx, db, index, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	return callbacks.Delete().After("gorm:delete").Register("search_index:delete", func(tx *gorm.DB) {
+		reindexStatement(tx, db, index, false)
+	})
+}
+
+// reindexStatement reindexes the rows written by a statement, once its
+// transaction commits when it runs in one.
+func reindexStatement(tx, db *gorm.DB, index SearchIndex, update bool) {
+	searchType, ok := statementSearchType(tx)
+	if !ok {
+		return
+	}
+	if update && !touchesSearchableColumns(tx, searchType) {
+		return
+	}
+
+	ids := statementIDs(tx)
+	if pending, ok := tx.Statement.ConnPool.(*searchIndexTx); ok {
+		pending.queue(func() { reindexRows(db, index, searchType, ids) })
+		return
+	}
+	reindexRows(tx.Session(&gorm.Session{NewDB: true}), index, searchType, ids)
+}
+
+// reindexRows brings the index in line with rows as db reads them. Statements
+// may carry only some columns, so the rows are read back before indexing, and
+// rows that no longer exist are removed.
+func reindexRows(db *gorm.DB, index SearchIndex, searchType string, ids []uint) {
+	for _, id := range ids {
+		var doc SearchDocument
+		var err error
+
+		switch searchType {
+		case SearchTypeUser:
+			var user models.User
+			err = db.First(&user, id).Error
+			// Users who opted out of search are removed like deleted ones
+			if err == nil && !user.Searchable {
+				err = gorm.ErrRecordNotFound
+			}
+			doc = UserDocument(user)
+		case SearchTypeSkill:
+			var skill models.Skill
+			err = db.First(&skill, id).Error
+			doc = SkillDocument(skill)
+		case SearchTypeJob:
+			var job models.Job
+			err = db.First(&job, id).Error
+			doc = JobDocument(job)
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := index.Remove(searchType, id); err != nil {
+				utils.Error("Failed to remove from search index: " + err.Error())
+			}
+			continue
+		}
+		if err == nil {
+			err = index.Index(doc)
+		}
+		if err != nil {
+			utils.Error("Failed to update search index: " + err.Error())
+		}
+	}
+}
+
+// searchIndexConnPool begins transactions that hold search index changes
+// until they commit
+type searchIndexConnPool struct {
+	gorm.ConnPool
+}
+
+// BeginTx begins a transaction on the wrapped pool
+func (p *searchIndexConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	var err error
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		err = gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &searchIndexTx{ConnPool: tx}, nil
+}
+
+// GetDBConn returns the wrapped *sql.DB, as db.DB() expects
+func (p *searchIndexConnPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// searchIndexTx is a transaction with the search index changes it has made.
+// Nested transactions share it through savepoints, so the changes are read
+// back from the database after commit rather than replayed.
+type searchIndexTx struct {
+	gorm.ConnPool
+
+	mu      sync.Mutex
+	pending []func()
+}
+
+// queue holds a change until the transaction commits
+func (tx *searchIndexTx) queue(change func()) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.pending = append(tx.pending, change)
+}
+
+// Commit commits the transaction, then applies its changes to the index
+func (tx *searchIndexTx) Commit() error {
+	if err := tx.ConnPool.(gorm.TxCommitter).Commit(); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	pending := tx.pending
+	tx.pending = nil
+	tx.mu.Unlock()
+	for _, change := range pending {
+		change()
+	}
+	return nil
+}
+
+// Rollback rolls back the transaction and drops its changes
+func (tx *searchIndexTx) Rollback() error {
+	tx.mu.Lock()
+	tx.pending = nil
+	tx.mu.Unlock()
+	return tx.ConnPool.(gorm.TxCommitter).Rollback()
+}
+
+// statementSearchType returns the search type of a successful statement's table
+func statementSearchType(tx *gorm.DB) (string, bool) {
+	if tx.Error != nil || tx.Statement.Schema == nil || tx.RowsAffected == 0 {
+		return "", false
+	}
+	searchType, ok := searchableTables[tx.Statement.Schema.Table]
+	return searchType, ok
+}
+
+// touchesSearchableColumns reports whether an update may change a search document.
+// Column updates name their columns; struct updates are assumed to change them.
+func touchesSearchableColumns(tx *gorm.DB, searchType string) bool {
+	updates, ok := tx.Statement.Dest.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	for _, column := range searchableColumns[searchType] {
+		if _, ok := updates[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// statementIDs returns the primary keys of the models a statement wrote
+func statementIDs(tx *gorm.DB) []uint {
+	field := tx.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+
+	var ids []uint
+	collect := func(value reflect.Value) {
+		if id, zero := field.ValueOf(tx.Statement.Context, value); !zero {
+			if id, ok := id.(uint); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	value := tx.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collect(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		collect(value)
+	}
+	return ids
+}
@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/services"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSearchIndexCallbacksWaitForCommit(t *testing.T) {
+	// Commits and rollbacks are what's under test, so this can't run in the
+	// rolled back transaction of openTestDB
+	source := os.Getenv("TEST_DB_SOURCE")
+	if source == "" {
+		t.Skip("TEST_DB_SOURCE not set, skipping database test")
+	}
+	db, err := gorm.Open(postgres.Open(source), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Skill{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	index := services.NewMemorySearchIndex()
+	if err := services.RegisterSearchIndexCallbacks(db, index); err != nil {
+		t.Fatalf("Failed to register callbacks: %v", err)
+	}
+
+	var created []uint
+	t.Cleanup(func() {
+		if len(created) > 0 {
+			db.Delete(&models.Skill{}, created)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	indexed := func(name string) bool {
+		hits, err := index.Search(services.SearchTypeSkill, name, services.IndexOptions{})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		return len(hits) > 0
+	}
+
+	errRollback := errors.New("roll back")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		skill := models.Skill{Name: "Quokkaherding"}
+		if err := tx.Create(&skill).Error; err != nil {
+			return err
+		}
+		created = append(created, skill.ID)
+		if indexed("quokkaherding") {
+			t.Error("Expected an uncommitted skill not to be indexed yet")
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected the transaction to roll back, got %v", err)
+	}
+	if indexed("quokkaherding") {
+		t.Error("Expected a rolled back skill not to be indexed")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		kept := models.Skill{Name: "Marimbatuning"}
+		if err := tx.Create(&kept).Error; err != nil {
+			return err
+		}
+		created = append(created, kept.ID)
+
+		// A rolled back savepoint drops only its own changes
+		tx.Transaction(func(tx *gorm.DB) error {
+			dropped := models.Skill{Name: "Zitherpolishing"}
+			if err := tx.Create(&dropped).Error; err != nil {
+				return err
+			}
+			created = append(created, dropped.ID)
+			return errRollback
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if !indexed("marimbatuning") {
+		t.Error("Expected a committed skill to be indexed")
+	}
+	if indexed("zitherpolishing") {
+		t.Error("Expected a skill from a rolled back savepoint not to be indexed")
+	}
+
+	for _, id := range created {
+		if err := db.Delete(&models.Skill{ID: id}).Error; err != nil {
+			t.Fatalf("Failed to delete skill: %v", err)
+		}
+	}
+	created = nil
+	if indexed("marimbatuning") {
+		t.Error("Expected a deleted skill to be removed from the index")
+	}
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchAPIVersion is the version of the search response format
+const SearchAPIVersion = 2
+
+// Search hit types
+const (
+	SearchTypeUser  = "user"
+	SearchTypeSkill = "skill"
+	SearchTypeJob   = "job"
+	SearchTypeVideo = "video"
+)
+
+// Facet names
+const (
+	FacetCategory        = "category"
+	FacetExperienceLevel = "experience_level"
+	FacetJobType         = "job_type"
+	FacetLocation        = "location"
+)
+
+// Search page sizes
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchTypes lists every searchable type in the order results are counted
+var SearchTypes = []string{SearchTypeUser, SearchTypeSkill, SearchTypeJob, SearchTypeVideo}
+
+// SearchFacets lists every facet returned with search results
+var SearchFacets = []string{FacetCategory, FacetExperienceLevel, FacetJobType, FacetLocation}
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SearchQuery describes a search request
+type SearchQuery struct {
+	Text    string            `json:"q"`
+	Types   []string          `json:"types,omitempty"`   // empty means every type
+	Filters map[string]string `json:"filters,omitempty"` // facet name to required value
+	Limit   int               `json:"-"`
+	Cursor  string            `json:"-"`
+
+	CreatedAfter  time.Time `json:"-"` // when set, only records created after it match
+	Authenticated bool      `json:"-"` // whether the searcher is logged in
+}
+
+// SearchHit is a single typed search result
+type SearchHit struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id"`
+	Title   string            `json:"title"`
+	Snippet string            `json:"snippet,omitempty"`
+	Rank    float64           `json:"rank"`
+	Data    interface{}       `json:"data"`
+	Facets  map[string]string `json:"-"` // facet values of the hit, keyed by facet name
+}
+
+// FacetBucket counts the hits sharing one facet value
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchResponse is the versioned search result page
+type SearchResponse struct {
+	Version    int                      `json:"version"`
+	Query      string                   `json:"query"`
+	Hits       []SearchHit              `json:"hits"`
+	Total      int                      `json:"total"`
+	Counts     map[string]int           `json:"counts"`
+	Facets     map[string][]FacetBucket `json:"facets"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// SearchService runs searches across users, skills, jobs and videos
+type SearchService struct {
+	Index     SearchIndex
+	UploadDir string
+}
+
+// NewSearchService creates a new search service backed by the given index
+func NewSearchService(index SearchIndex) *SearchService {
+	return &SearchService{Index: index, UploadDir: "./uploads"}
+}
+
+// ParseSearchType maps a type name, singular or plural, to its canonical form
+func ParseSearchType(name string) (string, bool) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "s")
+	for _, t := range SearchTypes {
+		if t == name {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// Search runs the query and returns one page of typed results. Each type's
+// index returns only the hits that could be on the page, and counts its
+// matches and facet values separately.
+func (s *SearchService) Search(q SearchQuery) (*SearchResponse, error) {
+	after, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.limit()
+
+	var hits []SearchHit
+	counts := make(map[string]SearchCount)
+	for _, searchType := range q.searchTypes() {
+		if !supportsFilters(searchType, q.Filters) {
+			continue
+		}
+
+		if searchType == SearchTypeVideo {
+			// Videos are matched by scanning the upload directory, so they are paged here
+			videos, err := searchVideos(s.UploadDir, q.Text, q.CreatedAfter)
+			if err != nil {
+				return nil, err
+			}
+			counts[searchType] = countHits(videos)
+			hits = append(hits, after.filter(videos)...)
+			continue
+		}
+
+		opts := q.indexOptions()
+		opts.After = after.position(searchType)
+		// One more than a page shows whether another page follows
+		opts.Limit = limit + 1
+
+		count, err := s.Index.Count(searchType, q.Text, opts)
+		if err != nil {
+			return nil, err
+		}
+		typeHits, err := s.Index.Search(searchType, q.Text, opts)
+		if err != nil {
+			return nil, err
+		}
+		counts[searchType] = count
+		hits = append(hits, typeHits...)
+	}
+
+	return newSearchResponse(q, counts, hits, limit), nil
+}
+
+// searchVideos matches uploaded videos by their original filename.
+// Videos are ranked by the share of query terms found in the name.
+// A non-zero createdAfter skips videos uploaded before it.
+func searchVideos(uploadDir, text string, createdAfter time.Time) ([]SearchHit, error) {
+	terms := strings.Fields(strings.ToLower(text))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	files, err := os.ReadDir(uploadDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var hits []SearchHit
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".meta") {
+			continue
+		}
+
+		if !createdAfter.IsZero() {
+			info, err := file.Info()
+			if err != nil || !info.ModTime().After(createdAfter) {
+				continue
+			}
+		}
+
+		storedName := strings.TrimSuffix(file.Name(), ".meta")
+		originalName, err := os.ReadFile(filepath.Join(uploadDir, file.Name()))
+		if err != nil {
+			continue
+		}
+
+		name := strings.ToLower(string(originalName))
+		matched := 0
+		for _, term := range terms {
+			if strings.Contains(name, term) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+
+		hits = append(hits, SearchHit{
+			Type:  SearchTypeVideo,
+			ID:    storedName,
+			Title: string(originalName),
+			Rank:  float64(matched) / float64(len(terms)),
+			Data: map[string]interface{}{
+				"name":             storedName,
+				"originalFilename": string(originalName),
+				"path":             "/uploads/" + storedName,
+			},
+		})
+	}
+
+	return hits, nil
+}
+
+// BuildSearchResponse filters, counts, facets and paginates hits already
+// gathered in memory, such as mock results, as Search does with an index.
+func BuildSearchResponse(q SearchQuery, hits []SearchHit) (*SearchResponse, error) {
+	after, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[string][]SearchHit)
+	for _, hit := range hits {
+		if facetsMatch(hit.Facets, q.Filters) {
+			byType[hit.Type] = append(byType[hit.Type], hit)
+		}
+	}
+
+	var page []SearchHit
+	counts := make(map[string]SearchCount)
+	for searchType, typeHits := range byType {
+		counts[searchType] = countHits(typeHits)
+		page = append(page, after.filter(typeHits)...)
+	}
+
+	return newSearchResponse(q, counts, page, q.limit()), nil
+}
+
+// newSearchResponse builds the response to a query from each type's counts
+// and the hits that may be on the page, which are all ranked after the cursor.
+// Hits are ordered by rank, then by type and ID so pages are stable.
+func newSearchResponse(q SearchQuery, counts map[string]SearchCount, hits []SearchHit, limit int) *SearchResponse {
+	response := &SearchResponse{
+		Version: SearchAPIVersion,
+		Query:   q.Text,
+		Hits:    []SearchHit{},
+		Counts:  make(map[string]int),
+	}
+
+	for _, searchType := range q.searchTypes() {
+		response.Counts[searchType] = 0
+	}
+	facets := make(map[string]map[string]int)
+	for searchType, count := range counts {
+		response.Counts[searchType] += count.Total
+		response.Total += count.Total
+		for name, values := range count.Facets {
+			if facets[name] == nil {
+				facets[name] = make(map[string]int)
+			}
+			for value, n := range values {
+				facets[name][value] += n
+			}
+		}
+	}
+	response.Facets = facetBuckets(facets)
+
+	sort.SliceStable(hits, func(i, j int) bool { return hitBefore(hits[i], hits[j]) })
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[limit-1]
+		response.NextCursor = encodeCursor(searchCursor{Rank: last.Rank, Type: last.Type, ID: last.ID})
+	}
+	if len(hits) > 0 {
+		response.Hits = hits
+	}
+	return response
+}
+
+// hitBefore reports whether hit a comes before b: by rank, then type, then ID.
+// IDs are compared as numbers when both are, as the indexes order them.
+func hitBefore(a, b SearchHit) bool {
+	if a.Rank != b.Rank {
+		return a.Rank > b.Rank
+	}
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	idA, errA := strconv.ParseUint(a.ID, 10, 64)
+	idB, errB := strconv.ParseUint(b.ID, 10, 64)
+	if errA == nil && errB == nil {
+		return idA < idB
+	}
+	return a.ID < b.ID
+}
+
+// limit returns the page size of the query
+func (q SearchQuery) limit() int {
+	if q.Limit <= 0 {
+		return DefaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		return MaxSearchLimit
+	}
+	return q.Limit
+}
+
+// indexOptions returns the options the query passes to the search index
+func (q SearchQuery) indexOptions() IndexOptions {
+	return IndexOptions{CreatedAfter: q.CreatedAfter, Authenticated: q.Authenticated, Filters: q.Filters}
+}
+
+// searchTypes returns the types the query covers
+func (q SearchQuery) searchTypes() []string {
+	if len(q.Types) == 0 {
+		return SearchTypes
+	}
+	return q.Types
+}
+
+// facetsMatch reports whether facets carry every filtered value
+func facetsMatch(facets, filters map[string]string) bool {
+	for name, value := range filters {
+		if value == "" {
+			continue
+		}
+		if !strings.EqualFold(facets[name], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// countHits counts hits and their facet values
+func countHits(hits []SearchHit) SearchCount {
+	count := SearchCount{Total: len(hits), Facets: make(map[string]map[string]int)}
+	for _, hit := range hits {
+		for name, value := range hit.Facets {
+			if count.Facets[name] == nil {
+				count.Facets[name] = make(map[string]int)
+			}
+			count.Facets[name][value]++
+		}
+	}
+	return count
+}
+
+// facetBuckets orders the counted values of each facet, most common first.
+// Empty values are left out.
+func facetBuckets(counts map[string]map[string]int) map[string][]FacetBucket {
+	facets := make(map[string][]FacetBucket, len(SearchFacets))
+
+	for _, name := range SearchFacets {
+		buckets := make([]FacetBucket, 0, len(counts[name]))
+		for value, count := range counts[name] {
+			if value != "" && count > 0 {
+				buckets = append(buckets, FacetBucket{Value: value, Count: count})
+			}
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			if buckets[i].Count != buckets[j].Count {
+				return buckets[i].Count > buckets[j].Count
+			}
+			return buckets[i].Value < buckets[j].Value
+		})
+		facets[name] = buckets
+	}
+
+	return facets
+}
+
+// searchCursor is the decoded form of a pagination cursor: the last hit of
+// the previous page, which the next page starts after
+type searchCursor struct {
+	Rank float64 `json:"r"`
+	Type string  `json:"t"`
+	ID   string  `json:"i"`
+}
+
+// lastID is above every ID, so a position at it skips every document of its rank
+const lastID = math.MaxInt64
+
+// position returns where a type's hits on the page start. Hits of the
+// cursor's rank come before it when their type does, and after it when
+// their type follows it.
+func (c *searchCursor) position(searchType string) *SearchPosition {
+	if c == nil {
+		return nil
+	}
+	switch {
+	case searchType < c.Type:
+		return &SearchPosition{Rank: c.Rank, ID: lastID}
+	case searchType > c.Type:
+		return &SearchPosition{Rank: c.Rank}
+	}
+	id, _ := strconv.ParseUint(c.ID, 10, 64)
+	return &SearchPosition{Rank: c.Rank, ID: uint(id)}
+}
+
+// filter returns the hits that come after the cursor
+func (c *searchCursor) filter(hits []SearchHit) []SearchHit {
+	if c == nil {
+		return hits
+	}
+	last := SearchHit{Rank: c.Rank, Type: c.Type, ID: c.ID}
+	var after []SearchHit
+	for _, hit := range hits {
+		if hitBefore(last, hit) {
+			after = append(after, hit)
+		}
+	}
+	return after
+}
+
+// encodeCursor returns an opaque cursor pointing after a hit
+func encodeCursor(c searchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the hit a cursor points after; an empty cursor is the
+// first page and decodes to nil
+func decodeCursor(cursor string) (*searchCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c searchCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if searchType, ok := ParseSearchType(c.Type); !ok || searchType != c.Type {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := strconv.ParseUint(c.ID, 10, 64); err != nil && c.Type != SearchTypeVideo {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Suggestion page sizes
+const (
+	DefaultSuggestLimit = 8
+	MaxSuggestLimit     = 20
+)
+
+// SearchSuggestion is a completion offered while the user is typing
+type SearchSuggestion struct {
+	Type  string  `json:"type"`
+	ID    uint    `json:"id"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// Suggest returns ranked completions of a prefix from skill names, job titles
+// and user names. Repeated texts of one type, such as a skill offered by several
+// teachers, are returned once. Members-only profiles need an authenticated searcher.
+func (s *SearchService) Suggest(prefix string, types []string, limit int, authenticated bool) ([]SearchSuggestion, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	if limit > MaxSuggestLimit {
+		limit = MaxSuggestLimit
+	}
+	if len(types) == 0 {
+		types = []string{SearchTypeSkill, SearchTypeJob, SearchTypeUser}
+	}
+
+	var suggestions []SearchSuggestion
+	for _, searchType := range types {
+		if searchType == SearchTypeVideo {
+			continue
+		}
+		found, err := s.Index.Suggest(searchType, prefix, limit, IndexOptions{Authenticated: authenticated})
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, found...)
+	}
+
+	return RankSuggestions(suggestions, limit), nil
+}
+
+// RankSuggestions orders suggestions by score, drops repeated texts of the same
+// type and keeps at most limit entries.
+func RankSuggestions(suggestions []SearchSuggestion, limit int) []SearchSuggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return len(suggestions[i].Text) < len(suggestions[j].Text)
+	})
+
+	seen := make(map[string]bool)
+	ranked := []SearchSuggestion{}
+	for _, suggestion := range suggestions {
+		key := suggestion.Type + ":" + strings.ToLower(suggestion.Text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ranked = append(ranked, suggestion)
+		if len(ranked) == limit {
+			break
+		}
+	}
+	return ranked
+}
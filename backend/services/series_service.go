@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSeriesNotFound is returned when a series doesn't exist or the user isn't part of it
+	ErrSeriesNotFound = errors.New("series not found")
+	// ErrSeriesCancelled is returned when cancelling a series twice
+	ErrSeriesCancelled = errors.New("series has been cancelled")
+	// ErrNoUpcomingSessions is returned when a series has no sessions left to change
+	ErrNoUpcomingSessions = errors.New("no upcoming sessions in the series")
+)
+
+// OccurrenceError reports the session of a series a change failed on. Changes
+// to a series apply to all its sessions or none.
+type OccurrenceError struct {
+	Start time.Time
+	Err   error
+}
+
+func (e *OccurrenceError) Error() string {
+	return fmt.Sprintf("session at %s: %v", e.Start.UTC().Format(time.RFC3339), e.Err)
+}
+
+func (e *OccurrenceError) Unwrap() error { return e.Err }
+
+// SeriesDetails is a recurring series with its sessions in start time order
+type SeriesDetails struct {
+	models.ScheduleSeries
+	Sessions []models.Schedule `json:"sessions"`
+}
+
+// In returns a copy of the series and its sessions with their times in loc
+func (d SeriesDetails) In(loc *time.Location) SeriesDetails {
+	d.ScheduleSeries = d.ScheduleSeries.In(loc)
+	sessions := make([]models.Schedule, len(d.Sessions))
+	for i := range d.Sessions {
+		sessions[i] = d.Sessions[i].In(loc)
+	}
+	d.Sessions = sessions
+	return d
+}
+
+// RequestSeries requests every session of a recurring series for a learner.
+// Each is checked, priced and requested like a single session, and if any of
+// them can't be booked none are.
+func (s *BookingService) RequestSeries(series *models.ScheduleSeries) (*SeriesDetails, error) {
+	var details *SeriesDetails
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		skill, err := repositories.NewSkillRepository(dbTx).GetSkillByID(series.SkillID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSkillNotFound
+			}
+			return err
+		}
+		if skill.UserID == series.LearnerID {
+			return ErrOwnSkill
+		}
+		series.TeacherID = skill.UserID
+
+		occurrences, err := series.Occurrences()
+		if err != nil {
+			return &InvalidSessionError{Err: err}
+		}
+		series.Status = models.SeriesActive
+		if err := repositories.NewScheduleRepository(dbTx).CreateSeries(series); err != nil {
+			return err
+		}
+
+		booking := &BookingService{DB: dbTx}
+		details = &SeriesDetails{ScheduleSeries: *series}
+		for _, occurrence := range occurrences {
+			schedule := models.Schedule{
+				LearnerID: series.LearnerID,
+				SkillID:   series.SkillID,
+				StartTime: occurrence.Start,
+				EndTime:   occurrence.End,
+				SeriesID:  &series.ID,
+			}
+			created, err := booking.RequestSession(&schedule)
+			if err != nil {
+				return &OccurrenceError{Start: occurrence.Start, Err: err}
+			}
+			details.Sessions = append(details.Sessions, *created)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// Series returns a recurring series the user takes part in with its sessions
+func (s *BookingService) Series(id, userID uint) (*SeriesDetails, error) {
+	scheduleRepo := repositories.NewScheduleRepository(s.DB)
+	series, err := scheduleRepo.GetSeriesByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, err
+	}
+	if !series.IsParticipant(userID) {
+		return nil, ErrSeriesNotFound
+	}
+	sessions, err := scheduleRepo.GetSchedulesBySeries(series.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SeriesDetails{ScheduleSeries: *series, Sessions: sessions}, nil
+}
+
+// AcceptSeries accepts the requests and new times the other participant made
+// for the series' upcoming sessions, as AcceptSession does for each
+func (s *BookingService) AcceptSeries(id, actorID uint) (*SeriesDetails, error) {
+	return s.changeSeries(id, actorID, func(booking *BookingService, series *models.ScheduleSeries, upcoming []models.Schedule) error {
+		answered := 0
+		for _, schedule := range upcoming {
+			if !awaitsAnswerFrom(&schedule, actorID) {
+				continue
+			}
+			if _, err := booking.AcceptSession(schedule.ID, actorID); err != nil {
+				return &OccurrenceError{Start: schedule.StartTime, Err: err}
+			}
+			answered++
+		}
+		if answered == 0 {
+			return ErrNoProposal
+		}
+		return nil
+	})
+}
+
+// DeclineSeries turns down the requests and new times the other participant
+// made for the series' upcoming sessions, as DeclineSession does for each.
+// Declining a series that was only requested cancels it.
+func (s *BookingService) DeclineSeries(id, actorID uint) (*SeriesDetails, error) {
+	return s.changeSeries(id, actorID, func(booking *BookingService, series *models.ScheduleSeries, upcoming []models.Schedule) error {
+		answered := 0
+		for _, schedule := range upcoming {
+			if !awaitsAnswerFrom(&schedule, actorID) {
+				continue
+			}
+			if _, err := booking.DeclineSession(schedule.ID, actorID); err != nil {
+				return &OccurrenceError{Start: schedule.StartTime, Err: err}
+			}
+			answered++
+		}
+		if answered == 0 {
+			return ErrNoProposal
+		}
+
+		scheduleRepo := repositories.NewScheduleRepository(booking.DB)
+		sessions, err := scheduleRepo.GetSchedulesBySeries(series.ID)
+		if err != nil {
+			return err
+		}
+		if hasActiveSession(sessions) {
+			return nil
+		}
+		series.Status = models.SeriesCancelled
+		return scheduleRepo.UpdateSeries(series)
+	})
+}
+
+// CancelSeries cancels the series and all its upcoming sessions, settling
+// each as CancelSession does. Sessions that already took place are kept.
+func (s *BookingService) CancelSeries(id, actorID uint) (*SeriesDetails, error) {
+	return s.changeSeries(id, actorID, func(booking *BookingService, series *models.ScheduleSeries, upcoming []models.Schedule) error {
+		if series.Status == models.SeriesCancelled {
+			return ErrSeriesCancelled
+		}
+		for _, schedule := range upcoming {
+			if _, err := booking.CancelSession(schedule.ID, actorID); err != nil {
+				return &OccurrenceError{Start: schedule.StartTime, Err: err}
+			}
+		}
+		series.Status = models.SeriesCancelled
+		return repositories.NewScheduleRepository(booking.DB).UpdateSeries(series)
+	})
+}
+
+// RescheduleSeries proposes new times for all the series' upcoming sessions,
+// given as the new times of the next one. Every session moves by the same
+// number of days, to the new wall-clock time and length in the series' time
+// zone, and each is proposed as ProposeTime does: requested sessions move
+// straight away, confirmed ones once the other participant accepts.
+func (s *BookingService) RescheduleSeries(id, actorID uint, start, end time.Time) (*SeriesDetails, error) {
+	return s.changeSeries(id, actorID, func(booking *BookingService, series *models.ScheduleSeries, upcoming []models.Schedule) error {
+		if len(upcoming) == 0 {
+			return ErrNoUpcomingSessions
+		}
+		loc, err := series.Location()
+		if err != nil {
+			return err
+		}
+
+		next, moved := upcoming[0].StartTime.In(loc), start.In(loc)
+		days := int(utils.Date(moved).Sub(utils.Date(next)).Hours() / 24)
+		clock := moved.Hour()*60 + moved.Minute()
+		length := end.Sub(start)
+
+		times := make([]models.TimeRange, len(upcoming))
+		for i, schedule := range upcoming {
+			date := utils.Date(schedule.StartTime.In(loc)).AddDate(0, 0, days)
+			newStart := utils.At(date, clock, loc)
+			times[i] = models.TimeRange{Start: newStart, End: newStart.Add(length)}
+			if i > 0 && times[i-1].End.After(newStart) {
+				return &InvalidSessionError{Err: errors.New("sessions of a series can't overlap")}
+			}
+		}
+
+		for i, schedule := range upcoming {
+			if _, err := booking.ProposeTime(schedule.ID, actorID, times[i].Start, times[i].End); err != nil {
+				return &OccurrenceError{Start: schedule.StartTime, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+// changeSeries applies one participant's change to the upcoming sessions of a
+// series in a transaction, with the series' row locked so changes to it run
+// in turn. Each session is changed through the BookingService, so it is
+// checked, recorded and notified as a single change would be; if any of them
+// fails, none are changed. Upcoming sessions are the requested and confirmed
+// ones that haven't started, in start time order.
+func (s *BookingService) changeSeries(id, actorID uint, change func(booking *BookingService, series *models.ScheduleSeries, upcoming []models.Schedule) error) (*SeriesDetails, error) {
+	var details *SeriesDetails
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		scheduleRepo := repositories.NewScheduleRepository(dbTx)
+		series, err := scheduleRepo.GetSeriesForUpdate(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSeriesNotFound
+			}
+			return err
+		}
+		if !series.IsParticipant(actorID) {
+			return ErrSeriesNotFound
+		}
+
+		sessions, err := scheduleRepo.GetSchedulesBySeries(series.ID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var upcoming []models.Schedule
+		for _, schedule := range sessions {
+			active := schedule.Status == models.StatusRequested || schedule.Status == models.StatusConfirmed
+			if active && schedule.StartTime.After(now) {
+				upcoming = append(upcoming, schedule)
+			}
+		}
+
+		if err := change(&BookingService{DB: dbTx}, series, upcoming); err != nil {
+			return err
+		}
+
+		sessions, err = scheduleRepo.GetSchedulesBySeries(series.ID)
+		if err != nil {
+			return err
+		}
+		details = &SeriesDetails{ScheduleSeries: *series, Sessions: sessions}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// awaitsAnswerFrom reports whether a session's request or proposed new time
+// is waiting for the user to answer it
+func awaitsAnswerFrom(schedule *models.Schedule, userID uint) bool {
+	pending := schedule.Status == models.StatusRequested ||
+		(schedule.Status == models.StatusConfirmed && schedule.HasProposal())
+	return pending && schedule.ProposedByID != userID
+}
+
+// hasActiveSession reports whether any of the sessions is still requested or confirmed
+func hasActiveSession(sessions []models.Schedule) bool {
+	for _, schedule := range sessions {
+		if schedule.Status == models.StatusRequested || schedule.Status == models.StatusConfirmed {
+			return true
+		}
+	}
+	return false
+}
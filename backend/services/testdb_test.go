@@ -0,0 +1,40 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to and migrates the Postgres database named by
+// TEST_DB_SOURCE and returns a transaction that is rolled back when the test
+// ends. Tests using it are skipped when TEST_DB_SOURCE is not set.
+func openTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	source := os.Getenv("TEST_DB_SOURCE")
+	if source == "" {
+		t.Skip("TEST_DB_SOURCE not set, skipping database test")
+	}
+
+	db, err := gorm.Open(postgres.Open(source), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Skill{}, &models.Transaction{}, &models.Schedule{}, &models.ScheduleSeries{}, &models.ScheduleEvent{}, &models.Availability{}, &models.BusyBlock{}, &models.SessionReminder{}, &models.Escrow{}, &models.Workshop{}, &models.WorkshopSeat{}, &models.Notification{}, &models.LedgerAccount{}, &models.LedgerJournal{}, &models.LedgerEntry{}, &models.TransactionDispute{}, &models.DisputeMessage{}, &models.DisputeEvidence{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
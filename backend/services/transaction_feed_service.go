@@ -0,0 +1,233 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/utils"
+	"gorm.io/gorm"
+)
+
+// Transaction feed page sizes
+const (
+	DefaultTransactionLimit = 50
+	MaxTransactionLimit     = 200
+)
+
+// exportBatchSize is how many transactions an export reads at a time
+const exportBatchSize = 500
+
+// Transaction export formats
+const (
+	ExportCSV  = "csv"
+	ExportOFX  = "ofx"
+	ExportJSON = "json"
+)
+
+// ErrExportFormat is returned for an export format other than csv, ofx or json
+var ErrExportFormat = errors.New("unknown export format")
+
+// TransactionPage is a page of a user's transaction feed
+type TransactionPage struct {
+	Transactions []repositories.TransactionFeedItem `json:"transactions"`
+	NextCursor   string                             `json:"next_cursor,omitempty"`
+}
+
+// TransactionFeedService pages through and exports users' transaction feeds
+type TransactionFeedService struct {
+	DB *gorm.DB
+}
+
+// NewTransactionFeedService creates a new transaction feed service
+func NewTransactionFeedService(db *gorm.DB) *TransactionFeedService {
+	return &TransactionFeedService{DB: db}
+}
+
+// Page returns a page of up to limit of the user's transactions matching the
+// filter, newest first. An empty cursor starts at the newest; the page's
+// NextCursor continues after it, and is empty on the last page.
+func (s *TransactionFeedService) Page(userID uint, filter repositories.TransactionFilter, cursor string, limit int) (*TransactionPage, error) {
+	after, err := decodeTransactionCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultTransactionLimit
+	}
+	if limit > MaxTransactionLimit {
+		limit = MaxTransactionLimit
+	}
+
+	// One more than the page tells whether there is a next one
+	items, err := repositories.NewTransactionFeedRepository(s.DB).GetFeed(userID, filter, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &TransactionPage{Transactions: items}
+	if len(items) > limit {
+		page.Transactions = items[:limit]
+		last := items[limit-1]
+		page.NextCursor = encodeTransactionCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+// Export writes all of the user's transactions matching the filter, newest
+// first, in a format for bookkeeping: csv, ofx or json
+func (s *TransactionFeedService) Export(w io.Writer, userID uint, filter repositories.TransactionFilter, format string, now time.Time) error {
+	if format != ExportCSV && format != ExportOFX && format != ExportJSON {
+		return ErrExportFormat
+	}
+
+	feedRepo := repositories.NewTransactionFeedRepository(s.DB)
+	var items []repositories.TransactionFeedItem
+	var after *repositories.TransactionCursor
+	for {
+		batch, err := feedRepo.GetFeed(userID, filter, after, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		items = append(items, batch...)
+		if len(batch) < exportBatchSize {
+			break
+		}
+		last := batch[len(batch)-1]
+		after = &repositories.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	switch format {
+	case ExportCSV:
+		return writeTransactionsCSV(w, items)
+	case ExportOFX:
+		return s.writeTransactionsOFX(w, userID, filter, items, now)
+	default:
+		return json.NewEncoder(w).Encode(struct {
+			ExportedAt   time.Time                          `json:"exported_at"`
+			Transactions []repositories.TransactionFeedItem `json:"transactions"`
+		}{now, items})
+	}
+}
+
+// writeTransactionsCSV writes one row per transaction, with amounts signed
+// from the user's side: negative when they sent it
+func writeTransactionsCSV(w io.Writer, items []repositories.TransactionFeedItem) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "date", "direction", "counterparty_id", "counterparty", "amount", "balance", "note", "refund_of"})
+	for _, item := range items {
+		direction, counterpartyID, counterparty, amount := itemSide(item)
+		balance, refundOf := "", ""
+		if item.Balance != nil {
+			balance = strconv.Itoa(*item.Balance)
+		}
+		if item.RefundOf != nil {
+			refundOf = strconv.FormatUint(uint64(*item.RefundOf), 10)
+		}
+		out.Write([]string{
+			strconv.FormatUint(uint64(item.ID), 10),
+			item.CreatedAt.UTC().Format(time.RFC3339),
+			direction,
+			strconv.FormatUint(uint64(counterpartyID), 10),
+			csvText(counterparty),
+			strconv.Itoa(amount),
+			balance,
+			csvText(item.Note),
+			refundOf,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeTransactionsOFX writes the transactions as an OFX statement of the
+// user's account, covering the filter's dates or else those of the transactions
+func (s *TransactionFeedService) writeTransactionsOFX(w io.Writer, userID uint, filter repositories.TransactionFilter, items []repositories.TransactionFeedItem, now time.Time) error {
+	balance, err := repositories.NewLedgerRepository(s.DB).Balance(models.LedgerUser, userID)
+	if err != nil {
+		return err
+	}
+
+	statement := utils.OFXStatement{
+		BankID:    "SKILLSWAP",
+		AccountID: strconv.FormatUint(uint64(userID), 10),
+		Start:     filter.From,
+		End:       filter.To,
+		Balance:   balance,
+		BalanceAt: now,
+	}
+	if statement.Start.IsZero() {
+		statement.Start = now
+		if len(items) > 0 {
+			statement.Start = items[len(items)-1].CreatedAt
+		}
+	}
+	if statement.End.IsZero() || statement.End.After(now) {
+		statement.End = now
+	}
+	for _, item := range items {
+		_, _, counterparty, amount := itemSide(item)
+		statement.Transactions = append(statement.Transactions, utils.OFXTransaction{
+			ID:     strconv.FormatUint(uint64(item.ID), 10),
+			Posted: item.CreatedAt,
+			Amount: amount,
+			Name:   counterparty,
+			Memo:   item.Note,
+		})
+	}
+	return utils.WriteOFX(w, statement, now)
+}
+
+// itemSide returns a feed item from its user's side: its direction, the
+// other participant, and its amount, negative when the user sent it
+func itemSide(item repositories.TransactionFeedItem) (string, uint, string, int) {
+	if item.IsSender {
+		return repositories.TransactionsSent, item.ReceiverID, item.ReceiverName, -item.Amount
+	}
+	return repositories.TransactionsReceived, item.SenderID, item.SenderName, item.Amount
+}
+
+// csvText keeps spreadsheets from running text that looks like a formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// transactionCursor is the decoded form of a transaction feed cursor
+type transactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// encodeTransactionCursor returns an opaque cursor continuing after the given transaction
+func encodeTransactionCursor(createdAt time.Time, id uint) string {
+	b, _ := json.Marshal(transactionCursor{CreatedAt: createdAt, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeTransactionCursor returns the transaction a cursor continues after;
+// an empty cursor is the first page
+func decodeTransactionCursor(cursor string) (*repositories.TransactionCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c transactionCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &repositories.TransactionCursor{CreatedAt: c.CreatedAt, ID: c.ID}, nil
+}
@@ -0,0 +1,26 @@
+package services_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"github.com/mplaczek99/SkillSwap/services"
+)
+
+func TestTransactionFeedRejectsBadInput(t *testing.T) {
+	// Both are refused before the database is touched
+	service := services.NewTransactionFeedService(nil)
+
+	for _, cursor := range []string{"not a cursor", "e30", "eyJpZCI6M30"} {
+		if _, err := service.Page(1, repositories.TransactionFilter{}, cursor, 10); !errors.Is(err, services.ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for %q, got %v", cursor, err)
+		}
+	}
+
+	if err := service.Export(io.Discard, 1, repositories.TransactionFilter{}, "xlsx", time.Now()); !errors.Is(err, services.ErrExportFormat) {
+		t.Errorf("Expected ErrExportFormat, got %v", err)
+	}
+}
@@ -0,0 +1,546 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/models"
+	"github.com/mplaczek99/SkillSwap/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrWorkshopNotFound is returned when a workshop doesn't exist
+	ErrWorkshopNotFound = errors.New("workshop not found")
+	// ErrNotSkillTeacher is returned when scheduling a workshop of someone else's skill
+	ErrNotSkillTeacher = errors.New("workshops can only be run for your own skills")
+	// ErrNotWorkshopTeacher is returned when someone other than the teacher manages a workshop
+	ErrNotWorkshopTeacher = errors.New("only the workshop's teacher can do that")
+	// ErrOwnWorkshop is returned when a teacher books a seat in their own workshop
+	ErrOwnWorkshop = errors.New("cannot book a seat in your own workshop")
+	// ErrAlreadyBooked is returned when booking a workshop the user already has a seat or waitlist place in
+	ErrAlreadyBooked = errors.New("already booked or waitlisted for this workshop")
+	// ErrNoSeat is returned when cancelling a seat the user doesn't have
+	ErrNoSeat = errors.New("no seat or waitlist place in this workshop")
+	// ErrWorkshopClosed is returned when changing a workshop that started or was cancelled
+	ErrWorkshopClosed = errors.New("workshop has started or been cancelled")
+	// ErrCheckInClosed is returned when checking in outside the workshop's check-in window
+	ErrCheckInClosed = errors.New("check-in is not open")
+	// ErrInvalidCheckInCode is returned when a check-in code matches no booked seat of the workshop
+	ErrInvalidCheckInCode = errors.New("invalid check-in code")
+	// ErrAlreadyCheckedIn is returned when checking in the same seat twice
+	ErrAlreadyCheckedIn = errors.New("attendee already checked in")
+)
+
+// checkInCodeAlphabet leaves out characters that are easily confused when read aloud
+const checkInCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// checkInCodeLength is the number of characters in a check-in code
+const checkInCodeLength = 8
+
+// WorkshopDetails is a workshop with how full it is and the viewer's own seat
+type WorkshopDetails struct {
+	models.Workshop
+	Booked   int                  `json:"booked"`
+	Waitlist int                  `json:"waitlist"`
+	Seat     *models.WorkshopSeat `json:"seat,omitempty"` // the viewer's seat or waitlist place
+}
+
+// RosterEntry is an attendee or waitlisted learner on a workshop's roster.
+// Check-in codes are left out; attendees show theirs to the teacher.
+type RosterEntry struct {
+	SeatID           uint       `json:"seat_id"`
+	UserID           uint       `json:"user_id"`
+	Name             string     `json:"name"`
+	Status           string     `json:"status"`
+	WaitlistPosition int        `json:"waitlist_position,omitempty"`
+	BookedAt         *time.Time `json:"booked_at,omitempty"`
+	CheckedIn        bool       `json:"checked_in"`
+}
+
+// Roster lists who booked a workshop and who is waiting for a seat, in order
+type Roster struct {
+	Workshop  models.Workshop `json:"workshop"`
+	Attendees []RosterEntry   `json:"attendees"`
+	Waitlist  []RosterEntry   `json:"waitlist"`
+}
+
+// WorkshopService schedules group workshops and manages their seats, waitlists
+// and check-ins. Seat payments are held in escrow like session payments.
+type WorkshopService struct {
+	DB *gorm.DB
+}
+
+// NewWorkshopService creates a new workshop service backed by the given database
+func NewWorkshopService(db *gorm.DB) *WorkshopService {
+	return &WorkshopService{DB: db}
+}
+
+// CreateWorkshop schedules a workshop of one of the teacher's skills at a time
+// they have no other session, workshop or busy time
+func (s *WorkshopService) CreateWorkshop(workshop *models.Workshop) (*models.Workshop, error) {
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		skill, err := repositories.NewSkillRepository(dbTx).GetSkillByID(workshop.SkillID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSkillNotFound
+			}
+			return err
+		}
+		if skill.UserID != workshop.TeacherID {
+			return ErrNotSkillTeacher
+		}
+		if err := workshop.Validate(skill); err != nil {
+			return &InvalidSessionError{Err: err}
+		}
+		if err := checkCalendars(dbTx, workshopSlot(workshop, workshop.TeacherID), 0); err != nil {
+			return err
+		}
+
+		workshop.Status = models.WorkshopScheduled
+		return repositories.NewWorkshopRepository(dbTx).CreateWorkshop(workshop)
+	})
+	if err != nil {
+		return nil, translateScheduleError(err)
+	}
+	return workshop, nil
+}
+
+// Details returns a workshop with its seat counts and the viewer's seat
+func (s *WorkshopService) Details(workshopID, viewerID uint) (*WorkshopDetails, error) {
+	repo := repositories.NewWorkshopRepository(s.DB)
+	workshop, err := repo.GetWorkshopByID(workshopID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkshopNotFound
+		}
+		return nil, err
+	}
+
+	seats, err := repo.GetSeats(workshopID, models.SeatBooked, models.SeatWaitlisted)
+	if err != nil {
+		return nil, err
+	}
+	details := WorkshopDetails{Workshop: *workshop}
+	for i, seat := range seats {
+		if seat.Status == models.SeatBooked {
+			details.Booked++
+		} else {
+			details.Waitlist++
+		}
+		if seat.UserID == viewerID {
+			details.Seat = &seats[i]
+		}
+	}
+	return &details, nil
+}
+
+// BookSeat books a seat in a workshop for a learner, holding its price in
+// escrow, or puts them on the waitlist once the workshop is full. The
+// workshop's row is locked so seats are never booked past its capacity.
+func (s *WorkshopService) BookSeat(workshopID, userID uint) (*models.WorkshopSeat, error) {
+	var seat models.WorkshopSeat
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		repo := repositories.NewWorkshopRepository(dbTx)
+		workshop, err := lockWorkshop(dbTx, workshopID)
+		if err != nil {
+			return err
+		}
+		if workshop.TeacherID == userID {
+			return ErrOwnWorkshop
+		}
+		now := time.Now()
+		if workshop.Status != models.WorkshopScheduled || !workshop.StartTime.After(now) {
+			return ErrWorkshopClosed
+		}
+
+		if _, err := repo.GetActiveSeat(workshopID, userID); err == nil {
+			return ErrAlreadyBooked
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := checkCalendars(dbTx, workshopSlot(workshop, userID), workshop.ID); err != nil {
+			return err
+		}
+
+		seat = models.WorkshopSeat{WorkshopID: workshop.ID, UserID: userID, Status: models.SeatWaitlisted}
+		if err := repo.CreateSeat(&seat); err != nil {
+			return err
+		}
+
+		booked, err := repo.CountBookedSeats(workshop.ID)
+		if err != nil {
+			return err
+		}
+		if booked >= workshop.Capacity {
+			return nil
+		}
+		return bookSeat(dbTx, workshop, &seat, now)
+	})
+	if err != nil {
+		return nil, translateScheduleError(err)
+	}
+	return &seat, nil
+}
+
+// CancelSeat gives up a learner's seat or waitlist place. Booked seats are
+// refunded under the teacher's cancellation policy and offered to the
+// waitlist, first come first served.
+func (s *WorkshopService) CancelSeat(workshopID, userID uint) (*models.WorkshopSeat, error) {
+	var seat *models.WorkshopSeat
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		repo := repositories.NewWorkshopRepository(dbTx)
+		workshop, err := lockWorkshop(dbTx, workshopID)
+		if err != nil {
+			return err
+		}
+		seat, err = repo.GetActiveSeat(workshopID, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoSeat
+			}
+			return err
+		}
+
+		now := time.Now()
+		wasBooked := seat.Status == models.SeatBooked
+		if wasBooked {
+			if !workshop.StartTime.After(now) {
+				return ErrWorkshopClosed
+			}
+			if err := settleSeatCancellation(dbTx, workshop, seat, now); err != nil {
+				return err
+			}
+		}
+
+		seat.Status = models.SeatCancelled
+		if err := repo.UpdateSeat(seat); err != nil {
+			return err
+		}
+		if !wasBooked {
+			return nil
+		}
+		return promoteWaitlist(dbTx, workshop, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seat, nil
+}
+
+// CancelWorkshop cancels a workshop that hasn't started, refunding every
+// booked seat in full and letting attendees and waitlisted learners know
+func (s *WorkshopService) CancelWorkshop(workshopID, teacherID uint) (*models.Workshop, error) {
+	var workshop *models.Workshop
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		var err error
+		workshop, err = lockWorkshop(dbTx, workshopID)
+		if err != nil {
+			return err
+		}
+		if workshop.TeacherID != teacherID {
+			return ErrNotWorkshopTeacher
+		}
+		now := time.Now()
+		if workshop.Status != models.WorkshopScheduled || !workshop.StartTime.After(now) {
+			return ErrWorkshopClosed
+		}
+
+		repo := repositories.NewWorkshopRepository(dbTx)
+		seats, err := repo.GetSeats(workshop.ID, models.SeatBooked, models.SeatWaitlisted)
+		if err != nil {
+			return err
+		}
+		for i := range seats {
+			seat := &seats[i]
+			if seat.Status == models.SeatBooked {
+				if err := refundSeat(dbTx, seat, now); err != nil {
+					return err
+				}
+			}
+			seat.Status = models.SeatCancelled
+			if err := repo.UpdateSeat(seat); err != nil {
+				return err
+			}
+			if err := notifyWorkshop(dbTx, workshop, seat.UserID, "Workshop cancelled"); err != nil {
+				return err
+			}
+		}
+
+		workshop.Status = models.WorkshopCancelled
+		return repo.UpdateWorkshop(workshop)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workshop, nil
+}
+
+// Roster lists a workshop's attendees and waitlist for its teacher
+func (s *WorkshopService) Roster(workshopID, teacherID uint) (*Roster, error) {
+	repo := repositories.NewWorkshopRepository(s.DB)
+	workshop, err := repo.GetWorkshopByID(workshopID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkshopNotFound
+		}
+		return nil, err
+	}
+	if workshop.TeacherID != teacherID {
+		return nil, ErrNotWorkshopTeacher
+	}
+
+	seats, err := repo.GetSeats(workshopID, models.SeatBooked, models.SeatWaitlisted)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, len(seats))
+	for i, seat := range seats {
+		userIDs[i] = seat.UserID
+	}
+	users, err := repositories.NewUserRepository(s.DB).GetUsersByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := Roster{Workshop: *workshop, Attendees: []RosterEntry{}, Waitlist: []RosterEntry{}}
+	for _, seat := range seats {
+		entry := RosterEntry{
+			SeatID:    seat.ID,
+			UserID:    seat.UserID,
+			Status:    seat.Status,
+			BookedAt:  seat.BookedAt,
+			CheckedIn: seat.CheckedInAt != nil,
+		}
+		if user := users[seat.UserID]; user != nil {
+			entry.Name = user.Name
+		}
+		if seat.Status == models.SeatBooked {
+			roster.Attendees = append(roster.Attendees, entry)
+		} else {
+			entry.WaitlistPosition = len(roster.Waitlist) + 1
+			roster.Waitlist = append(roster.Waitlist, entry)
+		}
+	}
+	return &roster, nil
+}
+
+// CheckIn checks in the attendee holding a check-in code, from CheckInWindow
+// before the workshop starts until it ends. Their seat's payment is released
+// to the teacher, since they were there.
+func (s *WorkshopService) CheckIn(workshopID, teacherID uint, code string) (*models.WorkshopSeat, error) {
+	var seat *models.WorkshopSeat
+	err := s.DB.Transaction(func(dbTx *gorm.DB) error {
+		workshop, err := lockWorkshop(dbTx, workshopID)
+		if err != nil {
+			return err
+		}
+		if workshop.TeacherID != teacherID {
+			return ErrNotWorkshopTeacher
+		}
+		if workshop.Status != models.WorkshopScheduled {
+			return ErrWorkshopClosed
+		}
+		now := time.Now()
+		if !workshop.CheckInOpen(now) {
+			return ErrCheckInClosed
+		}
+
+		repo := repositories.NewWorkshopRepository(dbTx)
+		seat, err = repo.GetSeatByCode(workshop.ID, NormalizeCheckInCode(code))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidCheckInCode
+			}
+			return err
+		}
+		if seat.CheckedInAt != nil {
+			return ErrAlreadyCheckedIn
+		}
+		seat.CheckedInAt = &now
+		if err := repo.UpdateSeat(seat); err != nil {
+			return err
+		}
+
+		escrowRepo := repositories.NewEscrowRepository(dbTx)
+		escrow, err := escrowRepo.GetSeatEscrowForUpdate(seat.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if escrow.Status != models.EscrowHeld {
+			return nil
+		}
+		return escrowRepo.ReleaseEscrow(escrow, workshopPaymentNote(workshop), now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seat, nil
+}
+
+// NormalizeCheckInCode tidies a check-in code as typed or read out, ignoring
+// case, spaces and dashes
+func NormalizeCheckInCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// lockWorkshop loads a workshop, locking its row until the transaction ends
+func lockWorkshop(dbTx *gorm.DB, workshopID uint) (*models.Workshop, error) {
+	workshop, err := repositories.NewWorkshopRepository(dbTx).GetWorkshopForUpdate(workshopID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkshopNotFound
+	}
+	return workshop, err
+}
+
+// workshopSlot describes a workshop's times as a session of one user, to check
+// against their calendar
+func workshopSlot(workshop *models.Workshop, userID uint) *models.Schedule {
+	return &models.Schedule{LearnerID: userID, StartTime: workshop.StartTime, EndTime: workshop.EndTime}
+}
+
+// bookSeat turns a waitlisted seat into a booked one, holding the seat price
+// in escrow under the teacher's current cancellation policy and giving the
+// attendee a check-in code. It returns repositories.ErrInsufficientPoints
+// without changing anything if the learner can't pay.
+func bookSeat(dbTx *gorm.DB, workshop *models.Workshop, seat *models.WorkshopSeat, now time.Time) error {
+	if workshop.SeatPrice > 0 {
+		policy, err := teacherPolicy(dbTx, workshop.TeacherID)
+		if err != nil {
+			return err
+		}
+		escrow := models.Escrow{
+			SeatID:             seat.ID,
+			LearnerID:          seat.UserID,
+			TeacherID:          workshop.TeacherID,
+			Amount:             workshop.SeatPrice,
+			CancellationPolicy: policy,
+		}
+		if err := repositories.NewEscrowRepository(dbTx).HoldEscrow(&escrow); err != nil {
+			return err
+		}
+	}
+
+	code, err := newCheckInCode()
+	if err != nil {
+		return err
+	}
+	seat.Status = models.SeatBooked
+	seat.Price = workshop.SeatPrice
+	seat.CheckInCode = code
+	seat.BookedAt = &now
+	return repositories.NewWorkshopRepository(dbTx).UpdateSeat(seat)
+}
+
+// promoteWaitlist books freed seats for waitlisted learners in the order they
+// joined. Learners who can no longer pay or attend keep their place and are
+// told why they were passed over.
+func promoteWaitlist(dbTx *gorm.DB, workshop *models.Workshop, now time.Time) error {
+	repo := repositories.NewWorkshopRepository(dbTx)
+	booked, err := repo.CountBookedSeats(workshop.ID)
+	if err != nil {
+		return err
+	}
+	if booked >= workshop.Capacity {
+		return nil
+	}
+	waitlist, err := repo.GetSeats(workshop.ID, models.SeatWaitlisted)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(waitlist) && booked < workshop.Capacity; i++ {
+		seat := &waitlist[i]
+		err := checkCalendars(dbTx, workshopSlot(workshop, seat.UserID), workshop.ID)
+		if err == nil {
+			err = bookSeat(dbTx, workshop, seat, now)
+		}
+		switch {
+		case err == nil:
+			booked++
+			err = notifyWorkshop(dbTx, workshop, seat.UserID, "Workshop seat booked")
+		case errors.Is(err, repositories.ErrInsufficientPoints):
+			err = notifyWorkshop(dbTx, workshop, seat.UserID, "Not enough SkillPoints for a workshop seat")
+		case errors.Is(err, ErrScheduleConflict), errors.Is(err, ErrParticipantBusy):
+			err = notifyWorkshop(dbTx, workshop, seat.UserID, "Workshop seat clashes with your calendar")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleSeatCancellation settles a booked seat's escrow when its learner
+// cancels, charging the late cancellation fee of the policy it was booked under
+func settleSeatCancellation(dbTx *gorm.DB, workshop *models.Workshop, seat *models.WorkshopSeat, now time.Time) error {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetSeatEscrowForUpdate(seat.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if escrow.Status != models.EscrowHeld {
+		return nil
+	}
+	fee := escrow.CancellationPolicy.Fee(escrow.Amount, workshop.StartTime, now)
+	return escrowRepo.SettleEscrow(escrow, fee, fmt.Sprintf("Late cancellation: %s", workshop.Title), now)
+}
+
+// refundSeat returns a booked seat's held payment to its learner in full
+func refundSeat(dbTx *gorm.DB, seat *models.WorkshopSeat, now time.Time) error {
+	escrowRepo := repositories.NewEscrowRepository(dbTx)
+	escrow, err := escrowRepo.GetSeatEscrowForUpdate(seat.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if escrow.Status != models.EscrowHeld {
+		return nil
+	}
+	return escrowRepo.RefundEscrow(escrow, now)
+}
+
+// notifyWorkshop tells a user about a change to their place in a workshop
+func notifyWorkshop(dbTx *gorm.DB, workshop *models.Workshop, userID uint, title string) error {
+	loc, err := userLocation(dbTx, userID)
+	if err != nil {
+		return err
+	}
+	notification := models.Notification{
+		UserID:  userID,
+		Kind:    models.NotificationWorkshopUpdate,
+		Title:   title,
+		Message: fmt.Sprintf("%s on %s", workshop.Title, FormatSessionTime(workshop.StartTime, loc)),
+		Link:    fmt.Sprintf("/workshops/%d", workshop.ID),
+	}
+	return repositories.NewNotificationRepository(dbTx).CreateNotification(&notification)
+}
+
+// workshopPaymentNote describes a seat's payment in transaction histories
+func workshopPaymentNote(workshop *models.Workshop) string {
+	return fmt.Sprintf("Workshop: %s", workshop.Title)
+}
+
+// newCheckInCode returns a random check-in code
+func newCheckInCode() (string, error) {
+	buf := make([]byte, checkInCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = checkInCodeAlphabet[int(b)%len(checkInCodeAlphabet)]
+	}
+	return string(buf), nil
+}
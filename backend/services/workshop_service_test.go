@@ -0,0 +1,15 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/services"
+)
+
+func TestNormalizeCheckInCode(t *testing.T) {
+	for _, code := range []string{"ABCD2345", "abcd2345", "ABCD-2345", " abcd 2345 "} {
+		if got := services.NormalizeCheckInCode(code); got != "ABCD2345" {
+			t.Errorf("%q: expected ABCD2345, got %q", code, got)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyThreshold is the minimum similarity for a fuzzy match.
+// It sits below pg_trgm's default of 0.3 so that a single transposition
+// in a short word, such as "pyhton" for "python", still matches.
+const FuzzyThreshold = 0.25
+
+// Trigrams returns the set of trigrams in s, built the same way as pg_trgm:
+// each lowercased word is padded with two spaces in front and one behind.
+func Trigrams(s string) map[string]struct{} {
+	trigrams := make(map[string]struct{})
+	for _, word := range words(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigrams[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return trigrams
+}
+
+// TrigramSimilarity returns the share of trigrams a and b have in common, from 0 to 1
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := Trigrams(a), Trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// WordSimilarity returns the best similarity between the query and any run of
+// consecutive words in text, so a short query can match inside a long title.
+func WordSimilarity(query, text string) float64 {
+	queryWords := len(words(query))
+	textWords := words(text)
+	if queryWords == 0 || len(textWords) == 0 {
+		return 0
+	}
+
+	best := 0.0
+	for i := range textWords {
+		end := i + queryWords
+		if end > len(textWords) {
+			end = len(textWords)
+		}
+		if sim := TrigramSimilarity(query, strings.Join(textWords[i:end], " ")); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// FuzzyScore scores how well text matches a query: 1 for a substring match,
+// otherwise the word similarity if it reaches FuzzyThreshold, and 0 for no match.
+func FuzzyScore(query, text string) float64 {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0
+	}
+	if strings.Contains(strings.ToLower(text), query) {
+		return 1
+	}
+	if sim := WordSimilarity(query, text); sim >= FuzzyThreshold {
+		return sim
+	}
+	return 0
+}
+
+// HasWordPrefix reports whether any word in text starts with prefix, ignoring case
+func HasWordPrefix(text, prefix string) bool {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return false
+	}
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, prefix) {
+		return true
+	}
+	for _, word := range words(lower) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestionScore ranks text as a completion of prefix: values starting with the
+// prefix score above 2, values with a later word starting with it score above 1,
+// and close trigram matches score their similarity. Non-matches score 0.
+func SuggestionScore(prefix, text string) float64 {
+	lowerPrefix := strings.ToLower(strings.TrimSpace(prefix))
+	similarity := TrigramSimilarity(prefix, text)
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(text), lowerPrefix):
+		return 2 + similarity
+	case HasWordPrefix(text, lowerPrefix):
+		return 1 + similarity
+	}
+	if wordSimilarity := WordSimilarity(prefix, text); wordSimilarity >= FuzzyThreshold {
+		return wordSimilarity
+	}
+	return 0
+}
+
+// words splits s into lowercased alphanumeric words
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Highlight wraps every case-insensitive occurrence of the terms in <mark> tags
+// and trims the text to roughly maxLen runes around the first match. Matches
+// are found on the text itself, since lowercasing can change its byte length.
+func Highlight(text string, terms []string, maxLen int) string {
+	runes := []rune(text)
+	termRunes := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			termRunes = append(termRunes, []rune(term))
+		}
+	}
+
+	first := -1
+	for i := range runes {
+		if matchLength(runes, i, termRunes) > 0 {
+			first = i
+			break
+		}
+	}
+
+	start := 0
+	if first > maxLen/2 {
+		start = first - maxLen/4
+		for start > 0 && runes[start-1] != ' ' {
+			start--
+		}
+	}
+	end := len(runes)
+	if end-start > maxLen {
+		end = start + maxLen
+		for end < len(runes) && runes[end] != ' ' {
+			end++
+		}
+	}
+	fragment := runes[start:end]
+
+	var b strings.Builder
+	for i := 0; i < len(fragment); {
+		n := matchLength(fragment, i, termRunes)
+		if n == 0 {
+			b.WriteRune(fragment[i])
+			i++
+			continue
+		}
+		b.WriteString("<mark>")
+		b.WriteString(string(fragment[i : i+n]))
+		b.WriteString("</mark>")
+		i += n
+	}
+	return b.String()
+}
+
+// matchLength returns the length in runes of the longest term found at
+// runes[i], ignoring case, or 0 when none is
+func matchLength(runes []rune, i int, terms [][]rune) int {
+	longest := 0
+	for _, term := range terms {
+		if len(term) <= longest || i+len(term) > len(runes) {
+			continue
+		}
+		matched := true
+		for j, r := range term {
+			if !equalFold(runes[i+j], r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			longest = len(term)
+		}
+	}
+	return longest
+}
+
+// equalFold reports whether two runes are equal under Unicode case folding
+func equalFold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchTerms splits a query into the lowercased words it searches for
+func SearchTerms(query string) []string {
+	return words(query)
+}
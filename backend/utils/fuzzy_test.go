@@ -0,0 +1,101 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func TestTrigramSimilarity(t *testing.T) {
+	if sim := utils.TrigramSimilarity("python", "python"); sim != 1 {
+		t.Errorf("Expected identical words to have similarity 1, got %f", sim)
+	}
+
+	if sim := utils.TrigramSimilarity("python", "cooking"); sim >= utils.FuzzyThreshold {
+		t.Errorf("Expected unrelated words to be below the threshold, got %f", sim)
+	}
+
+	if sim := utils.TrigramSimilarity("", "python"); sim != 0 {
+		t.Errorf("Expected empty string to have similarity 0, got %f", sim)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       string
+		text        string
+		expectMatch bool
+	}{
+		{"Exact Substring", "python", "Advanced Python Programming", true},
+		{"Transposed Letters", "pyhton", "Python", true},
+		{"Missing Letter", "javscript", "JavaScript for beginners", true},
+		{"Unrelated", "pyhton", "Italian Cooking", false},
+		{"Empty Query", "", "Python", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score := utils.FuzzyScore(tc.query, tc.text)
+			if tc.expectMatch && score == 0 {
+				t.Errorf("Expected %q to match %q", tc.query, tc.text)
+			}
+			if !tc.expectMatch && score != 0 {
+				t.Errorf("Expected %q not to match %q, got score %f", tc.query, tc.text, score)
+			}
+		})
+	}
+}
+
+func TestHasWordPrefix(t *testing.T) {
+	if !utils.HasWordPrefix("Advanced Python", "pyt") {
+		t.Error("Expected a later word to match the prefix")
+	}
+	if !utils.HasWordPrefix("Python", "PY") {
+		t.Error("Expected prefix matching to ignore case")
+	}
+	if utils.HasWordPrefix("Python", "thon") {
+		t.Error("Expected a mid-word fragment not to match")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := utils.Highlight("Learn Python and more python", []string{"python"}, 200)
+	want := "Learn <mark>Python</mark> and more <mark>python</mark>"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestHighlightTextChangingLengthWhenLowercased(t *testing.T) {
+	// "ẞ" is three bytes but lowercases to the two byte "ß"
+	got := utils.Highlight("ẞẞẞẞẞẞẞẞẞẞ guitar", []string{"guitar"}, 200)
+	if want := "ẞẞẞẞẞẞẞẞẞẞ <mark>guitar</mark>"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got = utils.Highlight(strings.Repeat("ẞẞẞ ", 100)+"guitar", []string{"guitar"}, 40)
+	if !strings.HasSuffix(got, "<mark>guitar</mark>") || utf8.RuneCountInString(got) > 60 {
+		t.Errorf("Expected a trimmed snippet ending at the match, got %q", got)
+	}
+
+	got = utils.Highlight("Straẞe", []string{"straße"}, 200)
+	if want := "<mark>Straẞe</mark>"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSuggestionScore(t *testing.T) {
+	prefix := utils.SuggestionScore("pyt", "Python")
+	word := utils.SuggestionScore("pyt", "Advanced Python")
+	typo := utils.SuggestionScore("pyhton", "Python")
+
+	if !(prefix > word && word > typo && typo > 0) {
+		t.Errorf("Expected prefix > word prefix > typo > 0, got %f, %f, %f", prefix, word, typo)
+	}
+	if score := utils.SuggestionScore("pyt", "Cooking"); score != 0 {
+		t.Errorf("Expected no match to score 0, got %f", score)
+	}
+}
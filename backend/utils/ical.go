@@ -0,0 +1,496 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses used in iCalendar feeds
+const (
+	ICalTentative = "TENTATIVE"
+	ICalConfirmed = "CONFIRMED"
+	ICalCancelled = "CANCELLED"
+)
+
+// Layouts of UTC date-times such as "20260302T090000Z" and local ones such as "20260302T090000"
+const (
+	icalUTCLayout   = "20060102T150405Z"
+	icalLocalLayout = "20060102T150405"
+)
+
+// maxICalLine is the longest content line, in octets, before it is folded
+const maxICalLine = 75
+
+// ICalEvent is a VEVENT in the subset of RFC 5545 used for session feeds and imports.
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time // In the time zone of the event's DTSTART
+	End         time.Time
+	AllDay      bool
+	Status      string // TENTATIVE, CONFIRMED or CANCELLED; empty when unset
+	Sequence    int    // Revision of the event, bumped on every change
+	Stamp       time.Time
+	Transparent bool        // TRANSP:TRANSPARENT, the event doesn't make its owner busy
+	RRule       string      // Recurrence rule, if the event repeats
+	ExDates     []time.Time // Start times of skipped occurrences
+}
+
+// Occurrence is one time a calendar event takes place.
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// WriteICalendar formats events as an iCalendar object named name. Event times
+// are written as local times in loc, with a VTIMEZONE describing its offsets, so
+// clients show them in that zone; a nil loc or UTC writes them in UTC.
+func WriteICalendar(name string, loc *time.Location, events []ICalEvent) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	zoned := loc != time.UTC && loc.String() != "UTC"
+	formatTime := func(property string, t time.Time) string {
+		if !zoned {
+			return property + ":" + t.UTC().Format(icalUTCLayout)
+		}
+		return property + ";TZID=" + loc.String() + ":" + t.In(loc).Format(icalLocalLayout)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//SkillSwap//Sessions//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(name))
+	if zoned {
+		line("X-WR-TIMEZONE:" + loc.String())
+		if len(events) > 0 {
+			first, last := events[0].Start, events[0].End
+			for _, e := range events {
+				if e.Start.Before(first) {
+					first = e.Start
+				}
+				if e.End.After(last) {
+					last = e.End
+				}
+			}
+			writeVTimezone(line, loc, first, last)
+		}
+	}
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeICalText(e.UID))
+		line("DTSTAMP:" + e.Stamp.UTC().Format(icalUTCLayout))
+		line(formatTime("DTSTART", e.Start))
+		line(formatTime("DTEND", e.End))
+		line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+		if e.Status != "" {
+			line("STATUS:" + e.Status)
+		}
+		line("SUMMARY:" + escapeICalText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeICalText(e.Description))
+		}
+		line("LAST-MODIFIED:" + e.Stamp.UTC().Format(icalUTCLayout))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// writeVTimezone describes loc's offsets from first to last as a VTIMEZONE, with
+// one observance for each offset period, from the one in force at first
+func writeVTimezone(line func(string), loc *time.Location, first, last time.Time) {
+	line("BEGIN:VTIMEZONE")
+	line("TZID:" + loc.String())
+
+	for at := first; ; {
+		start, end := at.In(loc).ZoneBounds()
+		writeObservance(line, loc, start, at)
+		if end.IsZero() || end.After(last) {
+			break
+		}
+		at = end
+	}
+	line("END:VTIMEZONE")
+}
+
+// writeObservance describes the offset period of loc beginning at start, which is
+// zero when the period has always been in force; at is an instant within it
+func writeObservance(line func(string), loc *time.Location, start, at time.Time) {
+	name, offset := at.In(loc).Zone()
+	fromOffset := offset
+	begins := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !start.IsZero() {
+		_, fromOffset = start.Add(-time.Second).In(loc).Zone()
+		// Observances start at the local time before the change
+		begins = start.Add(time.Duration(fromOffset) * time.Second).UTC()
+	}
+
+	kind := "STANDARD"
+	if at.In(loc).IsDST() {
+		kind = "DAYLIGHT"
+	}
+	line("BEGIN:" + kind)
+	line("DTSTART:" + begins.Format(icalLocalLayout))
+	line("TZOFFSETFROM:" + formatICalOffset(fromOffset))
+	line("TZOFFSETTO:" + formatICalOffset(offset))
+	line("TZNAME:" + escapeICalText(name))
+	line("END:" + kind)
+}
+
+// formatICalOffset formats a UTC offset in seconds such as "+0100" or "-0430"
+func formatICalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// ICalError is returned for iCalendar data that can't be read. Its message can be shown to users.
+type ICalError struct {
+	Err error
+}
+
+func (e *ICalError) Error() string { return e.Err.Error() }
+
+func (e *ICalError) Unwrap() error { return e.Err }
+
+// ParseICalendar reads the events of an iCalendar object. Times with a TZID are
+// read in that time zone, and floating times and dates in loc, the time zone of
+// the calendar's owner. Components nested in events, such as alarms, are
+// ignored. Malformed data returns an *ICalError.
+func ParseICalendar(r io.Reader, loc *time.Location) ([]ICalEvent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, &ICalError{Err: err}
+	}
+	events, err := parseICalEvents(lines, loc)
+	if err != nil {
+		return nil, &ICalError{Err: err}
+	}
+	return events, nil
+}
+
+// parseICalEvents reads the events from unfolded content lines
+func parseICalEvents(lines []string, loc *time.Location) ([]ICalEvent, error) {
+	var (
+		events   []ICalEvent
+		stack    []string
+		event    *ICalEvent
+		duration *time.Duration
+		calendar bool
+	)
+	for n, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		name, params, value, err := parseICalLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch name {
+		case "BEGIN":
+			component := strings.ToUpper(value)
+			stack = append(stack, component)
+			if component == "VCALENDAR" {
+				calendar = true
+			}
+			if component == "VEVENT" && len(stack) == 2 {
+				event = &ICalEvent{}
+				duration = nil
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, value)
+			}
+			stack = stack[:len(stack)-1]
+			if event != nil && len(stack) == 1 {
+				if err := finishICalEvent(event, duration); err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				events = append(events, *event)
+				event = nil
+			}
+			continue
+		}
+
+		// Only properties of the event itself matter
+		if event == nil || len(stack) != 2 {
+			continue
+		}
+		switch name {
+		case "UID":
+			event.UID = unescapeICalText(value)
+		case "SUMMARY":
+			event.Summary = unescapeICalText(value)
+		case "DESCRIPTION":
+			event.Description = unescapeICalText(value)
+		case "STATUS":
+			event.Status = strings.ToUpper(value)
+		case "TRANSP":
+			event.Transparent = strings.EqualFold(value, "TRANSPARENT")
+		case "SEQUENCE":
+			event.Sequence, _ = strconv.Atoi(value)
+		case "RRULE":
+			event.RRule = value
+		case "DTSTAMP":
+			event.Stamp, _, _ = parseICalTime(value, params, loc)
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseICalTime(value, params, loc)
+		case "DTEND":
+			event.End, _, err = parseICalTime(value, params, loc)
+		case "DURATION":
+			var d time.Duration
+			d, err = parseICalDuration(value)
+			duration = &d
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				var exdate time.Time
+				if exdate, _, err = parseICalTime(v, params, loc); err != nil {
+					break
+				}
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n+1, name, err)
+		}
+	}
+
+	if !calendar {
+		return nil, errors.New("not an iCalendar file")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+	return events, nil
+}
+
+// Occurrences returns the event's occurrences overlapping from up to to, in start
+// order. Recurring events repeat at the same wall-clock time in their start's time
+// zone. Rules ParseRRule doesn't support return an error.
+func (e ICalEvent) Occurrences(from, to time.Time) ([]Occurrence, error) {
+	if e.RRule == "" {
+		if e.Start.Before(to) && e.End.After(from) {
+			return []Occurrence{{Start: e.Start, End: e.End}}, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := ParseRRule(e.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := e.Start.Location()
+	length := e.End.Sub(e.Start)
+	minutes := e.Start.Hour()*60 + e.Start.Minute()
+	// Occurrences starting up to a length earlier can still reach into the range
+	first := Date(from.Add(-length).In(loc))
+	last := Date(to.In(loc))
+
+	var occurrences []Occurrence
+	for _, date := range rule.Dates(Date(e.Start), first, last) {
+		start := At(date, minutes, loc)
+		if e.skips(start) {
+			continue
+		}
+		end := start.Add(length)
+		if start.Before(to) && end.After(from) {
+			occurrences = append(occurrences, Occurrence{Start: start, End: end})
+		}
+	}
+	return occurrences, nil
+}
+
+// skips reports whether an occurrence starting at start is excluded
+func (e ICalEvent) skips(start time.Time) bool {
+	for _, exdate := range e.ExDates {
+		if exdate.Equal(start) || (e.AllDay && Date(exdate).Equal(Date(start))) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishICalEvent checks an event has a start and works out its end when only a
+// duration, or neither, was given
+func finishICalEvent(event *ICalEvent, duration *time.Duration) error {
+	if event.Start.IsZero() {
+		return errors.New("event has no DTSTART")
+	}
+	if event.End.IsZero() {
+		switch {
+		case duration != nil:
+			event.End = event.Start.Add(*duration)
+		case event.AllDay:
+			event.End = event.Start.AddDate(0, 0, 1)
+		default:
+			event.End = event.Start
+		}
+	}
+	if event.End.Before(event.Start) {
+		return errors.New("event ends before it starts")
+	}
+	return nil
+}
+
+// unfoldICalLines reads content lines, joining folded continuation lines
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			lines[len(lines)-1] += text[1:]
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalLine splits a content line such as "DTSTART;TZID=Europe/Warsaw:20260302T090000"
+// into its upper-case name, parameters and value
+func parseICalLine(line string) (string, map[string]string, string, error) {
+	// The value starts at the first colon outside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+// parseICalTime reads a DATE or DATE-TIME value, in loc unless it is in UTC or
+// names its time zone. allDay is true for dates.
+func parseICalTime(value string, params map[string]string, loc *time.Location) (t time.Time, allDay bool, err error) {
+	if tzid := params["TZID"]; tzid != "" {
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+
+	switch {
+	case strings.EqualFold(params["VALUE"], "DATE") || len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, loc)
+		allDay = true
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse(icalUTCLayout, value)
+	default:
+		t, err = time.ParseInLocation(icalLocalLayout, value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+	}
+	return t, allDay, nil
+}
+
+// parseICalDuration reads a duration such as "PT1H30M", "P1D" or "P2W"
+func parseICalDuration(value string) (time.Duration, error) {
+	s := value
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+	}
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	timeUnits := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+
+	var total time.Duration
+	number := ""
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			units = timeUnits
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(number)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			total += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescapeICalText reverses escapeICalText
+func unescapeICalText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// foldICalLine splits a content line into lines of at most 75 octets, never
+// inside a UTF-8 sequence. Continuation lines start with a space.
+func foldICalLine(s string) string {
+	if len(s) <= maxICalLine {
+		return s
+	}
+
+	var b strings.Builder
+	limit := maxICalLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines lose an octet to the leading space
+		limit = maxICalLine - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,175 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func TestWriteICalendar(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	calendar := utils.WriteICalendar("Sessions", nil, []utils.ICalEvent{{
+		UID:      "schedule-1@skillswap",
+		Summary:  "Teaching: Go, the language; " + strings.Repeat("long ", 20),
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Status:   utils.ICalCancelled,
+		Sequence: 3,
+		Stamp:    start,
+	}})
+
+	for _, want := range []string{
+		"UID:schedule-1@skillswap\r\n",
+		"DTSTART:20260302T090000Z\r\n",
+		"SEQUENCE:3\r\n",
+		"STATUS:CANCELLED\r\n",
+		`SUMMARY:Teaching: Go\, the language\; long`,
+	} {
+		if !strings.Contains(calendar, want) {
+			t.Errorf("Expected calendar to contain %q:\n%s", want, calendar)
+		}
+	}
+	for _, line := range strings.Split(calendar, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines to be folded at 75 octets, got %d: %q", len(line), line)
+		}
+	}
+
+	// What is written reads back the same
+	events, err := utils.ParseICalendar(strings.NewReader(calendar), time.UTC)
+	if err != nil {
+		t.Fatalf("Expected calendar to parse, got %v", err)
+	}
+	if len(events) != 1 || !strings.HasPrefix(events[0].Summary, "Teaching: Go, the language; long") || !events[0].Start.Equal(start) {
+		t.Errorf("Unexpected events %+v", events)
+	}
+}
+
+func TestWriteICalendarInTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	events := []utils.ICalEvent{
+		{UID: "before", Start: start, End: start.Add(time.Hour), Stamp: start},
+		{UID: "after", Start: start.AddDate(0, 0, 14), End: start.AddDate(0, 0, 14).Add(time.Hour), Stamp: start},
+	}
+	calendar := utils.WriteICalendar("Sessions", loc, events)
+
+	for _, want := range []string{
+		"X-WR-TIMEZONE:America/New_York\r\n",
+		"TZID:America/New_York\r\n",
+		// Daylight saving time starts at 02:00 local time on 2026-03-08
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260308T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\n",
+		"DTSTART;TZID=America/New_York:20260302T090000\r\n",
+		"DTSTART;TZID=America/New_York:20260316T100000\r\n",
+		"DTSTAMP:20260302T140000Z\r\n",
+	} {
+		if !strings.Contains(calendar, want) {
+			t.Errorf("Expected calendar to contain %q:\n%s", want, calendar)
+		}
+	}
+
+	parsed, err := utils.ParseICalendar(strings.NewReader(calendar), time.UTC)
+	if err != nil {
+		t.Fatalf("Expected calendar to parse, got %v", err)
+	}
+	for i := range events {
+		if !parsed[i].Start.Equal(events[i].Start) || !parsed[i].End.Equal(events[i].End) {
+			t.Errorf("Expected %v to %v, got %v to %v", events[i].Start, events[i].End, parsed[i].Start, parsed[i].End)
+		}
+	}
+}
+
+func TestParseICalendarFloatingTimes(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20260302T090000\r\nDTEND:20260302T100000\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	events, err := utils.ParseICalendar(strings.NewReader(data), loc)
+	if err != nil {
+		t.Fatalf("Expected calendar to parse, got %v", err)
+	}
+	if want := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC); !events[0].Start.Equal(want) {
+		t.Errorf("Expected floating times in the owner's zone, got %v", events[0].Start.UTC())
+	}
+}
+
+func TestParseICalendar(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"SUMMARY:Standup",
+		"DTSTART;TZID=America/New_York:20260302T090000",
+		"DURATION:PT30M",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		"EXDATE;TZID=America/New_York:20260316T090000",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday",
+		"DTSTART;VALUE=DATE:20260305",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := utils.ParseICalendar(strings.NewReader(data), time.UTC)
+	if err != nil {
+		t.Fatalf("Expected calendar to parse, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if !events[1].AllDay || !events[1].Transparent || events[1].End.Sub(events[1].Start) != 24*time.Hour {
+		t.Errorf("Expected a free all-day event, got %+v", events[1])
+	}
+
+	occurrences, err := events[0].Occurrences(
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 24, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected occurrences, got %v", err)
+	}
+	// 2026-03-16 is excluded, and New York moves to daylight saving time on 2026-03-08
+	expected := []time.Time{
+		time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 9, 13, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 23, 13, 0, 0, 0, time.UTC),
+	}
+	if len(occurrences) != len(expected) {
+		t.Fatalf("Expected %d occurrences, got %v", len(expected), occurrences)
+	}
+	for i, o := range occurrences {
+		if !o.Start.Equal(expected[i]) || o.End.Sub(o.Start) != 30*time.Minute {
+			t.Errorf("Expected occurrence at %v, got %v to %v", expected[i], o.Start.UTC(), o.End.UTC())
+		}
+	}
+}
+
+func TestParseICalendarErrors(t *testing.T) {
+	invalid := map[string]string{
+		"Not A Calendar":    "hello",
+		"Missing End":       "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20260302T090000Z\r\nEND:VEVENT",
+		"Missing Start":     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\nEND:VCALENDAR",
+		"Invalid Time":      "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:tomorrow\r\nEND:VEVENT\r\nEND:VCALENDAR",
+		"Unknown Time Zone": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;TZID=Mars/Olympus:20260302T090000\r\nEND:VEVENT\r\nEND:VCALENDAR",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := utils.ParseICalendar(strings.NewReader(data), time.UTC)
+			if _, ok := err.(*utils.ICalError); !ok {
+				t.Errorf("Expected an ICalError, got %v", err)
+			}
+		})
+	}
+}
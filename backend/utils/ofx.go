@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Layout of OFX date-times, written in UTC such as "20260302090000.000[0:UTC]"
+const ofxLayout = "20060102150405.000[0:UTC]"
+
+// maxOFXName is the longest payee name OFX allows, in characters
+const maxOFXName = 32
+
+// OFXStatement is an account statement in the subset of OFX 2.2 used for
+// SkillPoints exports. SkillPoints have no ISO 4217 code, so amounts are in
+// XXX, the code for no currency.
+type OFXStatement struct {
+	BankID       string
+	AccountID    string
+	Start        time.Time
+	End          time.Time
+	Transactions []OFXTransaction
+	Balance      int
+	BalanceAt    time.Time
+}
+
+// OFXTransaction is a STMTTRN: points added to the account, or taken out when negative
+type OFXTransaction struct {
+	ID     string
+	Posted time.Time
+	Amount int
+	Name   string
+	Memo   string
+}
+
+// ofxStatus is the success status of a response
+var ofxStatus = &ofxStatusElement{Code: 0, Severity: "INFO"}
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   *ofxStatusElement `xml:"STATUS"`
+			Server   string            `xml:"DTSERVER"`
+			Language string            `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Response struct {
+			UID       string            `xml:"TRNUID"`
+			Status    *ofxStatusElement `xml:"STATUS"`
+			Statement struct {
+				Currency string `xml:"CURDEF"`
+				Account  struct {
+					BankID string `xml:"BANKID"`
+					ID     string `xml:"ACCTID"`
+					Type   string `xml:"ACCTTYPE"`
+				} `xml:"BANKACCTFROM"`
+				List struct {
+					Start        string                `xml:"DTSTART"`
+					End          string                `xml:"DTEND"`
+					Transactions []ofxTransactionEntry `xml:"STMTTRN"`
+				} `xml:"BANKTRANLIST"`
+				Balance struct {
+					Amount string `xml:"BALAMT"`
+					At     string `xml:"DTASOF"`
+				} `xml:"LEDGERBAL"`
+			} `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatusElement struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransactionEntry struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	ID     string `xml:"FITID"`
+	Name   string `xml:"NAME,omitempty"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+// WriteOFX writes a statement as an OFX 2.2 document, generated at now
+func WriteOFX(w io.Writer, statement OFXStatement, now time.Time) error {
+	var doc ofxDocument
+	doc.SignOn.Response.Status = ofxStatus
+	doc.SignOn.Response.Server = formatOFXTime(now)
+	doc.SignOn.Response.Language = "ENG"
+
+	response := &doc.Bank.Response
+	response.UID = "0"
+	response.Status = ofxStatus
+	response.Statement.Currency = "XXX"
+	response.Statement.Account.BankID = statement.BankID
+	response.Statement.Account.ID = statement.AccountID
+	response.Statement.Account.Type = "CHECKING"
+	response.Statement.List.Start = formatOFXTime(statement.Start)
+	response.Statement.List.End = formatOFXTime(statement.End)
+	for _, tx := range statement.Transactions {
+		kind := "CREDIT"
+		if tx.Amount < 0 {
+			kind = "DEBIT"
+		}
+		response.Statement.List.Transactions = append(response.Statement.List.Transactions, ofxTransactionEntry{
+			Type:   kind,
+			Posted: formatOFXTime(tx.Posted),
+			Amount: strconv.Itoa(tx.Amount),
+			ID:     tx.ID,
+			Name:   truncateOFXName(tx.Name),
+			Memo:   tx.Memo,
+		})
+	}
+	response.Statement.Balance.Amount = strconv.Itoa(statement.Balance)
+	response.Statement.Balance.At = formatOFXTime(statement.BalanceAt)
+
+	if _, err := io.WriteString(w, xml.Header+
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// formatOFXTime formats t in UTC
+func formatOFXTime(t time.Time) string {
+	return t.UTC().Format(ofxLayout)
+}
+
+// truncateOFXName shortens a payee name to the characters OFX allows
+func truncateOFXName(name string) string {
+	if utf8.RuneCountInString(name) <= maxOFXName {
+		return name
+	}
+	return string([]rune(name)[:maxOFXName])
+}
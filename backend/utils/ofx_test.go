@@ -0,0 +1,58 @@
+package utils_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func TestWriteOFX(t *testing.T) {
+	posted := time.Date(2026, 3, 2, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	statement := utils.OFXStatement{
+		BankID:    "SKILLSWAP",
+		AccountID: "7",
+		Start:     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:       time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Transactions: []utils.OFXTransaction{
+			{ID: "12", Posted: posted, Amount: -15, Name: "A very long name of a guitar teacher", Memo: "Lessons <3 & more"},
+			{ID: "11", Posted: posted.Add(-time.Hour), Amount: 40, Name: "Bob"},
+		},
+		Balance:   125,
+		BalanceAt: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var b strings.Builder
+	if err := utils.WriteOFX(&b, statement, statement.BalanceAt); err != nil {
+		t.Fatalf("WriteOFX failed: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`<?OFX OFXHEADER="200" VERSION="220"`,
+		"<CURDEF>XXX</CURDEF>",
+		"<ACCTID>7</ACCTID>",
+		"<DTSTART>20260301000000.000[0:UTC]</DTSTART>",
+		"<TRNTYPE>DEBIT</TRNTYPE>",
+		"<DTPOSTED>20260302083000.000[0:UTC]</DTPOSTED>",
+		"<TRNAMT>-15</TRNAMT>",
+		"<NAME>A very long name of a guitar tea</NAME>",
+		"<MEMO>Lessons &lt;3 &amp; more</MEMO>",
+		"<TRNTYPE>CREDIT</TRNTYPE>",
+		"<BALAMT>125</BALAMT>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the statement to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "<STMTTRN>") != 2 {
+		t.Errorf("Expected 2 transactions:\n%s", out)
+	}
+
+	body := out[strings.Index(out, "<OFX>"):]
+	if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+		t.Errorf("Expected well-formed XML, got %v", err)
+	}
+}
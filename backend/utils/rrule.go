@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of calendar dates such as "2026-03-08"
+const DateLayout = "2006-01-02"
+
+// Recurrence frequencies supported by RRule
+const (
+	FreqDaily  = "DAILY"
+	FreqWeekly = "WEEKLY"
+)
+
+// rruleDays maps RFC 5545 day codes to weekdays
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// RRule is a recurrence rule in the subset of RFC 5545 used for availability:
+// FREQ=DAILY or WEEKLY, with INTERVAL, BYDAY, COUNT and UNTIL.
+// Rules recur over calendar dates, so the wall-clock time they are paired
+// with stays the same across daylight saving changes.
+type RRule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday // Weekly rules default to the weekday of the first date
+	Count    int            // 0 means no limit
+	Until    time.Time      // Last date, inclusive; zero means no limit
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20261231".
+// An "RRULE:" prefix is allowed. UNTIL is read as a date.
+func ParseRRule(s string) (RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	rule := RRule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return RRule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if rule.Freq != FreqDaily && rule.Freq != FreqWeekly {
+				return RRule{}, fmt.Errorf("unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return RRule{}, fmt.Errorf("invalid interval %q", value)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return RRule{}, fmt.Errorf("invalid count %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			if len(value) < 8 {
+				return RRule{}, fmt.Errorf("invalid until %q", value)
+			}
+			until, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return RRule{}, fmt.Errorf("invalid until %q", value)
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := rruleDays[strings.ToUpper(strings.TrimSpace(code))]
+				if !ok {
+					return RRule{}, fmt.Errorf("invalid day %q", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return RRule{}, fmt.Errorf("unsupported rule part %q", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return RRule{}, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return RRule{}, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	return rule, nil
+}
+
+// String formats the rule in RFC 5545 syntax
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Dates returns the dates from first to last, inclusive, the rule recurs on when
+// it starts at start. Dates are calendar dates at midnight UTC; COUNT is counted
+// from start, so occurrences before first still use it up.
+func (r RRule) Dates(start, first, last time.Time) []time.Time {
+	start, first, last = Date(start), Date(first), Date(last)
+	if !r.Until.IsZero() && r.Until.Before(last) {
+		last = r.Until
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	days := map[time.Weekday]bool{}
+	for _, day := range r.ByDay {
+		days[day] = true
+	}
+	if len(days) == 0 {
+		days[start.Weekday()] = true
+	}
+
+	var dates []time.Time
+	count := 0
+	for d := start; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if !r.matches(start, d, interval, days) {
+			continue
+		}
+		count++
+		if r.Count > 0 && count > r.Count {
+			break
+		}
+		if !d.Before(first) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// matches reports whether the rule recurs on d
+func (r RRule) matches(start, d time.Time, interval int, days map[time.Weekday]bool) bool {
+	elapsed := int(d.Sub(start).Hours() / 24)
+	if r.Freq == FreqDaily {
+		return elapsed%interval == 0 && (len(r.ByDay) == 0 || days[d.Weekday()])
+	}
+	if !days[d.Weekday()] {
+		return false
+	}
+	weeks := int(weekStart(d).Sub(weekStart(start)).Hours() / (24 * 7))
+	return weeks%interval == 0
+}
+
+// weekStart returns the Monday of d's week
+func weekStart(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// Date returns the calendar date of t at midnight UTC
+func Date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// At returns the instant a calendar date shows the wall-clock time minutes
+// after midnight in loc. Building it from the wall clock, rather than adding
+// minutes to midnight, keeps it right on days with a daylight saving change.
+// Times skipped by the change are moved forward by its length.
+func At(date time.Time, minutes int, loc *time.Location) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, minutes/60, minutes%60, 0, 0, loc)
+}
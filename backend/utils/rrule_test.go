@@ -0,0 +1,107 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse(utils.DateLayout, s)
+	return d
+}
+
+func formatDates(dates []time.Time) []string {
+	formatted := make([]string, len(dates))
+	for i, d := range dates {
+		formatted[i] = d.Format(utils.DateLayout)
+	}
+	return formatted
+}
+
+func TestParseRRule(t *testing.T) {
+	rule, err := utils.ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20261231")
+	if err != nil {
+		t.Fatalf("Expected rule to parse, got %v", err)
+	}
+	if rule.Freq != utils.FreqWeekly || rule.Interval != 2 || len(rule.ByDay) != 2 {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+	if !rule.Until.Equal(date("2026-12-31")) {
+		t.Errorf("Expected until 2026-12-31, got %v", rule.Until)
+	}
+
+	invalid := []string{
+		"",
+		"FREQ=MONTHLY",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ",
+	}
+	for _, s := range invalid {
+		if _, err := utils.ParseRRule(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestRRuleDates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     string
+		start    string
+		first    string
+		last     string
+		expected []string
+	}{
+		{
+			"Weekly Defaults To Start Weekday", "FREQ=WEEKLY", "2026-03-02", "2026-03-01", "2026-03-20",
+			[]string{"2026-03-02", "2026-03-09", "2026-03-16"},
+		},
+		{
+			"Every Other Week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2026-03-02", "2026-03-01", "2026-03-20",
+			[]string{"2026-03-02", "2026-03-06", "2026-03-16", "2026-03-20"},
+		},
+		{
+			"Count From Start", "FREQ=DAILY;COUNT=3", "2026-03-02", "2026-03-03", "2026-03-20",
+			[]string{"2026-03-03", "2026-03-04"},
+		},
+		{
+			"Until Inclusive", "FREQ=DAILY;UNTIL=20260304", "2026-03-02", "2026-03-01", "2026-03-20",
+			[]string{"2026-03-02", "2026-03-03", "2026-03-04"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := utils.ParseRRule(tc.rule)
+			if err != nil {
+				t.Fatalf("Expected rule to parse, got %v", err)
+			}
+			got := formatDates(rule.Dates(date(tc.start), date(tc.first), date(tc.last)))
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("Expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAtKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	before := utils.At(date("2026-03-07"), 9*60, loc)
+	after := utils.At(date("2026-03-09"), 9*60, loc)
+	if before.UTC().Hour() != 14 || after.UTC().Hour() != 13 {
+		t.Errorf("Expected 14:00Z and 13:00Z, got %v and %v", before.UTC(), after.UTC())
+	}
+}
@@ -0,0 +1,254 @@
+package utils
+
+import "strings"
+
+// stopWords are common English words left out of search indexes
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "to": true, "was": true, "with": true,
+}
+
+// Tokenize splits text into lowercased, stemmed search terms without stop words
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range words(text) {
+		if stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, Stem(word))
+	}
+	return tokens
+}
+
+// Stem reduces an English word to its stem using the Porter algorithm,
+// so that "teaching", "teaches" and "teach" share one index term.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			// Only plain ASCII words are stemmed
+			return word
+		}
+	}
+
+	w := []byte(word)
+	w = stemStep1a(w)
+	w = stemStep1b(w)
+	w = stemStep1c(w)
+	w = stemStep2(w)
+	w = stemStep3(w)
+	w = stemStep4(w)
+	w = stemStep5(w)
+	return string(w)
+}
+
+// isConsonant reports whether w[i] is a consonant in the Porter sense
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w
+func measure(w []byte) int {
+	n, i := 0, 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+// hasVowel reports whether w contains a vowel
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsDoubleConsonant reports whether w ends in a double consonant
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant, where the last is not w, x or y
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	return w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'y'
+}
+
+// replaceSuffix swaps suffix for replacement when the remaining stem has a measure above minMeasure.
+// It reports whether w ended with suffix, whether or not it was replaced.
+func replaceSuffix(w []byte, suffix, replacement string, minMeasure int) ([]byte, bool) {
+	if !strings.HasSuffix(string(w), suffix) {
+		return w, false
+	}
+	stem := w[:len(w)-len(suffix)]
+	if measure(stem) > minMeasure {
+		return append(stem[:len(stem):len(stem)], replacement...), true
+	}
+	return w, true
+}
+
+func stemStep1a(w []byte) []byte {
+	s := string(w)
+	switch {
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(s, "ss"):
+		return w
+	case strings.HasSuffix(s, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func stemStep1b(w []byte) []byte {
+	s := string(w)
+	if strings.HasSuffix(s, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case strings.HasSuffix(s, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case strings.HasSuffix(s, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	t := string(stem)
+	switch {
+	case strings.HasSuffix(t, "at"), strings.HasSuffix(t, "bl"), strings.HasSuffix(t, "iz"):
+		return append(stem[:len(stem):len(stem)], 'e')
+	case endsDoubleConsonant(stem):
+		last := stem[len(stem)-1]
+		if last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem[:len(stem):len(stem)], 'e')
+	}
+	return stem
+}
+
+func stemStep1c(w []byte) []byte {
+	n := len(w)
+	if w[n-1] == 'y' && hasVowel(w[:n-1]) {
+		out := append(w[:n-1:n-1], 'i')
+		return out
+	}
+	return w
+}
+
+// step2Suffixes map double suffixes to single ones, checked in order
+var step2Suffixes = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func stemStep2(w []byte) []byte {
+	for _, pair := range step2Suffixes {
+		if out, matched := replaceSuffix(w, pair[0], pair[1], 0); matched {
+			return out
+		}
+	}
+	return w
+}
+
+// step3Suffixes map suffixes to simpler ones, checked in order
+var step3Suffixes = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func stemStep3(w []byte) []byte {
+	for _, pair := range step3Suffixes {
+		if out, matched := replaceSuffix(w, pair[0], pair[1], 0); matched {
+			return out
+		}
+	}
+	return w
+}
+
+// step4Suffixes are removed when the stem has a measure above 1
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func stemStep4(w []byte) []byte {
+	s := string(w)
+	// Longest suffix first so "ement" wins over "ment" and "ent"
+	best := ""
+	for _, suffix := range step4Suffixes {
+		if strings.HasSuffix(s, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return w
+	}
+
+	stem := w[:len(w)-len(best)]
+	if measure(stem) <= 1 {
+		return w
+	}
+	if best == "ion" {
+		if len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't') {
+			return w
+		}
+	}
+	return stem
+}
+
+func stemStep5(w []byte) []byte {
+	n := len(w)
+	if w[n-1] == 'e' {
+		stem := w[:n-1]
+		m := measure(stem)
+		if m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+
+	n = len(w)
+	if n > 1 && w[n-1] == 'l' && endsDoubleConsonant(w) && measure(w) > 1 {
+		w = w[:n-1]
+	}
+	return w
+}
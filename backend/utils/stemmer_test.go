@@ -0,0 +1,43 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mplaczek99/SkillSwap/utils"
+)
+
+func TestStem(t *testing.T) {
+	testCases := map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"cats":        "cat",
+		"agreed":      "agre",
+		"plastered":   "plaster",
+		"motoring":    "motor",
+		"hopping":     "hop",
+		"filing":      "file",
+		"happy":       "happi",
+		"relational":  "relat",
+		"hopefulness": "hope",
+		"adjustment":  "adjust",
+		"teaching":    "teach",
+		"teaches":     "teach",
+		"programming": "program",
+		"go":          "go",
+	}
+
+	for word, want := range testCases {
+		if got := utils.Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := utils.Tokenize("Teaching the Guitar to Beginners")
+	want := []string{"teach", "guitar", "beginn"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -13,38 +13,40 @@ describe("Transactions.vue", () => {
   beforeEach(() => {
     // Mock axios response for /api/transactions
     axios.get.mockResolvedValue({
-      data: [
-        {
-          id: 1,
-          senderId: 2,
-          receiverId: 1,
-          amount: 15,
-          createdAt: new Date(Date.now() - 86400000), // 1 day ago
-          senderName: "Alice Smith",
-          receiverName: "Test User",
-          note: "For JavaScript tutoring",
-        },
-        {
-          id: 2,
-          senderId: 1,
-          receiverId: 3,
-          amount: 5,
-          createdAt: new Date(Date.now() - 172800000), // 2 days ago
-          senderName: "Test User",
-          receiverName: "Bob Johnson",
-          note: "For cooking lessons",
-        },
-        {
-          id: 3,
-          senderId: 4,
-          receiverId: 1,
-          amount: 10,
-          createdAt: new Date(Date.now() - 259200000), // 3 days ago
-          senderName: "Carol Williams",
-          receiverName: "Test User",
-          note: "For guitar lessons",
-        },
-      ],
+      data: {
+        transactions: [
+          {
+            id: 1,
+            senderId: 2,
+            receiverId: 1,
+            amount: 15,
+            createdAt: new Date(Date.now() - 86400000), // 1 day ago
+            senderName: "Alice Smith",
+            receiverName: "Test User",
+            note: "For JavaScript tutoring",
+          },
+          {
+            id: 2,
+            senderId: 1,
+            receiverId: 3,
+            amount: 5,
+            createdAt: new Date(Date.now() - 172800000), // 2 days ago
+            senderName: "Test User",
+            receiverName: "Bob Johnson",
+            note: "For cooking lessons",
+          },
+          {
+            id: 3,
+            senderId: 4,
+            receiverId: 1,
+            amount: 10,
+            createdAt: new Date(Date.now() - 259200000), // 3 days ago
+            senderName: "Carol Williams",
+            receiverName: "Test User",
+            note: "For guitar lessons",
+          },
+        ],
+      },
     });
 
     // Create a mock store